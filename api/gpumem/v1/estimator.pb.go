@@ -0,0 +1,1075 @@
+// Schema of the gRPC API served by `gpu-mem-for-llm serve --grpc-addr`, for generating
+// typed clients. The messages mirror the JSON of the HTTP API: the fields are named after
+// the flags of the main command, as in POST /estimate.
+//
+// Fields are only ever added to this version; a change that breaks clients goes into v2.
+// After editing, regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: api/gpumem/v1/estimator.proto
+
+package gpumemv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// EstimateRequest describes a model, named after the flags of the main command. Exactly
+// one of precision, quant and bytes_per_param is required, and at most one of gpu and
+// gpu_memory.
+type EstimateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Parameter size, such as "7b", "1.5b" or "1.8t".
+	Size string `protobuf:"bytes,1,opt,name=size,proto3" json:"size,omitempty"`
+	// Precision of the weights by name, such as "fp16", "int4" or "Q4_K_M".
+	Precision string `protobuf:"bytes,2,opt,name=precision,proto3" json:"precision,omitempty"`
+	// llama.cpp quantization scheme, such as "Q4_K_M".
+	Quant string `protobuf:"bytes,3,opt,name=quant,proto3" json:"quant,omitempty"`
+	// Custom bytes per parameter, such as 0.75 for fp6.
+	BytesPerParam float64 `protobuf:"fixed64,4,opt,name=bytes_per_param,json=bytesPerParam,proto3" json:"bytes_per_param,omitempty"`
+	// Overhead as a percentage, 20 when left out.
+	Overhead         *int32 `protobuf:"varint,5,opt,name=overhead,proto3,oneof" json:"overhead,omitempty"`
+	NumLayers        int32  `protobuf:"varint,6,opt,name=num_layers,json=numLayers,proto3" json:"num_layers,omitempty"`
+	HiddenDim        int32  `protobuf:"varint,7,opt,name=hidden_dim,json=hiddenDim,proto3" json:"hidden_dim,omitempty"`
+	VocabSize        int32  `protobuf:"varint,8,opt,name=vocab_size,json=vocabSize,proto3" json:"vocab_size,omitempty"`
+	IntermediateSize int32  `protobuf:"varint,9,opt,name=intermediate_size,json=intermediateSize,proto3" json:"intermediate_size,omitempty"`
+	TiedEmbeddings   bool   `protobuf:"varint,10,opt,name=tied_embeddings,json=tiedEmbeddings,proto3" json:"tied_embeddings,omitempty"`
+	HeadDim          int32  `protobuf:"varint,11,opt,name=head_dim,json=headDim,proto3" json:"head_dim,omitempty"`
+	KvHeads          int32  `protobuf:"varint,12,opt,name=kv_heads,json=kvHeads,proto3" json:"kv_heads,omitempty"`
+	// Context length in tokens to size the KV cache for; requires num_layers and hidden_dim.
+	Context       int32 `protobuf:"varint,13,opt,name=context,proto3" json:"context,omitempty"`
+	SlidingWindow int32 `protobuf:"varint,14,opt,name=sliding_window,json=slidingWindow,proto3" json:"sliding_window,omitempty"`
+	// Sequences held in the KV cache at once, 1 when left out.
+	BatchSize int32 `protobuf:"varint,15,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	// Precision or quantized type of the KV cache, such as "fp8" or "q4_0".
+	KvDtype string `protobuf:"bytes,16,opt,name=kv_dtype,json=kvDtype,proto3" json:"kv_dtype,omitempty"`
+	// GPUs to shard the model across with tensor parallelism, 1 when left out.
+	TensorParallel int32 `protobuf:"varint,17,opt,name=tensor_parallel,json=tensorParallel,proto3" json:"tensor_parallel,omitempty"`
+	// "inference", the default, or "train".
+	Mode string `protobuf:"bytes,18,opt,name=mode,proto3" json:"mode,omitempty"`
+	// GPU of the database to check the fit against, such as "h100".
+	Gpu string `protobuf:"bytes,19,opt,name=gpu,proto3" json:"gpu,omitempty"`
+	// Memory of each GPU to check the fit against, such as "80gb".
+	GpuMemory string `protobuf:"bytes,20,opt,name=gpu_memory,json=gpuMemory,proto3" json:"gpu_memory,omitempty"`
+}
+
+func (x *EstimateRequest) Reset() {
+	*x = EstimateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EstimateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EstimateRequest) ProtoMessage() {}
+
+func (x *EstimateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EstimateRequest.ProtoReflect.Descriptor instead.
+func (*EstimateRequest) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EstimateRequest) GetSize() string {
+	if x != nil {
+		return x.Size
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetPrecision() string {
+	if x != nil {
+		return x.Precision
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetQuant() string {
+	if x != nil {
+		return x.Quant
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetBytesPerParam() float64 {
+	if x != nil {
+		return x.BytesPerParam
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetOverhead() int32 {
+	if x != nil && x.Overhead != nil {
+		return *x.Overhead
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetNumLayers() int32 {
+	if x != nil {
+		return x.NumLayers
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetHiddenDim() int32 {
+	if x != nil {
+		return x.HiddenDim
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetVocabSize() int32 {
+	if x != nil {
+		return x.VocabSize
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetIntermediateSize() int32 {
+	if x != nil {
+		return x.IntermediateSize
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetTiedEmbeddings() bool {
+	if x != nil {
+		return x.TiedEmbeddings
+	}
+	return false
+}
+
+func (x *EstimateRequest) GetHeadDim() int32 {
+	if x != nil {
+		return x.HeadDim
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetKvHeads() int32 {
+	if x != nil {
+		return x.KvHeads
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetContext() int32 {
+	if x != nil {
+		return x.Context
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetSlidingWindow() int32 {
+	if x != nil {
+		return x.SlidingWindow
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetBatchSize() int32 {
+	if x != nil {
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetKvDtype() string {
+	if x != nil {
+		return x.KvDtype
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetTensorParallel() int32 {
+	if x != nil {
+		return x.TensorParallel
+	}
+	return 0
+}
+
+func (x *EstimateRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetGpu() string {
+	if x != nil {
+		return x.Gpu
+	}
+	return ""
+}
+
+func (x *EstimateRequest) GetGpuMemory() string {
+	if x != nil {
+		return x.GpuMemory
+	}
+	return ""
+}
+
+// Component is a single part of an estimate, such as the weights or the KV cache.
+type Component struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name    string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Bytes   int64   `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Percent float64 `protobuf:"fixed64,3,opt,name=percent,proto3" json:"percent,omitempty"`
+}
+
+func (x *Component) Reset() {
+	*x = Component{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Component) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Component) ProtoMessage() {}
+
+func (x *Component) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Component.ProtoReflect.Descriptor instead.
+func (*Component) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Component) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Component) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Component) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+// EstimateResponse is the estimate, the same as the output of --json.
+type EstimateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MemSize string `protobuf:"bytes,1,opt,name=mem_size,json=memSize,proto3" json:"mem_size,omitempty"`
+	// Set when the model is sharded across several GPUs.
+	MemSizePerGpu     string            `protobuf:"bytes,2,opt,name=mem_size_per_gpu,json=memSizePerGpu,proto3" json:"mem_size_per_gpu,omitempty"`
+	MemBytes          int64             `protobuf:"varint,3,opt,name=mem_bytes,json=memBytes,proto3" json:"mem_bytes,omitempty"`
+	MemBytesPerGpu    int64             `protobuf:"varint,4,opt,name=mem_bytes_per_gpu,json=memBytesPerGpu,proto3" json:"mem_bytes_per_gpu,omitempty"`
+	Parameters        int64             `protobuf:"varint,5,opt,name=parameters,proto3" json:"parameters,omitempty"`
+	Precision         string            `protobuf:"bytes,6,opt,name=precision,proto3" json:"precision,omitempty"`
+	BytesPerParameter float64           `protobuf:"fixed64,7,opt,name=bytes_per_parameter,json=bytesPerParameter,proto3" json:"bytes_per_parameter,omitempty"`
+	OverheadPercent   float64           `protobuf:"fixed64,8,opt,name=overhead_percent,json=overheadPercent,proto3" json:"overhead_percent,omitempty"`
+	Components        []*Component      `protobuf:"bytes,9,rep,name=components,proto3" json:"components,omitempty"`
+	Breakdown         map[string]string `protobuf:"bytes,10,rep,name=breakdown,proto3" json:"breakdown,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Gpu               string            `protobuf:"bytes,11,opt,name=gpu,proto3" json:"gpu,omitempty"`
+	// Set when the request gives gpu or gpu_memory.
+	Fits            *bool   `protobuf:"varint,12,opt,name=fits,proto3,oneof" json:"fits,omitempty"`
+	GpusRequired    int32   `protobuf:"varint,13,opt,name=gpus_required,json=gpusRequired,proto3" json:"gpus_required,omitempty"`
+	HeadroomBytes   int64   `protobuf:"varint,14,opt,name=headroom_bytes,json=headroomBytes,proto3" json:"headroom_bytes,omitempty"`
+	HeadroomPercent float64 `protobuf:"fixed64,15,opt,name=headroom_percent,json=headroomPercent,proto3" json:"headroom_percent,omitempty"`
+}
+
+func (x *EstimateResponse) Reset() {
+	*x = EstimateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EstimateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EstimateResponse) ProtoMessage() {}
+
+func (x *EstimateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EstimateResponse.ProtoReflect.Descriptor instead.
+func (*EstimateResponse) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EstimateResponse) GetMemSize() string {
+	if x != nil {
+		return x.MemSize
+	}
+	return ""
+}
+
+func (x *EstimateResponse) GetMemSizePerGpu() string {
+	if x != nil {
+		return x.MemSizePerGpu
+	}
+	return ""
+}
+
+func (x *EstimateResponse) GetMemBytes() int64 {
+	if x != nil {
+		return x.MemBytes
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetMemBytesPerGpu() int64 {
+	if x != nil {
+		return x.MemBytesPerGpu
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetParameters() int64 {
+	if x != nil {
+		return x.Parameters
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetPrecision() string {
+	if x != nil {
+		return x.Precision
+	}
+	return ""
+}
+
+func (x *EstimateResponse) GetBytesPerParameter() float64 {
+	if x != nil {
+		return x.BytesPerParameter
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetOverheadPercent() float64 {
+	if x != nil {
+		return x.OverheadPercent
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetComponents() []*Component {
+	if x != nil {
+		return x.Components
+	}
+	return nil
+}
+
+func (x *EstimateResponse) GetBreakdown() map[string]string {
+	if x != nil {
+		return x.Breakdown
+	}
+	return nil
+}
+
+func (x *EstimateResponse) GetGpu() string {
+	if x != nil {
+		return x.Gpu
+	}
+	return ""
+}
+
+func (x *EstimateResponse) GetFits() bool {
+	if x != nil && x.Fits != nil {
+		return *x.Fits
+	}
+	return false
+}
+
+func (x *EstimateResponse) GetGpusRequired() int32 {
+	if x != nil {
+		return x.GpusRequired
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetHeadroomBytes() int64 {
+	if x != nil {
+		return x.HeadroomBytes
+	}
+	return 0
+}
+
+func (x *EstimateResponse) GetHeadroomPercent() float64 {
+	if x != nil {
+		return x.HeadroomPercent
+	}
+	return 0
+}
+
+// FitRequest gives a memory budget and exactly one of precision, quant and
+// bytes_per_param.
+type FitRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Memory available, such as "24gb".
+	Vram          string  `protobuf:"bytes,1,opt,name=vram,proto3" json:"vram,omitempty"`
+	Precision     string  `protobuf:"bytes,2,opt,name=precision,proto3" json:"precision,omitempty"`
+	Quant         string  `protobuf:"bytes,3,opt,name=quant,proto3" json:"quant,omitempty"`
+	BytesPerParam float64 `protobuf:"fixed64,4,opt,name=bytes_per_param,json=bytesPerParam,proto3" json:"bytes_per_param,omitempty"`
+	// Overhead as a percentage, 20 when left out.
+	Overhead *int32 `protobuf:"varint,5,opt,name=overhead,proto3,oneof" json:"overhead,omitempty"`
+}
+
+func (x *FitRequest) Reset() {
+	*x = FitRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FitRequest) ProtoMessage() {}
+
+func (x *FitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FitRequest.ProtoReflect.Descriptor instead.
+func (*FitRequest) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *FitRequest) GetVram() string {
+	if x != nil {
+		return x.Vram
+	}
+	return ""
+}
+
+func (x *FitRequest) GetPrecision() string {
+	if x != nil {
+		return x.Precision
+	}
+	return ""
+}
+
+func (x *FitRequest) GetQuant() string {
+	if x != nil {
+		return x.Quant
+	}
+	return ""
+}
+
+func (x *FitRequest) GetBytesPerParam() float64 {
+	if x != nil {
+		return x.BytesPerParam
+	}
+	return 0
+}
+
+func (x *FitRequest) GetOverhead() int32 {
+	if x != nil && x.Overhead != nil {
+		return *x.Overhead
+	}
+	return 0
+}
+
+// FitResponse is the largest model that fits, the same as the output of fit --json.
+type FitResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Largest size in the notation of EstimateRequest.size, such as "13b".
+	MaxSize       string `protobuf:"bytes,1,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`
+	MaxParameters int64  `protobuf:"varint,2,opt,name=max_parameters,json=maxParameters,proto3" json:"max_parameters,omitempty"`
+}
+
+func (x *FitResponse) Reset() {
+	*x = FitResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FitResponse) ProtoMessage() {}
+
+func (x *FitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FitResponse.ProtoReflect.Descriptor instead.
+func (*FitResponse) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FitResponse) GetMaxSize() string {
+	if x != nil {
+		return x.MaxSize
+	}
+	return ""
+}
+
+func (x *FitResponse) GetMaxParameters() int64 {
+	if x != nil {
+		return x.MaxParameters
+	}
+	return 0
+}
+
+type ListGPUsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListGPUsRequest) Reset() {
+	*x = ListGPUsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListGPUsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGPUsRequest) ProtoMessage() {}
+
+func (x *ListGPUsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGPUsRequest.ProtoReflect.Descriptor instead.
+func (*ListGPUsRequest) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{5}
+}
+
+// GPU is a GPU of the database.
+type GPU struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name        string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Memory      string `protobuf:"bytes,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	MemoryBytes int64  `protobuf:"varint,3,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
+	// Memory bandwidth in GB/s.
+	Bandwidth float64 `protobuf:"fixed64,4,opt,name=bandwidth,proto3" json:"bandwidth,omitempty"`
+}
+
+func (x *GPU) Reset() {
+	*x = GPU{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GPU) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GPU) ProtoMessage() {}
+
+func (x *GPU) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GPU.ProtoReflect.Descriptor instead.
+func (*GPU) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GPU) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GPU) GetMemory() string {
+	if x != nil {
+		return x.Memory
+	}
+	return ""
+}
+
+func (x *GPU) GetMemoryBytes() int64 {
+	if x != nil {
+		return x.MemoryBytes
+	}
+	return 0
+}
+
+func (x *GPU) GetBandwidth() float64 {
+	if x != nil {
+		return x.Bandwidth
+	}
+	return 0
+}
+
+// ListGPUsResponse holds the GPUs, which GET /gpus returns as a bare JSON array.
+type ListGPUsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Gpus []*GPU `protobuf:"bytes,1,rep,name=gpus,proto3" json:"gpus,omitempty"`
+}
+
+func (x *ListGPUsResponse) Reset() {
+	*x = ListGPUsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_gpumem_v1_estimator_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListGPUsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGPUsResponse) ProtoMessage() {}
+
+func (x *ListGPUsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_gpumem_v1_estimator_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGPUsResponse.ProtoReflect.Descriptor instead.
+func (*ListGPUsResponse) Descriptor() ([]byte, []int) {
+	return file_api_gpumem_v1_estimator_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListGPUsResponse) GetGpus() []*GPU {
+	if x != nil {
+		return x.Gpus
+	}
+	return nil
+}
+
+var File_api_gpumem_v1_estimator_proto protoreflect.FileDescriptor
+
+var file_api_gpumem_v1_estimator_proto_rawDesc = []byte{
+	0x0a, 0x1d, 0x61, 0x70, 0x69, 0x2f, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2f, 0x76, 0x31, 0x2f,
+	0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x09, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x22, 0x81, 0x05, 0x0a, 0x0f, 0x45,
+	0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x69,
+	0x7a, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f,
+	0x70, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0d, 0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x12, 0x1f,
+	0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05,
+	0x48, 0x00, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x1d, 0x0a, 0x0a, 0x6e, 0x75, 0x6d, 0x5f, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x09, 0x6e, 0x75, 0x6d, 0x4c, 0x61, 0x79, 0x65, 0x72, 0x73, 0x12, 0x1d,
+	0x0a, 0x0a, 0x68, 0x69, 0x64, 0x64, 0x65, 0x6e, 0x5f, 0x64, 0x69, 0x6d, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x09, 0x68, 0x69, 0x64, 0x64, 0x65, 0x6e, 0x44, 0x69, 0x6d, 0x12, 0x1d, 0x0a,
+	0x0a, 0x76, 0x6f, 0x63, 0x61, 0x62, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x09, 0x76, 0x6f, 0x63, 0x61, 0x62, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x2b, 0x0a, 0x11,
+	0x69, 0x6e, 0x74, 0x65, 0x72, 0x6d, 0x65, 0x64, 0x69, 0x61, 0x74, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x10, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6d, 0x65,
+	0x64, 0x69, 0x61, 0x74, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x69, 0x65,
+	0x64, 0x5f, 0x65, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x0a, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0e, 0x74, 0x69, 0x65, 0x64, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69, 0x6e,
+	0x67, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x68, 0x65, 0x61, 0x64, 0x5f, 0x64, 0x69, 0x6d, 0x18, 0x0b,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x68, 0x65, 0x61, 0x64, 0x44, 0x69, 0x6d, 0x12, 0x19, 0x0a,
+	0x08, 0x6b, 0x76, 0x5f, 0x68, 0x65, 0x61, 0x64, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x07, 0x6b, 0x76, 0x48, 0x65, 0x61, 0x64, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74,
+	0x65, 0x78, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65,
+	0x78, 0x74, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x6c, 0x69, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x77, 0x69,
+	0x6e, 0x64, 0x6f, 0x77, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x73, 0x6c, 0x69, 0x64,
+	0x69, 0x6e, 0x67, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x1d, 0x0a, 0x0a, 0x62, 0x61, 0x74,
+	0x63, 0x68, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x62,
+	0x61, 0x74, 0x63, 0x68, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x6b, 0x76, 0x5f, 0x64,
+	0x74, 0x79, 0x70, 0x65, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6b, 0x76, 0x44, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x74, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x5f, 0x70, 0x61,
+	0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x74, 0x65,
+	0x6e, 0x73, 0x6f, 0x72, 0x50, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65, 0x6c, 0x12, 0x12, 0x0a, 0x04,
+	0x6d, 0x6f, 0x64, 0x65, 0x18, 0x12, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65,
+	0x12, 0x10, 0x0a, 0x03, 0x67, 0x70, 0x75, 0x18, 0x13, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x67,
+	0x70, 0x75, 0x12, 0x1d, 0x0a, 0x0a, 0x67, 0x70, 0x75, 0x5f, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79,
+	0x18, 0x14, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x67, 0x70, 0x75, 0x4d, 0x65, 0x6d, 0x6f, 0x72,
+	0x79, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x22, 0x4f,
+	0x0a, 0x09, 0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x07, 0x70, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x22,
+	0xa0, 0x05, 0x0a, 0x10, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x65, 0x6d, 0x5f, 0x73, 0x69, 0x7a, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x6d, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x27, 0x0a, 0x10, 0x6d, 0x65, 0x6d, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x5f, 0x70, 0x65, 0x72, 0x5f,
+	0x67, 0x70, 0x75, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6d, 0x65, 0x6d, 0x53, 0x69,
+	0x7a, 0x65, 0x50, 0x65, 0x72, 0x47, 0x70, 0x75, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x65, 0x6d, 0x5f,
+	0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x6d, 0x65, 0x6d,
+	0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x29, 0x0a, 0x11, 0x6d, 0x65, 0x6d, 0x5f, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x67, 0x70, 0x75, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0e, 0x6d, 0x65, 0x6d, 0x42, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x47, 0x70, 0x75,
+	0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73,
+	0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2e,
+	0x0a, 0x13, 0x62, 0x79, 0x74, 0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x72, 0x61,
+	0x6d, 0x65, 0x74, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x11, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x50, 0x65, 0x72, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x29,
+	0x0a, 0x10, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x5f, 0x70, 0x65, 0x72, 0x63, 0x65,
+	0x6e, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65,
+	0x61, 0x64, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x0a, 0x63, 0x6f, 0x6d,
+	0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x6f, 0x6e,
+	0x65, 0x6e, 0x74, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x73, 0x12,
+	0x48, 0x0a, 0x09, 0x62, 0x72, 0x65, 0x61, 0x6b, 0x64, 0x6f, 0x77, 0x6e, 0x18, 0x0a, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x2a, 0x2e, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x45,
+	0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x42, 0x72, 0x65, 0x61, 0x6b, 0x64, 0x6f, 0x77, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x09,
+	0x62, 0x72, 0x65, 0x61, 0x6b, 0x64, 0x6f, 0x77, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x70, 0x75,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x67, 0x70, 0x75, 0x12, 0x17, 0x0a, 0x04, 0x66,
+	0x69, 0x74, 0x73, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x04, 0x66, 0x69, 0x74,
+	0x73, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x0d, 0x67, 0x70, 0x75, 0x73, 0x5f, 0x72, 0x65, 0x71,
+	0x75, 0x69, 0x72, 0x65, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x67, 0x70, 0x75,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x68, 0x65, 0x61,
+	0x64, 0x72, 0x6f, 0x6f, 0x6d, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0d, 0x68, 0x65, 0x61, 0x64, 0x72, 0x6f, 0x6f, 0x6d, 0x42, 0x79, 0x74, 0x65, 0x73,
+	0x12, 0x29, 0x0a, 0x10, 0x68, 0x65, 0x61, 0x64, 0x72, 0x6f, 0x6f, 0x6d, 0x5f, 0x70, 0x65, 0x72,
+	0x63, 0x65, 0x6e, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x68, 0x65, 0x61, 0x64,
+	0x72, 0x6f, 0x6f, 0x6d, 0x50, 0x65, 0x72, 0x63, 0x65, 0x6e, 0x74, 0x1a, 0x3c, 0x0a, 0x0e, 0x42,
+	0x72, 0x65, 0x61, 0x6b, 0x64, 0x6f, 0x77, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x66, 0x69,
+	0x74, 0x73, 0x22, 0xaa, 0x01, 0x0a, 0x0a, 0x46, 0x69, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x72, 0x61, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x76, 0x72, 0x61, 0x6d, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x65, 0x63, 0x69, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x71, 0x75, 0x61, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x0f, 0x62, 0x79, 0x74,
+	0x65, 0x73, 0x5f, 0x70, 0x65, 0x72, 0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0d, 0x62, 0x79, 0x74, 0x65, 0x73, 0x50, 0x65, 0x72, 0x50, 0x61, 0x72, 0x61,
+	0x6d, 0x12, 0x1f, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x88,
+	0x01, 0x01, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x22,
+	0x4f, 0x0a, 0x0b, 0x46, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x19,
+	0x0a, 0x08, 0x6d, 0x61, 0x78, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x61, 0x78, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x6d, 0x61, 0x78,
+	0x5f, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0d, 0x6d, 0x61, 0x78, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x73,
+	0x22, 0x11, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x50, 0x55, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0x72, 0x0a, 0x03, 0x47, 0x50, 0x55, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x12, 0x21, 0x0a, 0x0c, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79,
+	0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x6d, 0x65,
+	0x6d, 0x6f, 0x72, 0x79, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x62, 0x61, 0x6e,
+	0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x62, 0x61,
+	0x6e, 0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x22, 0x36, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x47,
+	0x50, 0x55, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22, 0x0a, 0x04, 0x67,
+	0x70, 0x75, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x67, 0x70, 0x75, 0x6d,
+	0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x50, 0x55, 0x52, 0x04, 0x67, 0x70, 0x75, 0x73, 0x32,
+	0xd2, 0x01, 0x0a, 0x10, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x6f, 0x72, 0x53, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x12, 0x43, 0x0a, 0x08, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65,
+	0x12, 0x1a, 0x2e, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x73, 0x74,
+	0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67,
+	0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x45, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x34, 0x0a, 0x03, 0x46, 0x69, 0x74,
+	0x12, 0x15, 0x2e, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x74,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x69, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x43, 0x0a, 0x08, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x50, 0x55, 0x73, 0x12, 0x1a, 0x2e, 0x67, 0x70,
+	0x75, 0x6d, 0x65, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x50, 0x55, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d,
+	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x50, 0x55, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x42, 0x3b, 0x5a, 0x39, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x61, 0x73, 0x68, 0x70, 0x72, 0x61, 0x6f, 0x2f, 0x67, 0x70, 0x75, 0x2d, 0x6d,
+	0x65, 0x6d, 0x2d, 0x66, 0x6f, 0x72, 0x2d, 0x6c, 0x6c, 0x6d, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x67,
+	0x70, 0x75, 0x6d, 0x65, 0x6d, 0x2f, 0x76, 0x31, 0x3b, 0x67, 0x70, 0x75, 0x6d, 0x65, 0x6d, 0x76,
+	0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_gpumem_v1_estimator_proto_rawDescOnce sync.Once
+	file_api_gpumem_v1_estimator_proto_rawDescData = file_api_gpumem_v1_estimator_proto_rawDesc
+)
+
+func file_api_gpumem_v1_estimator_proto_rawDescGZIP() []byte {
+	file_api_gpumem_v1_estimator_proto_rawDescOnce.Do(func() {
+		file_api_gpumem_v1_estimator_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_gpumem_v1_estimator_proto_rawDescData)
+	})
+	return file_api_gpumem_v1_estimator_proto_rawDescData
+}
+
+var file_api_gpumem_v1_estimator_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_api_gpumem_v1_estimator_proto_goTypes = []any{
+	(*EstimateRequest)(nil),  // 0: gpumem.v1.EstimateRequest
+	(*Component)(nil),        // 1: gpumem.v1.Component
+	(*EstimateResponse)(nil), // 2: gpumem.v1.EstimateResponse
+	(*FitRequest)(nil),       // 3: gpumem.v1.FitRequest
+	(*FitResponse)(nil),      // 4: gpumem.v1.FitResponse
+	(*ListGPUsRequest)(nil),  // 5: gpumem.v1.ListGPUsRequest
+	(*GPU)(nil),              // 6: gpumem.v1.GPU
+	(*ListGPUsResponse)(nil), // 7: gpumem.v1.ListGPUsResponse
+	nil,                      // 8: gpumem.v1.EstimateResponse.BreakdownEntry
+}
+var file_api_gpumem_v1_estimator_proto_depIdxs = []int32{
+	1, // 0: gpumem.v1.EstimateResponse.components:type_name -> gpumem.v1.Component
+	8, // 1: gpumem.v1.EstimateResponse.breakdown:type_name -> gpumem.v1.EstimateResponse.BreakdownEntry
+	6, // 2: gpumem.v1.ListGPUsResponse.gpus:type_name -> gpumem.v1.GPU
+	0, // 3: gpumem.v1.EstimatorService.Estimate:input_type -> gpumem.v1.EstimateRequest
+	3, // 4: gpumem.v1.EstimatorService.Fit:input_type -> gpumem.v1.FitRequest
+	5, // 5: gpumem.v1.EstimatorService.ListGPUs:input_type -> gpumem.v1.ListGPUsRequest
+	2, // 6: gpumem.v1.EstimatorService.Estimate:output_type -> gpumem.v1.EstimateResponse
+	4, // 7: gpumem.v1.EstimatorService.Fit:output_type -> gpumem.v1.FitResponse
+	7, // 8: gpumem.v1.EstimatorService.ListGPUs:output_type -> gpumem.v1.ListGPUsResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_api_gpumem_v1_estimator_proto_init() }
+func file_api_gpumem_v1_estimator_proto_init() {
+	if File_api_gpumem_v1_estimator_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_gpumem_v1_estimator_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*EstimateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*Component); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*EstimateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*FitRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*FitResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*ListGPUsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*GPU); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_gpumem_v1_estimator_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*ListGPUsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_api_gpumem_v1_estimator_proto_msgTypes[0].OneofWrappers = []any{}
+	file_api_gpumem_v1_estimator_proto_msgTypes[2].OneofWrappers = []any{}
+	file_api_gpumem_v1_estimator_proto_msgTypes[3].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_api_gpumem_v1_estimator_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_gpumem_v1_estimator_proto_goTypes,
+		DependencyIndexes: file_api_gpumem_v1_estimator_proto_depIdxs,
+		MessageInfos:      file_api_gpumem_v1_estimator_proto_msgTypes,
+	}.Build()
+	File_api_gpumem_v1_estimator_proto = out.File
+	file_api_gpumem_v1_estimator_proto_rawDesc = nil
+	file_api_gpumem_v1_estimator_proto_goTypes = nil
+	file_api_gpumem_v1_estimator_proto_depIdxs = nil
+}
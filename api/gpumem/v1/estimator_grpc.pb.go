@@ -0,0 +1,214 @@
+// Schema of the gRPC API served by `gpu-mem-for-llm serve --grpc-addr`, for generating
+// typed clients. The messages mirror the JSON of the HTTP API: the fields are named after
+// the flags of the main command, as in POST /estimate.
+//
+// Fields are only ever added to this version; a change that breaks clients goes into v2.
+// After editing, regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/gpumem/v1/estimator.proto
+
+package gpumemv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EstimatorService_Estimate_FullMethodName = "/gpumem.v1.EstimatorService/Estimate"
+	EstimatorService_Fit_FullMethodName      = "/gpumem.v1.EstimatorService/Fit"
+	EstimatorService_ListGPUs_FullMethodName = "/gpumem.v1.EstimatorService/ListGPUs"
+)
+
+// EstimatorServiceClient is the client API for EstimatorService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// EstimatorService estimates the GPU memory needed to serve or train a model.
+type EstimatorServiceClient interface {
+	// Estimate estimates the memory for a model, as POST /estimate.
+	Estimate(ctx context.Context, in *EstimateRequest, opts ...grpc.CallOption) (*EstimateResponse, error)
+	// Fit finds the largest model that fits a memory budget, as the fit subcommand.
+	Fit(ctx context.Context, in *FitRequest, opts ...grpc.CallOption) (*FitResponse, error)
+	// ListGPUs lists the GPUs of the database, as GET /gpus.
+	ListGPUs(ctx context.Context, in *ListGPUsRequest, opts ...grpc.CallOption) (*ListGPUsResponse, error)
+}
+
+type estimatorServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEstimatorServiceClient(cc grpc.ClientConnInterface) EstimatorServiceClient {
+	return &estimatorServiceClient{cc}
+}
+
+func (c *estimatorServiceClient) Estimate(ctx context.Context, in *EstimateRequest, opts ...grpc.CallOption) (*EstimateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EstimateResponse)
+	err := c.cc.Invoke(ctx, EstimatorService_Estimate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *estimatorServiceClient) Fit(ctx context.Context, in *FitRequest, opts ...grpc.CallOption) (*FitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FitResponse)
+	err := c.cc.Invoke(ctx, EstimatorService_Fit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *estimatorServiceClient) ListGPUs(ctx context.Context, in *ListGPUsRequest, opts ...grpc.CallOption) (*ListGPUsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGPUsResponse)
+	err := c.cc.Invoke(ctx, EstimatorService_ListGPUs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EstimatorServiceServer is the server API for EstimatorService service.
+// All implementations must embed UnimplementedEstimatorServiceServer
+// for forward compatibility.
+//
+// EstimatorService estimates the GPU memory needed to serve or train a model.
+type EstimatorServiceServer interface {
+	// Estimate estimates the memory for a model, as POST /estimate.
+	Estimate(context.Context, *EstimateRequest) (*EstimateResponse, error)
+	// Fit finds the largest model that fits a memory budget, as the fit subcommand.
+	Fit(context.Context, *FitRequest) (*FitResponse, error)
+	// ListGPUs lists the GPUs of the database, as GET /gpus.
+	ListGPUs(context.Context, *ListGPUsRequest) (*ListGPUsResponse, error)
+	mustEmbedUnimplementedEstimatorServiceServer()
+}
+
+// UnimplementedEstimatorServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEstimatorServiceServer struct{}
+
+func (UnimplementedEstimatorServiceServer) Estimate(context.Context, *EstimateRequest) (*EstimateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Estimate not implemented")
+}
+func (UnimplementedEstimatorServiceServer) Fit(context.Context, *FitRequest) (*FitResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Fit not implemented")
+}
+func (UnimplementedEstimatorServiceServer) ListGPUs(context.Context, *ListGPUsRequest) (*ListGPUsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGPUs not implemented")
+}
+func (UnimplementedEstimatorServiceServer) mustEmbedUnimplementedEstimatorServiceServer() {}
+func (UnimplementedEstimatorServiceServer) testEmbeddedByValue()                          {}
+
+// UnsafeEstimatorServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EstimatorServiceServer will
+// result in compilation errors.
+type UnsafeEstimatorServiceServer interface {
+	mustEmbedUnimplementedEstimatorServiceServer()
+}
+
+func RegisterEstimatorServiceServer(s grpc.ServiceRegistrar, srv EstimatorServiceServer) {
+	// If the following call pancis, it indicates UnimplementedEstimatorServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EstimatorService_ServiceDesc, srv)
+}
+
+func _EstimatorService_Estimate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EstimatorServiceServer).Estimate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EstimatorService_Estimate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EstimatorServiceServer).Estimate(ctx, req.(*EstimateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EstimatorService_Fit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EstimatorServiceServer).Fit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EstimatorService_Fit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EstimatorServiceServer).Fit(ctx, req.(*FitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EstimatorService_ListGPUs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGPUsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EstimatorServiceServer).ListGPUs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EstimatorService_ListGPUs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EstimatorServiceServer).ListGPUs(ctx, req.(*ListGPUsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EstimatorService_ServiceDesc is the grpc.ServiceDesc for EstimatorService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EstimatorService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gpumem.v1.EstimatorService",
+	HandlerType: (*EstimatorServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Estimate",
+			Handler:    _EstimatorService_Estimate_Handler,
+		},
+		{
+			MethodName: "Fit",
+			Handler:    _EstimatorService_Fit_Handler,
+		},
+		{
+			MethodName: "ListGPUs",
+			Handler:    _EstimatorService_ListGPUs_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/gpumem/v1/estimator.proto",
+}
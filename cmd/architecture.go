@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// architectureFlags lists the flags the parameter count is derived from when no size is given
+var architectureFlags = []string{"num-layers", "hidden-dim", "intermediate-size", "vocab-size"}
+
+// architectureAliases maps the short and alternative names of the architecture flags to
+// the flags they stand for
+var architectureAliases = map[string]string{
+	"layers":         "num-layers",
+	"hidden":         "hidden-dim",
+	"vocab":          "vocab-size",
+	"tie-embeddings": "tied-embeddings",
+}
+
+// normalizeArchitectureFlags lets the architecture flags be given by their short names,
+// such as --layers for --num-layers
+func normalizeArchitectureFlags(f *pflag.FlagSet, name string) pflag.NormalizedName {
+	if alias, ok := architectureAliases[name]; ok {
+		name = alias
+	}
+	return pflag.NormalizedName(name)
+}
+
+// applyArchitectureSize sets the size from the architecture flags when no size has been
+// given any other way, so a model can be described by its dimensions alone.
+func applyArchitectureSize(cmd *cobra.Command) error {
+	sizeFromArchitecture = false
+	if cmd.Flags().Changed("size") || cmd.Flags().Changed("total-params") {
+		return nil
+	}
+	for _, flag := range architectureFlags {
+		if !cmd.Flags().Changed(flag) {
+			return nil
+		}
+	}
+
+	if numLayers <= 0 || hiddenDim <= 0 || intermediateSize <= 0 || vocabSize <= 0 {
+		return errors.New("invalid architecture; --num-layers, --hidden-dim, --intermediate-size and --vocab-size must all be greater than 0")
+	}
+
+	params := estimator.CalculateArchitectureParameters(numLayers, hiddenDim, intermediateSize, vocabSize, tiedEmbeddings)
+
+	// A mixture of experts has an MLP for every expert in place of the single one, and a
+	// router in each layer picking between them
+	if experts > 1 {
+		params += (experts-1)*estimator.CalculateExpertParameters(numLayers, hiddenDim, intermediateSize) +
+			estimator.CalculateRouterParameters(numLayers, hiddenDim, experts)
+	}
+	sizeFromArchitecture = true
+	return cmd.Flags().Set("size", fmt.Sprint(params))
+}
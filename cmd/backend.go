@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// execBackendPrefix selects a program implementing the estimator as the backend
+const execBackendPrefix = "exec:"
+
+// selectedBackend is the estimator of --backend, the built-in one unless another is
+// selected
+var selectedBackend estimator.Estimator = estimator.EstimatorFunc(estimator.Calculate)
+
+// selectBackend selects the estimator registered under a name, or with exec: the program
+// that follows it along with its arguments, such as exec:./fitted-model --gpu h100
+func selectBackend(name string) error {
+	if command, ok := strings.CutPrefix(name, execBackendPrefix); ok {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return fmt.Errorf("invalid backend %q; %s must be followed by a program", name, execBackendPrefix)
+		}
+		selectedBackend = estimator.CommandEstimator{Path: fields[0], Args: fields[1:]}
+		return nil
+	}
+	backend, err := estimator.Backend(name)
+	if err != nil {
+		return err
+	}
+	selectedBackend = backend
+	return nil
+}
+
+// calculate estimates the model with the backend of --backend
+func calculate(in estimator.ModelSpec) (estimator.Estimate, error) {
+	return selectedBackend.Estimate(in)
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// batchModel is a single model of a batch input file: a name for the report along with
+// the same fields as POST /estimate
+type batchModel struct {
+	Name string `json:"name"`
+	estimateRequest
+}
+
+// jsonBatchEstimate is the shape of a single model in the batch JSON output
+type jsonBatchEstimate struct {
+	Name string `json:"name"`
+	jsonEstimate
+}
+
+// batchStringFields lists the fields of a model that hold strings, so a YAML value such
+// as a size of 7000000000 is kept as the string it is meant to be
+var batchStringFields = func() map[string]bool {
+	fields := map[string]bool{"name": true}
+	t := reflect.TypeOf(estimateRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.String {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// batchYAMLValue converts a YAML scalar of the given field to the JSON value it stands for
+func batchYAMLValue(key, value string) any {
+	if unquoted, quoted := unquoteYAML(value); quoted {
+		return unquoted
+	}
+	if batchStringFields[key] {
+		return value
+	}
+	if b, err := strconv.ParseBool(value); err == nil {
+		return b
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return value
+}
+
+// parseBatchYAML parses a list of models in the subset of YAML read by config files, each
+// starting with "- " and followed by its "key: value" settings, optionally under a
+// "models:" key. A model can also be given on one line as a flow mapping, such as
+// "- {name: b, size: 13b}". It returns the models as a JSON array so they decode like a
+// JSON input file.
+func parseBatchYAML(path string, data []byte) ([]byte, error) {
+	var models []map[string]any
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "models:" || line == "---" {
+			continue
+		}
+
+		if item, found := strings.CutPrefix(line, "-"); found {
+			models = append(models, make(map[string]any))
+			line = cutYAMLComment(strings.TrimSpace(item))
+			if line == "" {
+				continue
+			}
+		}
+		if len(models) == 0 {
+			return nil, fmt.Errorf("%s:%d: expected a list of models starting with '- '", path, lineNumber)
+		}
+
+		if isYAMLFlowMap(line) {
+			pairs, err := parseYAMLFlowMap(cutYAMLComment(line))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, lineNumber, err)
+			}
+			for _, pair := range pairs {
+				models[len(models)-1][pair[0]] = batchYAMLValue(pair[0], pair[1])
+			}
+			continue
+		}
+		key, value, found := cutYAMLKeyValue(line)
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected 'key: value', got %q", path, lineNumber, line)
+		}
+		models[len(models)-1][key] = batchYAMLValue(key, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(models)
+}
+
+// readBatchModels reads the models of a batch input file, a JSON array when the file ends
+// in .json and YAML otherwise
+func readBatchModels(path string) ([]batchModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".json") {
+		data, err = parseBatchYAML(path, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var models []batchModel
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&models); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("%s: no models to estimate", path)
+	}
+	return models, nil
+}
+
+// batchCmd estimates every model listed in a file and reports them together
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Estimate memory for every model listed in a YAML or JSON file",
+	Long: `Read a list of models from --input and estimate the memory for each one,
+reporting them together along with the memory they need in all. Each model takes the
+same fields as POST /estimate of the serve subcommand, plus a name for the report:
+
+- name: chat
+  size: 70b
+  precision: fp8
+  context: 8192
+  num_layers: 80
+  hidden_dim: 8192
+  gpu: h100
+- name: embeddings
+  size: 335m
+  precision: fp16
+- {name: reranker, size: 560m, precision: fp16}
+
+A file ending in .json holds the same list as a JSON array. The --overhead flag applies
+to every model that doesn't set its own.
+
+For example:
+./gpu-mem-for-llm batch --input models.yaml --output markdown
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+		models, err := readBatchModels(batchInput)
+		if err != nil {
+			return err
+		}
+
+		output := make([]jsonBatchEstimate, 0, len(models))
+		rows := make([]estimateRow, 0, len(models))
+		var total int
+		for i, m := range models {
+			name := m.Name
+			if name == "" {
+				name = m.Size
+			}
+			if m.Overhead == nil {
+				m.Overhead = &overhead
+			}
+			estimate, err := serveEstimate(m.estimateRequest)
+			if err != nil {
+				return fmt.Errorf("%s: model %d (%s): %v", batchInput, i+1, name, err)
+			}
+			output = append(output, jsonBatchEstimate{Name: name, jsonEstimate: estimate})
+			rows = append(rows, estimateRow{
+				Model:      name,
+				Precision:  estimate.Precision,
+				Context:    m.Context,
+				GPUs:       max(m.TensorParallel, 1),
+				Components: estimate.Components,
+				MemBytes:   estimate.MemBytes,
+			})
+			total += estimate.MemBytes
+		}
+
+		if tabularFormats[format] {
+			return writeTable(out, format, rows)
+		}
+		if format != "text" {
+			return writeStructured(out, format, output)
+		}
+
+		var anyGPU bool
+		for _, e := range output {
+			anyGPU = anyGPU || e.Fits != nil
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		header := "Model\tSize\tPrecision\tContext\tGPUs\tMemory"
+		if anyGPU {
+			header += "\tFits"
+		}
+		fmt.Fprintln(tw, header)
+		for i, e := range output {
+			context := "-"
+			if rows[i].Context > 0 {
+				context = strconv.Itoa(rows[i].Context)
+			}
+			row := fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s", e.Name, models[i].Size, e.Precision, context, rows[i].GPUs, e.MemSize)
+			if anyGPU {
+				verdict := "-"
+				if e.Fits != nil {
+					verdict = "no"
+					if *e.Fits {
+						verdict = "yes"
+					}
+				}
+				row += "\t" + verdict
+			}
+			fmt.Fprintln(tw, row)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Estimated memory required in all: %s across %d models\n", formatMemory(total), len(models))
+		return nil
+	},
+}
+
+var batchInput string
+
+func init() {
+	batchCmd.Flags().StringVar(&batchInput, "input", "", "YAML or JSON file listing the models to estimate - required")
+	batchCmd.MarkFlagRequired("input")
+	batchCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage for the models that don't set their own")
+	batchCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for the gpu field")
+	batchCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	batchCmd.Flags().StringVar(&outputFormat, "output", "", "output format ("+strings.Join(outputFormats, ", ")+"), --json being the same as json (default text)")
+	batchCmd.MarkFlagsMutuallyExclusive("json", "output")
+	rootCmd.AddCommand(batchCmd)
+}
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// calibrationSample is a model whose memory was measured, with its estimate without any
+// overhead
+type calibrationSample struct {
+	Model    string
+	Measured int
+	Base     int
+}
+
+// jsonCalibrationSample is the shape of a single measurement in the calibrate JSON output
+type jsonCalibrationSample struct {
+	Model                  string  `json:"model"`
+	MeasuredBytes          int     `json:"measured_bytes"`
+	EstimatedBytes         int     `json:"estimated_bytes_without_overhead"`
+	ImpliedOverheadPercent float64 `json:"implied_overhead_percent"`
+}
+
+// jsonCalibration is the shape of the output produced by calibrate with --json
+type jsonCalibration struct {
+	Framework       string                  `json:"framework,omitempty"`
+	Setting         string                  `json:"setting"`
+	OverheadPercent int                     `json:"overhead_percent"`
+	PreviousPercent int                     `json:"previous_percent"`
+	ConfigFile      string                  `json:"config_file,omitempty"`
+	Samples         []jsonCalibrationSample `json:"samples"`
+}
+
+// calibrationSpec returns the model of a calibration argument, a model of the registry or
+// a parameter size, at the precision, context and batch size of the flags
+func calibrationSpec(name string) (estimator.ModelSpec, error) {
+	precision, err := getPrecision()
+	if err != nil {
+		return estimator.ModelSpec{}, err
+	}
+	input := estimator.ModelSpec{Precision: precision}
+
+	if m, err := getRegistryModel(name); err == nil {
+		input.ParameterSize = m.Parameters
+		input.NumLayers = m.NumLayers
+		input.HiddenDim = m.HiddenDim
+		input.IntermediateSize = m.IntermediateSize
+		input.VocabSize = m.VocabSize
+		input.TiedEmbeddings = m.TiedEmbeddings
+		input.SlidingWindow = m.SlidingWindow
+		input.HeadDim = m.HeadDim
+		if input.HeadDim == 0 && m.Heads > 0 {
+			input.HeadDim = m.HiddenDim / m.Heads
+		}
+		if input.HeadDim > 0 {
+			input.KVHeads = m.KVHeads
+		}
+	} else if input.ParameterSize, err = getParameterSize(name); err != nil {
+		return estimator.ModelSpec{}, fmt.Errorf("invalid model %q; must be a model of the registry or a size", name)
+	}
+
+	if contextLength > 0 {
+		if input.NumLayers <= 0 || input.HiddenDim <= 0 {
+			return estimator.ModelSpec{}, fmt.Errorf("--context requires the architecture of %s, which only models of the registry have", name)
+		}
+		input.ContextLength = contextLength
+		input.BatchSize = batchSize
+	}
+	if kvDtype != "" {
+		if input.KVPrecision, err = estimator.KVPrecisionByName(kvDtype); err != nil {
+			return estimator.ModelSpec{}, err
+		}
+	}
+	if framework != "" {
+		preset, err := getFrameworkPreset(framework)
+		if err != nil {
+			return estimator.ModelSpec{}, err
+		}
+		input.KVBlockSize = preset.KVBlockSize
+		input.RoundContext = true
+	}
+	return input, nil
+}
+
+// calibrationSamples returns the measurement of each argument, given as MODEL=MEMORY, or
+// for a single MODEL the memory in use on the local GPUs
+func calibrationSamples(args []string) ([]calibrationSample, error) {
+	var samples []calibrationSample
+	for _, arg := range args {
+		name, memory, given := strings.Cut(arg, "=")
+		input, err := calibrationSpec(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var measured int
+		if given {
+			if measured, err = parseMemorySize(memory); err != nil {
+				return nil, err
+			}
+		} else {
+			if len(args) > 1 {
+				return nil, fmt.Errorf("invalid measurement %q; give the memory as MODEL=MEMORY when calibrating from several models", arg)
+			}
+			var devices []int
+			if watchDevices != "" {
+				if devices, err = parseDevices(watchDevices); err != nil {
+					return nil, err
+				}
+			}
+			gpus, err := watchedGPUs(devices)
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range gpus {
+				measured += g.Memory - g.Free
+			}
+			input.TensorParallel = len(gpus)
+		}
+
+		result, err := calculate(input)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, calibrationSample{Model: name, Measured: measured, Base: result.Total * result.GPUs})
+	}
+	return samples, nil
+}
+
+// calibrateOverhead returns the overhead percentage, rounded to a whole percent and at
+// least 0, that makes the estimates of all the samples together match their measurements
+func calibrateOverhead(samples []calibrationSample) int {
+	var measured, base int
+	for _, s := range samples {
+		measured += s.Measured
+		base += s.Base
+	}
+	return max(int(math.Round(100*(float64(measured)/float64(base)-1))), 0)
+}
+
+// printCalibration prints each measurement next to its estimate without overhead, with the
+// overhead it implies
+func printCalibration(w io.Writer, samples []calibrationSample) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Model\tMeasured\tEstimate without overhead\tImplied overhead")
+	for _, s := range samples {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f%%\n", s.Model, formatMemory(s.Measured), formatMemory(s.Base), 100*(float64(s.Measured)/float64(s.Base)-1))
+	}
+	return tw.Flush()
+}
+
+// calibrateCmd learns the overhead of a framework from the measured memory of models
+var calibrateCmd = &cobra.Command{
+	Use:   "calibrate MODEL[=MEMORY]...",
+	Short: "Learn the overhead percentage of a framework from measured memory usage",
+	Long: `Provide one or more models of the registry or sizes, each with the memory it was
+measured to use as MODEL=MEMORY, to back-solve the overhead percentage that makes the
+estimates match the measurements. A single MODEL without a memory is measured from the
+memory in use on the local GPUs, all of them or those given with --devices, while it
+runs. The overhead is saved to the config file as overhead-FRAMEWORK for the --framework
+given, which estimates with that framework then use in place of its preset, or as
+overhead without one, replacing the 20% default.
+
+For example:
+./gpu-mem-for-llm calibrate --framework vllm --precision bf16 --context 8192 llama3.1-8b=19.2gb mistral-7b=17.6gb
+`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if modelDB != "" {
+			if err := loadModelRegistry(modelDB); err != nil {
+				return err
+			}
+		}
+		if !precisionFlagChanged(cmd) {
+			return errors.New("a precision is required, such as --precision bf16")
+		}
+		return checkMutuallyExclusivePrecisionFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		setting, previous := "overhead", 20
+		if framework != "" {
+			preset, err := getFrameworkPreset(framework)
+			if err != nil {
+				return err
+			}
+			setting, previous = calibratedOverheadPrefix+strings.ToLower(framework), preset.Overhead
+		}
+		samples, err := calibrationSamples(args)
+		if err != nil {
+			return err
+		}
+		overhead := calibrateOverhead(samples)
+
+		var path string
+		if !calibrateDryRun {
+			if path, err = writableConfigPath(); err != nil {
+				return err
+			}
+			if err := saveConfigSetting(path, setting, fmt.Sprint(overhead)); err != nil {
+				return err
+			}
+		}
+
+		if jsonOutput {
+			output := jsonCalibration{
+				Framework:       strings.ToLower(framework),
+				Setting:         setting,
+				OverheadPercent: overhead,
+				PreviousPercent: previous,
+				ConfigFile:      path,
+				Samples:         make([]jsonCalibrationSample, 0, len(samples)),
+			}
+			for _, s := range samples {
+				output.Samples = append(output.Samples, jsonCalibrationSample{
+					Model:                  s.Model,
+					MeasuredBytes:          s.Measured,
+					EstimatedBytes:         s.Base,
+					ImpliedOverheadPercent: math.Round(1000*(float64(s.Measured)/float64(s.Base)-1)) / 10,
+				})
+			}
+			return writeStructured(out, "json", output)
+		}
+
+		if err := printCalibration(out, samples); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Calibrated overhead: %d%% (was %d%%)\n", overhead, previous)
+		if path != "" {
+			fmt.Fprintf(out, "Saved %s: %d to %s\n", setting, overhead, path)
+		}
+		return nil
+	},
+}
+
+var calibrateDryRun bool
+
+func init() {
+	addPrecisionFlags(calibrateCmd)
+	calibrateCmd.Flags().StringVar(&modelDB, "model-db", "", "JSON file of additional models")
+	calibrateCmd.Flags().StringVar(&framework, "framework", "", "serving framework the measurements were taken with ("+strings.Join(frameworkNames(), ", ")+")")
+	calibrateCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens the KV cache was sized for")
+	calibrateCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	calibrateCmd.Flags().StringVar(&kvDtype, "kv-dtype", "", "precision or quantized type of the KV cache when it differs from the weights (e.g., fp8)")
+	calibrateCmd.Flags().StringVar(&watchDevices, "devices", "", "comma separated indices of the GPUs to measure a single model on (default all of them)")
+	calibrateCmd.Flags().StringVar(&vendor, "vendor", "auto", "vendor of the GPUs to measure (auto, "+strings.Join(gpuVendors, ", ")+")")
+	calibrateCmd.Flags().StringVar(&configFile, "config-file", "", "config file to save the overhead to (default $XDG_CONFIG_HOME/"+userConfigFile+" or $HOME/"+defaultConfigFile+")")
+	calibrateCmd.Flags().BoolVar(&calibrateDryRun, "dry-run", false, "print the calibrated overhead without saving it")
+	calibrateCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(calibrateCmd)
+}
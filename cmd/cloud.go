@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// cloudInstanceLimit is the number of the cheapest instances that fit shown with --cost
+const cloudInstanceLimit = 5
+
+// cloudInstance is a cloud instance type with its GPUs and on-demand price
+type cloudInstance struct {
+	// Provider is the cloud the instance is offered on, such as "aws"
+	Provider string
+	// GPU is the name of its GPUs in the GPU database
+	GPU string
+	// GPUs is the number of GPUs of the instance
+	GPUs int
+	// PricePerHour is the on-demand price of the whole instance in US dollars
+	PricePerHour float64
+}
+
+// jsonCloudInstance is the shape of an instance in a user-provided price table file
+type jsonCloudInstance struct {
+	Provider     string  `json:"provider"`
+	GPU          string  `json:"gpu"`
+	GPUs         int     `json:"gpus"`
+	PricePerHour float64 `json:"price_per_hour"`
+}
+
+// cloudInstances maps the name of each built-in instance type to its GPUs and price. The
+// prices are on-demand list prices in US dollars in regions such as us-east and change
+// often, so they are only a guide; --cost-db replaces them with current ones.
+var cloudInstances = map[string]cloudInstance{
+	"g5.xlarge":                 {Provider: "aws", GPU: "a10g", GPUs: 1, PricePerHour: 1.006},
+	"g5.12xlarge":               {Provider: "aws", GPU: "a10g", GPUs: 4, PricePerHour: 5.672},
+	"g5.48xlarge":               {Provider: "aws", GPU: "a10g", GPUs: 8, PricePerHour: 16.288},
+	"g6.xlarge":                 {Provider: "aws", GPU: "l4", GPUs: 1, PricePerHour: 0.805},
+	"g6.12xlarge":               {Provider: "aws", GPU: "l4", GPUs: 4, PricePerHour: 4.602},
+	"g6.48xlarge":               {Provider: "aws", GPU: "l4", GPUs: 8, PricePerHour: 13.35},
+	"p4d.24xlarge":              {Provider: "aws", GPU: "a100-40gb", GPUs: 8, PricePerHour: 32.773},
+	"p4de.24xlarge":             {Provider: "aws", GPU: "a100-80gb", GPUs: 8, PricePerHour: 40.966},
+	"p5.48xlarge":               {Provider: "aws", GPU: "h100", GPUs: 8, PricePerHour: 98.32},
+	"g2-standard-8":             {Provider: "gcp", GPU: "l4", GPUs: 1, PricePerHour: 0.854},
+	"g2-standard-24":            {Provider: "gcp", GPU: "l4", GPUs: 2, PricePerHour: 2.0},
+	"g2-standard-48":            {Provider: "gcp", GPU: "l4", GPUs: 4, PricePerHour: 4.0},
+	"g2-standard-96":            {Provider: "gcp", GPU: "l4", GPUs: 8, PricePerHour: 8.0},
+	"a2-highgpu-1g":             {Provider: "gcp", GPU: "a100-40gb", GPUs: 1, PricePerHour: 3.673},
+	"a2-highgpu-2g":             {Provider: "gcp", GPU: "a100-40gb", GPUs: 2, PricePerHour: 7.347},
+	"a2-highgpu-4g":             {Provider: "gcp", GPU: "a100-40gb", GPUs: 4, PricePerHour: 14.694},
+	"a2-highgpu-8g":             {Provider: "gcp", GPU: "a100-40gb", GPUs: 8, PricePerHour: 29.387},
+	"a2-ultragpu-1g":            {Provider: "gcp", GPU: "a100-80gb", GPUs: 1, PricePerHour: 5.069},
+	"a2-ultragpu-8g":            {Provider: "gcp", GPU: "a100-80gb", GPUs: 8, PricePerHour: 40.55},
+	"a3-highgpu-8g":             {Provider: "gcp", GPU: "h100", GPUs: 8, PricePerHour: 88.25},
+	"Standard_NV36ads_A10_v5":   {Provider: "azure", GPU: "a10", GPUs: 1, PricePerHour: 3.2},
+	"Standard_NC24ads_A100_v4":  {Provider: "azure", GPU: "a100-80gb", GPUs: 1, PricePerHour: 3.673},
+	"Standard_NC48ads_A100_v4":  {Provider: "azure", GPU: "a100-80gb", GPUs: 2, PricePerHour: 7.346},
+	"Standard_NC96ads_A100_v4":  {Provider: "azure", GPU: "a100-80gb", GPUs: 4, PricePerHour: 14.692},
+	"Standard_ND96asr_v4":       {Provider: "azure", GPU: "a100-40gb", GPUs: 8, PricePerHour: 27.197},
+	"Standard_ND96amsr_A100_v4": {Provider: "azure", GPU: "a100-80gb", GPUs: 8, PricePerHour: 32.77},
+	"Standard_ND96isr_H100_v5":  {Provider: "azure", GPU: "h100", GPUs: 8, PricePerHour: 98.32},
+	"gpu_1x_a10":                {Provider: "lambda", GPU: "a10", GPUs: 1, PricePerHour: 0.75},
+	"gpu_1x_a100_sxm4":          {Provider: "lambda", GPU: "a100-40gb", GPUs: 1, PricePerHour: 1.29},
+	"gpu_8x_a100_80gb_sxm4":     {Provider: "lambda", GPU: "a100-80gb", GPUs: 8, PricePerHour: 14.32},
+	"gpu_1x_h100_pcie":          {Provider: "lambda", GPU: "h100", GPUs: 1, PricePerHour: 2.49},
+	"gpu_8x_h100_sxm5":          {Provider: "lambda", GPU: "h100", GPUs: 8, PricePerHour: 23.92},
+}
+
+// loadCloudInstances adds the instances from a JSON file mapping instance names to their
+// provider, GPU, number of GPUs and price per hour, such as
+// {"my-node": {"provider": "on-prem", "gpu": "h100", "gpus": 8, "price_per_hour": 20}},
+// to the built-in price table. Entries in the file replace built-in instances of the
+// same name.
+func loadCloudInstances(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var instances map[string]jsonCloudInstance
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name, instance := range instances {
+		if _, err := getGPUSpec(instance.GPU); err != nil {
+			return fmt.Errorf("%s: instance %q: %v", path, name, err)
+		}
+		if instance.GPUs <= 0 || instance.PricePerHour <= 0 {
+			return fmt.Errorf("%s: instance %q: gpus and price_per_hour must be greater than 0", path, name)
+		}
+		cloudInstances[name] = cloudInstance{
+			Provider:     instance.Provider,
+			GPU:          strings.ToLower(instance.GPU),
+			GPUs:         instance.GPUs,
+			PricePerHour: instance.PricePerHour,
+		}
+	}
+	return nil
+}
+
+// cloudOption is an instance type that fits the estimate, with the GPUs of it the model
+// needs and its cost
+type cloudOption struct {
+	Instance     string
+	Provider     string
+	GPU          string
+	GPUs         int
+	GPUsNeeded   int
+	PricePerHour float64
+	PerMonth     float64
+}
+
+// jsonCloudOption is the shape of an instance that fits in the --json output
+type jsonCloudOption struct {
+	Instance     string  `json:"instance"`
+	Provider     string  `json:"provider"`
+	GPU          string  `json:"gpu"`
+	GPUs         int     `json:"gpus"`
+	GPUsNeeded   int     `json:"gpus_needed"`
+	CostPerHour  float64 `json:"cost_per_hour"`
+	CostPerMonth float64 `json:"cost_per_month"`
+}
+
+// cheapestCloudInstances returns the cheapest instance types whose GPUs hold the memory
+// each GPU needs and, between them, the memory of the whole deployment, cheapest first
+func cheapestCloudInstances(perGPU, gpus int) []cloudOption {
+	var options []cloudOption
+	for name, instance := range cloudInstances {
+		spec, ok := gpuDatabase[instance.GPU]
+		if !ok || spec.Memory <= 0 {
+			continue
+		}
+		// A deployment sharded by the estimate needs every shard to fit on one GPU
+		if gpus > 1 && perGPU > spec.Memory {
+			continue
+		}
+		needed := estimator.CalculateGPUCount(perGPU*gpus, spec.Memory, gpus)
+		if needed > instance.GPUs {
+			continue
+		}
+		_, perMonth := estimator.CalculateCost(1, instance.PricePerHour)
+		options = append(options, cloudOption{
+			Instance:     name,
+			Provider:     instance.Provider,
+			GPU:          instance.GPU,
+			GPUs:         instance.GPUs,
+			GPUsNeeded:   needed,
+			PricePerHour: instance.PricePerHour,
+			PerMonth:     perMonth,
+		})
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].PricePerHour != options[j].PricePerHour {
+			return options[i].PricePerHour < options[j].PricePerHour
+		}
+		return options[i].Instance < options[j].Instance
+	})
+	return options[:min(len(options), cloudInstanceLimit)]
+}
+
+// jsonCloudOptions returns the cheapest instances that fit for --json
+func jsonCloudOptions(options []cloudOption) []jsonCloudOption {
+	instances := make([]jsonCloudOption, 0, len(options))
+	for _, o := range options {
+		instances = append(instances, jsonCloudOption{
+			Instance:     o.Instance,
+			Provider:     o.Provider,
+			GPU:          o.GPU,
+			GPUs:         o.GPUs,
+			GPUsNeeded:   o.GPUsNeeded,
+			CostPerHour:  o.PricePerHour,
+			CostPerMonth: math.Round(o.PerMonth*100) / 100,
+		})
+	}
+	return instances
+}
+
+// printCloudOptions prints the cheapest instances that fit with their hourly and monthly
+// cost
+func printCloudOptions(w io.Writer, options []cloudOption) {
+	if len(options) == 0 {
+		fmt.Fprintln(w, "Cloud cost: no instance in the price table fits; add one with --cost-db")
+		return
+	}
+	fmt.Fprintln(w, "Cheapest cloud instances that fit (on-demand, USD):")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, o := range options {
+		fmt.Fprintf(tw, "  %s\t%s\t%dx %s\t$%.2f/hour\t$%.0f/month\n", o.Provider, o.Instance, o.GPUs, o.GPU, o.PricePerHour, o.PerMonth)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// jsonCluster is the shape of the layout across the nodes of a cluster in the --json
+// output
+type jsonCluster struct {
+	Nodes                   int    `json:"nodes"`
+	GPUsPerNode             int    `json:"gpus_per_node"`
+	Replicas                int    `json:"replicas"`
+	GPUsPerReplica          int    `json:"gpus_per_replica"`
+	IdleGPUs                int    `json:"idle_gpus,omitempty"`
+	MemSizePerGPU           string `json:"mem_size_per_gpu"`
+	MemBytesPerGPU          int    `json:"mem_bytes_per_gpu"`
+	MemSizePerNode          string `json:"mem_size_per_node"`
+	MemBytesPerNode         int    `json:"mem_bytes_per_node"`
+	MemSize                 string `json:"mem_size"`
+	MemBytes                int    `json:"mem_bytes"`
+	CrossNodeTensorParallel bool   `json:"cross_node_tensor_parallel,omitempty"`
+}
+
+// jsonClusterLayout converts a cluster layout to its --json shape
+func jsonClusterLayout(layout estimator.ClusterLayout) *jsonCluster {
+	return &jsonCluster{
+		Nodes:                   layout.Nodes,
+		GPUsPerNode:             layout.GPUsPerNode,
+		Replicas:                layout.Replicas,
+		GPUsPerReplica:          layout.GPUsPerReplica,
+		IdleGPUs:                layout.IdleGPUs,
+		MemSizePerGPU:           formatMemory(layout.PerGPU),
+		MemBytesPerGPU:          layout.PerGPU,
+		MemSizePerNode:          formatMemory(layout.PerNode),
+		MemBytesPerNode:         layout.PerNode,
+		MemSize:                 formatMemory(layout.Total),
+		MemBytes:                layout.Total,
+		CrossNodeTensorParallel: layout.CrossNodeTensorParallel,
+	}
+}
+
+// printClusterLayout prints the memory of each GPU, each node and the whole cluster,
+// warning when tensor parallelism would have to cross nodes
+func printClusterLayout(w io.Writer, layout estimator.ClusterLayout, tensorParallel int) {
+	fmt.Fprintf(w, "Cluster: %d nodes of %d GPUs, %d replicas of %d GPUs each\n", layout.Nodes, layout.GPUsPerNode, layout.Replicas, layout.GPUsPerReplica)
+	fmt.Fprintf(w, "  per GPU:  %s\n", formatMemory(layout.PerGPU))
+	fmt.Fprintf(w, "  per node: %s\n", formatMemory(layout.PerNode))
+	fmt.Fprintf(w, "  total:    %s\n", formatMemory(layout.Total))
+	if layout.IdleGPUs > 0 {
+		fmt.Fprintf(w, "  idle:     %d GPUs, too few for another replica\n", layout.IdleGPUs)
+	}
+	if layout.CrossNodeTensorParallel {
+		fmt.Fprintf(w, "Warning: tensor parallel degree %d doesn't fit within nodes of %d GPUs, so its all-reduces would cross the inter-node network; keep it within a node and add --pipeline-parallel stages\n", tensorParallel, layout.GPUsPerNode)
+	}
+}
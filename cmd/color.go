@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ANSI escape sequences of the colors of the utilization
+const (
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiRed    = "\033[31m"
+	ansiReset  = "\033[0m"
+)
+
+// colorModes are the values --color accepts
+var colorModes = []string{"auto", "always", "never"}
+
+// useColor reports whether the output is colored: always or never as --color says, and
+// otherwise when it is a terminal and NO_COLOR isn't set
+func useColor(w io.Writer) (bool, error) {
+	switch strings.ToLower(colorMode) {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := w.(*os.File)
+		return ok && isTerminal(f) && os.Getenv("NO_COLOR") == "", nil
+	}
+	return false, fmt.Errorf("unknown color %q; must be one of %s", colorMode, strings.Join(colorModes, ", "))
+}
+
+// parsePercent parses a percentage such as 90 or 90%
+func parsePercent(s string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid percentage %q; must be greater than 0 and at most 100, such as 90%%", s)
+	}
+	return percent, nil
+}
+
+// utilizationColor returns the color of a utilization of the memory: green below the
+// threshold, yellow from it up to the whole memory and red beyond
+func utilizationColor(utilization, warnAt float64) string {
+	switch {
+	case utilization > 100:
+		return ansiRed
+	case utilization >= warnAt:
+		return ansiYellow
+	}
+	return ansiGreen
+}
+
+// colorize wraps the text in the color when it isn't empty
+func colorize(text, color string) string {
+	if color == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// comparison is the estimate for a model at one of the precisions being compared
+type comparison struct {
+	Precision     string
+	BytesPerParam estimator.Precision
+	Estimate      estimator.Estimate
+}
+
+// jsonComparison is the shape of a single precision in the compare JSON output
+type jsonComparison struct {
+	Precision         string          `json:"precision"`
+	BytesPerParameter json.Number     `json:"bytes_per_parameter"`
+	MemSize           string          `json:"mem_size"`
+	MemBytes          int             `json:"mem_bytes"`
+	SavingBytes       int             `json:"saving_bytes"`
+	SavingPercent     float64         `json:"saving_percent"`
+	Components        []jsonComponent `json:"components"`
+	Fits              *bool           `json:"fits,omitempty"`
+}
+
+// compareModel estimates the model at every supported precision followed by each of the
+// given llama.cpp quantization schemes. The KV cache follows the weights for the
+// precisions and is kept in fp16 for the quantization schemes, as the main command does.
+func compareModel(input estimator.ModelSpec, quants []string) ([]comparison, error) {
+	fp16, err := estimator.PrecisionByName("fp16")
+	if err != nil {
+		return nil, err
+	}
+
+	var comparisons []comparison
+	add := func(name string, precision, kvPrecision estimator.Precision) error {
+		input.Precision = precision
+		input.KVPrecision = kvPrecision
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+		comparisons = append(comparisons, comparison{Precision: name, BytesPerParam: precision, Estimate: result})
+		return nil
+	}
+
+	for _, name := range estimator.PrecisionNames() {
+		precision, err := estimator.PrecisionByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(name, precision, 0); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range quants {
+		precision, err := estimator.QuantPrecision(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(strings.ToUpper(name), precision, fp16); err != nil {
+			return nil, err
+		}
+	}
+	return comparisons, nil
+}
+
+// saving returns the memory an estimate saves over the baseline in bytes and as a
+// percentage of the baseline
+func saving(baseline, c comparison) (int, float64) {
+	saved := baseline.Estimate.Total - c.Estimate.Total
+	return saved, 100 * float64(saved) / float64(baseline.Estimate.Total)
+}
+
+// compareCmd estimates the memory for one model at every precision side by side
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare the memory for a model at every precision side by side",
+	Long: `Provide the model size to estimate the memory it needs at every supported precision,
+from fp32 down to ternary, in a single table along with the memory saved over fp32. Add llama.cpp
+quantization schemes with --quants, size a KV cache with --context, and give --gpu or
+--gpu-memory to see which precisions fit.
+
+For example:
+./gpu-mem-for-llm compare --size 70b --quants Q8_0,Q4_K_M --gpu h100
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+		parameterSize, err := getParameterSize(size)
+		if err != nil {
+			return err
+		}
+		gpuMemoryBytes, err := resolveGPUMemory()
+		if err != nil {
+			return err
+		}
+		if contextLength > 0 && (numLayers <= 0 || hiddenDim <= 0) {
+			return errors.New("--context requires --num-layers and --hidden-dim to size the KV cache")
+		}
+
+		input := estimator.ModelSpec{
+			ParameterSize: parameterSize,
+			Overhead:      float32(overhead),
+			NumLayers:     numLayers,
+			HiddenDim:     hiddenDim,
+		}
+		if contextLength > 0 {
+			input.ContextLength = contextLength
+			input.BatchSize = batchSize
+		}
+
+		comparisons, err := compareModel(input, compareQuants)
+		if err != nil {
+			return err
+		}
+		// Every estimate is compared with the first, which takes the most bytes per parameter
+		baseline := comparisons[0]
+
+		if tabularFormats[format] {
+			rows := make([]estimateRow, 0, len(comparisons))
+			for _, c := range comparisons {
+				rows = append(rows, estimateRow{
+					Model:      size,
+					Precision:  c.Precision,
+					Context:    contextLength,
+					GPUs:       c.Estimate.GPUs,
+					Components: jsonComponents(c.Estimate),
+					MemBytes:   c.Estimate.Total,
+				})
+			}
+			return writeTable(out, format, rows)
+		}
+
+		if format != "text" {
+			output := make([]jsonComparison, 0, len(comparisons))
+			for _, c := range comparisons {
+				savedBytes, savedPercent := saving(baseline, c)
+				comparison := jsonComparison{
+					Precision:         c.Precision,
+					BytesPerParameter: json.Number(strconv.FormatFloat(float64(c.BytesPerParam), 'g', -1, 32)),
+					MemSize:           formatMemory(c.Estimate.Total),
+					MemBytes:          c.Estimate.Total,
+					SavingBytes:       savedBytes,
+					SavingPercent:     savedPercent,
+					Components:        jsonComponents(c.Estimate),
+				}
+				if gpuMemoryBytes > 0 {
+					fits := c.Estimate.Total <= gpuMemoryBytes
+					comparison.Fits = &fits
+				}
+				output = append(output, comparison)
+			}
+			return writeStructured(out, format, output)
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		header := "Precision\tBytes per parameter\tWeights"
+		if contextLength > 0 {
+			header += "\tKV cache"
+		}
+		header += "\tTotal\tSaved vs " + baseline.Precision + "\tSaving vs " + baseline.Precision
+		if gpuMemoryBytes > 0 {
+			header += "\tFits"
+		}
+		fmt.Fprintln(tw, header)
+		for _, c := range comparisons {
+			breakdown := c.Estimate.BreakdownIn(memoryUnit)
+			row := fmt.Sprintf("%s\t%g\t%s", c.Precision, float32(c.BytesPerParam), breakdown["weights"])
+			if contextLength > 0 {
+				row += "\t" + breakdown["kv cache"]
+			}
+			savedBytes, savedPercent := saving(baseline, c)
+			row += fmt.Sprintf("\t%s\t%s\t%.0f%%", formatMemory(c.Estimate.Total), formatMemory(savedBytes), savedPercent)
+			if gpuMemoryBytes > 0 {
+				verdict := "no"
+				if c.Estimate.Total <= gpuMemoryBytes {
+					verdict = "yes"
+				}
+				row += "\t" + verdict
+			}
+			fmt.Fprintln(tw, row)
+		}
+		return tw.Flush()
+	},
+}
+
+var compareQuants []string
+
+func init() {
+	compareCmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b, 1.5b or 1.8t) - required")
+	compareCmd.MarkFlagRequired("size")
+	compareCmd.Flags().StringSliceVar(&compareQuants, "quants", nil, "llama.cpp quantization schemes to compare as well (e.g., Q8_0,Q4_K_M)")
+	compareCmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32)")
+	compareCmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096)")
+	compareCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the KV cache for (requires --num-layers and --hidden-dim)")
+	compareCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	compareCmd.Flags().StringVar(&gpuMemory, "gpu-memory", "", "memory of each GPU (e.g., 80gb) to check each precision against")
+	compareCmd.Flags().StringVar(&gpu, "gpu", "", "GPU model from the built-in database (e.g., h100) to check each precision against")
+	compareCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for --gpu")
+	compareCmd.MarkFlagsMutuallyExclusive("gpu-memory", "gpu")
+	compareCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	compareCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	compareCmd.Flags().StringVar(&outputFormat, "output", "", "output format ("+strings.Join(outputFormats, ", ")+"), --json being the same as json (default text)")
+	compareCmd.MarkFlagsMutuallyExclusive("json", "output")
+	rootCmd.AddCommand(compareCmd)
+}
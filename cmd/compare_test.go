@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+func TestSavingInt4OverFP16(t *testing.T) {
+	comparisons, err := compareModel(estimator.ModelSpec{ParameterSize: 7_000_000_000, Overhead: 20}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]comparison{}
+	for _, c := range comparisons {
+		byName[c.Precision] = c
+	}
+	fp16, int4 := byName["fp16"], byName["int4"]
+
+	// 7b parameters at 2 bytes and 0.5 bytes each, plus 20% overhead
+	const want = 12_600_000_000
+	saved, percent := saving(fp16, int4)
+	if saved < want-1 || saved > want+1 {
+		t.Errorf("saving(fp16, int4) = %d bytes, want %d", saved, want)
+	}
+	if saved != fp16.Estimate.Total-int4.Estimate.Total {
+		t.Errorf("saving(fp16, int4) = %d bytes, want the difference of the totals %d", saved, fp16.Estimate.Total-int4.Estimate.Total)
+	}
+	if percent < 74.99 || percent > 75.01 {
+		t.Errorf("saving(fp16, int4) = %.2f%%, want 75%%", percent)
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// precisionCompletions lists the names the flags taking a precision accept
+func precisionCompletions() []string {
+	return append(estimator.PrecisionNames(), estimator.QuantNames()...)
+}
+
+// flagCompletions lists the values shell completion offers for each flag taking one of a
+// known set of names, whichever command it is defined on
+var flagCompletions = map[string]func() []string{
+	"precision":         precisionCompletions,
+	"ab-precision":      precisionCompletions,
+	"embed-precision":   precisionCompletions,
+	"head-precision":    precisionCompletions,
+	"load-precision":    precisionCompletions,
+	"base-precision":    precisionCompletions,
+	"adapter-precision": precisionCompletions,
+	"draft-precision":   precisionCompletions,
+	"vision-precision":  precisionCompletions,
+	"index-precision":   precisionCompletions,
+	"max-precision":     precisionCompletions,
+	"quant":             estimator.QuantNames,
+	"kv-dtype":          estimator.KVPrecisionNames,
+	"model":             modelNames,
+	"gpu":               gpuNames,
+	"framework":         frameworkNames,
+	"optimizer":         func() []string { return estimator.Optimizers },
+	"fsdp":              func() []string { return estimator.FSDPStrategies },
+	"fp8-scaling":       func() []string { return []string{"tensor", "channel"} },
+	"mode":              func() []string { return estimateModes },
+	"output":            func() []string { return outputFormats },
+	"vendor":            func() []string { return append([]string{"auto"}, gpuVendors...) },
+	"unit":              func() []string { return append([]string{"auto"}, estimator.MemoryUnitNames...) },
+	"color":             func() []string { return colorModes },
+	"log-format":        func() []string { return logFormats },
+	"schemes":           func() []string { return quantSchemes },
+	"backend":           func() []string { return append(estimator.BackendNames(), execBackendPrefix) },
+}
+
+// fileFlags lists the flags taking a file, with the extensions shell completion offers,
+// any file when there are none
+var fileFlags = map[string][]string{
+	"gpu-db":        {"json"},
+	"model-db":      {"json"},
+	"config-file":   nil,
+	"template-file": nil,
+}
+
+// dirFlags lists the flags taking a directory
+var dirFlags = []string{"model-dir", "hf-cache-dir", "dir"}
+
+// registerFlagCompletions registers the completions of flagCompletions, fileFlags and
+// dirFlags on the command and every command below it that defines those flags, so
+// completing a value such as --precision offers the names it accepts
+func registerFlagCompletions(cmd *cobra.Command) {
+	for name, values := range flagCompletions {
+		if cmd.LocalNonPersistentFlags().Lookup(name) == nil && cmd.PersistentFlags().Lookup(name) == nil {
+			continue
+		}
+		// A flag is only registered once, so a second run in the same process is fine
+		cmd.RegisterFlagCompletionFunc(name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var matches []string
+			for _, value := range values() {
+				if strings.HasPrefix(strings.ToLower(value), strings.ToLower(toComplete)) && !slices.Contains(matches, value) {
+					matches = append(matches, value)
+				}
+			}
+			return matches, cobra.ShellCompDirectiveNoFileComp
+		})
+	}
+	for name, extensions := range fileFlags {
+		if cmd.LocalNonPersistentFlags().Lookup(name) != nil || cmd.PersistentFlags().Lookup(name) != nil {
+			cmd.MarkFlagFilename(name, extensions...)
+		}
+	}
+	for _, name := range dirFlags {
+		if cmd.LocalNonPersistentFlags().Lookup(name) != nil {
+			cmd.MarkFlagDirname(name)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		registerFlagCompletions(sub)
+	}
+}
@@ -0,0 +1,511 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// defaultConfigFile is the name of the config file looked up in the home directory
+	// when --config-file is not given
+	defaultConfigFile = ".gpu-mem-for-llm.yaml"
+
+	// userConfigFile is the path of the config file looked up in the user's config
+	// directory, such as ~/.config on Linux, before the one in the home directory
+	userConfigFile = "gpu-mem-for-llm/config.yaml"
+
+	// calibratedOverheadPrefix starts the settings holding the overhead calibrated for a
+	// framework, such as overhead-vllm
+	calibratedOverheadPrefix = "overhead-"
+)
+
+// calibratedOverheads maps each framework to the overhead percentage calibrated for it in
+// the config file, which replaces the overhead of its preset
+var calibratedOverheads = map[string]int{}
+
+// configEntry is a single setting read from a config file along with the line it is on
+// and the profile it belongs to, empty for the settings outside of any profile
+type configEntry struct {
+	Key     string
+	Value   string
+	Line    int
+	Profile string
+}
+
+// readConfigFile parses a config file and returns its settings keyed by name. The
+// settings of the given profile, when there is one, replace those outside of it.
+func readConfigFile(path, profile string) (map[string]string, error) {
+	entries, err := readConfigEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(entries))
+	var found bool
+	for _, e := range entries {
+		if e.Profile == "" {
+			if _, ok := values[e.Key]; !ok {
+				values[e.Key] = e.Value
+			}
+		}
+		if profile != "" && e.Profile == profile {
+			found = true
+			values[e.Key] = e.Value
+		}
+	}
+	if profile != "" && !found {
+		return nil, fmt.Errorf("%s: no profile named %q", path, profile)
+	}
+	return values, nil
+}
+
+// readConfigEntries parses a config file made of "key: value" lines, the subset of YAML
+// needed for flag defaults. Blank lines and comments starting with '#' are skipped and
+// values may be quoted. Named profiles are given under a "profiles:" line, each as an indented
+// "name:" line followed by its settings indented further.
+func readConfigEntries(path string) ([]configEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []configEntry
+	var inProfiles bool
+	var profile string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		text := scanner.Text()
+		line := strings.TrimSpace(text)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := cutYAMLKeyValue(line)
+		if !found {
+			return nil, fmt.Errorf("%s:%d: expected 'key: value', got %q", path, lineNumber, line)
+		}
+
+		// An unindented line ends the profiles, and within them a line without a value
+		// starts the next profile
+		indented := text[0] == ' ' || text[0] == '\t'
+		switch {
+		case !indented:
+			inProfiles = key == "profiles" && value == ""
+			profile = ""
+			if inProfiles {
+				continue
+			}
+		case !inProfiles:
+			return nil, fmt.Errorf("%s:%d: unexpected indentation outside of profiles", path, lineNumber)
+		case value == "":
+			profile = key
+			continue
+		case profile == "":
+			return nil, fmt.Errorf("%s:%d: expected a profile name before %q", path, lineNumber, key)
+		}
+
+		value, _ = unquoteYAML(value)
+		entries = append(entries, configEntry{Key: key, Value: value, Line: lineNumber, Profile: profile})
+	}
+
+	return entries, scanner.Err()
+}
+
+// applySettings sets every flag that has not been set yet from the given values, so
+// explicit flags always take precedence. Keys are flag names, plus "precision" to select
+// one of the precision flags, "format" to choose the output format and overhead-FRAMEWORK
+// for the overhead calibrated for a framework. The source names where the values came from
+// in error messages.
+func applySettings(cmd *cobra.Command, source string, values map[string]string) error {
+	for key, value := range values {
+		if err := checkSetting(cmd, key, value); err != nil {
+			return fmt.Errorf("%s: %v", source, err)
+		}
+
+		applied := false
+		switch {
+		case strings.HasPrefix(key, calibratedOverheadPrefix):
+			overhead, _ := strconv.Atoi(value)
+			calibratedOverheads[strings.ToLower(strings.TrimPrefix(key, calibratedOverheadPrefix))] = overhead
+			applied = true
+		case key == "precision":
+			if !precisionFlagChanged(cmd) {
+				if err := cmd.Flags().Set("precision", value); err != nil {
+					return err
+				}
+				applied = true
+			}
+		case key == "format":
+			// Quiet and templated output have no format, so a default format must not
+			// conflict with them
+			if !cmd.Flags().Changed("json") && !cmd.Flags().Changed("output") && !cmd.Flags().Changed("quiet") && !cmd.Flags().Changed("template") && !cmd.Flags().Changed("template-file") {
+				if err := cmd.Flags().Set("output", strings.ToLower(value)); err != nil {
+					return err
+				}
+				applied = true
+			}
+		default:
+			flag := cmd.Flags().Lookup(key)
+			// A default must not conflict with a flag it can't be combined with, such as a
+			// default --gpu with --gpu-memory or a default size with --total-params
+			if !flag.Changed && !exclusiveFlagChanged(cmd, key) {
+				if err := cmd.Flags().Set(key, value); err != nil {
+					return fmt.Errorf("%s: invalid %s: %v", source, key, err)
+				}
+				applied = true
+			}
+		}
+		if applied {
+			slog.Debug("applied setting", "source", source, "setting", key, "value", value)
+		} else {
+			slog.Debug("setting already given", "source", source, "setting", key, "value", value)
+		}
+	}
+
+	return nil
+}
+
+// mutuallyExclusiveAnnotation is the annotation cobra records the groups of
+// MarkFlagsMutuallyExclusive under, one space-separated group of flag names per value
+const mutuallyExclusiveAnnotation = "cobra_annotation_mutually_exclusive"
+
+// exclusiveFlags returns the flags the named flag is mutually exclusive with
+func exclusiveFlags(cmd *cobra.Command, name string) []string {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil {
+		return nil
+	}
+	var others []string
+	for _, group := range flag.Annotations[mutuallyExclusiveAnnotation] {
+		for _, other := range strings.Fields(group) {
+			if other != name {
+				others = append(others, other)
+			}
+		}
+	}
+	return others
+}
+
+// exclusiveFlagChanged reports whether a flag the named flag is mutually exclusive with
+// has been set
+func exclusiveFlagChanged(cmd *cobra.Command, name string) bool {
+	for _, other := range exclusiveFlags(cmd, name) {
+		if cmd.Flags().Changed(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// resetFlag returns a flag to its default value and marks it as not given
+func resetFlag(flag *pflag.Flag) error {
+	switch value := flag.Value.(type) {
+	case *sizeListValue:
+		*value.value, value.changed = flag.DefValue, false
+	case pflag.SliceValue:
+		var values []string
+		if defaults := strings.Trim(flag.DefValue, "[]"); defaults != "" {
+			values = strings.Split(defaults, ",")
+		}
+		if err := value.Replace(values); err != nil {
+			return err
+		}
+	default:
+		if err := flag.Value.Set(flag.DefValue); err != nil {
+			return err
+		}
+	}
+	flag.Changed = false
+	return nil
+}
+
+// applyModelSettings applies the settings derived from the model given with the named
+// flag, such as --model. They are defaults below every other source, except when the
+// model is given on the command line: it then replaces what the environment and the
+// config file gave those settings, and the flags they can't be combined with, so the size
+// and architecture all describe the model given.
+func applyModelSettings(cmd *cobra.Command, modelFlag, source string, settings map[string]string) error {
+	if commandLineFlags[modelFlag] {
+		for key := range settings {
+			names := []string{key}
+			if key == "precision" {
+				names = precisionFlags
+			}
+			for _, name := range names {
+				for _, n := range append([]string{name}, exclusiveFlags(cmd, name)...) {
+					flag := cmd.Flags().Lookup(n)
+					if flag == nil || !flag.Changed || commandLineFlags[n] {
+						continue
+					}
+					slog.Debug("replacing setting with the model's", "model", source, "setting", n, "value", flag.Value.String())
+					if err := resetFlag(flag); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return applySettings(cmd, source, settings)
+}
+
+// checkSetting reports whether the key names a setting of the command and the value is
+// valid for it, without applying it.
+func checkSetting(cmd *cobra.Command, key, value string) error {
+	switch key {
+	case "precision":
+		_, err := lookupPrecision(value)
+		return err
+	case "kv-dtype":
+		_, err := estimator.KVPrecisionByName(value)
+		return err
+	case "format":
+		for _, format := range outputFormats {
+			if strings.ToLower(value) == format {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown format %q; must be one of %s", value, strings.Join(outputFormats, ", "))
+	}
+	if name, ok := strings.CutPrefix(key, calibratedOverheadPrefix); ok {
+		if _, err := getFrameworkPreset(name); err != nil {
+			return fmt.Errorf("unknown setting %q; %v", key, err)
+		}
+		if overhead, err := strconv.Atoi(value); err != nil || overhead < 0 {
+			return fmt.Errorf("invalid %s: %q is not a valid percentage", key, value)
+		}
+		return nil
+	}
+
+	flag := cmd.Flags().Lookup(key)
+	if flag == nil || key == "config-file" || key == "config" || key == "profile" {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+
+	var err error
+	switch flag.Value.Type() {
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int":
+		_, err = strconv.Atoi(value)
+	case "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s: %q is not a valid %s", key, value, flag.Value.Type())
+	}
+	return nil
+}
+
+// validateConfigFile checks every setting of a config file without applying any of them
+// and returns a problem for each invalid line, prefixed with the path and line number.
+func validateConfigFile(cmd *cobra.Command, path string) ([]error, error) {
+	entries, err := readConfigEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []error
+	for _, e := range entries {
+		if err := checkSetting(cmd, e.Key, e.Value); err != nil {
+			if e.Profile != "" {
+				err = fmt.Errorf("profile %s: %v", e.Profile, err)
+			}
+			problems = append(problems, fmt.Errorf("%s:%d: %v", path, e.Line, err))
+		}
+	}
+	return problems, nil
+}
+
+// validateConfigCmd checks a config file without running an estimate
+var validateConfigCmd = &cobra.Command{
+	Use:   "validate-config PATH",
+	Short: "Check a config file for unknown settings and invalid values",
+	Args:  cobra.ExactArgs(1),
+	// The file given is checked on its own, so a broken default one doesn't get in the way
+	Annotations: map[string]string{noSettingsAnnotation: "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		problems, err := validateConfigFile(rootCmd, args[0])
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(cmd.ErrOrStderr(), problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%s: %d invalid settings", args[0], len(problems))
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: valid\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateConfigCmd)
+}
+
+// defaultConfigPath returns the first of the default config files that exists, the one in
+// the user's config directory and then the one in the home directory, or an empty path
+// when neither does
+func defaultConfigPath() string {
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, filepath.FromSlash(userConfigFile)))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, defaultConfigFile))
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfig applies the config file given with --config-file, or the default one when
+// it exists, along with the profile selected with --profile. A missing default config
+// file is silently ignored unless a profile is selected.
+func loadConfig(cmd *cobra.Command) error {
+	path := configFile
+	if path == "" {
+		path = defaultConfigPath()
+	}
+	if path == "" {
+		if profile != "" {
+			return fmt.Errorf("--profile %s requires a config file; none found at $XDG_CONFIG_HOME/%s or $HOME/%s", profile, userConfigFile, defaultConfigFile)
+		}
+		return nil
+	}
+
+	slog.Debug("reading config file", "path", path, "profile", profile)
+	values, err := readConfigFile(path, profile)
+	if err != nil {
+		return err
+	}
+
+	// The file is shared by every subcommand, so each one skips the settings it doesn't
+	// have, while the main command reports them as unknown
+	if cmd.HasParent() {
+		for key := range values {
+			if !isSetting(cmd, key) {
+				slog.Debug("setting not used by the command", "source", path, "setting", key, "command", cmd.Name())
+				delete(values, key)
+			}
+		}
+	}
+	return applySettings(cmd, path, values)
+}
+
+// isSetting reports whether the key names a setting the command takes: one of its flags,
+// "precision" and "format" when it has those flags, or an overhead calibrated for a
+// framework
+func isSetting(cmd *cobra.Command, key string) bool {
+	switch key {
+	case "precision":
+		return cmd.Flags().Lookup("precision") != nil
+	case "format":
+		return cmd.Flags().Lookup("output") != nil
+	case "config-file", "config", "profile":
+		return false
+	}
+	if strings.HasPrefix(key, calibratedOverheadPrefix) {
+		return true
+	}
+	return cmd.Flags().Lookup(key) != nil
+}
+
+// noSettingsAnnotation marks the subcommands that don't take settings from the
+// environment or the config file, such as validate-config, which checks a file itself
+const noSettingsAnnotation = "no-settings"
+
+// takesSettings reports whether the command applies the settings of the environment and
+// the config file: every subcommand except those marked with noSettingsAnnotation and the
+// help and shell completion commands Cobra adds
+func takesSettings(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+		if c.Annotations[noSettingsAnnotation] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// saveConfigSetting sets a setting outside of the profiles of a config file, replacing
+// the line that already sets it or adding one before the profiles, and creates the file
+// and its directory when they don't exist yet
+func saveConfigSetting(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	setting := fmt.Sprintf("%s: %s", key, value)
+	at := len(lines)
+	replace := false
+	for i, line := range lines {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, rest, _ := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if name == key {
+			at, replace = i, true
+			break
+		}
+		if name == "profiles" && strings.TrimSpace(rest) == "" && at == len(lines) {
+			at = i
+		}
+	}
+	if replace {
+		lines[at] = setting
+	} else {
+		lines = append(lines[:at], append([]string{setting}, lines[at:]...)...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return internalError(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		return internalError(err)
+	}
+	return nil
+}
+
+// writableConfigPath returns the config file settings are saved to: the one given with
+// --config-file, else the default one that exists, else the one in the user's config
+// directory
+func writableConfigPath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+	if path := defaultConfigPath(); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(userConfigFile)), nil
+}
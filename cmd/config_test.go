@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		problems []string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			contents: "# defaults\nprecision: fp16\noverhead: 15\nprofiles:\n  long:\n    context: 32768\n",
+		},
+		{
+			name:     "invalid settings",
+			contents: "precision: fp17\ncolour: never\noverhead: lots\n",
+			problems: []string{":1:", ":2:", ":3:"},
+		},
+		{
+			name:     "malformed",
+			contents: "precision fp16\n",
+			wantErr:  ":1: expected 'key: value'",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(tt.contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		problems, err := validateConfigFile(rootCmd, path)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateConfigFile(%s) = %v, want an error containing %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("validateConfigFile(%s) returned error: %v", tt.name, err)
+			continue
+		}
+		if len(problems) != len(tt.problems) {
+			t.Errorf("validateConfigFile(%s) = %v, want %d problems", tt.name, problems, len(tt.problems))
+			continue
+		}
+		for i, line := range tt.problems {
+			if !strings.HasPrefix(problems[i].Error(), path+line) {
+				t.Errorf("validateConfigFile(%s) problem %q, want it to start with %q", tt.name, problems[i], path+line)
+			}
+		}
+	}
+}
+
+func TestExclusiveFlagOutranksDefaults(t *testing.T) {
+	model := []string{"-s", "7b", "--precision", "fp16"}
+
+	// Flags given together on the command line still conflict
+	if code, _, errOut := runRoot(t, append(model, "--gpu", "h100", "--gpu-memory", "24gb")...); code != exitInvalidInput {
+		t.Errorf("--gpu with --gpu-memory exited with %d, want %d: %s", code, exitInvalidInput, errOut)
+	}
+
+	// A variable for one flag of a group gives way to another given on the command line
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("GPU_MEM_GPU", "h100")
+		t.Setenv("GPU_MEM_OUTPUT", "yaml")
+		got := estimateRoot(t, append(model, "--gpu-memory", "24gb")...)
+		if got.GPU != "" || got.GPUsRequired != 1 {
+			t.Errorf("GPU_MEM_GPU with --gpu-memory 24gb gave gpu %q and %d GPUs required, want no gpu and 1", got.GPU, got.GPUsRequired)
+		}
+
+		t.Setenv("GPU_MEM_OUTPUT", "")
+		t.Setenv("GPU_MEM_JSON", "true")
+		code, out, errOut := runRoot(t, append(model, "-q")...)
+		if code != 0 || out != "16.80 GB\n" {
+			t.Errorf("GPU_MEM_JSON with -q exited with %d and wrote %q, want 0 and %q: %s", code, out, "16.80 GB\n", errOut)
+		}
+	})
+
+	// So does a setting of the config file
+	t.Run("config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("output: yaml\ntensor-parallel: 2\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got := estimateRoot(t, append(model, "--config-file", path)...)
+		if got.MemBytesPerGPU == 0 {
+			t.Errorf("config tensor-parallel: 2 gave no memory per GPU, want it applied without --gpus")
+		}
+
+		code, _, errOut := runRoot(t, append(model, "--config-file", path, "--gpus", "4", "--output", "csv")...)
+		if code != 0 {
+			t.Errorf("config tensor-parallel: 2 with --gpus 4 exited with %d: %s", code, errOut)
+		}
+	})
+}
+
+func TestModelOutranksDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("size: 7b\nhidden-dim: 4096\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	model, err := getRegistryModel("llama3.1-70b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A model given on the command line replaces the size and architecture of the config
+	// file and the environment
+	t.Run("config", func(t *testing.T) {
+		got := estimateRoot(t, "--config-file", path, "--model", "llama3.1-70b", "--precision", "fp16")
+		if got.Parameters != model.Parameters {
+			t.Errorf("--model llama3.1-70b with size: 7b in the config file has %d parameters, want %d", got.Parameters, model.Parameters)
+		}
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("GPU_MEM_SIZE", "7b")
+		got := estimateRoot(t, "--model", "llama3.1-70b", "--precision", "fp16")
+		if got.Parameters != model.Parameters {
+			t.Errorf("--model llama3.1-70b with GPU_MEM_SIZE=7b has %d parameters, want %d", got.Parameters, model.Parameters)
+		}
+	})
+
+	// A size given on the command line still wins, and is rejected when it contradicts
+	// the architecture of the model
+	code, _, errOut := runRoot(t, "--model", "llama3.1-70b", "-s", "7b", "--precision", "fp16")
+	if code != exitInvalidInput || !strings.Contains(errOut, "--size is too small for --num-layers and --hidden-dim") {
+		t.Errorf("--model llama3.1-70b with -s 7b exited with %d: %s, want %d and the size rejected", code, errOut, exitInvalidInput)
+	}
+}
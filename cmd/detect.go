@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localGPU is a GPU found on the local machine, with its memory in bytes. The memory of
+// an Apple Silicon GPU is the unified memory it shares with the CPU, and what is free is
+// the working set macOS lets the GPU use.
+type localGPU struct {
+	Index   int
+	Name    string
+	Memory  int
+	Free    int
+	Unified bool
+}
+
+// jsonLocalGPU is the shape of a single local GPU in the --json output
+type jsonLocalGPU struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	Memory      string `json:"memory"`
+	MemoryBytes int    `json:"memory_bytes"`
+	Free        string `json:"free"`
+	FreeBytes   int    `json:"free_bytes"`
+	Unified     bool   `json:"unified,omitempty"`
+}
+
+// jsonDetected is the shape of the local GPUs and the fit against their free memory in
+// the --json output
+type jsonDetected struct {
+	GPUs          []jsonLocalGPU `json:"gpus"`
+	FitsOnOne     bool           `json:"fits_on_one"`
+	FitsAcrossAll bool           `json:"fits_across_all"`
+}
+
+// gpuVendors lists the vendors whose local GPUs --detect can find
+var gpuVendors = []string{"nvidia", "amd", "apple"}
+
+// nvidiaSMIQuery lists the fields nvidia-smi reports for each GPU, with memory in MiB
+var nvidiaSMIQuery = []string{"--query-gpu=index,name,memory.total,memory.free", "--format=csv,noheader,nounits"}
+
+// amdVendorID is the PCI vendor ID of AMD, as sysfs reports it for each card
+const amdVendorID = "0x1002"
+
+// drmPath is the sysfs directory of the cards the kernel's DRM drivers manage
+const drmPath = "/sys/class/drm"
+
+// detectGPUs enumerates the local GPUs of the vendor, or of whichever vendor has GPUs
+// when it is auto, which on macOS is Apple. Both NVIDIA and AMD having GPUs needs the
+// vendor to be given, as a model can't be split across them.
+func detectGPUs(vendor string) ([]localGPU, error) {
+	switch strings.ToLower(vendor) {
+	case "nvidia":
+		return detected(detectNvidiaGPUs())
+	case "amd":
+		return detected(detectAMDGPUs())
+	case "apple":
+		return detected(detectAppleGPU())
+	case "", "auto":
+		if runtime.GOOS == "darwin" {
+			return detected(detectAppleGPU())
+		}
+	default:
+		return nil, fmt.Errorf("unknown vendor %q; must be one of auto, %s", vendor, strings.Join(gpuVendors, ", "))
+	}
+
+	nvidia, nvidiaErr := detectNvidiaGPUs()
+	amd, amdErr := detectAMDGPUs()
+	switch {
+	case nvidiaErr == nil && amdErr == nil:
+		return nil, errors.New("found both NVIDIA and AMD GPUs; pick one with --vendor")
+	case nvidiaErr == nil:
+		return detected(nvidia, nil)
+	case amdErr == nil:
+		return detected(amd, nil)
+	}
+	return nil, internalError(fmt.Errorf("--detect found no GPUs: %v; %v", nvidiaErr, amdErr))
+}
+
+// detected marks a failure to query the GPUs of the machine as an error of the
+// environment rather than of the input, and logs the GPUs it found
+func detected(gpus []localGPU, err error) ([]localGPU, error) {
+	if err != nil {
+		return nil, internalError(err)
+	}
+	for _, g := range gpus {
+		slog.Debug("detected GPU", "index", g.Index, "name", g.Name, "memory_bytes", g.Memory, "free_bytes", g.Free, "unified", g.Unified)
+	}
+	return gpus, nil
+}
+
+// detectNvidiaGPUs enumerates the local NVIDIA GPUs with nvidia-smi
+func detectNvidiaGPUs() ([]localGPU, error) {
+	output, err := runSMI("nvidia-smi", nvidiaSMIQuery...)
+	if err != nil {
+		return nil, err
+	}
+	gpus, err := parseNvidiaSMI(string(output))
+	if err != nil {
+		return nil, err
+	}
+	if len(gpus) == 0 {
+		return nil, errors.New("nvidia-smi found no GPUs")
+	}
+	return gpus, nil
+}
+
+// detectAMDGPUs enumerates the local AMD GPUs with rocm-smi, or from sysfs when rocm-smi
+// isn't installed, as the amdgpu driver reports the VRAM of each card there as well
+func detectAMDGPUs() ([]localGPU, error) {
+	output, err := runSMI("rocm-smi", "--showproductname", "--showmeminfo", "vram", "--json")
+	if errors.Is(err, exec.ErrNotFound) {
+		gpus, sysfsErr := readSysfsAMDGPUs(drmPath)
+		if sysfsErr != nil {
+			return nil, fmt.Errorf("%v, and %v", err, sysfsErr)
+		}
+		return gpus, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	gpus, err := parseROCmSMI(output)
+	if err != nil {
+		return nil, err
+	}
+	if len(gpus) == 0 {
+		return nil, errors.New("rocm-smi found no GPUs")
+	}
+	return gpus, nil
+}
+
+// detectAppleGPU reads the unified memory of an Apple Silicon Mac with sysctl, along with
+// the share of it the GPU may use
+func detectAppleGPU() ([]localGPU, error) {
+	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
+		return nil, errors.New("Apple Silicon unified memory can only be detected on an M-series Mac")
+	}
+	output, err := runSMI("sysctl", "-n", "hw.memsize")
+	if err != nil {
+		return nil, err
+	}
+	memory, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected hw.memsize %q", strings.TrimSpace(string(output)))
+	}
+
+	// The limit raised with sysctl iogpu.wired_limit_mb replaces the default working set,
+	// and is 0 when it hasn't been set
+	var wiredLimitMB int
+	if output, err := runSMI("sysctl", "-n", "iogpu.wired_limit_mb"); err == nil {
+		wiredLimitMB, _ = strconv.Atoi(strings.TrimSpace(string(output)))
+	}
+
+	name := "Apple Silicon"
+	if output, err := runSMI("sysctl", "-n", "machdep.cpu.brand_string"); err == nil {
+		name = strings.TrimSpace(string(output))
+	}
+	return []localGPU{{
+		Name:    name,
+		Memory:  memory,
+		Free:    appleWorkingSetSize(memory, wiredLimitMB),
+		Unified: true,
+	}}, nil
+}
+
+// appleWorkingSetSize returns how much of the unified memory the GPU may use, following
+// Metal's recommendedMaxWorkingSetSize: about two thirds of the memory of Macs with less
+// than 36 GiB and three quarters of larger ones, leaving the rest to macOS and other apps
+func appleWorkingSetSize(memory, wiredLimitMB int) int {
+	if wiredLimitMB > 0 {
+		return min(wiredLimitMB*1024*1024, memory)
+	}
+	if memory < 36*1024*1024*1024 {
+		return memory * 2 / 3
+	}
+	return memory * 3 / 4
+}
+
+// runSMI runs a GPU management tool, returning what it prints on failure as the error
+func runSMI(name string, args ...string) ([]byte, error) {
+	slog.Debug("running GPU query", "command", name, "args", args)
+	output, err := exec.Command(name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s failed: %s", name, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return output, nil
+}
+
+// parseNvidiaSMI parses the CSV output of nvidia-smi for the nvidiaSMIQuery fields
+func parseNvidiaSMI(output string) ([]localGPU, error) {
+	var gpus []localGPU
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected nvidia-smi output %q: %v", line, err)
+		}
+		var mebibytes [2]int
+		for i, field := range fields[2:] {
+			mebibytes[i], err = strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected nvidia-smi output %q: %v", line, err)
+			}
+		}
+		gpus = append(gpus, localGPU{
+			Index:  index,
+			Name:   fields[1],
+			Memory: mebibytes[0] * 1024 * 1024,
+			Free:   mebibytes[1] * 1024 * 1024,
+		})
+	}
+	return gpus, nil
+}
+
+// parseROCmSMI parses the JSON output of rocm-smi, which reports each card under its
+// name, such as "card0", with the VRAM in bytes as strings
+func parseROCmSMI(output []byte) ([]localGPU, error) {
+	var cards map[string]map[string]string
+	if err := json.Unmarshal(output, &cards); err != nil {
+		return nil, fmt.Errorf("unexpected rocm-smi output: %v", err)
+	}
+
+	var gpus []localGPU
+	for card, info := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil {
+			// rocm-smi adds entries that aren't cards, such as "system"
+			continue
+		}
+		total, err := strconv.Atoi(info["VRAM Total Memory (B)"])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected rocm-smi output for %s: no VRAM total", card)
+		}
+		used, err := strconv.Atoi(info["VRAM Total Used Memory (B)"])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected rocm-smi output for %s: no VRAM used", card)
+		}
+		name := info["Card series"]
+		if name == "" {
+			name = "AMD GPU"
+		}
+		gpus = append(gpus, localGPU{Index: index, Name: name, Memory: total, Free: total - used})
+	}
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
+
+// readSysfsAMDGPUs reads the VRAM of each AMD card from the files the amdgpu driver keeps
+// under the DRM directory of sysfs
+func readSysfsAMDGPUs(dir string) ([]localGPU, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "card[0-9]*", "device", "mem_info_vram_total"))
+	if err != nil {
+		return nil, err
+	}
+
+	var gpus []localGPU
+	for _, totalPath := range paths {
+		device := filepath.Dir(totalPath)
+		card := filepath.Base(filepath.Dir(device))
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil {
+			// Connectors such as card0-DP-1 link to the same device as their card
+			continue
+		}
+		if vendorID, err := readSysfsValue(filepath.Join(device, "vendor")); err != nil || vendorID != amdVendorID {
+			continue
+		}
+		total, err := readSysfsInt(totalPath)
+		if err != nil {
+			return nil, err
+		}
+		used, err := readSysfsInt(filepath.Join(device, "mem_info_vram_used"))
+		if err != nil {
+			return nil, err
+		}
+		name := "AMD GPU"
+		if id, err := readSysfsValue(filepath.Join(device, "device")); err == nil {
+			name += " " + id
+		}
+		gpus = append(gpus, localGPU{Index: index, Name: name, Memory: total, Free: total - used})
+	}
+	if len(gpus) == 0 {
+		return nil, fmt.Errorf("no AMD GPUs found in %s", dir)
+	}
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Index < gpus[j].Index })
+	return gpus, nil
+}
+
+// readSysfsValue reads a single value from a sysfs file
+func readSysfsValue(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
+
+// readSysfsInt reads a single number from a sysfs file
+func readSysfsInt(path string) (int, error) {
+	value, err := readSysfsValue(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected value %q in %s", value, path)
+	}
+	return n, nil
+}
+
+// localFit reports whether the memory required fits in the free memory of a single local
+// GPU, and of all of them together when the model is split across them
+func localFit(required int, gpus []localGPU) (fitsOnOne, fitsAcrossAll bool) {
+	var free int
+	for _, g := range gpus {
+		fitsOnOne = fitsOnOne || required <= g.Free
+		free += g.Free
+	}
+	return fitsOnOne, required <= free
+}
+
+// jsonLocalGPUs returns the local GPUs with the fit of the memory required for --json
+func jsonLocalGPUs(required int, gpus []localGPU) *jsonDetected {
+	detected := &jsonDetected{}
+	for _, g := range gpus {
+		detected.GPUs = append(detected.GPUs, jsonLocalGPU{
+			Index:       g.Index,
+			Name:        g.Name,
+			Memory:      formatMemory(g.Memory),
+			MemoryBytes: g.Memory,
+			Free:        formatMemory(g.Free),
+			FreeBytes:   g.Free,
+			Unified:     g.Unified,
+		})
+	}
+	detected.FitsOnOne, detected.FitsAcrossAll = localFit(required, gpus)
+	return detected
+}
+
+// printLocalGPUs prints the local GPUs with their free memory and the verdict for the
+// memory required
+func printLocalGPUs(w io.Writer, required int, gpus []localGPU) {
+	// The unified memory of a Mac is shared with macOS and every other app, so the model
+	// is held to the share the GPU may use rather than to what is free
+	if len(gpus) == 1 && gpus[0].Unified {
+		g := gpus[0]
+		fmt.Fprintf(w, "Local GPU: %s, %s of %s unified memory usable by the GPU, the rest being left to macOS and other apps\n", g.Name, formatMemory(g.Free), formatMemory(g.Memory))
+		if required <= g.Free {
+			fmt.Fprintf(w, "Verdict: fits in the GPU's share of the unified memory with %s to spare, though other apps compete for it\n", formatMemory(g.Free-required))
+		} else {
+			fmt.Fprintf(w, "Verdict: does not fit in the GPU's share of the unified memory, short by %s\n", formatMemory(required-g.Free))
+		}
+		return
+	}
+
+	fmt.Fprintln(w, "Local GPUs:")
+	for _, g := range gpus {
+		fmt.Fprintf(w, "  %d: %s, %s free of %s\n", g.Index, g.Name, formatMemory(g.Free), formatMemory(g.Memory))
+	}
+	fitsOnOne, fitsAcrossAll := localFit(required, gpus)
+	switch {
+	case fitsOnOne:
+		fmt.Fprintln(w, "Verdict: fits in the free memory of a single local GPU")
+	case fitsAcrossAll:
+		fmt.Fprintf(w, "Verdict: fits in the free memory of the %d local GPUs together, but not on any single one\n", len(gpus))
+	default:
+		fmt.Fprintln(w, "Verdict: does not fit in the free memory of the local GPUs")
+	}
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// diffSetting is a setting of a spec, a flag with its value, or for the precision every
+// flag selecting it, as one can only be swapped for another together
+type diffSetting struct {
+	Name  string
+	Flags []string
+}
+
+// String formats the flags of the setting as they would be given, or "unset"
+func (s diffSetting) String() string {
+	if len(s.Flags) == 0 {
+		return "unset"
+	}
+	return strings.Join(s.Flags, " ")
+}
+
+// parseDiffSpec parses a spec given as the flags of a model, each flag as --name=value,
+// into its settings in the order of the flags
+func parseDiffSpec(spec string) ([]diffSetting, error) {
+	// The flags are recorded as they are parsed, which keeps them in the order given
+	var given []*pflag.Flag
+	flags := newDiffSpecCommand().Flags()
+	err := flags.ParseAll(strings.Fields(spec), func(f *pflag.Flag, value string) error {
+		if !slices.Contains(given, f) {
+			given = append(given, f)
+		}
+		return flags.Set(f.Name, value)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec %q: %v", spec, err)
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("invalid spec %q; unexpected argument %q", spec, flags.Arg(0))
+	}
+
+	var settings []diffSetting
+	for _, f := range given {
+		name := f.Name
+		if slices.Contains(precisionFlags, name) || name == "group-size" {
+			name = "precision"
+		}
+		flag := fmt.Sprintf("--%s=%s", f.Name, f.Value)
+		if f.Value.Type() == "bool" && f.Value.String() == "true" {
+			flag = "--" + f.Name
+		}
+		i := slices.IndexFunc(settings, func(s diffSetting) bool { return s.Name == name })
+		if i < 0 {
+			settings = append(settings, diffSetting{Name: name})
+			i = len(settings) - 1
+		}
+		settings[i].Flags = append(settings[i].Flags, flag)
+	}
+	return settings, nil
+}
+
+// newDiffSpecCommand returns a command with the flags a spec takes. Defining the flags
+// resets them to their defaults, so every spec starts from the same place.
+func newDiffSpecCommand() *cobra.Command {
+	c := &cobra.Command{Use: "spec"}
+	addModelFlags(c)
+	c.Flags().IntVar(&tensorParallel, "tensor-parallel", 1, "number of GPUs to shard the model across with tensor parallelism")
+	c.Flags().StringVar(&mode, "mode", "inference", "estimate for inference or full fine-tuning with train")
+	return c
+}
+
+// estimateDiffSpec estimates the model of the settings
+func estimateDiffSpec(settings []diffSetting) (estimator.Estimate, error) {
+	var args []string
+	for _, s := range settings {
+		args = append(args, s.Flags...)
+	}
+	c := newDiffSpecCommand()
+	if err := c.ParseFlags(args); err != nil {
+		return estimator.Estimate{}, err
+	}
+	if err := applyModelFlags(c); err != nil {
+		return estimator.Estimate{}, err
+	}
+	if !precisionFlagChanged(c) {
+		return estimator.Estimate{}, errors.New("a precision is required, such as --precision bf16")
+	}
+	input, err := modelSpecFromFlags()
+	if err != nil {
+		return estimator.Estimate{}, err
+	}
+	input.TensorParallel = tensorParallel
+	switch strings.ToLower(mode) {
+	case "inference":
+	case "train":
+		input.Train = true
+	default:
+		return estimator.Estimate{}, fmt.Errorf("unknown mode %q; must be one of %s", mode, strings.Join(estimateModes, ", "))
+	}
+	return calculate(input)
+}
+
+// diffChange is a setting changed from the first spec to the second, with the memory per
+// GPU it adds, or saves when negative
+type diffChange struct {
+	Setting string
+	From    diffSetting
+	To      diffSetting
+	Delta   int
+}
+
+// diffChanges applies each setting of the second spec that differs from the first one at
+// a time, in the order the second spec gives them followed by those it leaves out, so
+// their deltas add up to the difference between the two
+func diffChanges(a, b []diffSetting) ([]diffChange, estimator.Estimate, error) {
+	current := slices.Clone(a)
+	previous, err := estimateDiffSpec(current)
+	if err != nil {
+		return nil, estimator.Estimate{}, err
+	}
+
+	var changes []diffChange
+	apply := func(to diffSetting) error {
+		change := diffChange{Setting: to.Name, To: to}
+		i := slices.IndexFunc(current, func(s diffSetting) bool { return s.Name == to.Name })
+		if i >= 0 {
+			change.From = current[i]
+			current[i] = to
+		} else {
+			current = append(current, to)
+		}
+		if slices.Equal(change.From.Flags, to.Flags) {
+			return nil
+		}
+		result, err := estimateDiffSpec(current)
+		if err != nil {
+			return fmt.Errorf("error applying %s: %v", to, err)
+		}
+		change.Delta = result.Total - previous.Total
+		changes = append(changes, change)
+		previous = result
+		return nil
+	}
+	for _, s := range b {
+		if err := apply(s); err != nil {
+			return nil, estimator.Estimate{}, err
+		}
+	}
+	for _, s := range a {
+		if !slices.ContainsFunc(b, func(t diffSetting) bool { return t.Name == s.Name }) {
+			if err := apply(diffSetting{Name: s.Name}); err != nil {
+				return nil, estimator.Estimate{}, err
+			}
+		}
+	}
+	return changes, previous, nil
+}
+
+// jsonDiffComponent is the shape of a single component in the diff JSON output
+type jsonDiffComponent struct {
+	Name       string `json:"name"`
+	BytesFrom  int    `json:"bytes_from"`
+	BytesTo    int    `json:"bytes_to"`
+	DeltaBytes int    `json:"delta_bytes"`
+}
+
+// jsonDiffChange is the shape of a single changed setting in the diff JSON output
+type jsonDiffChange struct {
+	Setting    string `json:"setting"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	DeltaBytes int    `json:"delta_bytes"`
+}
+
+// jsonDiff is the shape of the output produced by diff with --json
+type jsonDiff struct {
+	MemBytesPerGPUFrom int                 `json:"mem_bytes_per_gpu_from"`
+	MemBytesPerGPUTo   int                 `json:"mem_bytes_per_gpu_to"`
+	DeltaBytes         int                 `json:"delta_bytes"`
+	Components         []jsonDiffComponent `json:"components"`
+	Changes            []jsonDiffChange    `json:"changes"`
+}
+
+// diffComponents lines the components of the two estimates up by name, in the order of
+// the first followed by those only the second has
+func diffComponents(a, b estimator.Estimate) []jsonDiffComponent {
+	var components []jsonDiffComponent
+	add := func(c jsonComponent, bytes func(*jsonDiffComponent) *int) {
+		i := slices.IndexFunc(components, func(d jsonDiffComponent) bool { return d.Name == c.Name })
+		if i < 0 {
+			components = append(components, jsonDiffComponent{Name: c.Name})
+			i = len(components) - 1
+		}
+		*bytes(&components[i]) += c.Bytes
+	}
+	for _, c := range jsonComponents(a) {
+		add(c, func(d *jsonDiffComponent) *int { return &d.BytesFrom })
+	}
+	for _, c := range jsonComponents(b) {
+		add(c, func(d *jsonDiffComponent) *int { return &d.BytesTo })
+	}
+	for i := range components {
+		components[i].DeltaBytes = components[i].BytesTo - components[i].BytesFrom
+	}
+	return components
+}
+
+// describeChange phrases a change and what it does to the memory, such as "setting
+// --kv-dtype=fp8 saves 6.20 GB"
+func describeChange(c diffChange) string {
+	var action string
+	switch {
+	case len(c.From.Flags) == 0:
+		action = "setting " + c.To.String()
+	case len(c.To.Flags) == 0:
+		action = "removing " + c.From.String()
+	default:
+		action = fmt.Sprintf("changing %s to %s", c.From, c.To)
+	}
+	switch {
+	case c.Delta > 0:
+		return fmt.Sprintf("%s costs %s", action, formatMemory(c.Delta))
+	case c.Delta < 0:
+		return fmt.Sprintf("%s saves %s", action, formatMemory(-c.Delta))
+	default:
+		return action + " makes no difference"
+	}
+}
+
+// printDiff prints the components of the two estimates side by side, followed by what
+// each changed setting does to the memory
+func printDiff(w io.Writer, output jsonDiff, changes []diffChange, perGPU bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Component\tFrom\tTo\tChange")
+	for _, c := range output.Components {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, formatMemory(c.BytesFrom), formatMemory(c.BytesTo), signedMemory(c.DeltaBytes))
+	}
+	total := "total"
+	if perGPU {
+		total = "total per GPU"
+	}
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", total, formatMemory(output.MemBytesPerGPUFrom), formatMemory(output.MemBytesPerGPUTo), signedMemory(output.DeltaBytes))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "The specs are the same")
+		return nil
+	}
+	fmt.Fprintln(w, "Changes, applied one at a time:")
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s\n", describeChange(c))
+	}
+	return nil
+}
+
+// diffCmd compares the estimates of two specs
+var diffCmd = &cobra.Command{
+	Use:   "diff --from SPEC --to SPEC",
+	Short: "Compare the estimates of two specs component by component",
+	Long: `Provide two specs with --from and --to, each the flags of a model quoted as a single
+value, to compare their estimates component by component and see what each setting
+changed from one to the other costs or saves, such as "setting --kv-dtype=fp8 saves
+1.07 GB". A spec takes the model flags of recommend-gpu along with --tensor-parallel and
+--mode. The changes are applied to the --from spec one at a time, in the order the --to
+spec gives them, so their costs add up to the difference; the flags selecting the
+precision change together. With --tensor-parallel, the memory is that of each GPU.
+
+For example:
+./gpu-mem-for-llm diff --from "--model llama3.1-8b --precision bf16 --context 8192" --to "--model llama3.1-8b --precision bf16 --context 32768 --kv-dtype fp8"
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		a, err := parseDiffSpec(diffFrom)
+		if err != nil {
+			return err
+		}
+		b, err := parseDiffSpec(diffTo)
+		if err != nil {
+			return err
+		}
+		resultA, err := estimateDiffSpec(a)
+		if err != nil {
+			return fmt.Errorf("error estimating --from: %v", err)
+		}
+		changes, resultB, err := diffChanges(a, b)
+		if err != nil {
+			return err
+		}
+
+		output := jsonDiff{
+			MemBytesPerGPUFrom: resultA.Total,
+			MemBytesPerGPUTo:   resultB.Total,
+			DeltaBytes:         resultB.Total - resultA.Total,
+			Components:         diffComponents(resultA, resultB),
+			Changes:            make([]jsonDiffChange, 0, len(changes)),
+		}
+		for _, c := range changes {
+			output.Changes = append(output.Changes, jsonDiffChange{Setting: c.Setting, From: c.From.String(), To: c.To.String(), DeltaBytes: c.Delta})
+		}
+		if jsonOutput {
+			return writeStructured(cmd.OutOrStdout(), "json", output)
+		}
+		return printDiff(cmd.OutOrStdout(), output, changes, resultA.GPUs > 1 || resultB.GPUs > 1)
+	},
+}
+
+var (
+	diffFrom string
+	diffTo   string
+)
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFrom, "from", "", "flags of the model to compare from (e.g., \"--model llama3.1-8b --precision bf16 --context 8192\")")
+	diffCmd.Flags().StringVar(&diffTo, "to", "", "flags of the model to compare to")
+	diffCmd.MarkFlagRequired("from")
+	diffCmd.MarkFlagRequired("to")
+	diffCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// draftFlags lists the flags describing the draft model of speculative decoding
+var draftFlags = []string{"draft-size", "draft-precision", "draft-num-layers", "draft-hidden-dim", "draft-head-dim", "draft-kv-heads"}
+
+// draftModel returns the draft model given with --draft-model or --draft-size, taking the
+// architecture from the registry model and replacing any part of it given with its own
+// flag. It is the zero DraftModel when there is no draft model.
+func draftModel(cmd *cobra.Command) (estimator.DraftModel, error) {
+	var draft estimator.DraftModel
+	if draftModelName == "" && draftSize == "" {
+		for _, name := range draftFlags {
+			if cmd.Flags().Changed(name) {
+				return draft, errors.New("--" + name + " requires --draft-model or --draft-size")
+			}
+		}
+		return draft, nil
+	}
+
+	if draftModelName != "" {
+		// The registry was already extended with --model-db for --model
+		if modelDB != "" && registryModelName == "" {
+			if err := loadModelRegistry(modelDB); err != nil {
+				return draft, err
+			}
+		}
+		m, err := getRegistryModel(draftModelName)
+		if err != nil {
+			return draft, err
+		}
+		draft = estimator.DraftModel{
+			ParameterSize: m.Parameters,
+			NumLayers:     m.NumLayers,
+			HiddenDim:     m.HiddenDim,
+			HeadDim:       m.HeadDim,
+			KVHeads:       m.KVHeads,
+		}
+		if draft.HeadDim == 0 && m.Heads > 0 {
+			draft.HeadDim = m.HiddenDim / m.Heads
+		}
+	}
+
+	if draftSize != "" {
+		parameterSize, err := getParameterSize(draftSize)
+		if err != nil {
+			return draft, err
+		}
+		draft.ParameterSize = parameterSize
+	}
+	for name, value := range map[string]*int{
+		"draft-num-layers": &draft.NumLayers,
+		"draft-hidden-dim": &draft.HiddenDim,
+		"draft-head-dim":   &draft.HeadDim,
+		"draft-kv-heads":   &draft.KVHeads,
+	} {
+		if cmd.Flags().Changed(name) {
+			*value, _ = cmd.Flags().GetInt(name)
+		}
+	}
+	if draft.KVHeads > 0 && draft.HeadDim <= 0 {
+		return draft, errors.New("--draft-kv-heads requires --draft-head-dim")
+	}
+
+	if draftPrecision != "" {
+		precision, err := estimator.PrecisionByName(draftPrecision)
+		if err != nil {
+			return draft, err
+		}
+		draft.Precision = precision
+	}
+	return draft, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// ensembleMember is one model of an ensemble along with the estimate for it on its own
+type ensembleMember struct {
+	Model    string
+	Estimate estimator.Estimate
+}
+
+// jsonEnsembleMember is the shape of a single model in the ensemble JSON output
+type jsonEnsembleMember struct {
+	Model    string `json:"model"`
+	MemSize  string `json:"mem_size"`
+	MemBytes int    `json:"mem_bytes"`
+}
+
+// jsonEnsemble is the shape of the output produced by the ensemble subcommand with --json
+type jsonEnsemble struct {
+	MemSize  string               `json:"mem_size"`
+	MemBytes int                  `json:"mem_bytes"`
+	Models   []jsonEnsembleMember `json:"models"`
+}
+
+// parseEnsembleMember parses a model given as SIZE:PRECISION, such as "7b:fp16" or
+// "13b:Q4_K_M".
+func parseEnsembleMember(value string) (estimator.ModelSpec, error) {
+	sizeValue, precisionName, found := strings.Cut(value, ":")
+	if !found {
+		return estimator.ModelSpec{}, fmt.Errorf("invalid model %q; must be in the form SIZE:PRECISION such as 7b:fp16", value)
+	}
+
+	parameterSize, err := getParameterSize(sizeValue)
+	if err != nil {
+		return estimator.ModelSpec{}, err
+	}
+	precision, err := lookupPrecision(precisionName)
+	if err != nil {
+		return estimator.ModelSpec{}, err
+	}
+
+	return estimator.ModelSpec{ParameterSize: parameterSize, Precision: precision}, nil
+}
+
+// calculateEnsemble estimates each model of an ensemble on its own and returns them along
+// with the combined memory. Every model stays resident to vote on each request, so the
+// ensemble needs the sum of its members.
+func calculateEnsemble(models []string, overhead float32) ([]ensembleMember, int, error) {
+	members := make([]ensembleMember, 0, len(models))
+	var total int
+	for _, model := range models {
+		input, err := parseEnsembleMember(model)
+		if err != nil {
+			return nil, 0, err
+		}
+		input.Overhead = overhead
+
+		result, err := calculate(input)
+		if err != nil {
+			return nil, 0, err
+		}
+		members = append(members, ensembleMember{Model: model, Estimate: result})
+		total += result.Total
+	}
+	return members, total, nil
+}
+
+// ensembleCmd estimates the memory for several models kept resident together for voting
+var ensembleCmd = &cobra.Command{
+	Use:   "ensemble SIZE:PRECISION...",
+	Short: "Estimate memory for an ensemble of models kept resident together",
+	Long: `Estimate the memory required to keep several models resident together, such as
+an ensemble voting on each request. Each model is given as its parameter size and
+precision separated by a colon, where the precision is any --precision accepts, such
+as int4 or Q4_K_M, and the overhead is applied to each of them.
+
+For example:
+./gpu-mem-for-llm ensemble 7b:fp16 13b:int4 3b:int8
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		members, total, err := calculateEnsemble(args, float32(overhead))
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			output := jsonEnsemble{
+				MemSize:  formatMemory(total),
+				MemBytes: total,
+				Models:   make([]jsonEnsembleMember, 0, len(members)),
+			}
+			for _, m := range members {
+				output.Models = append(output.Models, jsonEnsembleMember{
+					Model:    m.Model,
+					MemSize:  formatMemory(m.Estimate.Total),
+					MemBytes: m.Estimate.Total,
+				})
+			}
+			return writeStructured(out, "json", output)
+		}
+
+		fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(total))
+		tw := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		for _, m := range members {
+			fmt.Fprintf(tw, "  %s:\t%s\n", m.Model, formatMemory(m.Estimate.Total))
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	ensembleCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage, applied to each model")
+	ensembleCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(ensembleCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+func TestCalculateEnsemble(t *testing.T) {
+	members, total, err := calculateEnsemble([]string{"7b:fp16", "13b:int8", "1b:fp32"}, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("got %d members, want 3", len(members))
+	}
+
+	want := []int{16_800_000_000, 15_600_000_000, 4_800_000_000}
+	var sum int
+	for i, m := range members {
+		if m.Estimate.Total != want[i] {
+			t.Errorf("%s needs %d bytes, want %d", m.Model, m.Estimate.Total, want[i])
+		}
+		sum += m.Estimate.Total
+	}
+	if total != sum || total != 37_200_000_000 {
+		t.Errorf("ensemble needs %d bytes, want the sum of its members %d", total, 37_200_000_000)
+	}
+}
+
+func TestEnsembleMemberOfAnyPrecision(t *testing.T) {
+	// A member takes any precision --precision does, such as a llama.cpp scheme
+	input, err := parseEnsembleMember("13b:Q4_K_M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := estimator.QuantPrecision("Q4_K_M")
+	if input.ParameterSize != 13_000_000_000 || input.Precision != want {
+		t.Errorf("13b:Q4_K_M = %d parameters at %v bytes each, want 13000000000 at %v", input.ParameterSize, input.Precision, want)
+	}
+
+	if _, err := parseEnsembleMember("13b:q9"); err == nil {
+		t.Error("13b:q9 was accepted")
+	}
+}
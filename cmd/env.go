@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// precedence describes the order settings are applied in, for error messages
+const precedence = "command-line flags take precedence over GPU_MEM_* environment variables, which take precedence over the config file"
+
+// envPrefix starts the name of the environment variable of every setting
+const envPrefix = "GPU_MEM_"
+
+// envFlags lists the flags that choose where the other settings come from, which can be
+// set from their environment variables but not from the config file
+var envFlags = map[string]bool{"config-file": true, "config": true, "profile": true}
+
+// envName returns the environment variable of a setting, such as GPU_MEM_KV_DTYPE for
+// --kv-dtype
+func envName(setting string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// envSettings maps the environment variable of every flag of the command, along with
+// GPU_MEM_PRECISION and GPU_MEM_FORMAT when the command has those settings, to the
+// setting it supplies
+func envSettings(cmd *cobra.Command) map[string]string {
+	settings := make(map[string]string)
+	for _, setting := range []string{"precision", "format"} {
+		if isSetting(cmd, setting) {
+			settings[envName(setting)] = setting
+		}
+	}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "help", "version":
+			return
+		}
+		settings[envName(flag.Name)] = flag.Name
+	})
+	return settings
+}
+
+// applyEnv sets every flag that was not given on the command line from its environment
+// variable, when that variable is set.
+func applyEnv(cmd *cobra.Command) error {
+	settings := envSettings(cmd)
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+		setting := settings[name]
+		if envFlags[setting] {
+			if !cmd.Flags().Changed(setting) {
+				if err := cmd.Flags().Set(setting, value); err != nil {
+					return fmt.Errorf("%s: invalid %s: %v", name, setting, err)
+				}
+			}
+			continue
+		}
+		if err := applySettings(cmd, name, map[string]string{setting: value}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRequiredSettings reports a missing size or precision once the flags, environment
+// variables and config file have all been applied, explaining where each can be set.
+func checkRequiredSettings(cmd *cobra.Command) error {
+	if !cmd.Flags().Changed("size") && !cmd.Flags().Changed("total-params") {
+		return errors.New("a model size is required; set it with --size (or --total-params for a mixture-of-experts model), derive it from --num-layers, --hidden-dim, --intermediate-size and --vocab-size, or set GPU_MEM_SIZE or size in the config file (" + precedence + ")")
+	}
+	if !precisionFlagChanged(cmd) {
+		return errors.New("a precision is required; set it with one of " + precisionFlagList() + ", with GPU_MEM_PRECISION or with precision in the config file (" + precedence + ")")
+	}
+	return nil
+}
@@ -0,0 +1,1083 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// estimateSpec is the spec the root command estimates, built from its flags, along with
+// what the flags derive alongside it
+type estimateSpec struct {
+	input  estimator.ModelSpec
+	format string
+
+	// The value of --size or --total-params, the sizes it lists and the sweep of
+	// --sweep-context or --sweep-batch, nil without one
+	sizeValue string
+	sizes     []string
+	sweep     *sweep
+
+	parameterSize    int
+	activeParameters int
+	precision        estimator.Precision
+	contextTokens    int
+
+	// How the model is split across its GPUs, and the topology that only has its GPUs
+	// checked for the fit, empty when the GPUs follow from the memory
+	parallelism   string
+	topology      string
+	modelParallel int
+	dataParallel  int
+
+	// Whether training accumulates the gradients of micro-batches, and the global batch
+	// of each step
+	accumulating bool
+	globalBatch  int
+}
+
+// buildEstimateSpec builds the spec to estimate from the flags of the root command,
+// checking them along the way
+func buildEstimateSpec(cmd *cobra.Command) (estimateSpec, error) {
+	switch strings.ToLower(mode) {
+	case "inference", "train":
+	default:
+		return estimateSpec{}, fmt.Errorf("unknown mode %q; must be one of %s", mode, strings.Join(estimateModes, ", "))
+	}
+	if bytesOutput && !quiet {
+		return estimateSpec{}, errors.New("--bytes requires --quiet")
+	}
+	format, err := getOutputFormat()
+	if err != nil {
+		return estimateSpec{}, err
+	}
+	if jsonIncludeInputs && format == "text" {
+		return estimateSpec{}, errors.New("--json-include-inputs requires --json or another structured --output")
+	}
+	// The total parameters of a mixture-of-experts model stand in for the size
+	sizeValue := size
+	if totalParams != "" {
+		sizeValue = totalParams
+	}
+
+	// Several sizes are estimated alike and compared in a single table
+	sizes := strings.Split(sizeValue, ",")
+	if len(sizes) > 1 {
+		if err := checkMultipleSizes(cmd); err != nil {
+			return estimateSpec{}, err
+		}
+	}
+
+	// A sweep estimates the model at each of several context lengths or batch sizes in
+	// a single table
+	sweep, err := parseSweepFlags(cmd, sizes)
+	if err != nil {
+		return estimateSpec{}, err
+	}
+	parameterSize, err := getParameterSize(sizes[0])
+	if err != nil {
+		return estimateSpec{}, err
+	}
+
+	var activeParameters int
+	if activeParams != "" {
+		activeParameters, err = getParameterSize(activeParams)
+		if err != nil {
+			return estimateSpec{}, err
+		}
+	}
+
+	// The active parameters follow from the experts a token is routed to, since only
+	// the experts it skips go unused
+	if activeExperts > 0 {
+		if experts < 1 || activeExperts > experts {
+			return estimateSpec{}, errors.New("invalid experts; --active-experts must be between 1 and --experts")
+		}
+		if numLayers <= 0 || hiddenDim <= 0 || intermediateSize <= 0 {
+			return estimateSpec{}, errors.New("--experts requires --num-layers, --hidden-dim and --intermediate-size")
+		}
+		expertParameters := estimator.CalculateExpertParameters(numLayers, hiddenDim, intermediateSize)
+		activeParameters = estimator.CalculateActiveParameters(parameterSize, expertParameters, experts, activeExperts)
+		if activeParameters <= 0 {
+			return estimateSpec{}, errors.New("the experts from the architecture flags exceed the model size")
+		}
+	}
+
+	// The head dimension follows from the number of heads when that is given instead
+	attentionHeadDim := headDim
+	if heads > 0 && hiddenDim > 0 {
+		attentionHeadDim = hiddenDim / heads
+	}
+	if slidingWindow < 0 {
+		return estimateSpec{}, errors.New("invalid sliding-window; must be greater than 0")
+	}
+	if cmd.Flags().Changed("context") && contextLength < 1 {
+		return estimateSpec{}, errors.New("invalid context; must be at least 1")
+	}
+	if batchSize < 1 {
+		return estimateSpec{}, errors.New("invalid batch-size; must be at least 1")
+	}
+	if tensorParallel < 1 {
+		return estimateSpec{}, errors.New("invalid tensor-parallel; must be at least 1")
+	}
+	if bandwidth < 0 {
+		return estimateSpec{}, errors.New("invalid bandwidth; must not be negative")
+	}
+
+	precision, err := getPrecision()
+	if err != nil {
+		return estimateSpec{}, err
+	}
+
+	// The context can be derived from the length of a prompt in characters when the
+	// token count isn't known
+	contextTokens := contextLength
+	if promptChars > 0 {
+		if cmd.Flags().Changed("context") {
+			return estimateSpec{}, errors.New("--prompt-chars cannot be combined with --context")
+		}
+		if charsPerToken <= 0 {
+			return estimateSpec{}, errors.New("invalid characters per token; must be greater than 0")
+		}
+		contextTokens = estimator.CalculateTokenCount(promptChars, charsPerToken)
+	}
+
+	modelParallel, dataParallel, parallelism, topology := describeParallelism()
+
+	input := estimator.ModelSpec{
+		ParameterSize:         parameterSize,
+		ActiveParameters:      activeParameters,
+		Precision:             precision,
+		SparseFraction:        float32(sparseFraction),
+		Overhead:              float32(overhead),
+		HiddenDim:             hiddenDim,
+		VocabSize:             vocabSize,
+		NumLayers:             numLayers,
+		TiedEmbeddings:        tiedEmbeddings,
+		IntermediateSize:      intermediateSize,
+		FoldNorms:             foldNorms,
+		StreamWeights:         streamWeights,
+		DoubleBuffer:          doubleBuffer,
+		MaxPositions:          maxPositions,
+		RoPEScaling:           ropeScaling,
+		HeadDim:               attentionHeadDim,
+		HeadDimMultiple:       headDimMultiple,
+		Heads:                 heads,
+		KVHeads:               kvHeads,
+		ContextLength:         contextTokens,
+		BatchSize:             batchSize,
+		SkipActivations:       noActivations,
+		GradientCheckpointing: gradientCheckpointing,
+		KVBlockSize:           kvBlockSize,
+		RoundContext:          roundContext,
+		KVUtilization:         kvUtilization,
+		SlidingWindow:         slidingWindow,
+		TensorParallel:        modelParallel,
+		PipelineParallel:      pipelineParallel,
+		ZeROStage:             zeroStage,
+		FSDPStrategy:          strings.ToLower(fsdp),
+		FSDPShardDegree:       fsdpShardDegree,
+		OffloadOptimizer:      offloadOptimizer,
+		DataParallel:          dataParallel,
+		ReplicateEmbeddings:   replicateEmbeddings,
+		Train:                 strings.ToLower(mode) == "train",
+		LoRARank:              loraRank,
+		LoRATargets:           loraTargets,
+		LayerSkip:             layerSkip,
+		DraftTokens:           draftTokens,
+		MedusaHeads:           medusaHeads,
+		MedusaTreeTokens:      medusaTreeTokens,
+		IndexVectors:          indexVectors,
+		IndexDim:              indexDim,
+	}
+
+	input.Draft, err = draftModel(cmd)
+	if err != nil {
+		return estimateSpec{}, err
+	}
+	input.Vision, err = visionTower(cmd)
+	if err != nil {
+		return estimateSpec{}, err
+	}
+
+	// AdamW is assumed unless an optimizer is given, which then needs training to apply to
+	if cmd.Flags().Changed("optimizer") {
+		input.Optimizer = strings.ToLower(optimizerName)
+	}
+	// Training runs the global batch of each step as micro-batches on each data parallel
+	// rank, accumulating their gradients, so only a micro-batch is ever on the device
+	accumulating := cmd.Flags().Changed("micro-batch") || cmd.Flags().Changed("grad-accum")
+	if accumulating {
+		if !input.Train && loraRank == 0 {
+			return estimateSpec{}, errors.New("--micro-batch and --grad-accum require --mode train or --lora-rank")
+		}
+		if gradAccum <= 0 {
+			return estimateSpec{}, errors.New("invalid grad-accum; must be greater than 0")
+		}
+		if cmd.Flags().Changed("micro-batch") {
+			input.BatchSize = microBatch
+		} else {
+			split := gradAccum * dataParallel
+			if batchSize%split != 0 {
+				return estimateSpec{}, fmt.Errorf("--batch-size %d does not split evenly into %d accumulation steps on %d data parallel GPUs", batchSize, gradAccum, dataParallel)
+			}
+			input.BatchSize = batchSize / split
+		}
+	}
+	globalBatch := input.BatchSize * gradAccum * dataParallel
+
+	if cmd.Flags().Changed("rope-scaling") && maxPositions <= 0 {
+		return estimateSpec{}, errors.New("--rope-scaling requires --max-positions")
+	}
+
+	if kvBuckets != "" {
+		if contextTokens > 0 {
+			return estimateSpec{}, errors.New("--kv-buckets cannot be combined with --context or --prompt-chars")
+		}
+		input.KVBuckets, err = estimator.ParseKVBuckets(kvBuckets)
+		if err != nil {
+			return estimateSpec{}, err
+		}
+	}
+
+	if err := applyPrecisionFlags(&input); err != nil {
+		return estimateSpec{}, err
+	}
+
+	if err := applyFrameworkPreset(cmd, &input); err != nil {
+		return estimateSpec{}, err
+	}
+
+	if err := input.Validate(); err != nil {
+		return estimateSpec{}, err
+	}
+
+	return estimateSpec{
+		input:            input,
+		format:           format,
+		sizeValue:        sizeValue,
+		sizes:            sizes,
+		sweep:            sweep,
+		parameterSize:    parameterSize,
+		activeParameters: activeParameters,
+		precision:        precision,
+		contextTokens:    contextTokens,
+		parallelism:      parallelism,
+		topology:         topology,
+		modelParallel:    modelParallel,
+		dataParallel:     dataParallel,
+		accumulating:     accumulating,
+		globalBatch:      globalBatch,
+	}, nil
+}
+
+// describeParallelism returns how the GPUs of --gpus split the model, as the degree of
+// tensor parallelism and of data parallelism, and describes the split. The topology is
+// that of a split whose GPUs are only checked for the fit, empty otherwise.
+func describeParallelism() (modelParallel, dataParallel int, parallelism, topology string) {
+	// ZeRO and FSDP shard the training state across data parallel ranks, so the GPUs
+	// given with --gpus are those ranks rather than a tensor parallel group
+	var sharding string
+	switch {
+	case zeroStage > 0:
+		sharding = fmt.Sprintf("ZeRO-%d", zeroStage)
+	case fsdp != "":
+		sharding = "FSDP " + strings.ToLower(fsdp)
+	}
+	modelParallel, dataParallel = tensorParallel, 1
+	parallelism = fmt.Sprintf("tensor parallel degree %d", tensorParallel)
+	if sharding != "" {
+		modelParallel, dataParallel = 1, tensorParallel
+		parallelism = fmt.Sprintf("%s across %d GPUs", sharding, tensorParallel)
+	}
+
+	// Pipeline stages are sized for the largest one, and like ZeRO and FSDP their GPUs
+	// are only checked for the fit
+	topology = sharding
+	if pipelineParallel > 1 {
+		topology = fmt.Sprintf("%d pipeline stages", pipelineParallel)
+		parallelism = "largest of " + topology
+		if tensorParallel > 1 {
+			parallelism += fmt.Sprintf(", tensor parallel degree %d", tensorParallel)
+		}
+	}
+	return modelParallel, dataParallel, parallelism, topology
+}
+
+// applyPrecisionFlags sets the precisions of the spec that have flags of their own: those
+// of the KV cache, the index, a second copy of the weights, the embeddings and LM head, and
+// the sparsity and scales of the weights
+func applyPrecisionFlags(input *estimator.ModelSpec) error {
+	var err error
+	if pureBF16 {
+		if precisionName() != "bf16" {
+			return errors.New("--pure-bf16 requires --precision bf16")
+		}
+		input.PureBF16 = true
+	}
+
+	if fp8Scaling != "" {
+		if precisionName() != "fp8" {
+			return errors.New("--fp8-scaling requires --precision fp8")
+		}
+		input.FP8Scaling = strings.ToLower(fp8Scaling)
+	}
+
+	// llama.cpp and exllamav2 keep the KV cache in f16 whatever the weights are quantized to
+	kvPrecisionName := kvDtype
+	if kvPrecisionName == "" && (quant != "" || bitsPerWeight != 0) {
+		kvPrecisionName = "fp16"
+	}
+	if kvPrecisionName != "" {
+		input.KVPrecision, err = estimator.KVPrecisionByName(kvPrecisionName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexVectors > 0 {
+		input.IndexPrecision, err = estimator.PrecisionByName(indexPrecision)
+		if err != nil {
+			return err
+		}
+	}
+
+	if abPrecision != "" {
+		input.ABPrecision, err = lookupPrecision(abPrecision)
+		if err != nil {
+			return err
+		}
+		input.ABPrecisionName = lookupPrecisionName(abPrecision)
+	}
+
+	// Quantized checkpoints often keep the embeddings and LM head at a higher precision
+	// than the transformer blocks
+	if embedPrecision != "" {
+		if input.EmbeddingPrecision, err = lookupPrecision(embedPrecision); err != nil {
+			return err
+		}
+	}
+	if headPrecision != "" {
+		if input.HeadPrecision, err = lookupPrecision(headPrecision); err != nil {
+			return err
+		}
+	}
+
+	if sparsity != "" {
+		input.SparseKept, input.SparseGroup, err = estimator.ParseSparsity(sparsity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFrameworkPreset applies the preset of --framework to the spec. It replaces the
+// generic defaults, but explicit flags still win.
+func applyFrameworkPreset(cmd *cobra.Command, input *estimator.ModelSpec) error {
+	if err := checkFrameworkFlags(cmd); err != nil {
+		return err
+	}
+
+	if framework != "" {
+		preset, err := getFrameworkPreset(framework)
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("overhead") {
+			input.Overhead = float32(preset.Overhead)
+			if overhead, ok := calibratedOverheads[strings.ToLower(framework)]; ok {
+				input.Overhead = float32(overhead)
+			}
+		}
+		if !cmd.Flags().Changed("kv-block-size") {
+			input.KVBlockSize = preset.KVBlockSize
+		}
+		if preset.ActivationTokens > 0 {
+			if maxNumTokens <= 0 {
+				return errors.New("invalid max-num-tokens; must be greater than 0")
+			}
+			input.ActivationTokens = maxNumTokens
+		}
+		input.RoundContext = true
+	}
+	return nil
+}
+
+// estimateReport is the estimate of the root command and everything its flags report
+// alongside it
+type estimateReport struct {
+	estimateSpec
+	result estimator.Estimate
+
+	// The total before --round-to rounds it up, and the multiple it rounds to
+	rawTotal     int
+	roundToBytes int
+
+	// The buffers prewarmed at startup and the total they bring it to
+	prewarmBuffer estimator.Component
+	prewarmTotal  int
+
+	diskSize            int
+	lowTotal, highTotal int
+
+	// The GPUs needed and the memory of each, the layout of the cluster and the budget of
+	// --max-vram
+	gpuCount       int
+	gpuMemoryBytes int
+	fits           bool
+	cluster        *estimator.ClusterLayout
+	maxVRAMBytes   int
+
+	// The share of each GPU the estimate uses with --warn-at, the warning when it leaves
+	// too little headroom, and the color of the estimate
+	utilization float64
+	warning     string
+	color       string
+
+	localGPUs       []localGPU
+	kvPool          *kvPoolPlan
+	llamaCpp        *estimator.LlamaCppPlan
+	ram             estimator.HostRAM
+	hostMemoryBytes int
+	concurrency     int
+
+	memoryGPUs      int
+	throughputGPUs  int
+	headroomBytes   int
+	headroomPercent float64
+	costPerHour     float64
+	costPerMonth    float64
+	cloudOptions    []cloudOption
+	decodeBandwidth float64
+	tokensPerSecond float64
+
+	layers          jsonPerLayer
+	kvBytesPerToken int
+	showBreakdown   bool
+	sharded         bool
+}
+
+// newEstimateReport estimates the spec with the backend and works out everything the
+// flags of the root command report alongside the estimate, checking those flags along
+// the way
+func newEstimateReport(cmd *cobra.Command, out io.Writer, s estimateSpec) (*estimateReport, error) {
+	result, err := calculate(s.input)
+	if err != nil {
+		return nil, err
+	}
+	r := &estimateReport{estimateSpec: s, result: result}
+	if err := r.addTotals(); err != nil {
+		return nil, err
+	}
+	if err := r.addGPUs(cmd, out); err != nil {
+		return nil, err
+	}
+	if err := r.addDeployment(cmd); err != nil {
+		return nil, err
+	}
+	if err := r.addThroughput(cmd); err != nil {
+		return nil, err
+	}
+	if err := r.addDetails(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// addTotals works out the totals reported alongside the estimate: those at startup, on
+// disk and at either end of the range, and the estimate rounded up with --round-to
+func (r *estimateReport) addTotals() error {
+	// Frameworks pre-allocate the KV cache at startup for the largest context and batch
+	// they are configured to serve, which can exceed what the steady-state workload needs
+	if prewarm {
+		if maxContext <= 0 || numLayers <= 0 || hiddenDim <= 0 {
+			return errors.New("--prewarm requires --max-context, --num-layers and --hidden-dim")
+		}
+		prewarmInput := r.input
+		prewarmInput.ContextLength = maxContext
+		prewarmInput.BatchSize = r.input.BatchSize
+		if maxBatch > 0 {
+			prewarmInput.BatchSize = maxBatch
+		}
+		prewarmInput.KVBuckets = nil
+		var err error
+		r.prewarmBuffer, err = prewarmInput.KVCacheComponent()
+		if err != nil {
+			return err
+		}
+		prewarmResult, err := calculate(prewarmInput)
+		if err != nil {
+			return err
+		}
+		r.prewarmTotal = prewarmResult.Total * prewarmResult.GPUs
+	}
+
+	// The model files hold every parameter but none of the memory allocated at runtime
+	if disk {
+		var err error
+		if r.diskSize, err = estimator.CalculateDiskSize(r.input); err != nil {
+			return err
+		}
+	}
+
+	// The range is taken before rounding, which only applies to the point estimate
+	if uncertainty != 0 {
+		if uncertainty < 0 || uncertainty > 100 {
+			return errors.New("invalid uncertainty; must be between 0 and 100")
+		}
+		r.lowTotal, r.highTotal = r.result.UncertaintyRange(uncertainty)
+	}
+
+	// Memory is allocated in chunks, so the total can be rounded up to the next
+	// multiple once every component and the overhead have been added up
+	r.rawTotal = r.result.Total
+	if roundTo != "" {
+		var err error
+		r.roundToBytes, err = parseMemorySize(roundTo)
+		if err != nil {
+			return err
+		}
+		r.result.Total = estimator.RoundUpToMultiple(r.result.Total, r.roundToBytes)
+	}
+	return nil
+}
+
+// addGPUs works out the GPUs the estimate needs and how much of each one it uses, against
+// the memory of --gpu or --gpu-memory and the budget of --max-vram
+func (r *estimateReport) addGPUs(cmd *cobra.Command, out io.Writer) error {
+	// The number of GPUs needed follows from the memory of each one when it is known,
+	// otherwise it is the number the model is sharded across. ZeRO doesn't shrink
+	// every component with more GPUs, and neither does FSDP, so their GPUs are only
+	// checked for the fit.
+	var err error
+	r.gpuCount = r.result.GPUs
+	r.gpuMemoryBytes, err = resolveGPUMemory()
+	if err != nil {
+		return err
+	}
+	if r.gpuMemoryBytes > 0 && r.topology == "" {
+		r.gpuCount = estimator.CalculateGPUCount(r.result.Total*r.result.GPUs, r.gpuMemoryBytes, r.result.GPUs)
+	}
+
+	// A cluster holds as many replicas of the model as its nodes have GPUs for
+	if nodes > 0 {
+		layout, err := estimator.LayoutCluster(r.result, r.modelParallel, gpusPerNode, nodes)
+		if err != nil {
+			return err
+		}
+		r.cluster = &layout
+	} else if cmd.Flags().Changed("gpus-per-node") {
+		return errors.New("--gpus-per-node requires --nodes")
+	}
+
+	// A budget for the memory of each GPU fails the command once the output is written,
+	// so a CI job both sees the estimate and stops on it
+	if maxVRAM != "" {
+		r.maxVRAMBytes, err = parseMemorySize(maxVRAM)
+		if err != nil {
+			return err
+		}
+	}
+	// The share of each GPU's memory the estimate uses, against the budget when there is
+	// one and otherwise the memory of the GPU
+	var warnAtPercent float64
+	if warnAt != "" {
+		if warnAtPercent, err = parsePercent(warnAt); err != nil {
+			return err
+		}
+		budget := r.maxVRAMBytes
+		if budget == 0 {
+			budget = r.gpuMemoryBytes
+		}
+		if budget == 0 {
+			return errors.New("--warn-at requires --gpu, --gpu-memory or --max-vram")
+		}
+		r.utilization = 100 * float64(r.result.Total) / float64(budget)
+		if r.utilization >= warnAtPercent {
+			r.warning = fmt.Sprintf("the estimate uses %.1f%% of the %s of each GPU, leaving less than the %g%% headroom of --warn-at %g%%", r.utilization, formatMemory(budget), 100-warnAtPercent, warnAtPercent)
+		}
+	}
+	colored, err := useColor(out)
+	if err != nil {
+		return err
+	}
+	if colored && warnAt != "" {
+		r.color = utilizationColor(r.utilization, warnAtPercent)
+	}
+
+	// Whether the memory each GPU needs fits on a single one
+	r.fits = r.gpuMemoryBytes > 0 && r.result.Total <= r.gpuMemoryBytes
+	return nil
+}
+
+// checkFit fails the command when the estimate exceeds the budget of --max-vram, and the
+// same way when the verdict for the card of --gpu is that the model doesn't fit on it.
+// The memory of --gpu-memory only works out how many GPUs the model needs, so it never
+// fails the command.
+func (r *estimateReport) checkFit() error {
+	if r.maxVRAMBytes > 0 && r.result.Total > r.maxVRAMBytes {
+		return doesNotFitError(fmt.Errorf("estimated memory required of %s per GPU exceeds --max-vram of %s", formatMemory(r.result.Total), formatMemory(r.maxVRAMBytes)))
+	}
+	if gpu != "" && r.result.Total > r.gpuMemoryBytes {
+		return doesNotFitError(fmt.Errorf("estimated memory required of %s per GPU does not fit in the %s of %s", formatMemory(r.result.Total), formatMemory(r.gpuMemoryBytes), strings.ToLower(gpu)))
+	}
+	return nil
+}
+
+// addDeployment works out how the model is served: the local GPUs it fits on, the plans
+// of the framework, the host memory it needs and the sequences it serves at once
+func (r *estimateReport) addDeployment(cmd *cobra.Command) error {
+	// The local GPUs are checked against the memory they have free rather than their
+	// total, since other processes may already be using some of it
+	var err error
+	if detect {
+		r.localGPUs, err = detectGPUs(vendor)
+		if err != nil {
+			return err
+		}
+	}
+
+	// vLLM, TGI and TensorRT-LLM fill what the model leaves of each GPU with KV cache
+	// blocks, so their plan needs the memory of the GPUs and the layers
+	if framework != "" && r.gpuMemoryBytes > 0 && numLayers > 0 && hiddenDim > 0 {
+		preset, err := getFrameworkPreset(framework)
+		if err != nil {
+			return err
+		}
+		if preset.FractionFlag != "" {
+			plan, err := calculateKVPoolPlan(cmd, preset, r.input, r.result, r.gpuMemoryBytes)
+			if err != nil {
+				return err
+			}
+			r.kvPool = &plan
+		}
+	}
+
+	// llama.cpp splits the layers between the GPU and the CPU, so its flags follow from
+	// the memory of a single GPU
+	if strings.ToLower(framework) == "llama.cpp" && r.gpuMemoryBytes > 0 && numLayers > 0 && hiddenDim > 0 {
+		plan, err := estimator.PlanLlamaCpp(r.input, r.gpuMemoryBytes)
+		if err != nil {
+			return err
+		}
+		r.llamaCpp = &plan
+	}
+
+	// Loading stages the checkpoint in host memory alongside whatever stays there for as
+	// long as the model runs, which can run the host out of memory before the GPU
+	if showHostRAM {
+		var source estimator.Precision
+		if loadPrecision != "" {
+			if source, err = lookupPrecision(loadPrecision); err != nil {
+				return err
+			}
+		}
+		if r.ram, err = estimator.CalculateHostRAM(r.input, r.result, source, mmapCheckpoint); err != nil {
+			return err
+		}
+		var resident int
+		if r.kvPool != nil {
+			resident += r.kvPool.SwapBytes * r.result.GPUs
+		}
+		if r.llamaCpp != nil {
+			resident += r.llamaCpp.Split.CPUBytes
+		}
+		r.ram.Resident += resident
+		r.ram.Peak += resident
+
+		if hostMemory != "" {
+			if r.hostMemoryBytes, err = parseMemorySize(hostMemory); err != nil {
+				return err
+			}
+		} else if detected, err := detectHostMemory(); err == nil {
+			r.hostMemoryBytes = detected
+		}
+	} else if loadPrecision != "" || mmapCheckpoint || hostMemory != "" {
+		return errors.New("--load-precision, --mmap and --host-memory require --host-ram")
+	}
+
+	// How many sequences of the context the GPUs serve at once, for sizing a deployment
+	// rather than a single request
+	if capacity {
+		if r.gpuMemoryBytes == 0 || r.contextTokens == 0 || kvBuckets != "" {
+			return errors.New("--capacity requires --gpu-memory or --gpu, and --context rather than --kv-buckets")
+		}
+		// A paged server hands each sequence whole blocks
+		paged := r.input
+		paged.RoundContext = true
+		r.concurrency, err = estimator.CalculateConcurrency(paged, r.gpuMemoryBytes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addThroughput works out the GPUs a throughput target needs, the headroom and cost of
+// the GPUs and the decode speed they reach
+func (r *estimateReport) addThroughput(cmd *cobra.Command) error {
+	// A throughput target can need more GPUs than memory does, in which case it decides
+	r.memoryGPUs = r.gpuCount
+	if targetTokensPerSecond > 0 {
+		if r.gpuMemoryBytes == 0 || tokensPerSecondPerGPU <= 0 {
+			return errors.New("--target-tokens-per-second requires --gpu-memory or --gpu, and --tokens-per-second-per-gpu")
+		}
+		r.throughputGPUs = estimator.CalculateThroughputGPUCount(targetTokensPerSecond, tokensPerSecondPerGPU)
+		r.gpuCount = max(r.memoryGPUs, r.throughputGPUs)
+	}
+
+	// How much room is left on the GPUs once the model is placed, to judge how tight the
+	// fit is rather than only whether it fits
+	if r.gpuMemoryBytes > 0 {
+		r.headroomBytes, r.headroomPercent = estimator.CalculateHeadroom(r.result.Total*r.result.GPUs, r.gpuMemoryBytes, r.gpuCount)
+	}
+
+	r.costPerHour, r.costPerMonth = estimator.CalculateCost(r.gpuCount, pricePerHour)
+
+	// The cheapest cloud instances whose GPUs hold the estimate, from the price table
+	if cost {
+		if gpuDB != "" && gpu == "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+		if costDB != "" {
+			if err := loadCloudInstances(costDB); err != nil {
+				return err
+			}
+		}
+		r.cloudOptions = cheapestCloudInstances(r.result.Total, r.result.GPUs)
+	}
+
+	// Each GPU streams its own share of the weights for every generated token, which
+	// for a mixture of experts is only the share of the experts the token is routed to,
+	// and its share of the sequence's KV cache. A GPU from the database brings its own
+	// bandwidth.
+	r.decodeBandwidth = bandwidth
+	if gpu != "" && !cmd.Flags().Changed("bandwidth") {
+		spec, err := getGPUSpec(gpu)
+		if err != nil {
+			return err
+		}
+		r.decodeBandwidth = spec.Bandwidth
+	}
+	if r.decodeBandwidth > 0 {
+		readBytes := r.input.WeightMemory(r.input.ParametersPerToken()) / r.result.GPUs
+		if r.contextTokens > 0 && numLayers > 0 && hiddenDim > 0 {
+			sequence := r.input
+			sequence.BatchSize = 1
+			sequence.KVBuckets = nil
+			kvCache, err := sequence.KVCacheComponent()
+			if err != nil {
+				return err
+			}
+			shards := r.result.GPUs
+			if kvCache.ShardLimit > 0 {
+				shards = min(shards, kvCache.ShardLimit)
+			}
+			readBytes += kvCache.Bytes / shards
+		}
+		r.tokensPerSecond = estimator.EstimateDecodeTokensPerSecond(r.decodeBandwidth, readBytes)
+	}
+	return nil
+}
+
+// addDetails works out the details of the model reported with the estimate
+func (r *estimateReport) addDetails() error {
+	// The per-layer view is derived from the architecture rather than the parameter
+	// count, so it can be reconciled against a real model's reported size
+	if perLayer {
+		if numLayers <= 0 || hiddenDim <= 0 || intermediateSize <= 0 {
+			return errors.New("--per-layer requires --num-layers, --hidden-dim and --intermediate-size")
+		}
+		r.layers = calculatePerLayer(numLayers, hiddenDim, intermediateSize, vocabSize, tiedEmbeddings, foldNorms, r.precision)
+	}
+
+	// The cost of each extra token of context helps pick a context length to fit, which
+	// doesn't apply to training as it keeps no KV cache
+	if (r.contextTokens > 0 || kvBuckets != "") && !r.input.Train && r.input.LoRARank == 0 {
+		r.kvBytesPerToken = r.input.KVCacheBytesPerToken()
+	}
+
+	// Only show the breakdown when there is more than the weights to report, or when
+	// the weights need qualifying as holding every expert or only some of the layers
+	r.showBreakdown = len(r.result.Components) > 1 || r.activeParameters > 0 || streamWeights
+	r.sharded = r.result.GPUs > 1
+	return nil
+}
+
+// jsonEstimate returns the estimate and what is reported alongside it as structured output
+func (r *estimateReport) jsonEstimate(cmd *cobra.Command) jsonEstimate {
+	output := jsonEstimate{
+		MemSize:           formatMemory(r.rawTotal * r.result.GPUs),
+		MemBytes:          r.rawTotal * r.result.GPUs,
+		Parameters:        r.parameterSize,
+		Precision:         precisionName(),
+		BytesPerParameter: json.Number(strconv.FormatFloat(float64(r.precision), 'g', -1, 32)),
+		OverheadPercent:   r.result.OverheadPercent,
+		Components:        jsonComponents(r.result),
+	}
+	if r.sharded {
+		output.MemSizePerGPU = formatMemory(r.rawTotal)
+		output.MemBytesPerGPU = r.rawTotal
+	}
+	if uncertainty > 0 {
+		output.MemSizeLow = formatMemory(r.lowTotal * r.result.GPUs)
+		output.MemSizeHigh = formatMemory(r.highTotal * r.result.GPUs)
+	}
+	if roundTo != "" {
+		output.MemSizeRounded = formatMemory(r.result.Total * r.result.GPUs)
+		output.MemBytesRounded = r.result.Total * r.result.GPUs
+	}
+	if r.showBreakdown {
+		output.Breakdown = r.result.BreakdownIn(memoryUnit)
+	}
+	if breakdown {
+		for i, c := range output.Components {
+			output.Components[i].Percent = math.Round(componentShare(c.Bytes, r.result.Total)*10) / 10
+		}
+		if rounding := r.result.Total - r.rawTotal; rounding > 0 {
+			output.Components = append(output.Components, jsonComponent{
+				Name:    "rounding margin",
+				Bytes:   rounding,
+				Percent: math.Round(componentShare(rounding, r.result.Total)*10) / 10,
+			})
+		}
+	}
+	for i, s := range r.result.Stages {
+		output.Stages = append(output.Stages, jsonStage{
+			Stage:         i + 1,
+			Layers:        s.Layers,
+			MemSizePerGPU: formatMemory(s.Total),
+			MemBytes:      s.Total,
+		})
+	}
+	if r.cluster != nil {
+		output.Cluster = jsonClusterLayout(*r.cluster)
+	}
+	if len(r.result.Offloaded) > 0 {
+		output.Offloaded = make(map[string]string, len(r.result.Offloaded))
+		for _, c := range r.result.Offloaded {
+			output.Offloaded[c.Name] = formatMemory(c.Bytes)
+		}
+	}
+	if gpu != "" {
+		output.GPU = strings.ToLower(gpu)
+	}
+	if gpu != "" || (r.sharded && r.gpuMemoryBytes > 0) {
+		output.Fits = &r.fits
+	}
+	if r.gpuMemoryBytes > 0 {
+		output.GPUsRequired = r.gpuCount
+		output.HeadroomBytes = r.headroomBytes
+		output.HeadroomPercent = math.Round(r.headroomPercent*10) / 10
+	}
+	if targetTokensPerSecond > 0 {
+		output.GPUsForMemory = r.memoryGPUs
+		output.GPUsForThroughput = r.throughputGPUs
+		output.BindingConstraint = estimator.BindingConstraint(r.memoryGPUs, r.throughputGPUs)
+	}
+	if pricePerHour > 0 {
+		output.CostPerHour = r.costPerHour
+		output.CostPerMonth = r.costPerMonth
+	}
+	if cost {
+		output.CloudInstances = jsonCloudOptions(r.cloudOptions)
+	}
+	if perLayer {
+		output.PerLayer = &r.layers
+	}
+	if r.accumulating {
+		output.MicroBatch = r.input.BatchSize
+		output.GradAccum = gradAccum
+		output.GlobalBatch = r.globalBatch
+	}
+	if promptChars > 0 {
+		output.ContextTokens = r.contextTokens
+	}
+	if r.kvBytesPerToken > 0 {
+		output.KVCacheBytesPerToken = r.kvBytesPerToken
+	}
+	if prewarm {
+		output.PrewarmBuffers = formatMemory(r.prewarmBuffer.Bytes)
+		output.MemSizeAtStartup = formatMemory(r.prewarmTotal)
+	}
+	if showHostRAM {
+		output.HostRAM = jsonHostRAMPlan(r.ram, r.hostMemoryBytes)
+	}
+	if disk {
+		output.DiskSize = formatMemory(r.diskSize)
+		output.DiskBytes = r.diskSize
+	}
+	if r.activeParameters > 0 || sizeFromArchitecture {
+		output.TotalParameters = r.parameterSize
+	}
+	if r.activeParameters > 0 {
+		output.ActiveParameters = r.activeParameters
+		output.ActiveWeights = formatMemory(r.input.WeightMemory(r.activeParameters))
+	}
+	if explain {
+		output.Explanation = r.result.ExplanationIn(memoryUnit)
+	}
+	if r.decodeBandwidth > 0 {
+		output.EstimatedTokensPerSecond = math.Round(r.tokensPerSecond*10) / 10
+	}
+	if jsonIncludeInputs {
+		output.Inputs = resolvedInputs(cmd)
+	}
+	if detect {
+		output.Detected = jsonLocalGPUs(r.result.Total*r.result.GPUs, r.localGPUs)
+	}
+	if r.llamaCpp != nil {
+		output.LlamaCpp = jsonLlamaCppPlan(*r.llamaCpp)
+	}
+	if r.kvPool != nil {
+		output.KVPool = jsonKVPoolPlan(*r.kvPool)
+	}
+	if capacity {
+		output.MaxConcurrentSequences = &r.concurrency
+	}
+	if warnAt != "" {
+		output.UtilizationPercent = math.Round(r.utilization*10) / 10
+		output.Warning = r.warning
+	}
+	if r.maxVRAMBytes > 0 {
+		withinMaxVRAM := r.result.Total <= r.maxVRAMBytes
+		output.MaxVRAMBytes = r.maxVRAMBytes
+		output.WithinMaxVRAM = &withinMaxVRAM
+	}
+	return output
+}
+
+// print writes the estimate and what is reported alongside it as text
+func (r *estimateReport) print(out io.Writer) {
+	// The share of the memory is that of each GPU, so it colors the line of a GPU
+	if r.sharded {
+		fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(r.result.Total*r.result.GPUs))
+		fmt.Fprintln(out, colorize(fmt.Sprintf("Estimated memory required per GPU: %s (%s)", formatMemory(r.result.Total), r.parallelism), r.color))
+	} else {
+		fmt.Fprintln(out, colorize(fmt.Sprintf("Estimated memory required: %s", formatMemory(r.result.Total*r.result.GPUs)), r.color))
+	}
+	if uncertainty > 0 {
+		fmt.Fprintf(out, "Estimated range: %s to %s (overhead and KV cache within %g%%)\n", formatMemory(r.lowTotal*r.result.GPUs), formatMemory(r.highTotal*r.result.GPUs), uncertainty)
+	}
+	if sizeFromArchitecture {
+		fmt.Fprintf(out, "Parameters: %s (derived from the architecture)\n", estimator.FormatCount(r.parameterSize))
+	}
+	if r.activeParameters > 0 {
+		fmt.Fprintf(out, "Active parameters per token: %s of %s (the weights hold every expert)\n", estimator.FormatCount(r.activeParameters), estimator.FormatCount(r.parameterSize))
+		fmt.Fprintf(out, "Active weights per token: %s of %s resident\n", formatMemory(r.input.WeightMemory(r.activeParameters)), formatMemory(r.input.WeightMemory(r.parameterSize)))
+	}
+	if r.accumulating {
+		fmt.Fprintf(out, "Training batch: micro-batch of %d x %d accumulation steps x %d data parallel GPUs = global batch of %d\n", r.input.BatchSize, gradAccum, r.dataParallel, r.globalBatch)
+	}
+	if promptChars > 0 {
+		fmt.Fprintf(out, "Context: %d tokens (%d characters at %g characters per token)\n", r.contextTokens, promptChars, charsPerToken)
+	}
+	if r.kvBytesPerToken > 0 {
+		fmt.Fprintf(out, "KV cache per token: %s (across %d layers, for each sequence)\n", formatMemory(r.kvBytesPerToken), numLayers)
+	}
+	if prewarm {
+		fmt.Fprintf(out, "Prewarmed buffers: %s (%s, allocated at startup)\n", formatMemory(r.prewarmBuffer.Bytes), r.prewarmBuffer.Formula)
+		fmt.Fprintf(out, "Estimated memory required at startup: %s\n", formatMemory(r.prewarmTotal))
+	}
+	if disk {
+		fmt.Fprintf(out, "Disk size: %s (model files at %s, without the memory allocated at runtime)\n", formatMemory(r.diskSize), precisionName())
+	}
+	if showHostRAM {
+		staging := precisionName()
+		if loadPrecision != "" {
+			staging = strings.ToLower(loadPrecision)
+		}
+		printHostRAM(out, r.ram, r.hostMemoryBytes, staging)
+	}
+	if roundTo != "" {
+		fmt.Fprintf(out, "Rounded up from %s to a multiple of %s\n", formatMemory(r.rawTotal), formatMemory(r.roundToBytes))
+	}
+	if breakdown {
+		printBreakdownTable(out, r.result, r.result.Total-r.rawTotal)
+	} else if r.showBreakdown {
+		printBreakdown(out, r.result)
+	}
+	if len(r.result.Stages) > 0 {
+		printStages(out, r.result.Stages)
+	}
+	if r.cluster != nil {
+		printClusterLayout(out, *r.cluster, r.modelParallel)
+	}
+	for _, c := range r.result.Offloaded {
+		if r.sharded {
+			fmt.Fprintf(out, "Offloaded to host memory: %s %s per GPU\n", c.Name, formatMemory(c.Bytes))
+		} else {
+			fmt.Fprintf(out, "Offloaded to host memory: %s %s\n", c.Name, formatMemory(c.Bytes))
+		}
+	}
+	if targetTokensPerSecond > 0 {
+		fmt.Fprintf(out, "GPUs required for memory: %d (%s each)\n", r.memoryGPUs, formatMemory(r.gpuMemoryBytes))
+		fmt.Fprintf(out, "GPUs required for throughput: %d (%g tokens/s at %g tokens/s per GPU)\n", r.throughputGPUs, targetTokensPerSecond, tokensPerSecondPerGPU)
+		fmt.Fprintf(out, "GPUs required: %d (bound by %s)\n", r.gpuCount, estimator.BindingConstraint(r.memoryGPUs, r.throughputGPUs))
+	} else if r.gpuMemoryBytes > 0 && r.topology != "" {
+		if r.fits {
+			fmt.Fprintf(out, "Verdict: fits on %d GPUs of %s each with %s\n", r.gpuCount, formatMemory(r.gpuMemoryBytes), r.topology)
+		} else {
+			fmt.Fprintf(out, "Verdict: does not fit on %d GPUs of %s each with %s\n", r.gpuCount, formatMemory(r.gpuMemoryBytes), r.topology)
+		}
+	} else if r.gpuMemoryBytes > 0 {
+		fmt.Fprintf(out, "GPUs required: %d (%s each)\n", r.gpuCount, formatMemory(r.gpuMemoryBytes))
+	}
+	if gpu != "" {
+		if r.fits {
+			fmt.Fprintf(out, "Verdict: fits on %s\n", strings.ToLower(gpu))
+		} else {
+			fmt.Fprintf(out, "Verdict: does not fit on a single %s\n", strings.ToLower(gpu))
+		}
+	}
+	if r.sharded && r.gpuMemoryBytes > 0 && !r.fits {
+		fmt.Fprintf(out, "Warning: each shard needs %s, more than the %s of a single GPU\n", formatMemory(r.result.Total), formatMemory(r.gpuMemoryBytes))
+	}
+	if r.gpuMemoryBytes > 0 && (r.topology == "" || r.fits) {
+		fmt.Fprintf(out, "Headroom: %s (%.1f%% of %s)\n", formatMemory(r.headroomBytes), r.headroomPercent, formatMemory(r.gpuMemoryBytes*r.gpuCount))
+	}
+	if r.warning != "" {
+		fmt.Fprintln(out, colorize("Warning: "+r.warning, r.color))
+	}
+	if detect {
+		printLocalGPUs(out, r.result.Total*r.result.GPUs, r.localGPUs)
+	}
+	if capacity {
+		if r.concurrency == 0 {
+			fmt.Fprintf(out, "Concurrent sequences: not even one sequence of %d tokens fits in %s per GPU\n", r.contextTokens, formatMemory(r.gpuMemoryBytes))
+		} else {
+			fmt.Fprintf(out, "Concurrent sequences: up to %d of %d tokens in %s per GPU\n", r.concurrency, r.contextTokens, formatMemory(r.gpuMemoryBytes))
+		}
+	}
+	if r.llamaCpp != nil {
+		printLlamaCppPlan(out, *r.llamaCpp, numLayers, r.gpuMemoryBytes)
+	}
+	if r.kvPool != nil {
+		printKVPoolPlan(out, *r.kvPool)
+	}
+	if pricePerHour > 0 {
+		fmt.Fprintf(out, "Estimated cost: %.2f per hour, %.2f per month\n", r.costPerHour, r.costPerMonth)
+	}
+	if cost {
+		printCloudOptions(out, r.cloudOptions)
+	}
+	if explain {
+		printExplanation(out, r.result)
+	}
+	if r.decodeBandwidth > 0 {
+		fmt.Fprintf(out, "Estimated decode speed: ~%.1f tokens/s (rough estimate for a single stream at %g GB/s)\n", r.tokensPerSecond, r.decodeBandwidth)
+	}
+	if perLayer {
+		printPerLayer(out, r.layers)
+	}
+}
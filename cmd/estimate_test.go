@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+func TestPlanAcrossGPUs(t *testing.T) {
+	// 168 GB of weights and overhead needs seven 24 GB GPUs, which is a plan rather than
+	// a failure to fit
+	got := estimateRoot(t, "-s", "70b", "--precision", "fp16", "--gpu-memory", "24gb", "--price-per-hour", "2")
+	if got.GPUsRequired != 7 || got.CostPerHour != 14 {
+		t.Errorf("GPUs required = %d at %v per hour, want 7 at 14", got.GPUsRequired, got.CostPerHour)
+	}
+
+	// Throughput decides once memory needs three 80 GB GPUs
+	got = estimateRoot(t, "-s", "70b", "--precision", "fp16", "--gpu-memory", "80gb",
+		"--target-tokens-per-second", "1000", "--tokens-per-second-per-gpu", "200")
+	if got.GPUsForMemory != 3 || got.GPUsRequired != 5 {
+		t.Errorf("GPUs for memory = %d and required = %d, want 3 and 5", got.GPUsForMemory, got.GPUsRequired)
+	}
+
+	// The card of --gpu still gives a verdict that fails the command
+	if code, _, _ := runRoot(t, "-s", "70b", "--precision", "fp16", "--gpu", "h100"); code != exitDoesNotFit {
+		t.Errorf("70b at fp16 on an h100 exited with %d, want %d", code, exitDoesNotFit)
+	}
+	if code, _, errOut := runRoot(t, "-s", "7b", "--precision", "fp16", "--gpu", "h100"); code != 0 {
+		t.Errorf("7b at fp16 on an h100 exited with %d: %s", code, errOut)
+	}
+}
+
+func TestABPrecisionOfAnyName(t *testing.T) {
+	copyBytes := func(args ...string) int {
+		t.Helper()
+		got := estimateRoot(t, append([]string{"-s", "7b", "--precision", "fp16"}, args...)...)
+		for _, c := range got.Components {
+			if strings.HasSuffix(c.Name, " copy)") {
+				return c.Bytes
+			}
+		}
+		t.Fatalf("%v has no copy of the weights", args)
+		return 0
+	}
+
+	// The copy takes any precision --precision does, sized the same way
+	q4KM, _ := estimator.QuantPrecision("Q4_K_M")
+	if got, want := copyBytes("--ab-precision", "Q4_K_M"), estimator.CalculateWeightMemory(7_000_000_000, q4KM); got != want {
+		t.Errorf("Q4_K_M copy = %d bytes, want %d", got, want)
+	}
+	gptq := estimator.GroupQuantPrecision(4, 32)
+	if got, want := copyBytes("--ab-precision", "gptq", "--group-size", "32"), estimator.CalculateWeightMemory(7_000_000_000, gptq); got != want {
+		t.Errorf("gptq copy with groups of 32 = %d bytes, want %d", got, want)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// Exit statuses of the command, so a script can tell why it failed without parsing the
+// message
+const (
+	// exitInternal is a failure of the environment rather than of the input, such as a
+	// GPU that can't be queried, a server that can't be reached or output that can't be
+	// written
+	exitInternal = 1
+
+	// exitInvalidInput is a flag, argument or setting that is missing or invalid
+	exitInvalidInput = 2
+
+	// exitDoesNotFit is an estimate that exceeds the budget of --max-vram or the memory of
+	// the card of --gpu, or a model recommend-quant finds no precision to fit
+	exitDoesNotFit = 3
+)
+
+// exitKinds names each exit status in the JSON of an error
+var exitKinds = map[int]string{
+	exitInternal:     "internal",
+	exitInvalidInput: "invalid_input",
+	exitDoesNotFit:   "does_not_fit",
+}
+
+// exitError is an error that ends the command with an exit status other than that of
+// invalid input
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// internalError marks an error as a failure of the environment rather than the input
+func internalError(err error) error {
+	return &exitError{code: exitInternal, err: err}
+}
+
+// doesNotFitError marks an error as an estimate that exceeds its budget
+func doesNotFitError(err error) error {
+	return &exitError{code: exitDoesNotFit, err: err}
+}
+
+// exitCode returns the exit status of an error. Anything not marked otherwise is taken as
+// invalid input, which is what nearly every error of the command reports.
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	if errors.Is(err, errHFUnreachable) || errors.Is(err, estimator.ErrBackendFailed) {
+		return exitInternal
+	}
+	return exitInvalidInput
+}
+
+// jsonError is the shape of an error written to stderr with --json
+type jsonError struct {
+	Error    string `json:"error"`
+	Kind     string `json:"kind"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// writeError writes the error of a command to w, as a line of JSON when the output is
+// JSON and with a pointer to the usage of the command for invalid input otherwise
+func writeError(w io.Writer, err error, code int, commandPath string) {
+	if jsonOutput || strings.EqualFold(outputFormat, "json") {
+		writeStructured(w, "json", jsonError{Error: err.Error(), Kind: exitKinds[code], ExitCode: code})
+		return
+	}
+	fmt.Fprintln(w, "Error:", err)
+	if code == exitInvalidInput {
+		fmt.Fprintf(w, "Run '%s --help' for usage.\n", commandPath)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// printExplanation prints the arithmetic behind each component of the estimate
+func printExplanation(w io.Writer, e estimator.Estimate) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(tw, "Explanation:")
+	for _, line := range e.ExplanationIn(memoryUnit) {
+		label, formula, _ := strings.Cut(line, ": ")
+		fmt.Fprintf(tw, "  %s:\t%s\n", label, formula)
+	}
+	tw.Flush()
+}
+
+// printBreakdown prints each component of the estimate on its own line, followed by the overhead.
+func printBreakdown(w io.Writer, e estimator.Estimate) {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	for _, c := range e.Components {
+		fmt.Fprintf(tw, "  %s:\t%s\n", c.Name, formatMemory(c.Bytes))
+	}
+	fmt.Fprintf(tw, "  overhead (%g%%):\t%s\n", e.OverheadPercent, formatMemory(e.Overhead))
+	tw.Flush()
+}
+
+// printBreakdownTable prints a table of each component of the estimate, the overhead and
+// the margin added by rounding the total up, each with its bytes and its share of the
+// total, so the component worth shrinking stands out.
+func printBreakdownTable(w io.Writer, e estimator.Estimate, rounding int) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(name string, bytes int) {
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%.1f%%\n", name, formatMemory(bytes), bytes, componentShare(bytes, e.Total))
+	}
+	fmt.Fprintln(tw, "Breakdown:")
+	fmt.Fprintln(tw, "  Component\tMemory\tBytes\tShare")
+	for _, c := range e.Components {
+		row(c.Name, c.Bytes)
+	}
+	row(fmt.Sprintf("overhead (%g%%)", e.OverheadPercent), e.Overhead)
+	if rounding > 0 {
+		row("rounding margin", rounding)
+	}
+	row("total", e.Total)
+	tw.Flush()
+}
+
+// componentShare returns the share of the total a component takes as a percentage
+func componentShare(bytes, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(bytes) / float64(total)
+}
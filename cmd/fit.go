@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// jsonFit is the shape of the output produced by the fit subcommand with --json
+type jsonFit struct {
+	MaxSize       string `json:"max_size"`
+	MaxParameters int    `json:"max_parameters"`
+}
+
+// formatParameterSize formats a parameter count in the notation --size accepts, using the
+// largest unit that keeps it a whole number of at least one. The count is rounded down so
+// the model still fits.
+func formatParameterSize(params int) string {
+	switch {
+	case params >= 1_000_000_000:
+		return fmt.Sprintf("%db", params/1_000_000_000)
+	case params >= 1_000_000:
+		return fmt.Sprintf("%dm", params/1_000_000)
+	default:
+		return fmt.Sprintf("%dk", params/1_000)
+	}
+}
+
+// fitCmd finds the largest model that fits in a memory budget
+var fitCmd = &cobra.Command{
+	Use:   "fit",
+	Short: "Calculate the largest model that fits in a memory budget",
+	Long: `Provide the memory available, a precision and a percentage overhead to calculate
+the largest number of parameters that fit, in the same notation --size accepts.
+
+For example:
+./gpu-mem-for-llm fit --vram 24gb --precision int4 --overhead 20
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkMutuallyExclusivePrecisionFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		memory, err := parseMemorySize(vram)
+		if err != nil {
+			return err
+		}
+		precision, err := getPrecision()
+		if err != nil {
+			return err
+		}
+
+		maxParameters := estimator.CalculateMaxParameters(memory, precision, float32(overhead))
+
+		if jsonOutput {
+			return writeStructured(out, "json", jsonFit{MaxSize: formatParameterSize(maxParameters), MaxParameters: maxParameters})
+		}
+
+		fmt.Fprintf(out, "Maximum model size: %s (%s parameters)\n", formatParameterSize(maxParameters), estimator.FormatCount(maxParameters))
+		return nil
+	},
+}
+
+var vram string
+
+func init() {
+	fitCmd.Flags().StringVar(&vram, "vram", "", "memory available (e.g., 24gb) - required")
+	fitCmd.MarkFlagRequired("vram")
+	addPrecisionFlags(fitCmd)
+	fitCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	fitCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(fitCmd)
+}
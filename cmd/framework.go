@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// frameworkPreset holds the memory assumptions a serving framework makes, used in place
+// of the generic defaults when --framework is set.
+type frameworkPreset struct {
+	// Overhead is the default overhead percentage for CUDA context, graphs and buffers
+	Overhead int
+	// KVBlockSize is the number of tokens the KV cache is allocated in. The context is
+	// always rounded up to a whole number of blocks.
+	KVBlockSize int
+	// FractionFlag names the flag giving the share of memory the framework fills with its
+	// KV block pool, empty when the framework doesn't pool the KV cache
+	FractionFlag string
+	// OfFreeMemory is set when that share is of the memory the model leaves free rather
+	// than of the whole GPU
+	OfFreeMemory bool
+	// CUDAGraphs is the memory set aside for capturing CUDA graphs
+	CUDAGraphs int
+	// ActivationTokens is the number of tokens the activation buffers are built for up
+	// front, zero when they follow the context
+	ActivationTokens int
+}
+
+// frameworkPresets maps each supported framework name to its memory assumptions.
+// vLLM and TGI page the KV cache in blocks of 16 tokens and capture CUDA graphs, while
+// TensorRT-LLM pages it in blocks of 64 tokens and builds its activation buffers into the
+// engine for --max-num-tokens. llama.cpp allocates a contiguous cache padded to 256
+// tokens but carries less runtime overhead.
+var frameworkPresets = map[string]frameworkPreset{
+	"vllm":         {Overhead: 10, KVBlockSize: 16, FractionFlag: "gpu-memory-utilization", CUDAGraphs: 1_000_000_000},
+	"tgi":          {Overhead: 15, KVBlockSize: 16, FractionFlag: "cuda-memory-fraction", CUDAGraphs: 1_000_000_000},
+	"tensorrt-llm": {Overhead: 5, KVBlockSize: 64, FractionFlag: "kv-cache-free-gpu-memory-fraction", OfFreeMemory: true, ActivationTokens: 8192},
+	"llama.cpp":    {Overhead: 5, KVBlockSize: 256},
+}
+
+// getFrameworkPreset returns the preset for the framework given by name, such as "vllm"
+func getFrameworkPreset(name string) (frameworkPreset, error) {
+	preset, ok := frameworkPresets[strings.ToLower(name)]
+	if !ok {
+		return frameworkPreset{}, fmt.Errorf("unknown framework %q; must be one of %s", name, strings.Join(frameworkNames(), ", "))
+	}
+	return preset, nil
+}
+
+// frameworkNames returns the names of all framework presets in alphabetical order
+func frameworkNames() []string {
+	names := make([]string, 0, len(frameworkPresets))
+	for name := range frameworkPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
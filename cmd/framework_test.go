@@ -0,0 +1,18 @@
+package cmd
+
+import "testing"
+
+func TestFrameworkPresetOverhead(t *testing.T) {
+	vllm := estimateRoot(t, "-s", "7b", "--precision", "fp16", "--framework", "vllm")
+	llamaCPP := estimateRoot(t, "-s", "7b", "--precision", "fp16", "--framework", "llama.cpp")
+
+	if vllm.OverheadPercent != 10 || llamaCPP.OverheadPercent != 5 {
+		t.Errorf("overhead = %v%% for vllm and %v%% for llama.cpp, want 10%% and 5%%", vllm.OverheadPercent, llamaCPP.OverheadPercent)
+	}
+	if vllm.MemBytes == llamaCPP.MemBytes {
+		t.Errorf("vllm and llama.cpp both need %d bytes, want their presets to differ", vllm.MemBytes)
+	}
+	if want := 14_000_000_000 * 105 / 100; llamaCPP.MemBytes != want {
+		t.Errorf("llama.cpp needs %d bytes, want %d", llamaCPP.MemBytes, want)
+	}
+}
@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// ggufMagic is the first four bytes of every GGUF file
+const ggufMagic = "GGUF"
+
+// ggufMaxString bounds the length of a string read from the header, so a corrupt file is
+// reported rather than exhausting memory
+const ggufMaxString = 1 << 24
+
+// GGUF metadata value types
+const (
+	ggufTypeUint8   = 0
+	ggufTypeInt8    = 1
+	ggufTypeUint16  = 2
+	ggufTypeInt16   = 3
+	ggufTypeUint32  = 4
+	ggufTypeInt32   = 5
+	ggufTypeFloat32 = 6
+	ggufTypeBool    = 7
+	ggufTypeString  = 8
+	ggufTypeArray   = 9
+	ggufTypeUint64  = 10
+	ggufTypeInt64   = 11
+	ggufTypeFloat64 = 12
+)
+
+// ggmlType describes how the elements of a tensor are stored: in blocks of the given
+// number of elements, each taking the given number of bytes including its scales
+type ggmlType struct {
+	Name       string
+	BlockSize  int
+	BlockBytes int
+}
+
+// ggmlTypes maps the tensor types of GGUF files to their block layout, as defined by ggml
+var ggmlTypes = map[uint32]ggmlType{
+	0:  {"F32", 1, 4},
+	1:  {"F16", 1, 2},
+	2:  {"Q4_0", 32, 18},
+	3:  {"Q4_1", 32, 20},
+	6:  {"Q5_0", 32, 22},
+	7:  {"Q5_1", 32, 24},
+	8:  {"Q8_0", 32, 34},
+	9:  {"Q8_1", 32, 36},
+	10: {"Q2_K", 256, 84},
+	11: {"Q3_K", 256, 110},
+	12: {"Q4_K", 256, 144},
+	13: {"Q5_K", 256, 176},
+	14: {"Q6_K", 256, 210},
+	15: {"Q8_K", 256, 292},
+	16: {"IQ2_XXS", 256, 66},
+	17: {"IQ2_XS", 256, 74},
+	18: {"IQ3_XXS", 256, 98},
+	19: {"IQ1_S", 256, 50},
+	20: {"IQ4_NL", 32, 18},
+	21: {"IQ3_S", 256, 110},
+	22: {"IQ2_S", 256, 82},
+	23: {"IQ4_XS", 256, 136},
+	24: {"I8", 1, 1},
+	25: {"I16", 1, 2},
+	26: {"I32", 1, 4},
+	27: {"I64", 1, 8},
+	28: {"F64", 1, 8},
+	29: {"IQ1_M", 256, 56},
+	30: {"BF16", 1, 2},
+}
+
+// ggufTensor is the shape and type of a single tensor listed in the header
+type ggufTensor struct {
+	Name     string
+	Elements int
+	Type     ggmlType
+}
+
+// Bytes returns the size of the tensor's data in the file
+func (t ggufTensor) Bytes() int {
+	return (t.Elements + t.Type.BlockSize - 1) / t.Type.BlockSize * t.Type.BlockBytes
+}
+
+// ggufFile holds the header of a GGUF file: its metadata and the tensors it lists.
+// Arrays in the metadata are only kept as their length.
+type ggufFile struct {
+	Metadata map[string]any
+	Tensors  []ggufTensor
+}
+
+// ggufReader reads the little-endian values of a GGUF header
+type ggufReader struct {
+	r io.Reader
+}
+
+func (g ggufReader) uint32() (uint32, error) {
+	var v uint32
+	err := binary.Read(g.r, binary.LittleEndian, &v)
+	return v, err
+}
+
+func (g ggufReader) uint64() (uint64, error) {
+	var v uint64
+	err := binary.Read(g.r, binary.LittleEndian, &v)
+	return v, err
+}
+
+func (g ggufReader) string() (string, error) {
+	n, err := g.uint64()
+	if err != nil {
+		return "", err
+	}
+	if n > ggufMaxString {
+		return "", fmt.Errorf("string of %d bytes is too long", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(g.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// value reads a metadata value of the given type. Integers are returned as int64 or
+// uint64, and arrays as their number of elements.
+func (g ggufReader) value(valueType uint32) (any, error) {
+	switch valueType {
+	case ggufTypeUint8, ggufTypeBool:
+		var v uint8
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		if valueType == ggufTypeBool {
+			return v != 0, err
+		}
+		return uint64(v), err
+	case ggufTypeInt8:
+		// The int8 flag shadows the type, so the byte is sign-extended through an int16
+		var v uint8
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return int64(int16(uint16(v)<<8) >> 8), err
+	case ggufTypeUint16:
+		var v uint16
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return uint64(v), err
+	case ggufTypeInt16:
+		var v int16
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return int64(v), err
+	case ggufTypeUint32:
+		v, err := g.uint32()
+		return uint64(v), err
+	case ggufTypeInt32:
+		var v int32
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return int64(v), err
+	case ggufTypeUint64:
+		return g.uint64()
+	case ggufTypeInt64:
+		var v int64
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeFloat32:
+		var v float32
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return float64(v), err
+	case ggufTypeFloat64:
+		var v float64
+		err := binary.Read(g.r, binary.LittleEndian, &v)
+		return v, err
+	case ggufTypeString:
+		return g.string()
+	case ggufTypeArray:
+		elementType, err := g.uint32()
+		if err != nil {
+			return nil, err
+		}
+		n, err := g.uint64()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err := g.value(elementType); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unknown metadata value type %d", valueType)
+	}
+}
+
+// readGGUF reads the header of the GGUF file at the given path. Only the metadata and
+// tensor infos are read, never the tensor data that makes up the bulk of the file.
+func readGGUF(path string) (ggufFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ggufFile{}, err
+	}
+	defer f.Close()
+
+	file, err := parseGGUF(bufio.NewReader(f))
+	if err != nil {
+		return ggufFile{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return file, nil
+}
+
+// parseGGUF parses a GGUF header, version 2 or 3
+func parseGGUF(r io.Reader) (ggufFile, error) {
+	magic := make([]byte, len(ggufMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != ggufMagic {
+		return ggufFile{}, errors.New("not a GGUF file")
+	}
+
+	g := ggufReader{r}
+	version, err := g.uint32()
+	if err != nil {
+		return ggufFile{}, err
+	}
+	if version < 2 || version > 3 {
+		return ggufFile{}, fmt.Errorf("unsupported GGUF version %d; must be 2 or 3", version)
+	}
+	tensorCount, err := g.uint64()
+	if err != nil {
+		return ggufFile{}, err
+	}
+	metadataCount, err := g.uint64()
+	if err != nil {
+		return ggufFile{}, err
+	}
+
+	file := ggufFile{Metadata: make(map[string]any)}
+	for i := uint64(0); i < metadataCount; i++ {
+		key, err := g.string()
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("metadata %d: %v", i, err)
+		}
+		valueType, err := g.uint32()
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("metadata %s: %v", key, err)
+		}
+		value, err := g.value(valueType)
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("metadata %s: %v", key, err)
+		}
+		file.Metadata[key] = value
+	}
+
+	for i := uint64(0); i < tensorCount; i++ {
+		name, err := g.string()
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("tensor %d: %v", i, err)
+		}
+		dims, err := g.uint32()
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("tensor %s: %v", name, err)
+		}
+		elements := 1
+		for d := uint32(0); d < dims; d++ {
+			n, err := g.uint64()
+			if err != nil {
+				return ggufFile{}, fmt.Errorf("tensor %s: %v", name, err)
+			}
+			elements *= int(n)
+		}
+		typeID, err := g.uint32()
+		if err != nil {
+			return ggufFile{}, fmt.Errorf("tensor %s: %v", name, err)
+		}
+		tensorType, ok := ggmlTypes[typeID]
+		if !ok {
+			return ggufFile{}, fmt.Errorf("tensor %s: unknown tensor type %d", name, typeID)
+		}
+		// The offset of the data within the file isn't needed
+		if _, err := g.uint64(); err != nil {
+			return ggufFile{}, fmt.Errorf("tensor %s: %v", name, err)
+		}
+		file.Tensors = append(file.Tensors, ggufTensor{Name: name, Elements: elements, Type: tensorType})
+	}
+
+	return file, nil
+}
+
+// attentionHeads returns the head dimension and the number of key/value heads of the
+// architecture from the metadata, both zero when the KV heads are missing
+func (f ggufFile) attentionHeads(arch string, hidden int) (headDim, kvHeads int) {
+	return ggufAttentionHeads(f.metadataInt, arch, hidden)
+}
+
+// ggufAttentionHeads returns the head dimension and the number of key/value heads from the
+// GGUF metadata of an architecture, read with the given function, as both llama.cpp files
+// and the model info of Ollama hold them. The head dimension is the key length when it is
+// given and otherwise the hidden dimension divided by the attention heads.
+func ggufAttentionHeads(metadataInt func(key string) int, arch string, hidden int) (headDim, kvHeads int) {
+	heads := metadataInt(arch + ".attention.head_count")
+	kvHeads = metadataInt(arch + ".attention.head_count_kv")
+	if heads <= 0 || kvHeads <= 0 {
+		return 0, 0
+	}
+	headDim = metadataInt(arch + ".attention.key_length")
+	if headDim <= 0 {
+		headDim = hidden / heads
+	}
+	return headDim, kvHeads
+}
+
+// metadataInt returns an integer metadata value, or zero when it is missing
+func (f ggufFile) metadataInt(key string) int {
+	switch v := f.Metadata[key].(type) {
+	case uint64:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// architecture returns the model architecture named in the metadata, such as llama
+func (f ggufFile) architecture() string {
+	name, _ := f.Metadata["general.architecture"].(string)
+	return name
+}
+
+// sortedByCount returns the keys of the counts from the largest count to the smallest
+// and alphabetically among equals
+func sortedByCount(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// jsonGGUF is the shape of the output produced by the inspect-gguf subcommand with --json
+type jsonGGUF struct {
+	File          string            `json:"file"`
+	Architecture  string            `json:"architecture,omitempty"`
+	TensorCount   int               `json:"tensor_count"`
+	Parameters    int               `json:"parameters"`
+	TensorTypes   map[string]int    `json:"tensor_types"`
+	WeightBytes   int               `json:"weight_bytes"`
+	BitsPerWeight float64           `json:"bits_per_weight"`
+	NumLayers     int               `json:"num_layers,omitempty"`
+	HiddenDim     int               `json:"hidden_dim,omitempty"`
+	MemSize       string            `json:"mem_size"`
+	MemBytes      int               `json:"mem_bytes"`
+	Breakdown     map[string]string `json:"breakdown"`
+}
+
+// inspectGGUFCmd estimates the memory for a local GGUF file from its header
+var inspectGGUFCmd = &cobra.Command{
+	Use:   "inspect-gguf FILE",
+	Short: "Estimate memory for a local GGUF file from its header",
+	Long: `Read the header of a GGUF file, as used by llama.cpp, and estimate the memory for
+that exact file. The weights are the sum of the tensors listed in the header at their
+quantization types, and the architecture fields in the metadata size the KV cache with
+--context. Only the header is read, never the tensor data.
+
+For example:
+./gpu-mem-for-llm inspect-gguf llama-3.1-8b-instruct.Q4_K_M.gguf --context 8192
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		file, err := readGGUF(args[0])
+		if err != nil {
+			return err
+		}
+
+		var parameters, weightBytes int
+		tensorTypes := make(map[string]int)
+		for _, t := range file.Tensors {
+			parameters += t.Elements
+			weightBytes += t.Bytes()
+			tensorTypes[t.Type.Name]++
+		}
+		if parameters == 0 {
+			return fmt.Errorf("%s: no tensors in the file", args[0])
+		}
+
+		arch := file.architecture()
+		numLayers := file.metadataInt(arch + ".block_count")
+		hidden := file.metadataInt(arch + ".embedding_length")
+
+		// The quantization types mix within a file, so the weights are sized with the
+		// average bytes per parameter across all tensors
+		input := estimator.ModelSpec{
+			ParameterSize: parameters,
+			Precision:     estimator.Precision(float64(weightBytes) / float64(parameters)),
+			Overhead:      float32(overhead),
+		}
+		if contextLength > 0 {
+			if numLayers == 0 || hidden == 0 {
+				return fmt.Errorf("%s: the metadata has no block_count and embedding_length to size the KV cache", args[0])
+			}
+			// llama.cpp keeps the KV cache in f16 by default, whatever the weights are
+			kvPrecision, err := estimator.PrecisionByName("fp16")
+			if err != nil {
+				return err
+			}
+			input.NumLayers = numLayers
+			input.HiddenDim = hidden
+			input.HeadDim, input.KVHeads = file.attentionHeads(arch, hidden)
+			input.KVPrecision = kvPrecision
+			input.ContextLength = contextLength
+			input.BatchSize = batchSize
+		}
+
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+		bitsPerWeight := float64(weightBytes) * 8 / float64(parameters)
+
+		if jsonOutput {
+			return writeStructured(out, "json", jsonGGUF{
+				File:          args[0],
+				Architecture:  arch,
+				TensorCount:   len(file.Tensors),
+				Parameters:    parameters,
+				TensorTypes:   tensorTypes,
+				WeightBytes:   weightBytes,
+				BitsPerWeight: bitsPerWeight,
+				NumLayers:     numLayers,
+				HiddenDim:     hidden,
+				MemSize:       formatMemory(result.Total),
+				MemBytes:      result.Total,
+				Breakdown:     result.BreakdownIn(memoryUnit),
+			})
+		}
+
+		types := make([]string, 0, len(tensorTypes))
+		for _, name := range sortedByCount(tensorTypes) {
+			types = append(types, fmt.Sprintf("%s (%d)", name, tensorTypes[name]))
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		if arch != "" {
+			fmt.Fprintf(tw, "Architecture:\t%s\n", arch)
+		}
+		if numLayers > 0 && hidden > 0 {
+			fmt.Fprintf(tw, "Layers:\t%d x %d hidden\n", numLayers, hidden)
+		}
+		fmt.Fprintf(tw, "Tensors:\t%d\n", len(file.Tensors))
+		fmt.Fprintf(tw, "Tensor types:\t%s\n", strings.Join(types, ", "))
+		fmt.Fprintf(tw, "Parameters:\t%s\n", estimator.FormatCount(parameters))
+		fmt.Fprintf(tw, "Weights:\t%s (%.2f bits per weight)\n", formatMemory(weightBytes), bitsPerWeight)
+		tw.Flush()
+		fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(result.Total))
+		printBreakdown(out, result)
+		return nil
+	},
+}
+
+func init() {
+	inspectGGUFCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the f16 KV cache for")
+	inspectGGUFCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	inspectGGUFCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	inspectGGUFCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(inspectGGUFCmd)
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseMemorySize parses a memory size such as "24gb", "80GB" or "1.5t" and returns it in
+// bytes. Units are decimal to match estimator.FormatMemory: k, m, g and t, optionally followed by b.
+// With an i, as in "24gib", they are binary.
+func parseMemorySize(value string) (int, error) {
+	pattern := `^(\d+(?:\.\d+)?)\s*([kmgt])(i?)b?$`
+	re := regexp.MustCompile(pattern)
+
+	matches := re.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if matches == nil {
+		return 0, errors.New("invalid memory size; must be a number followed by 'kb', 'mb', 'gb' or 'tb', or 'kib', 'mib', 'gib' or 'tib'")
+	}
+
+	number, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %v", err)
+	}
+
+	multipliers := map[string]float64{
+		"k": 1_000,
+		"m": 1_000_000,
+		"g": 1_000_000_000,
+		"t": 1_000_000_000_000,
+	}
+
+	// Binary units, as nvidia-smi reports, are powers of 1024 instead
+	if matches[3] != "" {
+		binary := map[string]float64{"k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40}
+		return int(number * binary[matches[2]]), nil
+	}
+	return int(number * multipliers[matches[2]]), nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+// gpuSpec holds what the estimate needs to know about a GPU model
+type gpuSpec struct {
+	// Memory is the memory of a single GPU in bytes
+	Memory int
+	// Bandwidth is the memory bandwidth of a single GPU in GB/s, zero when unknown
+	Bandwidth float64
+}
+
+// jsonGPUSpec is the shape of a GPU in a user-provided GPU database file, with sizes in
+// the same notation as --gpu-memory
+type jsonGPUSpec struct {
+	Memory    string  `json:"memory"`
+	Bandwidth float64 `json:"bandwidth"`
+}
+
+// gpuDatabase maps the name of each built-in GPU to its specification. Memory sizes are
+// decimal to match estimator.FormatMemory, so a 24 GB card holds 24,000,000,000 bytes.
+// Bandwidths are from the spec sheets, for the SXM modules of the A100, H100 and H200.
+var gpuDatabase = map[string]gpuSpec{
+	"t4":        {Memory: 16_000_000_000, Bandwidth: 320},
+	"v100-16gb": {Memory: 16_000_000_000, Bandwidth: 900},
+	"v100-32gb": {Memory: 32_000_000_000, Bandwidth: 900},
+	"a10":       {Memory: 24_000_000_000, Bandwidth: 600},
+	"a10g":      {Memory: 24_000_000_000, Bandwidth: 600},
+	"l4":        {Memory: 24_000_000_000, Bandwidth: 300},
+	"rtx3090":   {Memory: 24_000_000_000, Bandwidth: 936},
+	"rtx4090":   {Memory: 24_000_000_000, Bandwidth: 1008},
+	"l40s":      {Memory: 48_000_000_000, Bandwidth: 864},
+	"a100-40gb": {Memory: 40_000_000_000, Bandwidth: 1555},
+	"a100-80gb": {Memory: 80_000_000_000, Bandwidth: 2039},
+	"h100":      {Memory: 80_000_000_000, Bandwidth: 3350},
+	"h200":      {Memory: 141_000_000_000, Bandwidth: 4800},
+	"mi250x":    {Memory: 128_000_000_000, Bandwidth: 3277},
+	"mi300x":    {Memory: 192_000_000_000, Bandwidth: 5300},
+}
+
+// loadGPUDatabase adds the GPUs from a JSON file mapping names to specifications, such as
+// {"my-card": {"memory": "48gb", "bandwidth": 960}}, to the built-in database. Entries in the file replace
+// built-in GPUs of the same name.
+func loadGPUDatabase(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var gpus map[string]jsonGPUSpec
+	if err := json.Unmarshal(data, &gpus); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name, spec := range gpus {
+		memory, err := parseMemorySize(spec.Memory)
+		if err != nil {
+			return fmt.Errorf("%s: GPU %q: %v", path, name, err)
+		}
+		gpuDatabase[strings.ToLower(name)] = gpuSpec{Memory: memory, Bandwidth: spec.Bandwidth}
+	}
+	return nil
+}
+
+// getGPUSpec returns the specification of the GPU given by name, such as "rtx4090"
+func getGPUSpec(name string) (gpuSpec, error) {
+	spec, ok := gpuDatabase[strings.ToLower(name)]
+	if !ok {
+		return gpuSpec{}, fmt.Errorf("unknown GPU %q; must be one of %s", name, strings.Join(gpuNames(), ", "))
+	}
+	return spec, nil
+}
+
+// gpuNames returns the names of all GPUs in the database in alphabetical order
+func gpuNames() []string {
+	names := make([]string, 0, len(gpuDatabase))
+	for name := range gpuDatabase {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveGPUMemory returns the memory of each GPU from --gpu, looked up in the database
+// and any --gpu-db file, or from --gpu-memory. It is zero when neither is given.
+func resolveGPUMemory() (int, error) {
+	if gpu != "" {
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return 0, err
+			}
+		}
+		spec, err := getGPUSpec(gpu)
+		if err != nil {
+			return 0, err
+		}
+		slog.Debug("using GPU database entry", "gpu", gpu, "memory_bytes", spec.Memory, "gpu_db", gpuDB)
+		return spec.Memory, nil
+	}
+	if gpuMemory != "" {
+		return parseMemorySize(gpuMemory)
+	}
+	return 0, nil
+}
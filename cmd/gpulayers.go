@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// jsonGPULayers is the shape of the output produced by the gpu-layers subcommand with --json
+type jsonGPULayers struct {
+	GPULayers   int    `json:"gpu_layers"`
+	CPULayers   int    `json:"cpu_layers"`
+	NGPULayers  int    `json:"n_gpu_layers"`
+	GPUMemSize  string `json:"gpu_mem_size"`
+	GPUMemBytes int    `json:"gpu_mem_bytes"`
+	CPUMemSize  string `json:"cpu_mem_size"`
+	CPUMemBytes int    `json:"cpu_mem_bytes"`
+}
+
+// gpuLayersCmd works out how many layers of a model fit on the GPU when the rest are
+// offloaded to the CPU
+var gpuLayersCmd = &cobra.Command{
+	Use:   "gpu-layers",
+	Short: "Calculate how many layers fit on the GPU with the rest offloaded to the CPU",
+	Long: `Provide the model size, its number of layers, a precision or quantization and the
+GPU memory available to calculate how many layers fit on the GPU and how much memory the
+rest need in system RAM, as llama.cpp's --n-gpu-layers splits a model. With --context,
+each layer on the GPU also holds its share of an f16 KV cache.
+
+For example:
+./gpu-mem-for-llm gpu-layers --size 70b --num-layers 80 --quant Q4_K_M --vram 24gb
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return checkMutuallyExclusivePrecisionFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		memory, err := parseMemorySize(vram)
+		if err != nil {
+			return err
+		}
+		parameterSize, err := getParameterSize(size)
+		if err != nil {
+			return err
+		}
+		precision, err := getPrecision()
+		if err != nil {
+			return err
+		}
+		kvPrecision, err := estimator.PrecisionByName("fp16")
+		if err != nil {
+			return err
+		}
+
+		split, err := estimator.CalculateLayerSplit(estimator.ModelSpec{
+			ParameterSize:  parameterSize,
+			Precision:      precision,
+			Overhead:       float32(overhead),
+			NumLayers:      numLayers,
+			HiddenDim:      hiddenDim,
+			VocabSize:      vocabSize,
+			TiedEmbeddings: tiedEmbeddings,
+			KVPrecision:    kvPrecision,
+			ContextLength:  contextLength,
+			BatchSize:      1,
+		}, memory)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return writeStructured(out, "json", jsonGPULayers{
+				GPULayers:   split.GPULayers,
+				CPULayers:   split.CPULayers,
+				NGPULayers:  split.NGPULayers(),
+				GPUMemSize:  formatMemory(split.GPUBytes),
+				GPUMemBytes: split.GPUBytes,
+				CPUMemSize:  formatMemory(split.CPUBytes),
+				CPUMemBytes: split.CPUBytes,
+			})
+		}
+
+		fmt.Fprintf(out, "Layers on the GPU: %d of %d (--n-gpu-layers %d)\n", split.GPULayers, numLayers, split.NGPULayers())
+		fmt.Fprintf(out, "Layers offloaded to the CPU: %d\n", split.CPULayers)
+		fmt.Fprintf(out, "GPU memory required: %s of %s\n", formatMemory(split.GPUBytes), formatMemory(memory))
+		fmt.Fprintf(out, "System RAM required: %s\n", formatMemory(split.CPUBytes))
+		return nil
+	},
+}
+
+func init() {
+	gpuLayersCmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b, 1.5b or 1.8t) - required")
+	gpuLayersCmd.MarkFlagRequired("size")
+	gpuLayersCmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32) - required")
+	gpuLayersCmd.MarkFlagRequired("num-layers")
+	gpuLayersCmd.Flags().StringVar(&vram, "vram", "", "memory of the GPU (e.g., 24gb) - required")
+	gpuLayersCmd.MarkFlagRequired("vram")
+	addPrecisionFlags(gpuLayersCmd)
+	gpuLayersCmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096), to keep the embeddings in system RAM and size the KV cache")
+	gpuLayersCmd.Flags().IntVar(&vocabSize, "vocab-size", 0, "model vocabulary size (e.g., 32000), to keep the embeddings in system RAM")
+	gpuLayersCmd.Flags().BoolVar(&tiedEmbeddings, "tied-embeddings", false, "the LM head shares its weights with the embedding table")
+	gpuLayersCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the f16 KV cache of the GPU layers for (requires --hidden-dim)")
+	gpuLayersCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	gpuLayersCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(gpuLayersCmd)
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	gpumemv1 "github.com/ashprao/gpu-mem-for-llm/api/gpumem/v1"
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// estimatorServer serves the EstimatorService of api/gpumem/v1 with the same calculation
+// as the HTTP API
+type estimatorServer struct {
+	gpumemv1.UnimplementedEstimatorServiceServer
+}
+
+// grpcError returns the status of an error, telling invalid requests apart from failures
+// of the environment such as a backend that can't be reached
+func grpcError(err error) error {
+	if exitCode(err) == exitInternal {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// grpcOverhead returns the overhead of a request, which is left out as nil
+func grpcOverhead(overhead *int32) *int {
+	if overhead == nil {
+		return nil
+	}
+	value := int(*overhead)
+	return &value
+}
+
+func (estimatorServer) Estimate(ctx context.Context, req *gpumemv1.EstimateRequest) (*gpumemv1.EstimateResponse, error) {
+	output, err := serveEstimate(estimateRequest{
+		Size:             req.GetSize(),
+		Precision:        req.GetPrecision(),
+		Quant:            req.GetQuant(),
+		BytesPerParam:    req.GetBytesPerParam(),
+		Overhead:         grpcOverhead(req.Overhead),
+		NumLayers:        int(req.GetNumLayers()),
+		HiddenDim:        int(req.GetHiddenDim()),
+		VocabSize:        int(req.GetVocabSize()),
+		IntermediateSize: int(req.GetIntermediateSize()),
+		TiedEmbeddings:   req.GetTiedEmbeddings(),
+		HeadDim:          int(req.GetHeadDim()),
+		KVHeads:          int(req.GetKvHeads()),
+		Context:          int(req.GetContext()),
+		SlidingWindow:    int(req.GetSlidingWindow()),
+		BatchSize:        int(req.GetBatchSize()),
+		KVDtype:          req.GetKvDtype(),
+		TensorParallel:   int(req.GetTensorParallel()),
+		Mode:             req.GetMode(),
+		GPU:              req.GetGpu(),
+		GPUMemory:        req.GetGpuMemory(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	bytesPerParameter, _ := strconv.ParseFloat(output.BytesPerParameter.String(), 64)
+	resp := &gpumemv1.EstimateResponse{
+		MemSize:           output.MemSize,
+		MemSizePerGpu:     output.MemSizePerGPU,
+		MemBytes:          int64(output.MemBytes),
+		MemBytesPerGpu:    int64(output.MemBytesPerGPU),
+		Parameters:        int64(output.Parameters),
+		Precision:         output.Precision,
+		BytesPerParameter: bytesPerParameter,
+		OverheadPercent:   float64(output.OverheadPercent),
+		Breakdown:         output.Breakdown,
+		Gpu:               output.GPU,
+		Fits:              output.Fits,
+		GpusRequired:      int32(output.GPUsRequired),
+		HeadroomBytes:     int64(output.HeadroomBytes),
+		HeadroomPercent:   output.HeadroomPercent,
+	}
+	for _, c := range output.Components {
+		resp.Components = append(resp.Components, &gpumemv1.Component{Name: c.Name, Bytes: int64(c.Bytes), Percent: c.Percent})
+	}
+	return resp, nil
+}
+
+func (estimatorServer) Fit(ctx context.Context, req *gpumemv1.FitRequest) (*gpumemv1.FitResponse, error) {
+	if req.GetVram() == "" {
+		return nil, grpcError(errors.New("vram is required"))
+	}
+	memory, err := parseMemorySize(req.GetVram())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	precision, _, err := requestPrecision(estimateRequest{Precision: req.GetPrecision(), Quant: req.GetQuant(), BytesPerParam: req.GetBytesPerParam()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	overhead := int32(20)
+	if req.Overhead != nil {
+		overhead = *req.Overhead
+	}
+
+	maxParameters := estimator.CalculateMaxParameters(memory, precision, float32(overhead))
+	return &gpumemv1.FitResponse{MaxSize: formatParameterSize(maxParameters), MaxParameters: int64(maxParameters)}, nil
+}
+
+func (estimatorServer) ListGPUs(ctx context.Context, req *gpumemv1.ListGPUsRequest) (*gpumemv1.ListGPUsResponse, error) {
+	resp := &gpumemv1.ListGPUsResponse{}
+	for _, gpu := range listGPUs() {
+		resp.Gpus = append(resp.Gpus, &gpumemv1.GPU{Name: gpu.Name, Memory: gpu.Memory, MemoryBytes: int64(gpu.MemoryBytes), Bandwidth: gpu.Bandwidth})
+	}
+	return resp, nil
+}
+
+// newGRPCServer returns a gRPC server of the EstimatorService
+func newGRPCServer() *grpc.Server {
+	server := grpc.NewServer()
+	gpumemv1.RegisterEstimatorServiceServer(server, estimatorServer{})
+	return server
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	gpumemv1 "github.com/ashprao/gpu-mem-for-llm/api/gpumem/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// grpcClient returns a client of a server of the EstimatorService listening in memory
+func grpcClient(t *testing.T) gpumemv1.EstimatorServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return gpumemv1.NewEstimatorServiceClient(conn)
+}
+
+func TestGRPCEstimate(t *testing.T) {
+	client := grpcClient(t)
+	ctx := context.Background()
+
+	req := &gpumemv1.EstimateRequest{Size: "7b", Precision: "fp16", Context: 8192, NumLayers: 32, HiddenDim: 4096, Gpu: "a10"}
+	resp, err := client.Estimate(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := serveEstimate(estimateRequest{Size: "7b", Precision: "fp16", Context: 8192, NumLayers: 32, HiddenDim: 4096, GPU: "a10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetMemBytes() != int64(want.MemBytes) || resp.GetMemSize() != want.MemSize {
+		t.Errorf("Estimate = %d (%s), want %d (%s) as POST /estimate", resp.GetMemBytes(), resp.GetMemSize(), want.MemBytes, want.MemSize)
+	}
+	if len(resp.GetComponents()) != len(want.Components) {
+		t.Errorf("Estimate has %d components, want %d", len(resp.GetComponents()), len(want.Components))
+	}
+	if resp.Fits == nil || resp.GetFits() != *want.Fits || resp.GetGpu() != "a10" {
+		t.Errorf("Estimate fits = %v on %q, want %v on a10", resp.Fits, resp.GetGpu(), *want.Fits)
+	}
+
+	// The overhead is 20 only when left out, so 0 is kept
+	resp, err = client.Estimate(ctx, &gpumemv1.EstimateRequest{Size: "7b", Precision: "fp16", Overhead: proto.Int32(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetOverheadPercent() != 0 || resp.GetMemBytes() != 14_000_000_000 {
+		t.Errorf("Estimate with overhead 0 = %d with %v%% overhead, want 14000000000 with 0%%", resp.GetMemBytes(), resp.GetOverheadPercent())
+	}
+	if resp.Fits != nil {
+		t.Errorf("Estimate without a GPU has fits = %v, want it left out", resp.GetFits())
+	}
+
+	_, err = client.Estimate(ctx, &gpumemv1.EstimateRequest{Size: "7b"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Estimate without a precision = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCFit(t *testing.T) {
+	client := grpcClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Fit(ctx, &gpumemv1.FitRequest{Vram: "24gb", Precision: "int4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetMaxSize() != "40b" || resp.GetMaxParameters() != 40_000_000_000 {
+		t.Errorf("Fit = %s (%d), want 40b (40000000000)", resp.GetMaxSize(), resp.GetMaxParameters())
+	}
+
+	for _, req := range []*gpumemv1.FitRequest{
+		{Precision: "int4"},
+		{Vram: "24gb"},
+		{Vram: "lots", Precision: "int4"},
+	} {
+		if _, err := client.Fit(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Fit(%v) = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestGRPCListGPUs(t *testing.T) {
+	client := grpcClient(t)
+
+	resp, err := client.ListGPUs(context.Background(), &gpumemv1.ListGPUsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetGpus()) != len(gpuDatabase) {
+		t.Fatalf("ListGPUs returned %d GPUs, want %d", len(resp.GetGpus()), len(gpuDatabase))
+	}
+	for _, gpu := range resp.GetGpus() {
+		if gpu.GetName() == "h100" {
+			if gpu.GetMemoryBytes() != int64(gpuDatabase["h100"].Memory) {
+				t.Errorf("h100 has %d bytes, want %d", gpu.GetMemoryBytes(), gpuDatabase["h100"].Memory)
+			}
+			return
+		}
+	}
+	t.Error("ListGPUs left out the h100")
+}
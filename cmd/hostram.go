@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// jsonHostRAM is the shape of the host memory needed to load the model in the --json
+// output
+type jsonHostRAM struct {
+	PeakBytes       int   `json:"peak_bytes"`
+	StagedBytes     int   `json:"staged_bytes"`
+	PageCache       bool  `json:"page_cache,omitempty"`
+	ResidentBytes   int   `json:"resident_bytes,omitempty"`
+	HostMemoryBytes int   `json:"host_memory_bytes,omitempty"`
+	Fits            *bool `json:"fits,omitempty"`
+}
+
+// detectHostMemory returns the system memory of the local machine, from /proc/meminfo on
+// Linux and sysctl on macOS
+func detectHostMemory() (int, error) {
+	switch runtime.GOOS {
+	case "linux":
+		f, err := os.Open("/proc/meminfo")
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			// The line reads "MemTotal:       65843424 kB"
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+				kb, err := strconv.Atoi(fields[1])
+				if err != nil {
+					return 0, fmt.Errorf("unexpected MemTotal %q", fields[1])
+				}
+				return kb * 1024, nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("no MemTotal in /proc/meminfo")
+	case "darwin":
+		output, err := runSMI("sysctl", "-n", "hw.memsize")
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(strings.TrimSpace(string(output)))
+	default:
+		return 0, fmt.Errorf("the host memory can't be detected on %s; give it with --host-memory", runtime.GOOS)
+	}
+}
+
+// hostRAMFits reports whether the host memory holds what loading the model needs. The
+// pages of a memory-mapped checkpoint can be evicted, so only the resident components
+// have to fit alongside them.
+func hostRAMFits(ram estimator.HostRAM, hostMemory int) bool {
+	if ram.PageCache {
+		return ram.Resident <= hostMemory
+	}
+	return ram.Peak <= hostMemory
+}
+
+// jsonHostRAMPlan converts the host memory needed to load the model to its --json shape,
+// with the fit against the host memory when it is known
+func jsonHostRAMPlan(ram estimator.HostRAM, hostMemory int) *jsonHostRAM {
+	output := &jsonHostRAM{
+		PeakBytes:       ram.Peak,
+		StagedBytes:     ram.Staged,
+		PageCache:       ram.PageCache,
+		ResidentBytes:   ram.Resident,
+		HostMemoryBytes: hostMemory,
+	}
+	if hostMemory > 0 {
+		fits := hostRAMFits(ram, hostMemory)
+		output.Fits = &fits
+	}
+	return output
+}
+
+// printHostRAM prints the host memory needed to load the model, warning when it is more
+// than the host memory
+func printHostRAM(w io.Writer, ram estimator.HostRAM, hostMemory int, staging string) {
+	fmt.Fprintf(w, "Peak host RAM while loading: %s\n", formatMemory(ram.Peak))
+	if ram.PageCache {
+		fmt.Fprintf(w, "  checkpoint: %s at %s, memory-mapped as page cache the kernel can reclaim\n", formatMemory(ram.Staged), staging)
+	} else {
+		fmt.Fprintf(w, "  checkpoint: %s at %s, staged while the weights are copied to the GPU\n", formatMemory(ram.Staged), staging)
+	}
+	if ram.Resident > 0 {
+		fmt.Fprintf(w, "  offloaded:  %s, resident for as long as the model runs\n", formatMemory(ram.Resident))
+	}
+	if hostMemory <= 0 || hostRAMFits(ram, hostMemory) {
+		return
+	}
+	if ram.PageCache {
+		fmt.Fprintf(w, "Warning: the offloaded components need %s of host RAM, more than the %s of this host\n", formatMemory(ram.Resident), formatMemory(hostMemory))
+	} else {
+		fmt.Fprintf(w, "Warning: loading needs %s of host RAM, more than the %s of this host, and may be killed by the OOM killer; memory-map the checkpoint or load it one shard at a time\n", formatMemory(ram.Peak), formatMemory(hostMemory))
+	}
+}
@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// hfDefaultEndpoint is the Hugging Face Hub the model files are fetched from unless
+	// HF_ENDPOINT points somewhere else, such as a mirror
+	hfDefaultEndpoint = "https://huggingface.co"
+
+	// hfTokenEnv is the environment variable holding the access token for gated models
+	hfTokenEnv = "HF_TOKEN"
+)
+
+// hfModelPattern matches a Hub model ID such as meta-llama/Llama-3.1-8B
+var hfModelPattern = regexp.MustCompile(`^[\w.-]+(/[\w.-]+)?$`)
+
+// hfConfig holds the fields of a model's config.json the estimate is derived from
+type hfConfig struct {
+	HiddenSize        int    `json:"hidden_size"`
+	NumHiddenLayers   int    `json:"num_hidden_layers"`
+	NumAttentionHeads int    `json:"num_attention_heads"`
+	NumKeyValueHeads  int    `json:"num_key_value_heads"`
+	IntermediateSize  int    `json:"intermediate_size"`
+	VocabSize         int    `json:"vocab_size"`
+	TieWordEmbeddings bool   `json:"tie_word_embeddings"`
+	TorchDtype        string `json:"torch_dtype"`
+}
+
+// hfSafetensorsIndex holds the fields of a sharded checkpoint's model.safetensors.index.json
+type hfSafetensorsIndex struct {
+	Metadata struct {
+		TotalSize int `json:"total_size"`
+	} `json:"metadata"`
+}
+
+// hfDtypes maps the torch_dtype of a config to the matching precision
+var hfDtypes = map[string]string{
+	"float32":  "fp32",
+	"float16":  "fp16",
+	"bfloat16": "bf16",
+}
+
+var (
+	// errHFNotFound is returned when the Hub has no such file for the model
+	errHFNotFound = errors.New("not found")
+
+	// errHFUnreachable is returned when the Hub can't be reached and the file isn't cached
+	errHFUnreachable = errors.New("the Hugging Face Hub can't be reached")
+)
+
+// hfCacheDir returns the directory the fetched model files are cached in, the one given
+// with --hf-cache-dir or a directory under the user's cache directory.
+func hfCacheDir() (string, error) {
+	if hfCache != "" {
+		return hfCache, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("no cache directory; set one with --hf-cache-dir: %v", err)
+	}
+	return filepath.Join(dir, "gpu-mem-for-llm", "huggingface"), nil
+}
+
+// fetchHFFile returns a file from the main branch of a model on the Hub. Fetched files are
+// written to the cache, which is read instead when offline or when the Hub can't be reached.
+func fetchHFFile(model, name string, offline bool) ([]byte, error) {
+	dir, err := hfCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(dir, filepath.FromSlash(model), name)
+
+	if offline {
+		slog.Debug("reading Hugging Face file from the cache", "model", model, "file", name, "path", cachePath)
+		data, err := os.ReadFile(cachePath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errHFNotFound
+		}
+		return data, err
+	}
+
+	endpoint := hfDefaultEndpoint
+	if value := os.Getenv("HF_ENDPOINT"); value != "" {
+		endpoint = value
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/"+model+"/resolve/main/"+name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Hugging Face endpoint: %v", err)
+	}
+	if token := os.Getenv(hfTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	slog.Debug("fetching from the Hugging Face Hub", "url", req.URL.String())
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		if data, cacheErr := os.ReadFile(cachePath); cacheErr == nil {
+			slog.Debug("the Hugging Face Hub is unreachable; using the cache", "path", cachePath, "error", err)
+			return data, nil
+		}
+		return nil, fmt.Errorf("error fetching %s for %s: %w: %v", name, model, errHFUnreachable, err)
+	}
+	defer resp.Body.Close()
+	slog.Debug("the Hugging Face Hub responded", "url", req.URL.String(), "status", resp.StatusCode)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errHFNotFound
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("access to %s is restricted; set %s to a token that has been granted access", model, hfTokenEnv)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("error fetching %s for %s: the Hub responded with %s", name, model, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s for %s: %v", name, model, err)
+	}
+
+	// The cache only saves a later fetch, so failing to write it isn't an error
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0o755); err == nil {
+		if err := os.WriteFile(cachePath, data, 0o644); err == nil {
+			slog.Debug("cached Hugging Face file", "path", cachePath)
+		}
+	}
+	return data, nil
+}
+
+// calculateHFParameters counts the parameters of a Llama-style model from its config. The
+// key and value projections are narrower than the query when the model uses grouped-query
+// attention.
+func calculateHFParameters(config hfConfig) int {
+	headDim := config.HiddenSize / config.NumAttentionHeads
+	kvHeads := config.NumKeyValueHeads
+	if kvHeads == 0 {
+		kvHeads = config.NumAttentionHeads
+	}
+
+	attention := 2*config.HiddenSize*config.HiddenSize + 2*config.HiddenSize*kvHeads*headDim
+	mlp := 3 * config.HiddenSize * config.IntermediateSize
+	blocks := config.NumHiddenLayers * (attention + mlp)
+
+	return blocks +
+		estimator.CalculateNormParameters(config.NumHiddenLayers, config.HiddenSize) +
+		estimator.CalculateEmbeddingParameters(config.VocabSize, config.HiddenSize, config.TieWordEmbeddings)
+}
+
+// hfSettings fetches the config of a model on the Hub and returns the settings derived
+// from it
+func hfSettings(model string, offline bool) (map[string]string, error) {
+	if !hfModelPattern.MatchString(model) || strings.Contains(model, "..") {
+		return nil, fmt.Errorf("invalid Hugging Face model %q; must be a model ID such as meta-llama/Llama-3.1-8B", model)
+	}
+
+	fetch := func(name string) ([]byte, error) {
+		return fetchHFFile(model, name, offline)
+	}
+	data, err := fetch("config.json")
+	if errors.Is(err, errHFNotFound) {
+		if offline {
+			return nil, fmt.Errorf("config.json for %s is not in the cache; run once without --hf-offline to fetch it", model)
+		}
+		return nil, fmt.Errorf("model %s not found on the Hugging Face Hub", model)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hfConfigSettings(model, data, fetch)
+}
+
+// modelDirSettings reads the config of a model in the Hugging Face format from a local
+// directory, such as a download or a fine-tuned checkpoint, and returns the settings
+// derived from it without reaching the Hub
+func modelDirSettings(dir string) (map[string]string, error) {
+	fetch := func(name string) ([]byte, error) {
+		slog.Debug("reading model directory file", "path", filepath.Join(dir, name))
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errHFNotFound
+		}
+		return data, err
+	}
+	data, err := fetch("config.json")
+	if errors.Is(err, errHFNotFound) {
+		return nil, fmt.Errorf("%s has no config.json; --model-dir must be the directory of a model in the Hugging Face format", dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hfConfigSettings(dir, data, fetch)
+}
+
+// hfConfigSettings returns the settings derived from the config.json of a model, fetching
+// any other file of the model with fetch. The parameter count comes from the checkpoint's
+// safetensors index when it has one, and is otherwise calculated from the architecture.
+func hfConfigSettings(model string, data []byte, fetch func(name string) ([]byte, error)) (map[string]string, error) {
+	var config hfConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("config.json for %s: %v", model, err)
+	}
+	if config.HiddenSize <= 0 || config.NumHiddenLayers <= 0 || config.NumAttentionHeads <= 0 {
+		return nil, fmt.Errorf("config.json for %s does not describe a transformer with hidden_size, num_hidden_layers and num_attention_heads", model)
+	}
+
+	settings := map[string]string{
+		"num-layers": fmt.Sprint(config.NumHiddenLayers),
+		"hidden-dim": fmt.Sprint(config.HiddenSize),
+		"head-dim":   fmt.Sprint(config.HiddenSize / config.NumAttentionHeads),
+	}
+	if config.NumKeyValueHeads > 0 {
+		settings["kv-heads"] = fmt.Sprint(config.NumKeyValueHeads)
+	}
+	if config.VocabSize > 0 {
+		settings["vocab-size"] = fmt.Sprint(config.VocabSize)
+		settings["tied-embeddings"] = fmt.Sprint(config.TieWordEmbeddings)
+	}
+	if config.IntermediateSize > 0 {
+		settings["intermediate-size"] = fmt.Sprint(config.IntermediateSize)
+	}
+
+	dtype, hasDtype := hfDtypes[config.TorchDtype]
+	if hasDtype {
+		settings["precision"] = dtype
+	}
+
+	// The index records the size of the checkpoint in bytes, which gives the parameter
+	// count exactly when the precision of the weights is known. Checkpoints in a single
+	// file have no index, and without one the count is calculated from the architecture.
+	var params int
+	if hasDtype {
+		data, err := fetch("model.safetensors.index.json")
+		if err != nil && !errors.Is(err, errHFNotFound) && !errors.Is(err, errHFUnreachable) {
+			return nil, err
+		}
+		if err == nil {
+			var index hfSafetensorsIndex
+			if err := json.Unmarshal(data, &index); err != nil {
+				return nil, fmt.Errorf("model.safetensors.index.json for %s: %v", model, err)
+			}
+			precision, err := estimator.PrecisionByName(dtype)
+			if err != nil {
+				return nil, err
+			}
+			params = int(float64(index.Metadata.TotalSize) / float64(precision))
+			slog.Debug("counted parameters from the safetensors index", "model", model, "total_size", index.Metadata.TotalSize, "dtype", dtype, "parameters", params)
+		}
+	}
+	if params == 0 {
+		if config.IntermediateSize <= 0 || config.VocabSize <= 0 {
+			return nil, fmt.Errorf("config.json for %s needs intermediate_size and vocab_size to count the parameters", model)
+		}
+		params = calculateHFParameters(config)
+		slog.Debug("counted parameters from the architecture", "model", model, "parameters", params)
+	}
+
+	settings["size"] = fmt.Sprint(params)
+
+	return settings, nil
+}
+
+// applyHFModel fills in every setting not given otherwise from the model given with
+// --hf-model, so flags, environment variables and the config file all take precedence.
+func applyHFModel(cmd *cobra.Command) error {
+	if hfModel == "" {
+		return nil
+	}
+	settings, err := hfSettings(hfModel, hfOffline)
+	if err != nil {
+		// The flags were fine, so the usage wouldn't help
+		cmd.SilenceUsage = true
+		return err
+	}
+	return applyModelSettings(cmd, "hf-model", hfModel, settings)
+}
+
+// applyModelDir fills in every setting not given otherwise from the model directory given
+// with --model-dir, the same as --hf-model does from the Hub
+func applyModelDir(cmd *cobra.Command) error {
+	if modelDir == "" {
+		return nil
+	}
+	settings, err := modelDirSettings(modelDir)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+	return applyModelSettings(cmd, "model-dir", modelDir, settings)
+}
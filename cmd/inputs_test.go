@@ -0,0 +1,26 @@
+package cmd
+
+import "testing"
+
+func TestResolvedInputs(t *testing.T) {
+	got := estimateRoot(t, "-s", "7b", "--precision", "int8", "--overhead", "15", "--json-include-inputs")
+
+	for flag, want := range map[string]string{
+		// Given on the command line
+		"size":      "7b",
+		"precision": "int8",
+		"overhead":  "15",
+		// Left at their defaults
+		"batch-size": "1",
+		"mode":       "inference",
+	} {
+		if got.Inputs[flag] != want {
+			t.Errorf("inputs[%q] = %q, want %q", flag, got.Inputs[flag], want)
+		}
+	}
+	for _, flag := range []string{"help", "version"} {
+		if value, ok := got.Inputs[flag]; ok {
+			t.Errorf("inputs[%q] = %q, want it left out", flag, value)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// errQuit is returned by prompt when the user asks to leave the interactive session
+// or the input is exhausted.
+var errQuit = errors.New("quit")
+
+// prompt writes the question and reads answers until parse accepts one. An empty answer
+// is replaced by the default value when one is given. Invalid answers are reported and
+// the question is asked again.
+func prompt(scanner *bufio.Scanner, out io.Writer, question, defaultValue string, parse func(string) error) error {
+	for {
+		if defaultValue != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, defaultValue)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return errQuit
+		}
+
+		answer := strings.TrimSpace(scanner.Text())
+		switch strings.ToLower(answer) {
+		case "quit", "exit", "q":
+			return errQuit
+		case "":
+			answer = defaultValue
+		}
+
+		if err := parse(answer); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+		return nil
+	}
+}
+
+// wizardInputs holds the answers of the wizard that the estimate is made from
+type wizardInputs struct {
+	Size      string
+	Precision string
+	Overhead  int
+	NumLayers int
+	HiddenDim int
+	Context   int
+	GPU       string
+}
+
+// wizardDefaults are the answers the wizard suggests before any are given
+var wizardDefaults = wizardInputs{Size: "7b", Precision: "fp16"}
+
+// wizardFields lists the inputs the wizard can change, in the order they are first asked
+var wizardFields = []string{"size", "precision", "overhead", "architecture", "context", "gpu"}
+
+// isTerminal reports whether the reader is a terminal rather than a pipe or a file. The
+// null device is a character device as well, so it is ruled out on its own.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+// choose asks for one of the options, given by its number in the list or by name
+func choose(scanner *bufio.Scanner, out io.Writer, question string, options []string, defaultValue string, chosen *string) error {
+	for i, option := range options {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, option)
+	}
+	return prompt(scanner, out, question, defaultValue, func(answer string) error {
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(options) {
+				return fmt.Errorf("invalid choice; must be between 1 and %d", len(options))
+			}
+			*chosen = options[n-1]
+			return nil
+		}
+		for _, option := range options {
+			if strings.EqualFold(answer, option) {
+				*chosen = option
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid choice %q; pick a number or one of %s", answer, strings.Join(options, ", "))
+	})
+}
+
+// askWizardField asks for a single input of the wizard, with its current value as the
+// default
+func askWizardField(scanner *bufio.Scanner, out io.Writer, field string, inputs *wizardInputs) error {
+	switch field {
+	case "size":
+		return prompt(scanner, out, "Model size (e.g., 7b)", inputs.Size, func(answer string) error {
+			if _, err := getParameterSize(answer); err != nil {
+				return err
+			}
+			inputs.Size = answer
+			return nil
+		})
+	case "precision":
+		return choose(scanner, out, "Precision", estimator.PrecisionNames(), inputs.Precision, &inputs.Precision)
+	case "overhead":
+		return prompt(scanner, out, "Overhead percentage", strconv.Itoa(inputs.Overhead), func(answer string) (err error) {
+			inputs.Overhead, err = parseWizardCount(answer)
+			if err != nil {
+				return errors.New("invalid overhead; must be a whole percentage of 0 or more")
+			}
+			return nil
+		})
+	case "architecture":
+		fmt.Fprintln(out, "The architecture sizes the KV cache; enter 0 to leave it out.")
+		err := prompt(scanner, out, "Number of layers", strconv.Itoa(inputs.NumLayers), func(answer string) (err error) {
+			inputs.NumLayers, err = parseWizardCount(answer)
+			return err
+		})
+		if err != nil || inputs.NumLayers == 0 {
+			return err
+		}
+		return prompt(scanner, out, "Hidden dimension", strconv.Itoa(inputs.HiddenDim), func(answer string) (err error) {
+			inputs.HiddenDim, err = parseWizardCount(answer)
+			return err
+		})
+	case "context":
+		if inputs.NumLayers == 0 || inputs.HiddenDim == 0 {
+			inputs.Context = 0
+			return nil
+		}
+		return prompt(scanner, out, "Context length in tokens", strconv.Itoa(inputs.Context), func(answer string) (err error) {
+			inputs.Context, err = parseWizardCount(answer)
+			return err
+		})
+	case "gpu":
+		gpuName := inputs.GPU
+		if gpuName == "" {
+			gpuName = "none"
+		}
+		err := choose(scanner, out, "GPU", append([]string{"none"}, gpuNames()...), gpuName, &gpuName)
+		if gpuName == "none" {
+			gpuName = ""
+		}
+		inputs.GPU = gpuName
+		return err
+	}
+	return nil
+}
+
+// parseWizardCount parses a count of zero or more, such as a number of layers
+func parseWizardCount(answer string) (int, error) {
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid number; must be a whole number of 0 or more")
+	}
+	return n, nil
+}
+
+// printWizardEstimate prints the estimate for the wizard's inputs, with the verdict for
+// the GPU when one is picked
+func printWizardEstimate(out io.Writer, inputs wizardInputs) error {
+	parameterSize, err := getParameterSize(inputs.Size)
+	if err != nil {
+		return err
+	}
+	precision, err := estimator.PrecisionByName(inputs.Precision)
+	if err != nil {
+		return err
+	}
+
+	input := estimator.ModelSpec{
+		ParameterSize: parameterSize,
+		Precision:     precision,
+		Overhead:      float32(inputs.Overhead),
+		NumLayers:     inputs.NumLayers,
+		HiddenDim:     inputs.HiddenDim,
+	}
+	if inputs.Context > 0 {
+		input.ContextLength = inputs.Context
+		input.BatchSize = 1
+	}
+	result, err := calculate(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Model: %s at %s", inputs.Size, inputs.Precision)
+	if inputs.Context > 0 {
+		fmt.Fprintf(out, ", %d tokens of context", inputs.Context)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(result.Total))
+	printBreakdown(out, result)
+	if inputs.GPU != "" {
+		spec, err := getGPUSpec(inputs.GPU)
+		if err != nil {
+			return err
+		}
+		if result.Total <= spec.Memory {
+			fmt.Fprintf(out, "Verdict: fits on %s with %s to spare\n", inputs.GPU, formatMemory(spec.Memory-result.Total))
+		} else {
+			fmt.Fprintf(out, "Verdict: does not fit on a single %s, short by %s\n", inputs.GPU, formatMemory(result.Total-spec.Memory))
+		}
+	}
+	fmt.Fprintln(out)
+	return nil
+}
+
+// runWizard asks for every input once and then shows the estimate, updating it each time
+// one of the inputs is changed until the input ends or the user quits.
+func runWizard(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(out, "Answer each question to estimate the memory required. Type 'quit' to exit.")
+
+	// The overhead starts from that of --overhead, the config file or the environment
+	inputs := wizardDefaults
+	inputs.Overhead = overhead
+	for _, field := range wizardFields {
+		if err := askWizardField(scanner, out, field, &inputs); err != nil {
+			return
+		}
+	}
+
+	for {
+		if err := printWizardEstimate(out, inputs); err != nil {
+			fmt.Fprintln(out, err)
+		}
+		var field string
+		if err := choose(scanner, out, "Change", wizardFields, "", &field); err != nil {
+			return
+		}
+		if err := askWizardField(scanner, out, field, &inputs); err != nil {
+			return
+		}
+		// A new architecture may make room for a context, or take it away
+		if field == "architecture" {
+			if err := askWizardField(scanner, out, "context", &inputs); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// interactiveCmd starts a wizard that picks each input from a menu and updates the
+// estimate as they change, for quick what-if estimates
+var interactiveCmd = &cobra.Command{
+	Use:     "interactive",
+	Aliases: []string{"tui"},
+	Short:   "Pick the model, precision, context and GPU from menus and see the estimate update",
+	Long: `Start a wizard that asks for the model size, precision, overhead, architecture,
+context length and GPU, picked from menus where there is a choice, and shows the
+estimate with the verdict for the GPU. Each input can then be changed on its own to see
+the estimate update. Press enter to accept the default shown in brackets. The wizard
+also starts when gpu-mem-for-llm is run in a terminal without any flags. Type 'quit' or
+send EOF (Ctrl-D) to exit.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runWizard(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	interactiveCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead percentage the wizard starts from")
+	rootCmd.AddCommand(interactiveCmd)
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// frameworkFlags maps the flags tuning a single framework's memory to that framework
+var frameworkFlags = map[string]string{
+	"gpu-memory-utilization":            "vllm",
+	"swap-space":                        "vllm",
+	"enforce-eager":                     "vllm",
+	"cuda-memory-fraction":              "tgi",
+	"kv-cache-free-gpu-memory-fraction": "tensorrt-llm",
+	"max-num-tokens":                    "tensorrt-llm",
+}
+
+// checkFrameworkFlags returns an error when a flag tuning a framework is given without it
+func checkFrameworkFlags(cmd *cobra.Command) error {
+	for name, f := range frameworkFlags {
+		if cmd.Flags().Changed(name) && strings.ToLower(framework) != f {
+			return fmt.Errorf("--%s requires --framework %s", name, f)
+		}
+	}
+	return nil
+}
+
+// kvPoolPlan describes how a serving framework divides the memory of each GPU: the share
+// it fills, what the model and its activations take out of it, and the pool of KV cache
+// blocks left over, which decides how long and how many sequences fit
+type kvPoolPlan struct {
+	Framework     string
+	FractionFlag  string
+	Fraction      float64
+	OfFreeMemory  bool
+	Reserved      int
+	Model         int
+	CUDAGraphs    int
+	KVPool        int
+	BlockSize     int
+	BlockBytes    int
+	Blocks        int
+	Context       int
+	Utilization   float64
+	SwapBytes     int
+	SwapBlocks    int
+	MaxModelLen   int
+	MaxNumSeqs    int
+	BuildPeak     int
+	NoRoomForKV   bool
+	ContextTooBig bool
+}
+
+// jsonKVPool is the shape of the KV block pool plan in the --json output
+type jsonKVPool struct {
+	Framework      string  `json:"framework"`
+	MemoryFraction float64 `json:"memory_fraction"`
+	ReservedBytes  int     `json:"reserved_bytes"`
+	ModelBytes     int     `json:"model_bytes"`
+	CUDAGraphBytes int     `json:"cuda_graph_bytes,omitempty"`
+	KVPoolBytes    int     `json:"kv_pool_bytes"`
+	BlockSize      int     `json:"block_size"`
+	Blocks         int     `json:"blocks"`
+	SwapBlocks     int     `json:"swap_blocks,omitempty"`
+	MaxModelLen    int     `json:"max_model_len"`
+	MaxNumSeqs     int     `json:"max_num_seqs,omitempty"`
+	BuildPeakBytes int     `json:"build_peak_bytes,omitempty"`
+}
+
+// calculateKVPoolPlan works out the KV block pool a framework allocates on each GPU of the
+// given memory. vLLM and TGI claim a fraction of the whole GPU, profile the weights and
+// the peak activations, set aside room for CUDA graphs and hand whatever is left to the
+// KV cache. TensorRT-LLM loads its engine first and gives the KV cache a fraction of the
+// memory still free.
+func calculateKVPoolPlan(cmd *cobra.Command, preset frameworkPreset, input estimator.ModelSpec, result estimator.Estimate, gpuMemoryBytes int) (kvPoolPlan, error) {
+	fraction, err := cmd.Flags().GetFloat64(preset.FractionFlag)
+	if err != nil {
+		return kvPoolPlan{}, err
+	}
+	if fraction <= 0 || fraction > 1 {
+		return kvPoolPlan{}, fmt.Errorf("invalid %s; must be greater than 0 and at most 1", preset.FractionFlag)
+	}
+	if swapSpace < 0 {
+		return kvPoolPlan{}, errors.New("invalid swap-space; must be 0 or more")
+	}
+
+	plan := kvPoolPlan{
+		Framework:    strings.ToLower(framework),
+		FractionFlag: preset.FractionFlag,
+		Fraction:     fraction,
+		OfFreeMemory: preset.OfFreeMemory,
+		BlockSize:    max(input.KVBlockSize, 1),
+		Context:      input.ContextLength,
+		Utilization:  input.KVUtilization,
+		CUDAGraphs:   preset.CUDAGraphs,
+	}
+	// Every KV cache lives in the pool, including the one of a draft model
+	var weights int
+	for _, c := range result.Components {
+		if strings.Contains(c.Name, "kv cache") {
+			continue
+		}
+		plan.Model += c.Bytes
+		if c.Name != "activations" && !strings.HasPrefix(c.Name, "overhead") {
+			weights += c.Bytes
+		}
+	}
+	if plan.Framework == "vllm" && enforceEager {
+		plan.CUDAGraphs = 0
+	}
+
+	// The memory of a single block follows from the cache of one block of tokens, split
+	// across the GPUs as the KV heads allow
+	block := input
+	block.ContextLength = plan.BlockSize
+	block.BatchSize = 1
+	block.KVBuckets = nil
+	block.RoundContext = false
+	block.SlidingWindow = 0
+	block.KVUtilization = 0
+	kvCache, err := block.KVCacheComponent()
+	if err != nil {
+		return kvPoolPlan{}, err
+	}
+	shards := max(input.TensorParallel, 1)
+	if kvCache.ShardLimit > 0 {
+		shards = min(shards, kvCache.ShardLimit)
+	}
+	plan.BlockBytes = kvCache.Bytes / shards
+
+	if plan.OfFreeMemory {
+		plan.Reserved = gpuMemoryBytes
+		plan.KVPool = int(fraction * float64(gpuMemoryBytes-plan.Model-plan.CUDAGraphs))
+	} else {
+		plan.Reserved = int(fraction * float64(gpuMemoryBytes))
+		plan.KVPool = plan.Reserved - plan.Model - plan.CUDAGraphs
+	}
+
+	// Building an engine holds the checkpoint's weights alongside the engine's own
+	if plan.Framework == "tensorrt-llm" {
+		plan.BuildPeak = plan.Model + weights
+	}
+
+	if plan.KVPool < plan.BlockBytes {
+		plan.NoRoomForKV = true
+		return plan, nil
+	}
+	plan.Blocks = plan.KVPool / plan.BlockBytes
+	plan.MaxModelLen = plan.Blocks * plan.BlockSize
+	if plan.Context > 0 {
+		// Only the blocks expected to hold tokens go to sequences
+		usable := plan.Blocks
+		if plan.Utilization > 0 {
+			usable = int(float64(plan.Blocks) * plan.Utilization)
+		}
+		plan.MaxNumSeqs = usable / ((plan.Context + plan.BlockSize - 1) / plan.BlockSize)
+		plan.ContextTooBig = plan.MaxNumSeqs == 0
+	}
+
+	// The swap space of vLLM holds blocks of preempted sequences in host memory
+	if plan.Framework == "vllm" {
+		plan.SwapBytes = int(swapSpace * 1024 * 1024 * 1024)
+		plan.SwapBlocks = plan.SwapBytes / plan.BlockBytes
+	}
+	return plan, nil
+}
+
+// jsonKVPoolPlan returns the KV block pool plan for --json
+func jsonKVPoolPlan(plan kvPoolPlan) *jsonKVPool {
+	return &jsonKVPool{
+		Framework:      plan.Framework,
+		MemoryFraction: plan.Fraction,
+		ReservedBytes:  plan.Reserved,
+		ModelBytes:     plan.Model,
+		CUDAGraphBytes: plan.CUDAGraphs,
+		KVPoolBytes:    max(plan.KVPool, 0),
+		BlockSize:      plan.BlockSize,
+		Blocks:         plan.Blocks,
+		SwapBlocks:     plan.SwapBlocks,
+		MaxModelLen:    plan.MaxModelLen,
+		MaxNumSeqs:     plan.MaxNumSeqs,
+		BuildPeakBytes: plan.BuildPeak,
+	}
+}
+
+// printKVPoolPlan prints how the framework divides the memory of each GPU and the longest
+// and most sequences its KV block pool serves
+func printKVPoolPlan(w io.Writer, plan kvPoolPlan) {
+	if plan.OfFreeMemory {
+		fmt.Fprintf(w, "%s with %s %g of the memory left free per GPU:\n", plan.Framework, plan.FractionFlag, plan.Fraction)
+	} else {
+		fmt.Fprintf(w, "%s with %s %g: %s reserved per GPU\n", plan.Framework, plan.FractionFlag, plan.Fraction, formatMemory(plan.Reserved))
+	}
+	fmt.Fprintf(w, "  model and activations: %s\n", formatMemory(plan.Model))
+	if plan.CUDAGraphs > 0 {
+		fmt.Fprintf(w, "  CUDA graphs:           %s\n", formatMemory(plan.CUDAGraphs))
+	}
+	if plan.BuildPeak > 0 {
+		fmt.Fprintf(w, "  engine build peak:     %s (the checkpoint and the engine's weights at once)\n", formatMemory(plan.BuildPeak))
+	}
+	if plan.NoRoomForKV {
+		fmt.Fprintf(w, "Verdict: no room is left for the KV cache; raise --%s or use more GPUs\n", plan.FractionFlag)
+		return
+	}
+	fmt.Fprintf(w, "  KV cache pool:         %s (%d blocks of %d tokens)\n", formatMemory(plan.KVPool), plan.Blocks, plan.BlockSize)
+	fmt.Fprintf(w, "max-model-len: up to %d tokens\n", plan.MaxModelLen)
+	if plan.ContextTooBig {
+		fmt.Fprintf(w, "Verdict: a sequence of %d tokens does not fit in the KV cache pool; lower the context to %d tokens\n", plan.Context, plan.MaxModelLen)
+	} else if plan.Context > 0 && plan.Utilization > 0 && plan.Utilization < 1 {
+		fmt.Fprintf(w, "max-num-seqs: up to %d sequences of %d tokens at once (with %g%% of the blocks holding tokens)\n", plan.MaxNumSeqs, plan.Context, plan.Utilization*100)
+	} else if plan.Context > 0 {
+		fmt.Fprintf(w, "max-num-seqs: up to %d sequences of %d tokens at once\n", plan.MaxNumSeqs, plan.Context)
+	}
+	if plan.SwapBytes > 0 {
+		fmt.Fprintf(w, "Swap space: %s of host memory per GPU for %d blocks of preempted sequences\n", formatMemory(plan.SwapBytes), plan.SwapBlocks)
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// jsonLlamaCpp is the shape of the suggested llama.cpp flags in the --json output
+type jsonLlamaCpp struct {
+	NGPULayers   int  `json:"n_gpu_layers"`
+	Context      int  `json:"ctx_size"`
+	Batch        int  `json:"batch_size"`
+	UBatch       int  `json:"ubatch_size"`
+	AllLayers    bool `json:"all_layers"`
+	ComputeBytes int  `json:"compute_buffer_bytes"`
+	GPUBytes     int  `json:"gpu_bytes"`
+	CPUBytes     int  `json:"cpu_bytes"`
+}
+
+// jsonLlamaCppPlan returns the suggested llama.cpp flags for --json
+func jsonLlamaCppPlan(plan estimator.LlamaCppPlan) *jsonLlamaCpp {
+	return &jsonLlamaCpp{
+		NGPULayers:   plan.NGPULayers,
+		Context:      plan.Context,
+		Batch:        plan.Batch,
+		UBatch:       plan.UBatch,
+		AllLayers:    plan.AllLayers,
+		ComputeBytes: plan.ComputeBytes,
+		GPUBytes:     plan.Split.GPUBytes,
+		CPUBytes:     plan.Split.CPUBytes,
+	}
+}
+
+// printLlamaCppPlan prints the llama.cpp flags suggested for the memory of the GPU and the
+// memory they take
+func printLlamaCppPlan(w io.Writer, plan estimator.LlamaCppPlan, numLayers, gpuMemoryBytes int) {
+	fmt.Fprintf(w, "llama.cpp flags for %s: -ngl %d -c %d -b %d -ub %d\n", formatMemory(gpuMemoryBytes), plan.NGPULayers, plan.Context, plan.Batch, plan.UBatch)
+	fmt.Fprintf(w, "  layers on the GPU: %d of %d", plan.Split.GPULayers, numLayers)
+	if plan.AllLayers {
+		fmt.Fprint(w, ", with the output layer")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  compute buffer:    %s\n", formatMemory(plan.ComputeBytes))
+	fmt.Fprintf(w, "  GPU memory:        %s\n", formatMemory(plan.Split.GPUBytes))
+	if plan.Split.CPUBytes > 0 {
+		fmt.Fprintf(w, "  system RAM:        %s\n", formatMemory(plan.Split.CPUBytes))
+	}
+}
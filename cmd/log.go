@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// logFormats are the values --log-format accepts
+var logFormats = []string{"text", "json"}
+
+// setupLogging sends the logs to w in the format of --log-format. The steps of the
+// estimate and the sources of its settings are logged at the debug level, shown with
+// --verbose, and otherwise only warnings are.
+func setupLogging(w io.Writer) error {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelDebug
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(logFormat) {
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format %q; must be one of %s", logFormat, strings.Join(logFormats, ", "))
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
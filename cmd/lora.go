@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// loraCmd estimates the memory for LoRA and QLoRA fine-tuning
+var loraCmd = &cobra.Command{
+	Use:   "lora",
+	Short: "Estimate memory for LoRA or QLoRA fine-tuning",
+	Long: `Estimate the memory for parameter-efficient fine-tuning with LoRA: the frozen base
+weights, possibly quantized, plus the trainable adapter weights, their gradients and
+the optimizer states, AdamW unless given another with --optimizer.
+
+For example, QLoRA with an NF4 base and bf16 adapters on every linear projection:
+./gpu-mem-for-llm lora --size 7b --num-layers 32 --hidden-dim 4096 --rank 16 --base-precision nf4 --adapter-precision bf16
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		parameterSize, err := getParameterSize(size)
+		if err != nil {
+			return err
+		}
+		basePrecision, err := estimator.PrecisionByName(loraBasePrecision)
+		if err != nil {
+			return err
+		}
+		adapterPrecision, err := estimator.PrecisionByName(loraAdapterPrecision)
+		if err != nil {
+			return err
+		}
+		if loraCmdRank <= 0 {
+			return errors.New("invalid rank; must be greater than 0")
+		}
+		if loraTargetFraction <= 0 || loraTargetFraction > 1 {
+			return errors.New("invalid target modules fraction; must be greater than 0 and at most 1")
+		}
+
+		result, err := calculate(estimator.ModelSpec{
+			ParameterSize:        parameterSize,
+			Precision:            basePrecision,
+			Overhead:             float32(overhead),
+			NumLayers:            numLayers,
+			HiddenDim:            hiddenDim,
+			LoRARank:             loraCmdRank,
+			LoRATargets:          estimator.CalculateLoRATargets(loraTargetFraction),
+			LoRAAdapterPrecision: adapterPrecision,
+			Optimizer:            strings.ToLower(optimizerName),
+		})
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return writeStructured(out, "json", jsonEstimate{MemSize: formatMemory(result.Total), Breakdown: result.BreakdownIn(memoryUnit)})
+		}
+
+		fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(result.Total))
+		printBreakdown(out, result)
+		return nil
+	},
+}
+
+var (
+	loraCmdRank          int
+	loraBasePrecision    string
+	loraAdapterPrecision string
+	loraTargetFraction   float64
+)
+
+func init() {
+	loraCmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b, 1.5b or 1.8t) - required")
+	loraCmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32) - required")
+	loraCmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096) - required")
+	loraCmd.MarkFlagRequired("size")
+	loraCmd.MarkFlagRequired("num-layers")
+	loraCmd.MarkFlagRequired("hidden-dim")
+	loraCmd.Flags().IntVar(&loraCmdRank, "rank", 16, "rank of the LoRA adapters")
+	loraCmd.Flags().Float64Var(&loraTargetFraction, "target-modules-fraction", 1, "fraction of the linear projections in each layer that get adapters")
+	loraCmd.Flags().StringVar(&loraBasePrecision, "base-precision", "fp16", "precision of the frozen base weights (e.g., nf4 for QLoRA)")
+	loraCmd.Flags().StringVar(&loraAdapterPrecision, "adapter-precision", "fp32", "precision of the adapter weights and gradients (e.g., bf16)")
+	loraCmd.Flags().StringVar(&optimizerName, "optimizer", "adamw", "optimizer whose states are kept for the adapters ("+strings.Join(estimator.Optimizers, ", ")+")")
+	loraCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	loraCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(loraCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// manSection is the section of the manual the pages go in, that of user commands
+const manSection = "1"
+
+// manEscaper escapes the characters roff would otherwise interpret
+var manEscaper = strings.NewReplacer(`\`, `\e`, "-", `\-`)
+
+// manText escapes text for roff, guarding the lines that would start with a request
+func manText(s string) string {
+	lines := strings.Split(strings.TrimSpace(manEscaper.Replace(s)), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// manPageName returns the name of the page of a command, such as gpu-mem-for-llm-serve
+func manPageName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// manCommands returns the command and every command below it that has a page of its own,
+// leaving out help and those that are hidden
+func manCommands(cmd *cobra.Command) []*cobra.Command {
+	commands := []*cobra.Command{cmd}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			commands = append(commands, manCommands(sub)...)
+		}
+	}
+	return commands
+}
+
+// writeManFlags writes a section listing the flags that aren't hidden
+func writeManFlags(w io.Writer, title string, flags *pflag.FlagSet) {
+	var buf bytes.Buffer
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		buf.WriteString(".TP\n")
+		if f.Shorthand != "" {
+			fmt.Fprintf(&buf, `\fB\-%s\fR, `, f.Shorthand)
+		}
+		fmt.Fprintf(&buf, `\fB\-\-%s\fR`, manText(f.Name))
+		if f.Value.Type() != "bool" {
+			fmt.Fprintf(&buf, `=\fI%s\fR`, manText(f.Value.Type()))
+		}
+		buf.WriteString("\n" + manText(f.Usage))
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "[]" {
+			fmt.Fprintf(&buf, " (default %s)", manText(f.DefValue))
+		}
+		buf.WriteString("\n")
+	})
+	if buf.Len() > 0 {
+		fmt.Fprintf(w, ".SH %s\n", title)
+		w.Write(buf.Bytes())
+	}
+}
+
+// writeManPage writes the roff source of the manual page of a command, with its usage,
+// description, flags and the pages of its parent and subcommands
+func writeManPage(w io.Writer, cmd *cobra.Command) {
+	name := manPageName(cmd)
+	fmt.Fprintf(w, ".TH \"%s\" \"%s\" \"\" \"%s %s\" \"User Commands\"\n", strings.ToUpper(name), manSection, cmd.Root().Name(), appVersion)
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", manText(name), manText(cmd.Short))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.nf\n%s\n.fi\n", manText(cmd.UseLine()))
+	if cmd.Long != "" {
+		fmt.Fprintf(w, ".SH DESCRIPTION\n.nf\n%s\n.fi\n", manText(cmd.Long))
+	}
+	writeManFlags(w, "OPTIONS", cmd.NonInheritedFlags())
+	writeManFlags(w, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+
+	var related []string
+	if cmd.HasParent() {
+		related = append(related, manPageName(cmd.Parent()))
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			related = append(related, manPageName(sub))
+		}
+	}
+	if len(related) > 0 {
+		fmt.Fprintln(w, ".SH SEE ALSO")
+		for i, page := range related {
+			separator := ","
+			if i == len(related)-1 {
+				separator = ""
+			}
+			fmt.Fprintf(w, "\\fB%s\\fR(%s)%s\n", manText(page), manSection, separator)
+		}
+	}
+}
+
+// manCmd generates the manual pages of every command
+var manCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate manual pages for every command",
+	// The pages describe the defaults of the flags, not those of the environment
+	Annotations: map[string]string{noSettingsAnnotation: "true"},
+	Long: `Write the manual page of gpu-mem-for-llm in roff to stdout, or with --dir a page for it
+and each of its subcommands, such as gpu-mem-for-llm-serve.1, to a directory for a
+package to install under share/man/man1.
+
+For example:
+./gpu-mem-for-llm man --dir /usr/local/share/man/man1
+./gpu-mem-for-llm man | man -l -
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		root := cmd.Root()
+
+		if manDir == "" {
+			writeManPage(cmd.OutOrStdout(), root)
+			return nil
+		}
+		if err := os.MkdirAll(manDir, 0o755); err != nil {
+			return internalError(err)
+		}
+		for _, c := range manCommands(root) {
+			var page bytes.Buffer
+			writeManPage(&page, c)
+			path := filepath.Join(manDir, manPageName(c)+"."+manSection)
+			if err := os.WriteFile(path, page.Bytes(), 0o644); err != nil {
+				return internalError(err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), path)
+		}
+		return nil
+	},
+}
+
+var manDir string
+
+func init() {
+	manCmd.Flags().StringVar(&manDir, "dir", "", "directory to write a page for every command to, instead of the page of gpu-mem-for-llm to stdout")
+	rootCmd.AddCommand(manCmd)
+}
@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// mcpProtocolVersion is the version of the Model Context Protocol the server speaks
+// unless the client asks for another
+const mcpProtocolVersion = "2024-11-05"
+
+// JSON-RPC error codes the server responds with
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification when it has no ID
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC 2.0 response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response carrying either a result or an error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// mcpTool is a tool as listed by tools/list, along with the function that calls it with
+// the arguments of a tools/call request
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	call        func(arguments json.RawMessage) (any, error)
+}
+
+// mcpProperty describes a single argument of a tool in its input schema
+type mcpProperty struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// mcpSchema returns the JSON schema of a tool's arguments
+func mcpSchema(properties []mcpProperty, required ...string) map[string]any {
+	props := make(map[string]any, len(properties))
+	for _, p := range properties {
+		props[p.Name] = map[string]string{"type": p.Type, "description": p.Description}
+	}
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
+
+// mcpModelProperties are the arguments describing the model, shared by the tools that
+// estimate it and named like the fields of POST /estimate
+var mcpModelProperties = []mcpProperty{
+	{"size", "string", "model parameter size, such as 7b, 1.5b or 1.8t"},
+	{"precision", "string", "precision of the weights, such as fp16, bf16, fp8, int8, int4, int2 or Q4_K_M"},
+	{"quant", "string", "llama.cpp quantization scheme of the weights in place of precision, such as Q4_K_M"},
+	{"bytes_per_param", "number", "custom bytes per parameter in place of precision"},
+	{"overhead", "integer", "overhead as a percentage (default 20)"},
+	{"num_layers", "integer", "number of transformer layers, to size the KV cache"},
+	{"hidden_dim", "integer", "model hidden dimension, to size the KV cache"},
+	{"vocab_size", "integer", "model vocabulary size"},
+	{"intermediate_size", "integer", "MLP intermediate size"},
+	{"tied_embeddings", "boolean", "the LM head shares its weights with the embedding table"},
+	{"head_dim", "integer", "attention head dimension"},
+	{"kv_heads", "integer", "key/value heads with grouped-query attention (requires head_dim)"},
+	{"context", "integer", "context length in tokens to size the KV cache for (requires num_layers and hidden_dim)"},
+	{"sliding_window", "integer", "sliding attention window in tokens, capping the context kept in the KV cache"},
+	{"batch_size", "integer", "number of sequences held in the KV cache at once (default 1)"},
+	{"kv_dtype", "string", "precision or quantized type of the KV cache when it differs from the weights, such as fp8 or q4_0"},
+	{"tensor_parallel", "integer", "number of GPUs the model is sharded across"},
+	{"mode", "string", "inference or train"},
+}
+
+// decodeArguments decodes the arguments of a tool call, rejecting unknown ones
+func decodeArguments(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 {
+		arguments = []byte("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(arguments))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// jsonGPURecommendation is the shape of a single GPU in the recommend_gpu result
+type jsonGPURecommendation struct {
+	GPU          string `json:"gpu"`
+	Memory       string `json:"memory"`
+	GPUsRequired int    `json:"gpus_required"`
+	FitsOnOne    bool   `json:"fits_on_one"`
+}
+
+// mcpTools returns the tools the server offers
+func mcpTools() []mcpTool {
+	return []mcpTool{
+		{
+			Name:        "estimate_memory",
+			Description: "Estimate the GPU memory required to serve or fine-tune an LLM, with a breakdown by component, and whether it fits on a GPU when one is given.",
+			InputSchema: mcpSchema(append(mcpModelProperties,
+				mcpProperty{"gpu", "string", "GPU model from the database, such as h100 or rtx4090, to check the fit against"},
+				mcpProperty{"gpu_memory", "string", "memory of each GPU, such as 24gb, to check the fit against"},
+			), "size"),
+			call: func(arguments json.RawMessage) (any, error) {
+				var req estimateRequest
+				if err := decodeArguments(arguments, &req); err != nil {
+					return nil, err
+				}
+				return serveEstimate(req)
+			},
+		},
+		{
+			Name:        "fit_model",
+			Description: "Calculate the largest model, in parameters, whose weights fit in a memory budget at a precision.",
+			InputSchema: mcpSchema([]mcpProperty{
+				{"vram", "string", "memory available, such as 24gb"},
+				{"precision", "string", "precision of the weights, such as fp16 or int4"},
+				{"overhead", "integer", "overhead as a percentage (default 20)"},
+			}, "vram", "precision"),
+			call: func(arguments json.RawMessage) (any, error) {
+				var req struct {
+					VRAM      string `json:"vram"`
+					Precision string `json:"precision"`
+					Overhead  *int   `json:"overhead"`
+				}
+				if err := decodeArguments(arguments, &req); err != nil {
+					return nil, err
+				}
+				memory, err := parseMemorySize(req.VRAM)
+				if err != nil {
+					return nil, err
+				}
+				precision, err := estimator.PrecisionByName(req.Precision)
+				if err != nil {
+					return nil, err
+				}
+				overheadPercent := 20
+				if req.Overhead != nil {
+					overheadPercent = *req.Overhead
+				}
+				maxParameters := estimator.CalculateMaxParameters(memory, precision, float32(overheadPercent))
+				return jsonFit{MaxSize: formatParameterSize(maxParameters), MaxParameters: maxParameters}, nil
+			},
+		},
+		{
+			Name:        "recommend_gpu",
+			Description: "Rank the GPUs of the database by how few of them a model needs, listing for each the number required and whether one is enough.",
+			InputSchema: mcpSchema(mcpModelProperties, "size"),
+			call: func(arguments json.RawMessage) (any, error) {
+				var req estimateRequest
+				if err := decodeArguments(arguments, &req); err != nil {
+					return nil, err
+				}
+				estimate, err := serveEstimate(req)
+				if err != nil {
+					return nil, err
+				}
+				required := estimate.MemBytes
+				minimum := max(req.TensorParallel, 1)
+
+				recommendations := make([]jsonGPURecommendation, 0, len(gpuDatabase))
+				for _, name := range gpuNames() {
+					memory := gpuDatabase[name].Memory
+					recommendations = append(recommendations, jsonGPURecommendation{
+						GPU:          name,
+						Memory:       formatMemory(memory),
+						GPUsRequired: estimator.CalculateGPUCount(required, memory, minimum),
+						FitsOnOne:    minimum == 1 && required <= memory,
+					})
+				}
+				// The fewest GPUs first, and the smallest of those that need as many
+				sort.SliceStable(recommendations, func(i, j int) bool {
+					a, b := recommendations[i], recommendations[j]
+					if a.GPUsRequired != b.GPUsRequired {
+						return a.GPUsRequired < b.GPUsRequired
+					}
+					return gpuDatabase[a.GPU].Memory < gpuDatabase[b.GPU].Memory
+				})
+				return map[string]any{
+					"mem_size":  estimate.MemSize,
+					"mem_bytes": required,
+					"gpus":      recommendations,
+				}, nil
+			},
+		},
+	}
+}
+
+// handleMCPRequest returns the response to a request, or nil for a notification
+func handleMCPRequest(req rpcRequest, tools []mcpTool) *rpcResponse {
+	if req.ID == nil {
+		return nil
+	}
+	resp := &rpcResponse{JSONRPC: "2.0", ID: req.ID}
+
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := params.ProtocolVersion
+		if version == "" {
+			version = mcpProtocolVersion
+		}
+		resp.Result = map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "gpu-mem-for-llm", "version": appVersion},
+		}
+	case "ping":
+		resp.Result = map[string]any{}
+	case "tools/list":
+		resp.Result = map[string]any{"tools": tools}
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			return resp
+		}
+		var tool *mcpTool
+		for i := range tools {
+			if tools[i].Name == params.Name {
+				tool = &tools[i]
+			}
+		}
+		if tool == nil {
+			resp.Error = &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+			return resp
+		}
+
+		// A tool that fails reports the problem to the model rather than as a protocol
+		// error, so the model can correct its arguments
+		var text string
+		result, err := tool.call(params.Arguments)
+		if err == nil {
+			var data []byte
+			data, err = versionedJSON(result)
+			text = string(data)
+		}
+		if err != nil {
+			resp.Result = map[string]any{
+				"content": []map[string]string{{"type": "text", "text": err.Error()}},
+				"isError": true,
+			}
+			return resp
+		}
+		resp.Result = map[string]any{"content": []map[string]string{{"type": "text", "text": text}}}
+	default:
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+	}
+	return resp
+}
+
+// serveMCP reads JSON-RPC messages from the input, one per line, and writes a response
+// to each request until the input ends
+func serveMCP(in io.Reader, out io.Writer) error {
+	tools := mcpTools()
+	enc := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req rpcRequest
+		var resp *rpcResponse
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp = &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}}
+		} else {
+			resp = handleMCPRequest(req, tools)
+		}
+		if resp == nil {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// mcpCmd exposes the estimator to LLM agents as Model Context Protocol tools
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve the estimator as Model Context Protocol tools over stdio",
+	Long: `Run a Model Context Protocol server over stdio, so LLM agents can call the
+estimator as tools and answer whether a model fits with its exact math:
+
+  estimate_memory  estimate the memory for a model, with the fields of POST /estimate
+  fit_model        the largest model that fits in a memory budget at a precision
+  recommend_gpu    the GPUs of the database ranked by how few of them a model needs
+
+Configure it as a stdio server in the agent, with the command:
+./gpu-mem-for-llm mcp
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+		return serveMCP(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	mcpCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for the tools")
+	rootCmd.AddCommand(mcpCmd)
+}
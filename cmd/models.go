@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// registryModel holds the parameter count and architecture of a well-known model
+type registryModel struct {
+	Parameters       int
+	NumLayers        int
+	HiddenDim        int
+	Heads            int
+	KVHeads          int
+	HeadDim          int
+	IntermediateSize int
+	VocabSize        int
+	TiedEmbeddings   bool
+	Experts          int
+	ActiveExperts    int
+	SlidingWindow    int
+
+	// Vision tower of a vision-language model, whose parameters are counted apart from
+	// the language model's
+	VisionParameters    int
+	ProjectorParameters int
+	ImageTokens         int
+}
+
+// jsonRegistryModel is the shape of a model in a user-provided model registry file, with
+// the parameter count in the same notation as --size
+type jsonRegistryModel struct {
+	Parameters       string `json:"parameters"`
+	NumLayers        int    `json:"num_layers"`
+	HiddenDim        int    `json:"hidden_dim"`
+	Heads            int    `json:"heads"`
+	KVHeads          int    `json:"kv_heads"`
+	HeadDim          int    `json:"head_dim"`
+	IntermediateSize int    `json:"intermediate_size"`
+	VocabSize        int    `json:"vocab_size"`
+	TiedEmbeddings   bool   `json:"tied_embeddings"`
+	Experts          int    `json:"experts"`
+	ActiveExperts    int    `json:"active_experts"`
+	SlidingWindow    int    `json:"sliding_window"`
+
+	VisionParameters    string `json:"vision_parameters"`
+	ProjectorParameters string `json:"projector_parameters"`
+	ImageTokens         int    `json:"image_tokens"`
+}
+
+// modelRegistry maps the name of each built-in model to its parameter count, taken from
+// the released checkpoint, and the architecture from its config.json
+var modelRegistry = map[string]registryModel{
+	"llama2-7b":     {Parameters: 6_738_415_616, NumLayers: 32, HiddenDim: 4096, Heads: 32, KVHeads: 32, IntermediateSize: 11008, VocabSize: 32000},
+	"llama2-13b":    {Parameters: 13_015_864_320, NumLayers: 40, HiddenDim: 5120, Heads: 40, KVHeads: 40, IntermediateSize: 13824, VocabSize: 32000},
+	"llama2-70b":    {Parameters: 68_976_648_192, NumLayers: 80, HiddenDim: 8192, Heads: 64, KVHeads: 8, IntermediateSize: 28672, VocabSize: 32000},
+	"llama3.1-8b":   {Parameters: 8_030_261_248, NumLayers: 32, HiddenDim: 4096, Heads: 32, KVHeads: 8, IntermediateSize: 14336, VocabSize: 128256},
+	"llama3.1-70b":  {Parameters: 70_553_706_496, NumLayers: 80, HiddenDim: 8192, Heads: 64, KVHeads: 8, IntermediateSize: 28672, VocabSize: 128256},
+	"llama3.1-405b": {Parameters: 405_853_388_800, NumLayers: 126, HiddenDim: 16384, Heads: 128, KVHeads: 8, IntermediateSize: 53248, VocabSize: 128256},
+	"llama3.2-1b":   {Parameters: 1_235_814_400, NumLayers: 16, HiddenDim: 2048, Heads: 32, KVHeads: 8, HeadDim: 64, IntermediateSize: 8192, VocabSize: 128256, TiedEmbeddings: true},
+	"llama3.2-3b":   {Parameters: 3_212_749_824, NumLayers: 28, HiddenDim: 3072, Heads: 24, KVHeads: 8, HeadDim: 128, IntermediateSize: 8192, VocabSize: 128256, TiedEmbeddings: true},
+	"mistral-7b":    {Parameters: 7_241_732_096, NumLayers: 32, HiddenDim: 4096, Heads: 32, KVHeads: 8, IntermediateSize: 14336, VocabSize: 32000, SlidingWindow: 4096},
+	"mixtral-8x7b":  {Parameters: 46_702_792_704, NumLayers: 32, HiddenDim: 4096, Heads: 32, KVHeads: 8, IntermediateSize: 14336, VocabSize: 32000, Experts: 8, ActiveExperts: 2},
+	"mixtral-8x22b": {Parameters: 140_620_634_112, NumLayers: 56, HiddenDim: 6144, Heads: 48, KVHeads: 8, IntermediateSize: 16384, VocabSize: 32768, Experts: 8, ActiveExperts: 2},
+	"qwen2.5-7b":    {Parameters: 7_615_616_512, NumLayers: 28, HiddenDim: 3584, Heads: 28, KVHeads: 4, IntermediateSize: 18944, VocabSize: 152064},
+	"qwen2.5-14b":   {Parameters: 14_770_033_664, NumLayers: 48, HiddenDim: 5120, Heads: 40, KVHeads: 8, IntermediateSize: 13824, VocabSize: 152064},
+	"qwen2.5-32b":   {Parameters: 32_763_876_352, NumLayers: 64, HiddenDim: 5120, Heads: 40, KVHeads: 8, IntermediateSize: 27648, VocabSize: 152064},
+	"qwen2.5-72b":   {Parameters: 72_706_203_648, NumLayers: 80, HiddenDim: 8192, Heads: 64, KVHeads: 8, IntermediateSize: 29568, VocabSize: 152064},
+	"gemma2-9b":     {Parameters: 9_241_705_984, NumLayers: 42, HiddenDim: 3584, Heads: 16, KVHeads: 8, HeadDim: 256, IntermediateSize: 14336, VocabSize: 256000, TiedEmbeddings: true},
+	"gemma2-27b":    {Parameters: 27_227_128_320, NumLayers: 46, HiddenDim: 4608, Heads: 32, KVHeads: 16, HeadDim: 128, IntermediateSize: 36864, VocabSize: 256000, TiedEmbeddings: true},
+	"phi3-mini":     {Parameters: 3_821_079_552, NumLayers: 32, HiddenDim: 3072, Heads: 32, KVHeads: 32, IntermediateSize: 8192, VocabSize: 32064},
+	"llava1.5-7b": {Parameters: 6_738_939_904, NumLayers: 32, HiddenDim: 4096, Heads: 32, KVHeads: 32, IntermediateSize: 11008, VocabSize: 32064,
+		VisionParameters: 303_507_456, ProjectorParameters: 20_979_712, ImageTokens: 576},
+	"llava1.5-13b": {Parameters: 13_016_519_680, NumLayers: 40, HiddenDim: 5120, Heads: 40, KVHeads: 40, IntermediateSize: 13824, VocabSize: 32064,
+		VisionParameters: 303_507_456, ProjectorParameters: 31_467_520, ImageTokens: 576},
+}
+
+// modelAliases maps other common names of the built-in models to their registry name
+var modelAliases = map[string]string{
+	"llama-2-7b":      "llama2-7b",
+	"llama-2-13b":     "llama2-13b",
+	"llama-2-70b":     "llama2-70b",
+	"llama3-8b":       "llama3.1-8b",
+	"llama-3-8b":      "llama3.1-8b",
+	"llama-3.1-8b":    "llama3.1-8b",
+	"llama3-70b":      "llama3.1-70b",
+	"llama-3-70b":     "llama3.1-70b",
+	"llama-3.1-70b":   "llama3.1-70b",
+	"llama-3.1-405b":  "llama3.1-405b",
+	"llama-3.2-1b":    "llama3.2-1b",
+	"llama-3.2-3b":    "llama3.2-3b",
+	"mistral-7b-v0.1": "mistral-7b",
+	"qwen2-7b":        "qwen2.5-7b",
+	"qwen2-72b":       "qwen2.5-72b",
+	"gemma-2-9b":      "gemma2-9b",
+	"gemma-2-27b":     "gemma2-27b",
+	"phi-3-mini":      "phi3-mini",
+	"llava-1.5-7b":    "llava1.5-7b",
+	"llava-1.5-13b":   "llava1.5-13b",
+}
+
+// loadModelRegistry adds the models from a JSON file mapping names to their parameter
+// count and architecture, such as {"my-model": {"parameters": "7b", "num_layers": 32}},
+// to the built-in registry. Entries in the file replace built-in models of the same name.
+func loadModelRegistry(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var models map[string]jsonRegistryModel
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&models); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name, m := range models {
+		parameters, err := getParameterSize(m.Parameters)
+		if err != nil {
+			return fmt.Errorf("%s: model %q: invalid parameters: %v", path, name, err)
+		}
+		var visionParameters, projectorParameters int
+		if m.VisionParameters != "" {
+			visionParameters, err = getParameterSize(m.VisionParameters)
+			if err != nil {
+				return fmt.Errorf("%s: model %q: invalid vision_parameters: %v", path, name, err)
+			}
+		}
+		if m.ProjectorParameters != "" {
+			projectorParameters, err = getParameterSize(m.ProjectorParameters)
+			if err != nil {
+				return fmt.Errorf("%s: model %q: invalid projector_parameters: %v", path, name, err)
+			}
+		}
+		name = strings.ToLower(name)
+		modelRegistry[name] = registryModel{
+			Parameters:       parameters,
+			NumLayers:        m.NumLayers,
+			HiddenDim:        m.HiddenDim,
+			Heads:            m.Heads,
+			KVHeads:          m.KVHeads,
+			HeadDim:          m.HeadDim,
+			IntermediateSize: m.IntermediateSize,
+			VocabSize:        m.VocabSize,
+			TiedEmbeddings:   m.TiedEmbeddings,
+			Experts:          m.Experts,
+			ActiveExperts:    m.ActiveExperts,
+			SlidingWindow:    m.SlidingWindow,
+
+			VisionParameters:    visionParameters,
+			ProjectorParameters: projectorParameters,
+			ImageTokens:         m.ImageTokens,
+		}
+		// A model of the file takes its name back from a built-in alias
+		delete(modelAliases, name)
+	}
+	return nil
+}
+
+// getRegistryModel returns the model given by its registry name or one of its aliases,
+// such as "llama3-8b"
+func getRegistryModel(name string) (registryModel, error) {
+	name = strings.ToLower(name)
+	if alias, ok := modelAliases[name]; ok {
+		name = alias
+	}
+	m, ok := modelRegistry[name]
+	if !ok {
+		return registryModel{}, fmt.Errorf("unknown model %q; must be one of %s", name, strings.Join(modelNames(), ", "))
+	}
+	return m, nil
+}
+
+// modelNames returns the names of all models in the registry in alphabetical order
+func modelNames() []string {
+	names := make([]string, 0, len(modelRegistry))
+	for name := range modelRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registrySettings returns the settings of a model of the registry, leaving out the
+// parts of the architecture it doesn't give
+func registrySettings(m registryModel) map[string]string {
+	settings := map[string]string{"size": fmt.Sprint(m.Parameters)}
+	for key, value := range map[string]int{
+		"num-layers":        m.NumLayers,
+		"hidden-dim":        m.HiddenDim,
+		"intermediate-size": m.IntermediateSize,
+		"vocab-size":        m.VocabSize,
+		"experts":           m.Experts,
+		"active-experts":    m.ActiveExperts,
+		"sliding-window":    m.SlidingWindow,
+		"vision-params":     m.VisionParameters,
+		"projector-params":  m.ProjectorParameters,
+		"image-tokens":      m.ImageTokens,
+	} {
+		if value > 0 {
+			settings[key] = fmt.Sprint(value)
+		}
+	}
+	if m.VocabSize > 0 {
+		settings["tied-embeddings"] = fmt.Sprint(m.TiedEmbeddings)
+	}
+
+	headDim := m.HeadDim
+	if headDim == 0 && m.Heads > 0 {
+		headDim = m.HiddenDim / m.Heads
+	}
+	if headDim > 0 {
+		settings["head-dim"] = fmt.Sprint(headDim)
+		if m.KVHeads > 0 {
+			settings["kv-heads"] = fmt.Sprint(m.KVHeads)
+		}
+	}
+	return settings
+}
+
+// applyRegistryModel fills in every setting not given otherwise from the model given with
+// --model, so flags, environment variables and the config file all take precedence.
+func applyRegistryModel(cmd *cobra.Command) error {
+	if registryModelName == "" {
+		return nil
+	}
+	// The flags were fine, so the usage wouldn't help
+	if modelDB != "" {
+		if err := loadModelRegistry(modelDB); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+	}
+	m, err := getRegistryModel(registryModelName)
+	if err != nil {
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	slog.Debug("using registry model", "model", registryModelName, "parameters", m.Parameters, "model_db", modelDB)
+	settings := registrySettings(m)
+	// A subcommand takes only the parts of the architecture it has flags for
+	for key := range settings {
+		if cmd.Flags().Lookup(key) == nil {
+			delete(settings, key)
+		}
+	}
+	// The experts would conflict with active parameters given directly, which replace them
+	if cmd.Flags().Changed("active-params") {
+		delete(settings, "experts")
+		delete(settings, "active-experts")
+	}
+	// The head dimension would conflict with a number of heads given directly, which the
+	// KV heads of the registry may not divide
+	if cmd.Flags().Changed("heads") {
+		delete(settings, "head-dim")
+		delete(settings, "kv-heads")
+	}
+	return applyModelSettings(cmd, "model", registryModelName, settings)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// addModelFlags defines the flags describing a model, by name from the registry or by its
+// size, precision and architecture, on a subcommand that searches over its hardware
+func addModelFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&registryModelName, "model", "", "well-known model to take the size and architecture from (e.g., llama3.1-8b)")
+	cmd.Flags().StringVar(&modelDB, "model-db", "", "JSON file of additional models for --model")
+	cmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b, 1.5b or 1.8t)")
+	addPrecisionFlags(cmd)
+	cmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	cmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32)")
+	cmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096)")
+	cmd.Flags().IntVar(&vocabSize, "vocab-size", 0, "model vocabulary size (e.g., 32000)")
+	cmd.Flags().IntVar(&intermediateSize, "intermediate-size", 0, "MLP intermediate size (e.g., 11008)")
+	cmd.Flags().BoolVar(&tiedEmbeddings, "tied-embeddings", false, "the LM head shares its weights with the embedding table")
+	cmd.Flags().IntVar(&headDim, "head-dim", 0, "attention head dimension (e.g., 128)")
+	cmd.Flags().IntVar(&kvHeads, "kv-heads", 0, "number of key/value heads with grouped-query attention (requires --head-dim)")
+	cmd.Flags().IntVar(&slidingWindow, "sliding-window", 0, "sliding attention window in tokens, capping the context kept in the KV cache")
+	cmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the KV cache for (requires --num-layers and --hidden-dim)")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	cmd.Flags().StringVar(&kvDtype, "kv-dtype", "", "precision or quantized type of the KV cache when it differs from the weights (e.g., fp8 or q4_0)")
+}
+
+// applyModelFlags fills in the flags of addModelFlags from --model and checks a model and
+// a single precision are given
+func applyModelFlags(cmd *cobra.Command) error {
+	if err := applyRegistryModel(cmd); err != nil {
+		return err
+	}
+	if size == "" {
+		return errors.New("one of --size or --model is required")
+	}
+	return checkMutuallyExclusivePrecisionFlags(cmd)
+}
+
+// modelSpecFromFlags returns the model given by the flags of addModelFlags
+func modelSpecFromFlags() (estimator.ModelSpec, error) {
+	parameterSize, err := getParameterSize(size)
+	if err != nil {
+		return estimator.ModelSpec{}, err
+	}
+	precision, err := getPrecision()
+	if err != nil {
+		return estimator.ModelSpec{}, err
+	}
+	if contextLength > 0 && (numLayers <= 0 || hiddenDim <= 0) {
+		return estimator.ModelSpec{}, errors.New("--context requires --num-layers and --hidden-dim to size the KV cache")
+	}
+
+	input := estimator.ModelSpec{
+		ParameterSize:    parameterSize,
+		Precision:        precision,
+		Overhead:         float32(overhead),
+		NumLayers:        numLayers,
+		HiddenDim:        hiddenDim,
+		VocabSize:        vocabSize,
+		IntermediateSize: intermediateSize,
+		TiedEmbeddings:   tiedEmbeddings,
+		HeadDim:          headDim,
+		KVHeads:          kvHeads,
+		SlidingWindow:    slidingWindow,
+	}
+	if contextLength > 0 {
+		input.ContextLength = contextLength
+		input.BatchSize = batchSize
+	}
+	// llama.cpp and exllamav2 keep the KV cache in f16 whatever the weights are quantized to
+	kvPrecisionName := kvDtype
+	if kvPrecisionName == "" && (quant != "" || bitsPerWeight != 0) {
+		kvPrecisionName = "fp16"
+	}
+	if kvPrecisionName != "" {
+		input.KVPrecision, err = estimator.KVPrecisionByName(kvPrecisionName)
+		if err != nil {
+			return estimator.ModelSpec{}, err
+		}
+	}
+	return input, nil
+}
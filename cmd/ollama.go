@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// ollamaDefaultHost is the address of the local Ollama server unless OLLAMA_HOST or
+// --ollama-host gives another
+const ollamaDefaultHost = "http://localhost:11434"
+
+// ollamaModel is an installed model as listed by /api/tags
+type ollamaModel struct {
+	Name    string `json:"name"`
+	Size    int    `json:"size"`
+	Details struct {
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+}
+
+// ollamaClient talks to the API of an Ollama server
+type ollamaClient struct {
+	host   string
+	client *http.Client
+}
+
+// newOllamaClient returns a client for the server at the given host, adding the scheme
+// when it is left out as OLLAMA_HOST allows
+func newOllamaClient(host string) ollamaClient {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return ollamaClient{host: strings.TrimSuffix(host, "/"), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// do sends a request to the API and decodes the JSON response into v
+func (c ollamaClient) do(method, path string, body any, v any) error {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.host+path, &payload)
+	if err != nil {
+		return fmt.Errorf("invalid Ollama host: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return internalError(fmt.Errorf("error contacting Ollama at %s; is it running? %v", c.host, err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error calling %s: Ollama responded with %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return nil
+}
+
+// models returns the models installed on the server
+func (c ollamaClient) models() ([]ollamaModel, error) {
+	var tags struct {
+		Models []ollamaModel `json:"models"`
+	}
+	if err := c.do(http.MethodGet, "/api/tags", nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags.Models, nil
+}
+
+// modelInfo returns the GGUF metadata of an installed model from /api/show, such as
+// general.parameter_count and llama.block_count
+func (c ollamaClient) modelInfo(name string) (map[string]any, error) {
+	var show struct {
+		ModelInfo map[string]any `json:"model_info"`
+	}
+	if err := c.do(http.MethodPost, "/api/show", map[string]string{"model": name}, &show); err != nil {
+		return nil, err
+	}
+	return show.ModelInfo, nil
+}
+
+// ollamaInt returns an integer from the model info, which JSON decodes as a float64, or
+// zero when it is missing
+func ollamaInt(info map[string]any, key string) int {
+	v, _ := info[key].(float64)
+	return int(v)
+}
+
+// ollamaEstimate estimates the memory for an installed model. The weights take the size
+// of the model on disk, which is dominated by its GGUF tensors, and the KV cache is kept
+// in f16 for the given context as Ollama does by default.
+func ollamaEstimate(model ollamaModel, info map[string]any, context int) (estimator.Estimate, int, error) {
+	arch, _ := info["general.architecture"].(string)
+	parameters := ollamaInt(info, "general.parameter_count")
+	if parameters <= 0 {
+		return estimator.Estimate{}, 0, fmt.Errorf("%s: no parameter count in the model info", model.Name)
+	}
+
+	input := estimator.ModelSpec{
+		ParameterSize: parameters,
+		Precision:     estimator.Precision(float64(model.Size) / float64(parameters)),
+		Overhead:      float32(overhead),
+	}
+
+	numLayers := ollamaInt(info, arch+".block_count")
+	hidden := ollamaInt(info, arch+".embedding_length")
+	if context > 0 && numLayers > 0 && hidden > 0 {
+		kvPrecision, err := estimator.PrecisionByName("fp16")
+		if err != nil {
+			return estimator.Estimate{}, 0, err
+		}
+		input.NumLayers = numLayers
+		input.HiddenDim = hidden
+		input.HeadDim, input.KVHeads = ggufAttentionHeads(func(key string) int { return ollamaInt(info, key) }, arch, hidden)
+		input.KVPrecision = kvPrecision
+		input.ContextLength = context
+		input.BatchSize = 1
+	}
+
+	result, err := calculate(input)
+	return result, parameters, err
+}
+
+// jsonOllamaModel is the shape of a single model in the ollama JSON output
+type jsonOllamaModel struct {
+	Name         string            `json:"name"`
+	Parameters   int               `json:"parameters"`
+	Quantization string            `json:"quantization,omitempty"`
+	MemSize      string            `json:"mem_size"`
+	MemBytes     int               `json:"mem_bytes"`
+	Breakdown    map[string]string `json:"breakdown"`
+	Fits         *bool             `json:"fits,omitempty"`
+}
+
+// ollamaCmd estimates the memory for every model installed in a local Ollama
+var ollamaCmd = &cobra.Command{
+	Use:   "ollama [MODEL...]",
+	Short: "Estimate memory for the models installed in Ollama",
+	Long: `List the models installed in a local Ollama with their parameter counts and
+quantization, and estimate the GPU memory each one needs: the weights as stored plus
+an f16 KV cache for --context tokens. Give model names to only estimate those, and
+--gpu or --gpu-memory to check which of them fit.
+
+For example:
+./gpu-mem-for-llm ollama --gpu rtx4090
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		gpuMemoryBytes, err := resolveGPUMemory()
+		if err != nil {
+			return err
+		}
+
+		host := ollamaHost
+		if host == "" {
+			host = os.Getenv("OLLAMA_HOST")
+		}
+		if host == "" {
+			host = ollamaDefaultHost
+		}
+		client := newOllamaClient(host)
+
+		models, err := client.models()
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			installed := make(map[string]ollamaModel, len(models))
+			for _, m := range models {
+				installed[m.Name] = m
+			}
+			models = models[:0]
+			for _, name := range args {
+				m, ok := installed[name]
+				if !ok {
+					// Ollama names models without a tag as the latest
+					m, ok = installed[name+":latest"]
+				}
+				if !ok {
+					return fmt.Errorf("model %q is not installed in Ollama", name)
+				}
+				models = append(models, m)
+			}
+		}
+
+		output := make([]jsonOllamaModel, 0, len(models))
+		for _, m := range models {
+			info, err := client.modelInfo(m.Name)
+			if err != nil {
+				return err
+			}
+			result, parameters, err := ollamaEstimate(m, info, ollamaContext)
+			if err != nil {
+				return err
+			}
+			model := jsonOllamaModel{
+				Name:         m.Name,
+				Parameters:   parameters,
+				Quantization: m.Details.QuantizationLevel,
+				MemSize:      formatMemory(result.Total),
+				MemBytes:     result.Total,
+				Breakdown:    result.BreakdownIn(memoryUnit),
+			}
+			if gpuMemoryBytes > 0 {
+				fits := result.Total <= gpuMemoryBytes
+				model.Fits = &fits
+			}
+			output = append(output, model)
+		}
+
+		if jsonOutput {
+			return writeStructured(out, "json", output)
+		}
+
+		if len(output) == 0 {
+			fmt.Fprintln(out, "No models installed in Ollama")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		header := "Model\tParameters\tQuantization\tMemory"
+		if gpuMemoryBytes > 0 {
+			header += "\tFits"
+		}
+		fmt.Fprintln(tw, header)
+		for _, m := range output {
+			row := fmt.Sprintf("%s\t%s\t%s\t%s", m.Name, formatParameterSize(m.Parameters), m.Quantization, m.MemSize)
+			if m.Fits != nil {
+				verdict := "no"
+				if *m.Fits {
+					verdict = "yes"
+				}
+				row += "\t" + verdict
+			}
+			fmt.Fprintln(tw, row)
+		}
+		return tw.Flush()
+	},
+}
+
+var (
+	ollamaHost    string
+	ollamaContext int
+)
+
+func init() {
+	ollamaCmd.Flags().StringVar(&ollamaHost, "ollama-host", "", "address of the Ollama server (default $OLLAMA_HOST or "+ollamaDefaultHost+")")
+	ollamaCmd.Flags().IntVar(&ollamaContext, "context", 2048, "context length in tokens to size the f16 KV cache for, Ollama's default num_ctx")
+	ollamaCmd.Flags().StringVar(&gpuMemory, "gpu-memory", "", "memory of each GPU (e.g., 24gb) to check each model against")
+	ollamaCmd.Flags().StringVar(&gpu, "gpu", "", "GPU model from the built-in database (e.g., rtx4090) to check each model against")
+	ollamaCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for --gpu")
+	ollamaCmd.MarkFlagsMutuallyExclusive("gpu-memory", "gpu")
+	ollamaCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	ollamaCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(ollamaCmd)
+}
@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// outputFormats lists the formats --output accepts
+var outputFormats = []string{"text", "json", "yaml", "csv", "tsv", "markdown"}
+
+// tabularFormats lists the formats writing one row per estimate
+var tabularFormats = map[string]bool{"csv": true, "tsv": true, "markdown": true}
+
+// memoryUnit is the unit of --unit memory sizes are formatted in, set before every command
+// runs. It is kept here rather than in the estimator so the library has no global state.
+var memoryUnit estimator.MemoryUnit
+
+// formatMemory formats the memory in bytes in the unit of --unit
+func formatMemory(memoryBytes int) string {
+	return memoryUnit.Format(memoryBytes)
+}
+
+// outputTemplate is the template of --template or --template-file the result is written
+// with, parsed by getOutputFormat
+var outputTemplate *template.Template
+
+// templateFuncs are the functions available to --template besides the built-in ones
+var templateFuncs = template.FuncMap{
+	// gi and mi format bytes as a Kubernetes quantity, rounded up, such as 20Gi
+	"gi": func(v any) (string, error) { return binaryQuantity(v, 1<<30, "Gi") },
+	"mi": func(v any) (string, error) { return binaryQuantity(v, 1<<20, "Mi") },
+	// memory formats bytes as the text output does, such as 20.00 GB
+	"memory": func(v any) (string, error) {
+		bytes, err := templateInt(v)
+		return formatMemory(bytes), err
+	},
+	// json encodes a value of the result, such as {{json .components}}
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// templateInt returns a number of the result given to a template function as an int
+func templateInt(v any) (int, error) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return int(f), err
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	}
+	return 0, fmt.Errorf("expected a number, got %v", v)
+}
+
+// binaryQuantity formats bytes in whole multiples of the unit, rounded up
+func binaryQuantity(v any, unit int, suffix string) (string, error) {
+	bytes, err := templateInt(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d%s", int(math.Ceil(float64(bytes)/float64(unit))), suffix), nil
+}
+
+// parseOutputTemplate parses the template of --template or the file of --template-file
+func parseOutputTemplate() (*template.Template, error) {
+	text := templateText
+	if templateFile != "" {
+		data, err := os.ReadFile(templateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// getOutputFormat returns the format the estimate is written in, json when --json is
+// given, template with --template or --template-file and text by default
+func getOutputFormat() (string, error) {
+	if jsonOutput {
+		return "json", nil
+	}
+	if templateText != "" || templateFile != "" {
+		tmpl, err := parseOutputTemplate()
+		if err != nil {
+			return "", err
+		}
+		outputTemplate = tmpl
+		return "template", nil
+	}
+	if outputFormat == "" {
+		return "text", nil
+	}
+	format := strings.ToLower(outputFormat)
+	for _, f := range outputFormats {
+		if f == format {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q; must be one of %s", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+// writeStructured writes a result in one of the structured formats. YAML is converted
+// from the JSON encoding, so both formats hold the same fields in the same order, and a
+// template is executed against the JSON encoding, so it refers to the same field names.
+func writeStructured(w io.Writer, format string, v any) error {
+	data, err := versionedJSON(v)
+	if err != nil {
+		return fmt.Errorf("error generating JSON: %v", err)
+	}
+	if format == "template" {
+		return writeTemplate(w, data)
+	}
+	if format == "yaml" {
+		data, err = jsonToYAML(data)
+		if err != nil {
+			return fmt.Errorf("error generating YAML: %v", err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	fmt.Fprintln(w, string(data))
+	return nil
+}
+
+// writeTemplate executes the output template against a result encoded as JSON, ending the
+// output with a newline when the template doesn't
+func writeTemplate(w io.Writer, data []byte) error {
+	var result any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := outputTemplate.Execute(&b, result); err != nil {
+		return fmt.Errorf("error executing template: %v", err)
+	}
+	if b.Len() > 0 && !bytes.HasSuffix(b.Bytes(), []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// estimateRow is a single estimate in the tabular output formats
+type estimateRow struct {
+	Model      string
+	Precision  string
+	Context    int
+	Batch      int
+	GPUs       int
+	Components []jsonComponent
+	MemBytes   int
+}
+
+// tableColumn returns the column holding the bytes of a component, such as kv_cache_bytes
+func tableColumn(component string) string {
+	column := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(component))
+	return strings.Trim(column, "_") + "_bytes"
+}
+
+// writeTable writes the estimates as CSV or TSV with a header row, or as a Markdown table.
+// Every component of any estimate gets a column, in the order they first appear, left
+// empty for the estimates without it. The batch size only gets a column when an estimate
+// carries one, as in a batch sweep.
+func writeTable(w io.Writer, format string, rows []estimateRow) error {
+	var columns, names []string
+	var batches bool
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		batches = batches || row.Batch > 0
+		for _, c := range row.Components {
+			if column := tableColumn(c.Name); !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	if format == "markdown" {
+		return writeMarkdownTable(w, names, rows, batches)
+	}
+
+	cw := csv.NewWriter(w)
+	if format == "tsv" {
+		cw.Comma = '\t'
+	}
+	header := []string{"model", "precision", "context"}
+	if batches {
+		header = append(header, "batch")
+	}
+	cw.Write(append(append(append(header, "gpus"), columns...), "mem_bytes"))
+	for _, row := range rows {
+		values := make(map[string]string, len(row.Components))
+		for _, c := range row.Components {
+			values[tableColumn(c.Name)] = strconv.Itoa(c.Bytes)
+		}
+		record := []string{row.Model, row.Precision, strconv.Itoa(row.Context)}
+		if batches {
+			record = append(record, strconv.Itoa(row.Batch))
+		}
+		record = append(record, strconv.Itoa(row.GPUs))
+		for _, column := range columns {
+			record = append(record, values[column])
+		}
+		cw.Write(append(record, strconv.Itoa(row.MemBytes)))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdownTable writes the estimates as a GitHub-flavored Markdown table with the
+// memory formatted for reading, the sizes aligned to the right
+func writeMarkdownTable(w io.Writer, names []string, rows []estimateRow, batches bool) error {
+	header := []string{"Model", "Precision", "Context"}
+	if batches {
+		header = append(header, "Batch")
+	}
+	header = append(append(append(header, "GPUs"), names...), "Total")
+	align := []string{"---", "---"}
+	for range header[2:] {
+		align = append(align, "---:")
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(header, " | "))
+	fmt.Fprintf(w, "| %s |\n", strings.Join(align, " | "))
+
+	for _, row := range rows {
+		values := make(map[string]string, len(row.Components))
+		for _, c := range row.Components {
+			values[c.Name] = formatMemory(c.Bytes)
+		}
+		context := strconv.Itoa(row.Context)
+		if row.Context == 0 {
+			context = "-"
+		}
+		cells := []string{markdownCell(row.Model), markdownCell(row.Precision), context}
+		if batches {
+			cells = append(cells, strconv.Itoa(row.Batch))
+		}
+		cells = append(cells, strconv.Itoa(row.GPUs))
+		for _, name := range names {
+			cells = append(cells, values[name])
+		}
+		cells = append(cells, formatMemory(row.MemBytes))
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+	return nil
+}
+
+// markdownCell escapes the pipes in a value so it stays in its cell of a Markdown table
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// yamlPlainPattern matches the strings that can be written in YAML without quotes
+var yamlPlainPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_ .()/-]*$`)
+
+// yamlScalar formats a string as a YAML scalar, quoting it unless it is plain text that
+// can't be read back as a number, a boolean or null
+func yamlScalar(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+		return strconv.Quote(s)
+	}
+	if !yamlPlainPattern.MatchString(s) || strings.HasSuffix(s, " ") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// jsonToYAML converts a JSON document into block-style YAML, keeping the order of the
+// object keys
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := writeYAMLValue(&buf, dec, 0, "", false); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeYAMLValue writes the next JSON value of the decoder as YAML, indented by the given
+// number of spaces. The prefix is the "key:" the value belongs to, empty for the items of
+// a list, and dash starts the line with the "- " of a list item in its last two spaces.
+func writeYAMLValue(buf *bytes.Buffer, dec *json.Decoder, indent int, prefix string, dash bool) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	pad := strings.Repeat(" ", indent)
+	if dash {
+		pad = strings.Repeat(" ", indent-2) + "- "
+	}
+	line := func(s string) {
+		if prefix != "" {
+			s = prefix + " " + s
+		}
+		fmt.Fprintln(buf, strings.TrimRight(pad+s, " "))
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if !dec.More() {
+			dec.Token()
+			line(map[json.Delim]string{'[': "[]", '{': "{}"}[t])
+			return nil
+		}
+
+		// The items of a collection under a key go on the lines below it, indented
+		child := indent
+		if prefix != "" {
+			line("")
+			child += 2
+			dash = false
+		} else if t == '[' && dash {
+			line("")
+			dash = false
+		}
+		for first := true; dec.More(); first = false {
+			if t == '[' {
+				if err := writeYAMLValue(buf, dec, child+2, "", true); err != nil {
+					return err
+				}
+				continue
+			}
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeYAMLValue(buf, dec, child, yamlScalar(key.(string))+":", dash && first); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case string:
+		line(yamlScalar(t))
+	case json.Number:
+		line(t.String())
+	case bool:
+		line(strconv.FormatBool(t))
+	case nil:
+		line("null")
+	}
+	return nil
+}
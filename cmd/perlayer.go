@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// jsonLayer is the shape of a single transformer block in the --per-layer JSON output
+type jsonLayer struct {
+	Index          int `json:"index"`
+	Parameters     int `json:"parameters"`
+	AttentionBytes int `json:"attention_bytes"`
+	MLPBytes       int `json:"mlp_bytes"`
+	NormBytes      int `json:"norm_bytes"`
+	TotalBytes     int `json:"total_bytes"`
+}
+
+// jsonPerLayer is the shape of the --per-layer JSON output
+type jsonPerLayer struct {
+	Layers          []jsonLayer `json:"layers"`
+	BlocksBytes     int         `json:"blocks_bytes"`
+	EmbeddingsBytes int         `json:"embeddings_bytes,omitempty"`
+	TotalBytes      int         `json:"total_bytes"`
+	Parameters      int         `json:"parameters"`
+}
+
+// calculatePerLayer returns the memory of every transformer block and the embeddings
+// derived from the architecture, without overhead. Folded norms need no memory of their own.
+func calculatePerLayer(numLayers, hiddenDim, intermediateSize, vocabSize int, tiedEmbeddings, foldNorms bool, precision estimator.Precision) jsonPerLayer {
+	params := estimator.CalculateLayerParameters(hiddenDim, intermediateSize)
+	if foldNorms {
+		params.Norms = 0
+	}
+	layer := jsonLayer{
+		Parameters:     params.Total(),
+		AttentionBytes: estimator.CalculateWeightMemory(params.Attention, precision),
+		MLPBytes:       estimator.CalculateWeightMemory(params.MLP, precision),
+		NormBytes:      estimator.CalculateWeightMemory(params.Norms, precision),
+		TotalBytes:     estimator.CalculateWeightMemory(params.Total(), precision),
+	}
+
+	perLayer := jsonPerLayer{
+		Layers:     make([]jsonLayer, numLayers),
+		Parameters: numLayers * params.Total(),
+	}
+	for i := range perLayer.Layers {
+		perLayer.Layers[i] = layer
+		perLayer.Layers[i].Index = i
+	}
+	perLayer.BlocksBytes = numLayers * layer.TotalBytes
+
+	embeddingParams := estimator.CalculateEmbeddingParameters(vocabSize, hiddenDim, tiedEmbeddings)
+	perLayer.EmbeddingsBytes = estimator.CalculateWeightMemory(embeddingParams, precision)
+	perLayer.Parameters += embeddingParams
+	perLayer.TotalBytes = perLayer.BlocksBytes + perLayer.EmbeddingsBytes
+
+	return perLayer
+}
+
+// printPerLayer prints a table with the memory of one transformer block split into its
+// attention, MLP and norms, followed by all the blocks, the embeddings and the total.
+func printPerLayer(w io.Writer, perLayer jsonPerLayer) {
+	layer := perLayer.Layers[0]
+	numLayers := len(perLayer.Layers)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Per-layer breakdown:")
+	fmt.Fprintln(tw, "  Component\tMemory")
+	fmt.Fprintf(tw, "  attention\t%s\n", formatMemory(layer.AttentionBytes))
+	fmt.Fprintf(tw, "  mlp\t%s\n", formatMemory(layer.MLPBytes))
+	fmt.Fprintf(tw, "  norms\t%s\n", formatMemory(layer.NormBytes))
+	fmt.Fprintf(tw, "  block (1 layer)\t%s\n", formatMemory(layer.TotalBytes))
+	fmt.Fprintf(tw, "  blocks (%d layers)\t%s\n", numLayers, formatMemory(perLayer.BlocksBytes))
+	if perLayer.EmbeddingsBytes > 0 {
+		fmt.Fprintf(tw, "  embeddings\t%s\n", formatMemory(perLayer.EmbeddingsBytes))
+	}
+	fmt.Fprintf(tw, "  total (%d parameters)\t%s\n", perLayer.Parameters, formatMemory(perLayer.TotalBytes))
+	tw.Flush()
+}
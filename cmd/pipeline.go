@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+// jsonStage is the shape of a single pipeline stage in the --pipeline-parallel JSON output
+type jsonStage struct {
+	Stage         int    `json:"stage"`
+	Layers        int    `json:"layers"`
+	MemSizePerGPU string `json:"mem_size_per_gpu"`
+	MemBytes      int    `json:"mem_bytes"`
+}
+
+// printStages prints a table with the layers of every pipeline stage and the memory each
+// of its GPUs needs, noting the stages holding the embeddings and the LM head.
+func printStages(w io.Writer, stages []estimator.PipelineStage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Pipeline stages:")
+	fmt.Fprintln(tw, "  Stage\tLayers\tMemory per GPU")
+	for i, s := range stages {
+		var note string
+		switch i {
+		case 0:
+			note = " (embeddings)"
+		case len(stages) - 1:
+			note = " (LM head)"
+		}
+		fmt.Fprintf(tw, "  %d%s\t%d\t%s\n", i+1, note, s.Layers, formatMemory(s.Total))
+	}
+	tw.Flush()
+}
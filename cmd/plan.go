@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// jsonParallelPlan is the shape of a single layout in the plan JSON output
+type jsonParallelPlan struct {
+	TensorParallel   int     `json:"tensor_parallel"`
+	PipelineParallel int     `json:"pipeline_parallel"`
+	DataParallel     int     `json:"data_parallel"`
+	MemSizePerGPU    string  `json:"mem_size_per_gpu"`
+	MemBytesPerGPU   int     `json:"mem_bytes_per_gpu"`
+	HeadroomBytes    int     `json:"headroom_bytes"`
+	HeadroomPercent  float64 `json:"headroom_percent"`
+}
+
+// jsonPlan is the shape of the output produced by the plan subcommand with --json
+type jsonPlan struct {
+	GPUs           int                `json:"gpus"`
+	GPUMemory      string             `json:"gpu_memory"`
+	GPUMemoryBytes int                `json:"gpu_memory_bytes"`
+	Plans          []jsonParallelPlan `json:"plans"`
+}
+
+// printParallelPlans prints the layouts that fit as a table, best first
+func printParallelPlans(w io.Writer, plans []estimator.ParallelPlan, gpuMemoryBytes int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TP\tPP\tDP\tMemory per GPU\tHeadroom per GPU")
+	for _, p := range plans {
+		headroom := gpuMemoryBytes - p.Estimate.Total
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s (%.1f%%)\n", p.TensorParallel, p.PipelineParallel, p.DataParallel, formatMemory(p.Estimate.Total), formatMemory(headroom), 100*float64(headroom)/float64(gpuMemoryBytes))
+	}
+	return tw.Flush()
+}
+
+// planCmd searches the parallelism layouts of a model across a cluster
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Rank the tensor, pipeline and data parallel layouts that fit a cluster",
+	Long: `Provide a model, a GPU and the shape of the cluster to list every combination of
+tensor parallel (TP), pipeline parallel (PP) and data parallel (DP) degrees using all of
+its GPUs whose memory per GPU fits. Tensor parallelism stays within a node. The layouts
+are ranked by the fewest GPUs per replica of the model, then by the fewest pipeline
+stages.
+
+For example:
+./gpu-mem-for-llm plan --model llama3.1-70b --precision bf16 --gpu a100-80gb --gpus-per-node 8 --nodes 2 --context 8192
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyModelFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		gpuMemoryBytes, err := resolveGPUMemory()
+		if err != nil {
+			return err
+		}
+		if gpuMemoryBytes == 0 {
+			return errors.New("one of --gpu or --gpu-memory is required")
+		}
+		input, err := modelSpecFromFlags()
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(mode) {
+		case "inference":
+		case "train":
+			input.Train = true
+		default:
+			return fmt.Errorf("unknown mode %q; must be one of %s", mode, strings.Join(estimateModes, ", "))
+		}
+		input.ZeROStage = zeroStage
+
+		plans, err := estimator.PlanParallelism(input, gpuMemoryBytes, planGPUsPerNode, planNodes)
+		if err != nil {
+			return err
+		}
+		gpus := planGPUsPerNode * planNodes
+
+		if jsonOutput {
+			output := jsonPlan{
+				GPUs:           gpus,
+				GPUMemory:      formatMemory(gpuMemoryBytes),
+				GPUMemoryBytes: gpuMemoryBytes,
+				Plans:          make([]jsonParallelPlan, 0, len(plans)),
+			}
+			for _, p := range plans {
+				headroom := gpuMemoryBytes - p.Estimate.Total
+				output.Plans = append(output.Plans, jsonParallelPlan{
+					TensorParallel:   p.TensorParallel,
+					PipelineParallel: p.PipelineParallel,
+					DataParallel:     p.DataParallel,
+					MemSizePerGPU:    formatMemory(p.Estimate.Total),
+					MemBytesPerGPU:   p.Estimate.Total,
+					HeadroomBytes:    headroom,
+					HeadroomPercent:  math.Round(1000*float64(headroom)/float64(gpuMemoryBytes)) / 10,
+				})
+			}
+			return writeStructured(out, "json", output)
+		}
+
+		fmt.Fprintf(out, "Layouts across %d nodes of %d GPUs with %s each:\n", planNodes, planGPUsPerNode, formatMemory(gpuMemoryBytes))
+		if len(plans) == 0 {
+			fmt.Fprintln(out, "No layout fits; add nodes, use a larger GPU or a lower precision")
+			return nil
+		}
+		return printParallelPlans(out, plans, gpuMemoryBytes)
+	},
+}
+
+var (
+	planGPUsPerNode int
+	planNodes       int
+)
+
+func init() {
+	addModelFlags(planCmd)
+	planCmd.Flags().StringVar(&mode, "mode", "inference", "plan for inference or full fine-tuning with train")
+	planCmd.Flags().IntVar(&zeroStage, "zero", 0, "DeepSpeed ZeRO stage (1, 2 or 3) partitioning the training state across the data parallel replicas of layouts without TP or PP")
+	planCmd.Flags().StringVar(&gpu, "gpu", "", "GPU model from the built-in database (e.g., h100)")
+	planCmd.Flags().StringVar(&gpuMemory, "gpu-memory", "", "memory of each GPU (e.g., 80gb)")
+	planCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for --gpu")
+	planCmd.MarkFlagsMutuallyExclusive("gpu-memory", "gpu")
+	planCmd.Flags().IntVar(&planGPUsPerNode, "gpus-per-node", 8, "number of GPUs in each node")
+	planCmd.Flags().IntVar(&planNodes, "nodes", 1, "number of nodes")
+	planCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// jsonPrecision is the shape of a single precision in the precisions JSON output
+type jsonPrecision struct {
+	Name          string  `json:"name"`
+	BytesPerParam float32 `json:"bytes_per_param"`
+}
+
+// precisionsCmd lists the supported precisions and the bytes each parameter takes
+var precisionsCmd = &cobra.Command{
+	Use:   "precisions",
+	Short: "List the supported precisions and their bytes per parameter",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		output := make([]jsonPrecision, 0, len(estimator.PrecisionNames()))
+		for _, name := range estimator.PrecisionNames() {
+			precision, err := estimator.PrecisionByName(name)
+			if err != nil {
+				return err
+			}
+			output = append(output, jsonPrecision{Name: name, BytesPerParam: float32(precision)})
+		}
+
+		if jsonOutput {
+			return writeStructured(out, "json", output)
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "Precision\tBytes per parameter")
+		for _, p := range output {
+			fmt.Fprintf(tw, "%s\t%g\n", p.Name, p.BytesPerParam)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	precisionsCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(precisionsCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPrecisionsList(t *testing.T) {
+	want := map[string]float32{"fp32": 4, "fp16": 2, "bf16": 2, "fp8": 1, "int8": 1, "int4": 0.5}
+
+	code, out, errOut := runRoot(t, "precisions", "--json")
+	if code != 0 {
+		t.Fatalf("precisions exited with %d: %s", code, errOut)
+	}
+	var listed []jsonPrecision
+	if err := json.Unmarshal([]byte(out), &listed); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	got := make(map[string]float32, len(listed))
+	for _, p := range listed {
+		got[p.Name] = p.BytesPerParam
+	}
+	for name, bytes := range want {
+		if got[name] != bytes {
+			t.Errorf("precisions lists %s at %g bytes per parameter, want %g", name, got[name], bytes)
+		}
+	}
+
+	code, out, errOut = runRoot(t, "precisions")
+	if code != 0 {
+		t.Fatalf("precisions exited with %d: %s", code, errOut)
+	}
+	rows := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n")[1:] {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			rows[fields[0]] = fields[1]
+		}
+	}
+	for name, bytes := range map[string]string{"fp32": "4", "fp16": "2", "int4": "0.5"} {
+		if rows[name] != bytes {
+			t.Errorf("precisions output %q lists %s at %q bytes per parameter, want %q", out, name, rows[name], bytes)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrewarmAtMaxContext(t *testing.T) {
+	got := estimateRoot(t, "-s", "7b", "--precision", "fp16", "--num-layers", "32", "--hidden-dim", "4096",
+		"--context", "2048", "--prewarm", "--max-context", "32768", "--unit", "B")
+
+	// The KV cache allocated at startup holds the whole of --max-context
+	if want := fmt.Sprintf("%d B", 32768*got.KVCacheBytesPerToken); got.PrewarmBuffers != want {
+		t.Errorf("prewarmed buffers = %q, want %q", got.PrewarmBuffers, want)
+	}
+	var atStartup int
+	if _, err := fmt.Sscanf(got.MemSizeAtStartup, "%d B", &atStartup); err != nil {
+		t.Fatalf("invalid memory at startup %q: %v", got.MemSizeAtStartup, err)
+	}
+	if atStartup <= got.MemBytes {
+		t.Errorf("memory at startup = %d bytes, want more than the %d of the steady state at 2048 tokens", atStartup, got.MemBytes)
+	}
+}
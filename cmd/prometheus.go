@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pushgatewayJob is the job the metrics are grouped under when pushed to a Pushgateway
+const pushgatewayJob = "gpu_mem_for_llm"
+
+// prometheusMetrics renders the estimate in the Prometheus text exposition format. The fit
+// gauge is only included when the memory of a GPU is known.
+func prometheusMetrics(requiredBytes, gpuMemoryBytes int, fits bool) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "# HELP gpu_mem_for_llm_required_bytes Estimated GPU memory required in bytes.")
+	fmt.Fprintln(&b, "# TYPE gpu_mem_for_llm_required_bytes gauge")
+	fmt.Fprintf(&b, "gpu_mem_for_llm_required_bytes %d\n", requiredBytes)
+	if gpuMemoryBytes > 0 {
+		fitValue := 0
+		if fits {
+			fitValue = 1
+		}
+		fmt.Fprintln(&b, "# HELP gpu_mem_for_llm_fits Whether the estimate fits in the memory of each GPU (1) or not (0).")
+		fmt.Fprintln(&b, "# TYPE gpu_mem_for_llm_fits gauge")
+		fmt.Fprintf(&b, "gpu_mem_for_llm_fits %d\n", fitValue)
+	}
+	return b.String()
+}
+
+// metricSample is a single value of a metric along with its labels, given as alternating
+// names and values
+type metricSample struct {
+	Labels []string
+	Value  int
+}
+
+// labelEscaper escapes a label value for the Prometheus text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric writes a metric family in the Prometheus text exposition format, leaving it
+// out when it has no samples
+func writeMetric(b *strings.Builder, name, kind, help string, samples []metricSample) {
+	if len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+	for _, s := range samples {
+		b.WriteString(name)
+		if len(s.Labels) > 0 {
+			pairs := make([]string, 0, len(s.Labels)/2)
+			for i := 0; i+1 < len(s.Labels); i += 2 {
+				pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", s.Labels[i], labelEscaper.Replace(s.Labels[i+1])))
+			}
+			fmt.Fprintf(b, "{%s}", strings.Join(pairs, ","))
+		}
+		fmt.Fprintf(b, " %d\n", s.Value)
+	}
+}
+
+// serveMetrics holds what GET /metrics of the serve subcommand reports: the estimates of
+// the models given with --models and the number of requests to each endpoint
+type serveMetrics struct {
+	Models []jsonBatchEstimate
+
+	mu       sync.Mutex
+	requests map[[3]string]int
+}
+
+// countRequest counts a response to a request of the API by method, path and status code
+func (m *serveMetrics) countRequest(method, path string, code int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.requests == nil {
+		m.requests = make(map[[3]string]int)
+	}
+	m.requests[[3]string{method, path, strconv.Itoa(code)}]++
+}
+
+// render returns the metrics in the Prometheus text exposition format. The memory of the
+// local GPUs, and the headroom each model would have on them, is only included when they
+// can be detected.
+func (m *serveMetrics) render(gpus []localGPU) string {
+	var b strings.Builder
+
+	var required, perGPU, headroom, deviceHeadroom []metricSample
+	for _, e := range m.Models {
+		perGPUBytes := e.MemBytes
+		if e.MemBytesPerGPU > 0 {
+			perGPUBytes = e.MemBytesPerGPU
+		}
+		required = append(required, metricSample{Labels: []string{"model", e.Name}, Value: e.MemBytes})
+		perGPU = append(perGPU, metricSample{Labels: []string{"model", e.Name}, Value: perGPUBytes})
+		if e.Fits != nil {
+			headroom = append(headroom, metricSample{Labels: []string{"model", e.Name, "gpu", e.GPU}, Value: e.HeadroomBytes})
+		}
+		for _, g := range gpus {
+			deviceHeadroom = append(deviceHeadroom, metricSample{Labels: []string{"model", e.Name, "device", strconv.Itoa(g.Index)}, Value: g.Free - perGPUBytes})
+		}
+	}
+	writeMetric(&b, "gpu_mem_for_llm_model_required_bytes", "gauge", "Estimated GPU memory required by a configured model in bytes.", required)
+	writeMetric(&b, "gpu_mem_for_llm_model_required_bytes_per_gpu", "gauge", "Estimated memory required on each GPU by a configured model in bytes.", perGPU)
+	writeMetric(&b, "gpu_mem_for_llm_model_headroom_bytes", "gauge", "Memory left on the GPUs of a configured model's gpu or gpu_memory in bytes, negative when it doesn't fit.", headroom)
+
+	var total, used []metricSample
+	for _, g := range gpus {
+		labels := []string{"device", strconv.Itoa(g.Index), "name", g.Name}
+		total = append(total, metricSample{Labels: labels, Value: g.Memory})
+		used = append(used, metricSample{Labels: labels, Value: g.Memory - g.Free})
+	}
+	writeMetric(&b, "gpu_mem_for_llm_device_memory_total_bytes", "gauge", "Memory of a local GPU in bytes.", total)
+	writeMetric(&b, "gpu_mem_for_llm_device_memory_used_bytes", "gauge", "Memory in use on a local GPU in bytes.", used)
+	writeMetric(&b, "gpu_mem_for_llm_model_device_headroom_bytes", "gauge", "Free memory of a local GPU left after loading a configured model's share in bytes, negative when it doesn't fit.", deviceHeadroom)
+
+	m.mu.Lock()
+	keys := make([][3]string, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.Join(keys[i][:], " ") < strings.Join(keys[j][:], " ")
+	})
+	var requests []metricSample
+	for _, key := range keys {
+		requests = append(requests, metricSample{Labels: []string{"method", key[0], "path", key[1], "code", key[2]}, Value: m.requests[key]})
+	}
+	m.mu.Unlock()
+	writeMetric(&b, "gpu_mem_for_llm_http_requests_total", "counter", "Requests to the API by method, path and status code.", requests)
+	return b.String()
+}
+
+// pushMetrics pushes the metrics to the Pushgateway at the given URL, replacing any
+// metrics previously pushed for the job.
+func pushMetrics(url, metrics string) error {
+	endpoint := strings.TrimSuffix(url, "/") + "/metrics/job/" + pushgatewayJob
+	req, err := http.NewRequest(http.MethodPut, endpoint, strings.NewReader(metrics))
+	if err != nil {
+		return fmt.Errorf("invalid pushgateway URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return internalError(fmt.Errorf("error pushing metrics: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("error pushing metrics: pushgateway responded with %s", resp.Status)
+	}
+	return nil
+}
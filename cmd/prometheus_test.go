@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPushgateway(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		method, path, body = r.Method, r.URL.Path, string(payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	code, _, errOut := runRoot(t, "-s", "7b", "--precision", "fp16", "--gpu-memory", "80gb", "--pushgateway-url", server.URL)
+	if code != 0 {
+		t.Fatalf("pushing the estimate exited with %d: %s", code, errOut)
+	}
+	if method != http.MethodPut || path != "/metrics/job/"+pushgatewayJob {
+		t.Errorf("pushed with %s %s, want PUT /metrics/job/%s", method, path, pushgatewayJob)
+	}
+	for _, metric := range []string{"gpu_mem_for_llm_required_bytes 16800000000\n", "gpu_mem_for_llm_fits 1\n"} {
+		if !strings.Contains(body, metric) {
+			t.Errorf("pushed payload %q does not contain %q", body, metric)
+		}
+	}
+}
+
+func TestPushgatewayRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	if err := pushMetrics(server.URL, prometheusMetrics(1, 0, false)); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("pushMetrics to a gateway responding 400 = %v, want an error with the status", err)
+	}
+}
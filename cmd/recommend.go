@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// recommendSorts lists the orders recommend-gpu accepts for --sort
+var recommendSorts = []string{"vram", "price"}
+
+// gpuOption is a number of GPUs of one model from the database that hosts the estimate
+// with the headroom asked for, with the cheapest instance offering as many of them
+type gpuOption struct {
+	GPU             string
+	GPUs            int
+	Memory          int
+	HeadroomBytes   int
+	HeadroomPercent float64
+	Instance        string
+	Provider        string
+	PricePerHour    float64
+}
+
+// jsonGPUOption is the shape of a single GPU option in the recommend-gpu JSON output
+type jsonGPUOption struct {
+	GPU             string  `json:"gpu"`
+	GPUs            int     `json:"gpus"`
+	Memory          string  `json:"memory"`
+	MemoryBytes     int     `json:"memory_bytes"`
+	HeadroomBytes   int     `json:"headroom_bytes"`
+	HeadroomPercent float64 `json:"headroom_percent"`
+	Instance        string  `json:"instance,omitempty"`
+	Provider        string  `json:"provider,omitempty"`
+	CostPerHour     float64 `json:"cost_per_hour,omitempty"`
+}
+
+// jsonRecommendation is the shape of the output produced by recommend-gpu with --json
+type jsonRecommendation struct {
+	MemSize         string          `json:"mem_size"`
+	MemBytes        int             `json:"mem_bytes"`
+	HeadroomPercent int             `json:"headroom_percent"`
+	GPUs            []jsonGPUOption `json:"gpus"`
+}
+
+// cheapestInstance returns the cheapest instance of the price table with at least the
+// given number of the GPU, and false when none has as many
+func cheapestInstance(gpu string, count int) (string, cloudInstance, bool) {
+	var best string
+	for name, instance := range cloudInstances {
+		if instance.GPU != gpu || instance.GPUs < count {
+			continue
+		}
+		current, ok := cloudInstances[best]
+		if !ok || instance.PricePerHour < current.PricePerHour || (instance.PricePerHour == current.PricePerHour && name < best) {
+			best = name
+		}
+	}
+	instance, ok := cloudInstances[best]
+	return best, instance, ok
+}
+
+// recommendGPUs returns, for every GPU of the database, the fewest of it that hold the
+// required memory while leaving the headroom percentage of each free, up to maxGPUs of
+// them. The options are sorted by their combined memory or by the price of the cheapest
+// instance, with the GPUs no instance offers last.
+func recommendGPUs(required, headroom, maxGPUs int, order string) []gpuOption {
+	var options []gpuOption
+	for _, name := range gpuNames() {
+		memory := gpuDatabase[name].Memory
+		usable := memory * (100 - headroom) / 100
+		if usable <= 0 {
+			continue
+		}
+		count := estimator.CalculateGPUCount(required, usable, 1)
+		if count > maxGPUs {
+			continue
+		}
+		option := gpuOption{GPU: name, GPUs: count, Memory: memory * count}
+		option.HeadroomBytes, option.HeadroomPercent = estimator.CalculateHeadroom(required, memory, count)
+		if instance, spec, ok := cheapestInstance(name, count); ok {
+			option.Instance = instance
+			option.Provider = spec.Provider
+			option.PricePerHour = spec.PricePerHour
+		}
+		options = append(options, option)
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if order == "price" && (a.Instance != "") != (b.Instance != "") {
+			return a.Instance != ""
+		}
+		if order == "price" && a.PricePerHour != b.PricePerHour {
+			return a.PricePerHour < b.PricePerHour
+		}
+		if a.Memory != b.Memory {
+			return a.Memory < b.Memory
+		}
+		return a.GPUs < b.GPUs
+	})
+	return options
+}
+
+// printGPUOptions prints the GPU options as a table, with the cheapest instance of each
+func printGPUOptions(w io.Writer, options []gpuOption) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GPU\tCount\tTotal memory\tHeadroom\tCheapest instance\tPrice")
+	for _, o := range options {
+		instance, price := "-", "-"
+		if o.Instance != "" {
+			instance = o.Provider + " " + o.Instance
+			price = fmt.Sprintf("$%.2f/hour", o.PricePerHour)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s (%.1f%%)\t%s\t%s\n", o.GPU, o.GPUs, formatMemory(o.Memory), formatMemory(o.HeadroomBytes), o.HeadroomPercent, instance, price)
+	}
+	return tw.Flush()
+}
+
+// recommendGPUCmd suggests the GPUs of the database that host a model
+var recommendGPUCmd = &cobra.Command{
+	Use:   "recommend-gpu",
+	Short: "Suggest the smallest or cheapest GPUs that host a model",
+	Long: `Provide a model, by name or by its size, and a precision to list every GPU of the
+database, alone or as several of the same GPU, that holds the estimate while leaving
+--headroom percent of each GPU free. The options are sorted by their combined memory, or
+with --sort price by the cheapest cloud instance offering as many of the GPU.
+
+For example:
+./gpu-mem-for-llm recommend-gpu --model llama3.1-70b --precision fp8 --context 8192 --sort price
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyModelFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		order := strings.ToLower(recommendSort)
+		if order != "vram" && order != "price" {
+			return fmt.Errorf("unknown sort %q; must be one of %s", recommendSort, strings.Join(recommendSorts, ", "))
+		}
+		if recommendHeadroom < 0 || recommendHeadroom >= 100 {
+			return errors.New("invalid headroom; must be 0 or more and less than 100")
+		}
+		if recommendMaxGPUs <= 0 {
+			return errors.New("invalid max-gpus; must be greater than 0")
+		}
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+		if costDB != "" {
+			if err := loadCloudInstances(costDB); err != nil {
+				return err
+			}
+		}
+
+		input, err := modelSpecFromFlags()
+		if err != nil {
+			return err
+		}
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+		options := recommendGPUs(result.Total, recommendHeadroom, recommendMaxGPUs, order)
+
+		if jsonOutput {
+			output := jsonRecommendation{
+				MemSize:         formatMemory(result.Total),
+				MemBytes:        result.Total,
+				HeadroomPercent: recommendHeadroom,
+				GPUs:            make([]jsonGPUOption, 0, len(options)),
+			}
+			for _, o := range options {
+				output.GPUs = append(output.GPUs, jsonGPUOption{
+					GPU:             o.GPU,
+					GPUs:            o.GPUs,
+					Memory:          formatMemory(o.Memory),
+					MemoryBytes:     o.Memory,
+					HeadroomBytes:   o.HeadroomBytes,
+					HeadroomPercent: math.Round(o.HeadroomPercent*10) / 10,
+					Instance:        o.Instance,
+					Provider:        o.Provider,
+					CostPerHour:     o.PricePerHour,
+				})
+			}
+			return writeStructured(out, "json", output)
+		}
+
+		fmt.Fprintf(out, "Estimated memory required: %s, leaving %d%% of each GPU free\n", formatMemory(result.Total), recommendHeadroom)
+		if len(options) == 0 {
+			fmt.Fprintf(out, "No GPU of the database hosts the model on %d GPUs or fewer; raise --max-gpus\n", recommendMaxGPUs)
+			return nil
+		}
+		return printGPUOptions(out, options)
+	},
+}
+
+var (
+	recommendHeadroom int
+	recommendMaxGPUs  int
+	recommendSort     string
+)
+
+func init() {
+	addModelFlags(recommendGPUCmd)
+	recommendGPUCmd.Flags().IntVar(&recommendHeadroom, "headroom", 10, "percentage of each GPU's memory to leave free")
+	recommendGPUCmd.Flags().IntVar(&recommendMaxGPUs, "max-gpus", 8, "largest number of GPUs of one model to suggest")
+	recommendGPUCmd.Flags().StringVar(&recommendSort, "sort", "vram", "order of the suggestions ("+strings.Join(recommendSorts, ", ")+")")
+	recommendGPUCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs to suggest")
+	recommendGPUCmd.Flags().StringVar(&costDB, "cost-db", "", "JSON file of additional cloud instances to price the GPUs with")
+	recommendGPUCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(recommendGPUCmd)
+}
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// quantSchemes lists the families of precisions recommend-quant accepts for --schemes
+var quantSchemes = []string{"all", "precisions", "gguf"}
+
+// duplicateQuants are the llama.cpp schemes that store the weights unquantized, as the
+// precisions of the same names do
+var duplicateQuants = map[string]bool{"F32": true, "F16": true, "BF16": true}
+
+// quantCandidate is a precision or llama.cpp quantization scheme recommend-quant tries,
+// with the estimate of the model at it
+type quantCandidate struct {
+	Name      string
+	Precision estimator.Precision
+	Estimate  estimator.Estimate
+	Fits      bool
+}
+
+// bitsPerWeight returns the bits each weight takes at the candidate, to two decimals
+func (c quantCandidate) bitsPerWeight() float64 {
+	return math.Round(float64(c.Precision)*800) / 100
+}
+
+// jsonQuantCandidate is the shape of a single candidate in the recommend-quant JSON output
+type jsonQuantCandidate struct {
+	Precision     string  `json:"precision"`
+	BitsPerWeight float64 `json:"bits_per_weight"`
+	MemSize       string  `json:"mem_size"`
+	MemBytes      int     `json:"mem_bytes"`
+	Fits          bool    `json:"fits"`
+}
+
+// jsonQuantRecommendation is the shape of the output produced by recommend-quant with --json
+type jsonQuantRecommendation struct {
+	Precision       string               `json:"precision"`
+	BitsPerWeight   float64              `json:"bits_per_weight"`
+	MemSize         string               `json:"mem_size"`
+	MemBytes        int                  `json:"mem_bytes"`
+	BudgetBytes     int                  `json:"budget_bytes"`
+	HeadroomBytes   int                  `json:"headroom_bytes"`
+	HeadroomPercent float64              `json:"headroom_percent"`
+	Candidates      []jsonQuantCandidate `json:"candidates"`
+}
+
+// quantCandidates returns the precisions and llama.cpp quantization schemes of the
+// families to search, from the most bytes per parameter to the fewest, leaving out those
+// taking more than maxPrecision. A precision comes before a scheme of the same size, and
+// the F32, F16 and BF16 schemes are left out as the precisions of the same names.
+func quantCandidates(schemes string, maxPrecision estimator.Precision) ([]quantCandidate, error) {
+	var candidates []quantCandidate
+	if schemes == "all" || schemes == "precisions" {
+		for _, name := range estimator.PrecisionNames() {
+			precision, err := estimator.PrecisionByName(name)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, quantCandidate{Name: name, Precision: precision})
+		}
+	}
+	if schemes == "all" || schemes == "gguf" {
+		for _, name := range estimator.QuantNames() {
+			if schemes == "all" && duplicateQuants[name] {
+				continue
+			}
+			precision, err := estimator.QuantPrecision(name)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, quantCandidate{Name: name, Precision: precision})
+		}
+	}
+
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if c.Precision <= maxPrecision {
+			kept = append(kept, c)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Precision > kept[j].Precision
+	})
+	return kept, nil
+}
+
+// estimateCandidates estimates the model at each candidate and marks those whose memory
+// per GPU fits in the budget. Serving frameworks keep the KV cache in fp16 however the
+// weights are quantized, so it stays in fp16 for the candidates below it, unless
+// kvPrecision gives it a type of its own, and the candidates differ in their weights alone.
+func estimateCandidates(input estimator.ModelSpec, candidates []quantCandidate, kvPrecision estimator.Precision, budget int) error {
+	fp16, err := estimator.PrecisionByName("fp16")
+	if err != nil {
+		return err
+	}
+	for i, c := range candidates {
+		input.Precision = c.Precision
+		input.KVPrecision = kvPrecision
+		if input.KVPrecision == 0 && c.Precision < fp16 {
+			input.KVPrecision = fp16
+		}
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+		candidates[i].Estimate = result
+		candidates[i].Fits = result.Total <= budget
+	}
+	return nil
+}
+
+// printQuantCandidates prints every candidate as a table, with the memory it needs and
+// whether it fits
+func printQuantCandidates(w io.Writer, candidates []quantCandidate) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Precision\tBits per weight\tTotal\tFits")
+	for _, c := range candidates {
+		verdict := "no"
+		if c.Fits {
+			verdict = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%g\t%s\t%s\n", c.Name, c.bitsPerWeight(), formatMemory(c.Estimate.Total), verdict)
+	}
+	return tw.Flush()
+}
+
+// applyQuantModel fills in the size and architecture of the model given with --hf-model or
+// --model-dir. The precision of the checkpoint is the most faithful one worth
+// downloading, so it becomes the default of --max-precision rather than a setting.
+func applyQuantModel(cmd *cobra.Command) error {
+	var source string
+	var settings map[string]string
+	var err error
+	switch {
+	case hfModel != "":
+		source = hfModel
+		settings, err = hfSettings(hfModel, hfOffline)
+	case modelDir != "":
+		source = modelDir
+		settings, err = modelDirSettings(modelDir)
+	default:
+		return nil
+	}
+	if err != nil {
+		// The flags were fine, so the usage wouldn't help
+		cmd.SilenceUsage = true
+		return err
+	}
+
+	if dtype, ok := settings["precision"]; ok && !cmd.Flags().Changed("max-precision") {
+		quantMaxPrecision = dtype
+	}
+	delete(settings, "precision")
+	for key := range settings {
+		if cmd.Flags().Lookup(key) == nil {
+			delete(settings, key)
+		}
+	}
+	return applySettings(cmd, source, settings)
+}
+
+// recommendQuantCmd picks the most faithful precision of a model that fits in a budget
+var recommendQuantCmd = &cobra.Command{
+	Use:   "recommend-quant",
+	Short: "Pick the most faithful precision or quantization of a model that fits a GPU",
+	Long: `Provide a model, by name, by its Hugging Face ID or by its size, and the memory of a
+GPU with --gpu, --gpu-memory or --max-vram to search the precisions and llama.cpp
+quantization schemes from the most bits per weight downward and report the first that
+fits at the requested context, along with the headroom it leaves. The search starts at
+--max-precision, which is the precision of the checkpoint for --hf-model and --model-dir
+and bf16 otherwise, and --schemes limits it to the precisions or the llama.cpp schemes.
+
+For example:
+./gpu-mem-for-llm recommend-quant --model llama3.1-70b --gpu rtx4090 --context 8192 --tensor-parallel 2
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyRegistryModel(cmd); err != nil {
+			return err
+		}
+		if err := applyQuantModel(cmd); err != nil {
+			return err
+		}
+		if size == "" {
+			return errors.New("one of --size, --model, --hf-model or --model-dir is required")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		schemes := strings.ToLower(quantSchemeFamily)
+		if schemes != "all" && schemes != "precisions" && schemes != "gguf" {
+			return fmt.Errorf("unknown schemes %q; must be one of %s", quantSchemeFamily, strings.Join(quantSchemes, ", "))
+		}
+		maxPrecision, err := lookupPrecision(quantMaxPrecision)
+		if err != nil {
+			return err
+		}
+		if quantHeadroom < 0 || quantHeadroom >= 100 {
+			return errors.New("invalid headroom; must be 0 or more and less than 100")
+		}
+		if tensorParallel <= 0 {
+			return errors.New("invalid tensor-parallel; must be greater than 0")
+		}
+
+		// The budget of each GPU is --max-vram when it is given and otherwise its memory
+		gpuMemoryBytes, err := resolveGPUMemory()
+		if err != nil {
+			return err
+		}
+		budget := gpuMemoryBytes
+		if maxVRAM != "" {
+			if budget, err = parseMemorySize(maxVRAM); err != nil {
+				return err
+			}
+		}
+		usable := budget * (100 - quantHeadroom) / 100
+
+		parameterSize, err := getParameterSize(size)
+		if err != nil {
+			return err
+		}
+		if contextLength > 0 && (numLayers <= 0 || hiddenDim <= 0) {
+			return errors.New("--context requires --num-layers and --hidden-dim to size the KV cache")
+		}
+		input := estimator.ModelSpec{
+			ParameterSize:    parameterSize,
+			Overhead:         float32(overhead),
+			NumLayers:        numLayers,
+			HiddenDim:        hiddenDim,
+			VocabSize:        vocabSize,
+			IntermediateSize: intermediateSize,
+			TiedEmbeddings:   tiedEmbeddings,
+			HeadDim:          headDim,
+			KVHeads:          kvHeads,
+			SlidingWindow:    slidingWindow,
+			TensorParallel:   tensorParallel,
+		}
+		if contextLength > 0 {
+			input.ContextLength = contextLength
+			input.BatchSize = batchSize
+		}
+		var kvPrecision estimator.Precision
+		if kvDtype != "" {
+			if kvPrecision, err = estimator.KVPrecisionByName(kvDtype); err != nil {
+				return err
+			}
+		}
+
+		candidates, err := quantCandidates(schemes, maxPrecision)
+		if err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("nothing of --schemes %s takes %g bytes per parameter or fewer; raise --max-precision", schemes, float32(maxPrecision))
+		}
+		if err := estimateCandidates(input, candidates, kvPrecision, usable); err != nil {
+			return err
+		}
+
+		best := -1
+		for i, c := range candidates {
+			if c.Fits {
+				best = i
+				break
+			}
+		}
+		if best < 0 {
+			smallest := candidates[len(candidates)-1]
+			return doesNotFitError(fmt.Errorf("no precision fits in %s of each GPU; the smallest, %s, needs %s", formatMemory(usable), smallest.Name, formatMemory(smallest.Estimate.Total)))
+		}
+		chosen := candidates[best]
+		headroomBytes, headroomPercent := estimator.CalculateHeadroom(chosen.Estimate.Total, budget, 1)
+
+		if jsonOutput {
+			output := jsonQuantRecommendation{
+				Precision:       chosen.Name,
+				BitsPerWeight:   chosen.bitsPerWeight(),
+				MemSize:         formatMemory(chosen.Estimate.Total),
+				MemBytes:        chosen.Estimate.Total,
+				BudgetBytes:     budget,
+				HeadroomBytes:   headroomBytes,
+				HeadroomPercent: math.Round(headroomPercent*10) / 10,
+				Candidates:      make([]jsonQuantCandidate, 0, len(candidates)),
+			}
+			for _, c := range candidates {
+				output.Candidates = append(output.Candidates, jsonQuantCandidate{
+					Precision:     c.Name,
+					BitsPerWeight: c.bitsPerWeight(),
+					MemSize:       formatMemory(c.Estimate.Total),
+					MemBytes:      c.Estimate.Total,
+					Fits:          c.Fits,
+				})
+			}
+			return writeStructured(out, "json", output)
+		}
+
+		if quantShowAll {
+			if err := printQuantCandidates(out, candidates); err != nil {
+				return err
+			}
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Best precision: %s (%g bits per weight)\n", chosen.Name, chosen.bitsPerWeight())
+		perGPU := ""
+		if tensorParallel > 1 {
+			perGPU = " per GPU"
+		}
+		fmt.Fprintf(out, "Estimated memory required: %s%s of %s, leaving %s (%.1f%%) free\n", formatMemory(chosen.Estimate.Total), perGPU, formatMemory(budget), formatMemory(headroomBytes), headroomPercent)
+		if best > 0 {
+			next := candidates[best-1]
+			fmt.Fprintf(out, "The next more faithful, %s, needs %s%s\n", next.Name, formatMemory(next.Estimate.Total), perGPU)
+		}
+		return nil
+	},
+}
+
+var (
+	quantMaxPrecision string
+	quantSchemeFamily string
+	quantHeadroom     int
+	quantShowAll      bool
+)
+
+func init() {
+	recommendQuantCmd.Flags().StringVar(&registryModelName, "model", "", "well-known model to take the size and architecture from (e.g., llama3.1-8b)")
+	recommendQuantCmd.Flags().StringVar(&modelDB, "model-db", "", "JSON file of additional models for --model")
+	recommendQuantCmd.Flags().StringVar(&hfModel, "hf-model", "", "Hugging Face model ID to derive the size and architecture from (e.g., meta-llama/Llama-3.1-8B)")
+	recommendQuantCmd.Flags().StringVar(&hfCache, "hf-cache-dir", "", "directory the files fetched for --hf-model are cached in (default the user cache directory)")
+	recommendQuantCmd.Flags().BoolVar(&hfOffline, "hf-offline", false, "read the files for --hf-model from the cache instead of the Hub")
+	recommendQuantCmd.Flags().StringVar(&modelDir, "model-dir", "", "local directory of a model in the Hugging Face format to derive the size and architecture from")
+	recommendQuantCmd.MarkFlagsMutuallyExclusive("model", "hf-model", "model-dir")
+	recommendQuantCmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b, 1.5b or 1.8t)")
+	recommendQuantCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	recommendQuantCmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32)")
+	recommendQuantCmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096)")
+	recommendQuantCmd.Flags().IntVar(&vocabSize, "vocab-size", 0, "model vocabulary size (e.g., 32000)")
+	recommendQuantCmd.Flags().IntVar(&intermediateSize, "intermediate-size", 0, "MLP intermediate size (e.g., 11008)")
+	recommendQuantCmd.Flags().BoolVar(&tiedEmbeddings, "tied-embeddings", false, "the LM head shares its weights with the embedding table")
+	recommendQuantCmd.Flags().IntVar(&headDim, "head-dim", 0, "attention head dimension (e.g., 128)")
+	recommendQuantCmd.Flags().IntVar(&kvHeads, "kv-heads", 0, "number of key/value heads with grouped-query attention (requires --head-dim)")
+	recommendQuantCmd.Flags().IntVar(&slidingWindow, "sliding-window", 0, "sliding attention window in tokens, capping the context kept in the KV cache")
+	recommendQuantCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the KV cache for (requires --num-layers and --hidden-dim)")
+	recommendQuantCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	recommendQuantCmd.Flags().StringVar(&kvDtype, "kv-dtype", "", "precision or quantized type of the KV cache for every candidate (e.g., fp8 or q4_0)")
+	recommendQuantCmd.Flags().IntVar(&tensorParallel, "tensor-parallel", 1, "number of GPUs to shard the model across with tensor parallelism")
+	recommendQuantCmd.Flags().StringVar(&gpu, "gpu", "", "GPU model from the built-in database (e.g., rtx4090) to fit the model on")
+	recommendQuantCmd.Flags().StringVar(&gpuMemory, "gpu-memory", "", "memory of each GPU (e.g., 24gb) to fit the model in")
+	recommendQuantCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for --gpu")
+	recommendQuantCmd.Flags().StringVar(&maxVRAM, "max-vram", "", "memory budget of each GPU (e.g., 20gb), in place of the memory of --gpu or --gpu-memory")
+	recommendQuantCmd.MarkFlagsMutuallyExclusive("gpu", "gpu-memory")
+	recommendQuantCmd.MarkFlagsOneRequired("gpu", "gpu-memory", "max-vram")
+	recommendQuantCmd.Flags().IntVar(&quantHeadroom, "headroom", 0, "percentage of the budget of each GPU to leave free")
+	recommendQuantCmd.Flags().StringVar(&quantMaxPrecision, "max-precision", "bf16", "most faithful precision to start the search from, which the precision of the checkpoint replaces for --hf-model and --model-dir")
+	recommendQuantCmd.Flags().StringVar(&quantSchemeFamily, "schemes", "all", "precisions to search ("+strings.Join(quantSchemes, ", ")+"): the named precisions, the llama.cpp quantization schemes or both")
+	recommendQuantCmd.Flags().BoolVar(&quantShowAll, "all", false, "list every candidate with the memory it needs before the recommendation")
+	recommendQuantCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(recommendQuantCmd)
+}
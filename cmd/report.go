@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// reportSetting is a single input of the model shown at the top of the report
+type reportSetting struct {
+	Name  string
+	Value string
+}
+
+// reportBar is a row of a table in the report with a bar sized against the largest row
+type reportBar struct {
+	Label string
+	Size  string
+	Share float64
+	Width float64
+}
+
+// reportSweepRow is a single value of a sweep in the report
+type reportSweepRow struct {
+	Value   int
+	KVCache string
+	reportBar
+}
+
+// reportSweep is a sweep over the context or the batch size in the report
+type reportSweep struct {
+	Name string
+	Unit string
+	Rows []reportSweepRow
+}
+
+// reportFitCell is whether the model fits on a number of a GPU, sharded with tensor
+// parallelism across them
+type reportFitCell struct {
+	Fits   bool
+	PerGPU string
+	Free   string
+}
+
+// reportFitRow is a GPU of the fit matrix with a cell for each number of GPUs
+type reportFitRow struct {
+	GPU    string
+	Memory string
+	Cells  []reportFitCell
+}
+
+// reportData is everything the report template renders
+type reportData struct {
+	Title      string
+	Generated  string
+	Version    string
+	Settings   []reportSetting
+	Total      string
+	Components []reportBar
+	Sweeps     []reportSweep
+	Headroom   int
+	GPUCounts  []int
+	Fits       []reportFitRow
+}
+
+// reportTemplate renders the report as a single page with its styles inline, so it can be
+// mailed or attached to a ticket and opened anywhere without fetching anything
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 2rem auto; max-width: 60rem; padding: 0 1rem; }
+h1 { font-size: 1.6rem; margin-bottom: 0.2rem; }
+h2 { font-size: 1.2rem; margin-top: 2rem; border-bottom: 1px solid #d0d7de; padding-bottom: 0.3rem; }
+.meta { color: #656d76; font-size: 0.85rem; }
+.total { font-size: 1.4rem; font-weight: 600; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+th, td { text-align: left; padding: 0.3rem 0.6rem; border-bottom: 1px solid #eaeef2; white-space: nowrap; }
+th { background: #f6f8fa; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+td.bar { width: 40%; }
+.bar div { background: #4f8ff7; height: 0.8rem; border-radius: 2px; }
+.settings td:first-child { color: #656d76; width: 12rem; }
+.fit { background: #dafbe1; }
+.nofit { background: #ffebe9; color: #82071e; }
+.fit, .nofit { text-align: center; }
+.fit small, .nofit small { display: block; color: #656d76; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Generated {{.Generated}} by gpu-mem-for-llm {{.Version}}</p>
+
+<h2>Model</h2>
+<table class="settings">
+{{- range .Settings}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+<p class="total">Total: {{.Total}}</p>
+
+<h2>Breakdown</h2>
+<table>
+<tr><th>Component</th><th>Memory</th><th>Share</th><th></th></tr>
+{{- range .Components}}
+<tr><td>{{.Label}}</td><td class="num">{{.Size}}</td><td class="num">{{printf "%.1f" .Share}}%</td><td class="bar"><div style="width: {{printf "%.1f" .Width}}%"></div></td></tr>
+{{- end}}
+</table>
+{{- range .Sweeps}}
+
+<h2>{{.Name}} sweep</h2>
+<table>
+<tr><th>{{.Name}} ({{.Unit}})</th><th>KV cache</th><th>Total</th><th></th></tr>
+{{- range .Rows}}
+<tr><td class="num">{{.Value}}</td><td class="num">{{.KVCache}}</td><td class="num">{{.Size}}</td><td class="bar"><div style="width: {{printf "%.1f" .Width}}%"></div></td></tr>
+{{- end}}
+</table>
+{{- end}}
+
+<h2>GPU fit</h2>
+<p class="meta">Memory of each GPU with the model sharded across them with tensor parallelism, leaving {{.Headroom}}% of each GPU free.</p>
+<table>
+<tr><th>GPU</th><th>Memory</th>{{range .GPUCounts}}<th>&times; {{.}}</th>{{end}}</tr>
+{{- range .Fits}}
+<tr><td>{{.GPU}}</td><td class="num">{{.Memory}}</td>{{range .Cells}}<td class="{{if .Fits}}fit{{else}}nofit{{end}}">{{if .Fits}}fits{{else}}no{{end}}<small>{{.PerGPU}}{{if .Fits}}, {{.Free}} free{{end}}</small></td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// reportSettings lists the inputs of the model given by the flags
+func reportSettings(model string, input estimator.ModelSpec) []reportSetting {
+	settings := []reportSetting{
+		{"Model", model},
+		{"Parameters", estimator.FormatCount(input.ParameterSize)},
+		{"Precision", precisionName()},
+	}
+	if input.ContextLength > 0 {
+		settings = append(settings, reportSetting{"Context", fmt.Sprintf("%d tokens", input.ContextLength)})
+		settings = append(settings, reportSetting{"Batch size", strconv.Itoa(input.BatchSize)})
+	}
+	if kvDtype != "" {
+		settings = append(settings, reportSetting{"KV cache precision", kvDtype})
+	}
+	if numLayers > 0 && hiddenDim > 0 {
+		settings = append(settings, reportSetting{"Layers x hidden", fmt.Sprintf("%d x %d", numLayers, hiddenDim)})
+	}
+	return append(settings, reportSetting{"Overhead", fmt.Sprintf("%d%%", overhead)})
+}
+
+// reportComponents returns the components of the estimate, each with its share of the
+// total and a bar against the largest
+func reportComponents(result estimator.Estimate) []reportBar {
+	components := jsonComponents(result)
+	var largest int
+	for _, c := range components {
+		largest = max(largest, c.Bytes)
+	}
+	bars := make([]reportBar, 0, len(components))
+	for _, c := range components {
+		bars = append(bars, reportBar{
+			Label: c.Name,
+			Size:  formatMemory(c.Bytes),
+			Share: componentShare(c.Bytes, result.Total),
+			Width: componentShare(c.Bytes, largest),
+		})
+	}
+	return bars
+}
+
+// reportSweepOf estimates the model at each value of the sweep
+func reportSweepOf(input estimator.ModelSpec, s sweep) (reportSweep, error) {
+	rows := make([]reportSweepRow, 0, len(s.Values))
+	totals := make([]int, 0, len(s.Values))
+	for _, value := range s.Values {
+		s.apply(&input, value)
+		result, err := calculate(input)
+		if err != nil {
+			return reportSweep{}, err
+		}
+		row := reportSweepRow{Value: value}
+		for _, c := range result.Components {
+			if c.Name == "kv cache" {
+				row.KVCache = formatMemory(c.Bytes)
+			}
+		}
+		row.Label = strconv.Itoa(value)
+		row.Size = formatMemory(result.Total)
+		rows = append(rows, row)
+		totals = append(totals, result.Total)
+	}
+	largest := slices.Max(totals)
+	for i := range rows {
+		rows[i].Width = componentShare(totals[i], largest)
+	}
+	return reportSweep{Name: s.Name, Unit: s.Unit, Rows: rows}, nil
+}
+
+// reportFitMatrix checks the model against every GPU of the database at each number of
+// GPUs, sharding it across them with tensor parallelism
+func reportFitMatrix(input estimator.ModelSpec, counts []int, headroom int) ([]reportFitRow, error) {
+	perGPU := make([]int, len(counts))
+	for i, count := range counts {
+		input.TensorParallel = count
+		result, err := calculate(input)
+		if err != nil {
+			return nil, err
+		}
+		perGPU[i] = result.Total
+	}
+
+	var rows []reportFitRow
+	for _, name := range gpuNames() {
+		memory := gpuDatabase[name].Memory
+		row := reportFitRow{GPU: name, Memory: formatMemory(memory)}
+		for _, required := range perGPU {
+			cell := reportFitCell{
+				Fits:   required <= memory*(100-headroom)/100,
+				PerGPU: formatMemory(required),
+			}
+			if cell.Fits {
+				cell.Free = formatMemory(memory - required)
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// reportCmd renders an estimate as a self-contained HTML page
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render a self-contained HTML report of a model's memory",
+	Long: `Provide a model, by name or by its size, and a precision to render a single HTML page
+with the breakdown of its estimate, sweeps over the context length and batch size, and a
+matrix of every GPU of the database against --gpu-counts of it, sharded with tensor
+parallelism, marking those that hold the model while leaving --headroom percent free.
+The page has no scripts or external assets, so it can be attached to a ticket or mailed
+and opened anywhere.
+
+The context sweep needs --num-layers and --hidden-dim, which --model gives, and the batch
+sweep --context as well; each is left out when its inputs are missing, or when it is set
+to an empty value.
+
+For example:
+./gpu-mem-for-llm report --model llama3.1-70b --precision fp8 --context 8192 --file report.html
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyModelFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if reportHeadroom < 0 || reportHeadroom >= 100 {
+			return errors.New("invalid headroom; must be 0 or more and less than 100")
+		}
+		counts, err := parseSweep(reportGPUCounts, parseBatchSize)
+		if err != nil {
+			return fmt.Errorf("invalid --gpu-counts: %v", err)
+		}
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+
+		input, err := modelSpecFromFlags()
+		if err != nil {
+			return err
+		}
+		input.BatchSize = batchSize
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+
+		model := modelLabel(size)
+		data := reportData{
+			Title:      reportTitle,
+			Generated:  time.Now().UTC().Format(time.RFC3339),
+			Version:    appVersion,
+			Settings:   reportSettings(model, input),
+			Total:      formatMemory(result.Total),
+			Components: reportComponents(result),
+			Headroom:   reportHeadroom,
+			GPUCounts:  counts,
+		}
+		if data.Title == "" {
+			data.Title = fmt.Sprintf("Memory report: %s at %s", model, precisionName())
+		}
+
+		// A sweep is left out when the model lacks what it needs, unless it was asked for
+		var sweeps []sweep
+		if reportSweepContext != "" && (numLayers > 0 && hiddenDim > 0 || cmd.Flags().Changed("sweep-context")) {
+			if numLayers <= 0 || hiddenDim <= 0 {
+				return errors.New("--sweep-context requires --num-layers and --hidden-dim to size the KV cache")
+			}
+			values, err := parseSweep(reportSweepContext, parseTokenCount)
+			if err != nil {
+				return err
+			}
+			sweeps = append(sweeps, contextSweep(values))
+		}
+		if reportSweepBatch != "" && (contextLength > 0 || cmd.Flags().Changed("sweep-batch")) {
+			if contextLength <= 0 {
+				return errors.New("--sweep-batch requires --context to size the KV cache of each sequence")
+			}
+			values, err := parseSweep(reportSweepBatch, parseBatchSize)
+			if err != nil {
+				return err
+			}
+			sweeps = append(sweeps, batchSweep(values))
+		}
+		for _, s := range sweeps {
+			swept, err := reportSweepOf(input, s)
+			if err != nil {
+				return err
+			}
+			data.Sweeps = append(data.Sweeps, swept)
+		}
+
+		if data.Fits, err = reportFitMatrix(input, counts, reportHeadroom); err != nil {
+			return err
+		}
+
+		var page bytes.Buffer
+		if err := reportTemplate.Execute(&page, data); err != nil {
+			return internalError(err)
+		}
+		if reportFile == "" {
+			_, err := cmd.OutOrStdout().Write(page.Bytes())
+			return err
+		}
+		if err := os.WriteFile(reportFile, page.Bytes(), 0o644); err != nil {
+			return internalError(err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Report written to %s\n", reportFile)
+		return nil
+	},
+}
+
+var (
+	reportFile         string
+	reportTitle        string
+	reportSweepContext string
+	reportSweepBatch   string
+	reportGPUCounts    string
+	reportHeadroom     int
+)
+
+func init() {
+	addModelFlags(reportCmd)
+	reportCmd.Flags().StringVar(&reportSweepContext, "sweep-context", "2k:128k", "context lengths to sweep, as a range doubling from the start, a range with a step or a list")
+	reportCmd.Flags().StringVar(&reportSweepBatch, "sweep-batch", "1:64", "batch sizes to sweep at --context, as a range doubling from the start, a range with a step or a list")
+	reportCmd.Flags().StringVar(&reportGPUCounts, "gpu-counts", "1,2,4,8", "numbers of GPUs to check each GPU of the database at")
+	reportCmd.Flags().IntVar(&reportHeadroom, "headroom", 10, "percentage of each GPU's memory to leave free")
+	reportCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for the fit matrix")
+	reportCmd.Flags().StringVar(&reportTitle, "title", "", "title of the report (default \"Memory report: MODEL at PRECISION\")")
+	reportCmd.Flags().StringVar(&reportFile, "file", "", "file to write the report to instead of stdout")
+	rootCmd.AddCommand(reportCmd)
+}
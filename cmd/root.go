@@ -4,113 +4,351 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // getParameterSize parses the parameter size value provided as a string and should be checked
-// to be in a form such as 100m for 100 million or 7b for 7 billion. If any other string is provided
-// an error is returned. If not, then the number is extracted and returned as an integer
+// to be in a form such as 500k for 500 thousand, 100m for 100 million, 7b for 7 billion or
+// 1.8t for 1.8 trillion. Fractional values such as 1.5b are accepted, as is a plain whole
+// number of parameters. If any other string is provided an error is returned. If not, then
+// the number of parameters is returned as an integer
 func getParameterSize(param string) (int, error) {
-	// Define regex pattern to match strings like "100m", "7b", etc.
-	pattern := `^(\d+)([mbMB])$`
+	// Define regex pattern to match strings like "500k", "1.5b", "1.8t" or "7000000000"
+	pattern := `^(\d+(?:\.\d+)?)([kmbtKMBT]?)$`
 	re := regexp.MustCompile(pattern)
 
 	// Match the input string against the pattern
 	matches := re.FindStringSubmatch(param)
 	if matches == nil {
-		return 0, errors.New("invalid format; must be an integer followed by 'm' or 'b'")
+		return 0, errors.New("invalid format; must be a number followed by 'k', 'm', 'b' or 't', or a whole number of parameters")
 	}
 
 	// Extract number and unit from the matches
 	numStr := matches[1]
-	unit := matches[2]
+	unit := strings.ToLower(matches[2])
 
-	number, err := strconv.Atoi(numStr)
+	number, err := strconv.ParseFloat(numStr, 64)
 	if err != nil {
 		return 0, fmt.Errorf("invalid number: %v", err)
 	}
 
-	switch unit {
-	case "m", "M":
-		return number * 1_000_000, nil
-	case "b", "B":
-		return number * 1_000_000_000, nil
-	default:
-		return 0, errors.New("invalid unit; must be 'm' or 'b'")
+	multipliers := map[string]float64{
+		"":  1,
+		"k": 1_000,
+		"m": 1_000_000,
+		"b": 1_000_000_000,
+		"t": 1_000_000_000_000,
 	}
+	if unit == "" && strings.Contains(numStr, ".") {
+		return 0, errors.New("invalid format; a number of parameters without a unit must be whole")
+	}
+
+	// A whole number is parsed exactly, while a float64 of 2^63 is the first past the
+	// largest int and can't be converted to one
+	tooLarge := fmt.Errorf("invalid size %q; must be at most %d parameters", param, math.MaxInt64)
+	if unit == "" {
+		params, err := strconv.Atoi(numStr)
+		if err != nil {
+			return 0, tooLarge
+		}
+		return params, nil
+	}
+	params := math.Round(number * multipliers[unit])
+	if params >= math.MaxInt64 {
+		return 0, tooLarge
+	}
+	return int(params), nil
+}
+
+// lookupPrecision returns the bytes per parameter of a precision given by name, such as
+// "fp16" or "gptq", or of a llama.cpp quantization scheme, such as "Q4_K_M"
+func lookupPrecision(name string) (estimator.Precision, error) {
+	switch strings.ToLower(name) {
+	case "gptq", "awq":
+		if groupSize <= 0 {
+			return 0, errors.New("invalid group size; must be greater than 0")
+		}
+		return estimator.GroupQuantPrecision(4, groupSize), nil
+	case "int2":
+		if groupSize <= 0 {
+			return 0, errors.New("invalid group size; must be greater than 0")
+		}
+		return estimator.GroupQuantPrecision(2, groupSize), nil
+	}
+	if precision, err := estimator.PrecisionByName(name); err == nil {
+		return precision, nil
+	}
+	if precision, err := estimator.QuantPrecision(name); err == nil {
+		return precision, nil
+	}
+	return 0, fmt.Errorf("unknown precision %q; must be one of %s, or a llama.cpp quantization scheme such as Q4_K_M", name, strings.Join(estimator.PrecisionNames(), ", "))
 }
 
 // func get precision value from the flags provided
-func getPrecision() (float32, error) {
-	if fp32 {
-		return 4, nil
+func getPrecision() (estimator.Precision, error) {
+	if precisionValue != "" {
+		return lookupPrecision(precisionValue)
+	} else if bitsPerWeight != 0 {
+		if bitsPerWeight < 0 {
+			return 0, errors.New("invalid bits per weight; must be greater than 0")
+		}
+		return estimator.Precision(bitsPerWeight / 8), nil
+	} else if bytesPerParam != 0 {
+		if bytesPerParam < 0 {
+			return 0, errors.New("invalid bytes per parameter; must be greater than 0")
+		}
+		return estimator.Precision(bytesPerParam), nil
+	} else if quant != "" {
+		return estimator.QuantPrecision(quant)
+	} else if fp32 {
+		return estimator.PrecisionByName("fp32")
 	} else if fp16 {
-		return 2, nil
+		return estimator.PrecisionByName("fp16")
 	} else if bf16 {
-		return 2, nil
+		return estimator.PrecisionByName("bf16")
+	} else if fp8 {
+		return estimator.PrecisionByName("fp8")
 	} else if int8 {
-		return 1, nil
+		return estimator.PrecisionByName("int8")
+	} else if gptq || awq {
+		if groupSize <= 0 {
+			return 0, errors.New("invalid group size; must be greater than 0")
+		}
+		return estimator.GroupQuantPrecision(4, groupSize), nil
+	} else if nf4 {
+		return estimator.PrecisionByName("nf4")
 	} else if int4 {
-		return 0.5, nil
+		return estimator.PrecisionByName("int4")
 	} else {
 		return 0, errors.New("no precision flag provided")
 	}
 }
 
-// calculateRequiredMemory returns the gpu memory required for serving llms
-func calculateRequiredMemory(parameterSize int, precision float32, overhead float32) int {
-	// Calculate the memory required for parameters
-	memoryForParams := float32(parameterSize) * precision
+// lookupPrecisionName returns the name of a precision accepted by lookupPrecision as it is
+// shown, in lower case for a named precision and in upper case for a quantization scheme
+func lookupPrecisionName(name string) string {
+	if _, err := estimator.PrecisionByName(name); err == nil {
+		return strings.ToLower(name)
+	}
+	return strings.ToUpper(name)
+}
 
-	// Convert overhead to a percentage
-	overhead /= 100
-	overhead = 1 + overhead
+// precisionName returns the name of the precision selected by the flags, such as fp16,
+// Q4_K_M or 4.65bpw, or custom for --bytes-per-param
+func precisionName() string {
+	switch {
+	case precisionValue != "":
+		return lookupPrecisionName(precisionValue)
+	case bitsPerWeight != 0:
+		return strconv.FormatFloat(bitsPerWeight, 'g', -1, 64) + "bpw"
+	case bytesPerParam != 0:
+		return "custom"
+	case quant != "":
+		return strings.ToUpper(quant)
+	}
+	for _, flag := range []struct {
+		set  bool
+		name string
+	}{{fp32, "fp32"}, {fp16, "fp16"}, {bf16, "bf16"}, {fp8, "fp8"}, {int8, "int8"}, {gptq, "gptq"}, {awq, "awq"}, {nf4, "nf4"}, {int4, "int4"}} {
+		if flag.set {
+			return flag.name
+		}
+	}
+	return ""
+}
 
-	// Add overhead to the calculated memory
-	totalMemoryRequired := int(memoryForParams * overhead)
+// deprecatedPrecisionFlags lists the boolean flags of each named precision, which
+// --precision replaces
+var deprecatedPrecisionFlags = []string{"fp32", "fp16", "bf16", "fp8", "int8", "gptq", "awq", "nf4", "int4"}
 
-	return totalMemoryRequired
-}
+// precisionFlags lists the flags that select the precision: the precision by name, the
+// llama.cpp quantization schemes, the effective bits per weight, the custom bytes per
+// parameter and the deprecated boolean flags
+var precisionFlags = append([]string{"precision", "quant", "bpw", "bytes-per-param"}, deprecatedPrecisionFlags...)
 
-// formatMemory takes an integer representing memory in bytes and returns a formatted string
-// with the memory in megabytes or gigabytes, depending on the size. The returned string is rounded
-// to two decimal places for readability.
-func formatMemory(memoryBytes int) string {
-	const (
-		megabyte = 1_000_000
-		gigabyte = 1_000_000_000
-	)
+// precisionFlagList formats the precision flags for error messages, leaving out the
+// deprecated ones
+func precisionFlagList() string {
+	return "--" + strings.Join(precisionFlags[:len(precisionFlags)-len(deprecatedPrecisionFlags)], ", --")
+}
 
-	if memoryBytes >= gigabyte {
-		return fmt.Sprintf("%.2f GB", float64(memoryBytes)/gigabyte)
+// precisionFlagChanged reports whether any of the precision flags has been set
+func precisionFlagChanged(cmd *cobra.Command) bool {
+	for _, flag := range precisionFlags {
+		if cmd.Flag(flag).Changed {
+			return true
+		}
 	}
+	return false
+}
 
-	return fmt.Sprintf("%d MB", memoryBytes/megabyte)
+// addPrecisionFlags defines the flag group for the precision values - a precision by name,
+// a llama.cpp quantization scheme, effective bits per weight and a custom bytes per
+// parameter, along with the
+// deprecated boolean flags of fp32, fp16, bf16, fp8, int8, gptq, awq, nf4 and int4 - on the
+// command, along with the group size of GPTQ and AWQ. One of them is required, but only
+// one of them can be provided at any given time, which
+// checkMutuallyExclusivePrecisionFlags enforces.
+func addPrecisionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&precisionValue, "precision", "", "precision of the weights ("+strings.Join(estimator.PrecisionNames(), ", ")+") or a llama.cpp quantization scheme (e.g., Q4_K_M)")
+	cmd.Flags().BoolVar(&fp32, "fp32", false, "use fp32 precision")
+	cmd.Flags().BoolVar(&fp16, "fp16", false, "use fp16 precision")
+	cmd.Flags().BoolVar(&bf16, "bf16", false, "use bf16 precision")
+	cmd.Flags().BoolVar(&fp8, "fp8", false, "use fp8 precision")
+	cmd.Flags().BoolVar(&int8, "int8", false, "use int8 precision")
+	cmd.Flags().BoolVar(&gptq, "gptq", false, "use 4-bit GPTQ precision with a scale and zero point per --group-size weights")
+	cmd.Flags().BoolVar(&awq, "awq", false, "use 4-bit AWQ precision with a scale and zero point per --group-size weights")
+	cmd.Flags().BoolVar(&nf4, "nf4", false, "use 4-bit NF4 precision with double-quantized block scales, as in QLoRA")
+	cmd.Flags().BoolVar(&int4, "int4", false, "use int4 precision")
+	cmd.Flags().StringVar(&quant, "quant", "", "use a llama.cpp quantization scheme at its effective bits per weight (e.g., Q4_K_M)")
+	cmd.Flags().Float64Var(&bitsPerWeight, "bpw", 0, "effective bits per weight of a quantization without a name of its own (e.g., 4.65 for exl2)")
+	cmd.Flags().Float64Var(&bytesPerParam, "bytes-per-param", 0, "custom bytes per parameter for formats without a precision flag (e.g., 0.75 for fp6)")
+	cmd.Flags().IntVar(&groupSize, "group-size", estimator.DefaultGroupSize, "number of weights sharing a scale and zero point with gptq, awq or int2")
+	// The deprecation is reported by checkMutuallyExclusivePrecisionFlags, since cobra
+	// would print it to the output, where it would break --json
+	for _, name := range deprecatedPrecisionFlags {
+		cmd.Flags().MarkHidden(name)
+	}
+	cmd.MarkFlagsOneRequired(precisionFlags...)
 }
 
 // checkMutuallyExclusivePrecisionFlags checks if multiple precision flags are provided at the same time.
-// It returns an error if more than one flag is set, nil otherwise.
+// It returns an error if more than one flag is set, nil otherwise. A deprecated precision
+// flag is reported on the error stream.
 func checkMutuallyExclusivePrecisionFlags(cmd *cobra.Command) error {
-	flags := []string{"fp32", "fp16", "bf16", "int8", "int4"}
 	var count int
 
-	for _, flag := range flags {
+	for _, flag := range precisionFlags {
 		if cmd.Flag(flag).Changed {
 			count++
 		}
 	}
+	for _, flag := range deprecatedPrecisionFlags {
+		if cmd.Flag(flag).Changed {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Flag --%s has been deprecated, use --precision %s instead\n", flag, flag)
+		}
+	}
 
 	if count > 1 {
-		return errors.New("only one of --fp32, --fp16, --bf16, --int8, --int4 can be set at a time")
+		return fmt.Errorf("only one of %s can be set at a time", precisionFlagList())
 	}
 
 	return nil
 }
 
+// resolvedInputs returns the value of every flag once the command line, environment
+// variables and config file have been applied, including the defaults, so a JSON result
+// records everything needed to reproduce it.
+func resolvedInputs(cmd *cobra.Command) map[string]string {
+	inputs := make(map[string]string)
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "help", "version":
+			return
+		}
+		inputs[flag.Name] = flag.Value.String()
+	})
+	return inputs
+}
+
+// jsonComponent is the shape of a single component of the estimate in the JSON output
+type jsonComponent struct {
+	Name    string  `json:"name"`
+	Bytes   int     `json:"bytes"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// modelLabel returns the name the model is shown under in tables: the Hugging Face or
+// registry model it was taken from, or its size
+func modelLabel(sizeValue string) string {
+	switch {
+	case hfModel != "":
+		return hfModel
+	case modelDir != "":
+		return filepath.Base(filepath.Clean(modelDir))
+	case registryModelName != "":
+		return registryModelName
+	}
+	return sizeValue
+}
+
+// jsonComponents returns every component of the estimate followed by the overhead, with
+// their raw byte counts
+func jsonComponents(e estimator.Estimate) []jsonComponent {
+	components := make([]jsonComponent, 0, len(e.Components)+1)
+	for _, c := range e.Components {
+		components = append(components, jsonComponent{Name: c.Name, Bytes: c.Bytes})
+	}
+	return append(components, jsonComponent{Name: "overhead", Bytes: e.Overhead})
+}
+
+// jsonEstimate is the shape of the output produced with the --json flag
+type jsonEstimate struct {
+	MemSize                  string            `json:"mem_size"`
+	MemSizePerGPU            string            `json:"mem_size_per_gpu,omitempty"`
+	MemSizeLow               string            `json:"mem_size_low,omitempty"`
+	MemSizeHigh              string            `json:"mem_size_high,omitempty"`
+	MemBytes                 int               `json:"mem_bytes"`
+	MemBytesPerGPU           int               `json:"mem_bytes_per_gpu,omitempty"`
+	MemSizeRounded           string            `json:"mem_size_rounded,omitempty"`
+	MemBytesRounded          int               `json:"mem_bytes_rounded,omitempty"`
+	Parameters               int               `json:"parameters"`
+	Precision                string            `json:"precision"`
+	BytesPerParameter        json.Number       `json:"bytes_per_parameter"`
+	OverheadPercent          float32           `json:"overhead_percent"`
+	Components               []jsonComponent   `json:"components"`
+	Breakdown                map[string]string `json:"breakdown,omitempty"`
+	GPU                      string            `json:"gpu,omitempty"`
+	Fits                     *bool             `json:"fits,omitempty"`
+	MaxConcurrentSequences   *int              `json:"max_concurrent_sequences,omitempty"`
+	MaxVRAMBytes             int               `json:"max_vram_bytes,omitempty"`
+	WithinMaxVRAM            *bool             `json:"within_max_vram,omitempty"`
+	UtilizationPercent       float64           `json:"utilization_percent,omitempty"`
+	Warning                  string            `json:"warning,omitempty"`
+	GPUsRequired             int               `json:"gpus_required,omitempty"`
+	HeadroomBytes            int               `json:"headroom_bytes,omitempty"`
+	HeadroomPercent          float64           `json:"headroom_percent,omitempty"`
+	GPUsForMemory            int               `json:"gpus_for_memory,omitempty"`
+	GPUsForThroughput        int               `json:"gpus_for_throughput,omitempty"`
+	BindingConstraint        string            `json:"binding_constraint,omitempty"`
+	CostPerHour              float64           `json:"cost_per_hour,omitempty"`
+	CostPerMonth             float64           `json:"cost_per_month,omitempty"`
+	CloudInstances           []jsonCloudOption `json:"cloud_instances,omitempty"`
+	PerLayer                 *jsonPerLayer     `json:"per_layer,omitempty"`
+	MicroBatch               int               `json:"micro_batch,omitempty"`
+	GradAccum                int               `json:"grad_accum,omitempty"`
+	GlobalBatch              int               `json:"global_batch,omitempty"`
+	ContextTokens            int               `json:"context_tokens,omitempty"`
+	KVCacheBytesPerToken     int               `json:"kv_cache_bytes_per_token,omitempty"`
+	PrewarmBuffers           string            `json:"prewarm_buffers,omitempty"`
+	MemSizeAtStartup         string            `json:"mem_size_at_startup,omitempty"`
+	DiskSize                 string            `json:"disk_size,omitempty"`
+	DiskBytes                int               `json:"disk_bytes,omitempty"`
+	HostRAM                  *jsonHostRAM      `json:"host_ram,omitempty"`
+	TotalParameters          int               `json:"total_parameters,omitempty"`
+	ActiveParameters         int               `json:"active_parameters,omitempty"`
+	ActiveWeights            string            `json:"active_weights,omitempty"`
+	Offloaded                map[string]string `json:"offloaded,omitempty"`
+	Stages                   []jsonStage       `json:"stages,omitempty"`
+	Cluster                  *jsonCluster      `json:"cluster,omitempty"`
+	Inputs                   map[string]string `json:"inputs,omitempty"`
+	Explanation              []string          `json:"explanation,omitempty"`
+	EstimatedTokensPerSecond float64           `json:"estimated_tokens_per_second,omitempty"`
+	Detected                 *jsonDetected     `json:"detected,omitempty"`
+	KVPool                   *jsonKVPool       `json:"kv_pool,omitempty"`
+	LlamaCpp                 *jsonLlamaCpp     `json:"llama_cpp,omitempty"`
+}
+
 // rootCmd represents the base command identified by the 'Use' attribute
 // when called without any subcommands. This name should be used in any
 // build scripts.
@@ -122,98 +360,603 @@ overhead to calculate the estimated gpu memory required
 to run the model. 
 
 For example:
-./gpu-mem-for-llm --size 7b --fp16 --overhead 30
+./gpu-mem-for-llm --size 7b --precision fp16 --overhead 30
 
 Flag details:
    --size: Specifies the size of the model parameters (e.g., "7b" for 7 billion). 
            This flag is required.
-   --fp32 | --fp16 | --bf16 | --int8 | --int4: These flags indicate the precision 
-           used during training and determine the memory requirement. 
-           Only one of them can be specified at a time.
+   --precision: Specifies the precision of the weights (e.g., "fp16" or "int4"), which 
+           determines the memory requirement. The boolean flags such as --fp16 are 
+           deprecated in its favor.
    --overhead: This flag specifies an optional overhead percentage as an integer 
            (e.g., "30" for 30%). 
            The default value is 20% if not provided.
 `,
 	Version: appVersion,
+	// The settings of the environment and the config file, the backend and the unit of
+	// memory sizes apply to every subcommand
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLogging(cmd.ErrOrStderr()); err != nil {
+			return err
+		}
+		// Environment variables are applied before the config file so they take precedence
+		// over it, while neither overrides a flag given on the command line. The schema
+		// doesn't depend on any setting.
+		commandLineFlags = make(map[string]bool)
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Changed {
+				commandLineFlags[flag.Name] = true
+			}
+		})
+		if takesSettings(cmd) && (cmd.HasParent() || !printSchema) {
+			if err := applyEnv(cmd); err != nil {
+				return err
+			}
+			if err := loadConfig(cmd); err != nil {
+				return err
+			}
+		}
+		if err := selectBackend(backend); err != nil {
+			return err
+		}
+		var err error
+		memoryUnit, err = estimator.ParseMemoryUnit(unit)
+		return err
+	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		return checkMutuallyExclusivePrecisionFlags(cmd)
+		// The schema doesn't depend on any setting, so it starts from the wizard's size and
+		// precision only to satisfy the required flags
+		if printSchema {
+			if err := cmd.Flags().Set("size", wizardDefaults.Size); err != nil {
+				return err
+			}
+			return cmd.Flags().Set("precision", wizardDefaults.Precision)
+		}
+		// The Hugging Face model fills in whatever the flags, the environment and the config
+		// file left, none of which it overrides
+		if err := applyHFModel(cmd); err != nil {
+			return err
+		}
+		if err := applyModelDir(cmd); err != nil {
+			return err
+		}
+		if err := applyRegistryModel(cmd); err != nil {
+			return err
+		}
+		if err := applyArchitectureSize(cmd); err != nil {
+			return err
+		}
+		// Run in a terminal with nothing set at all, the wizard asks for the settings
+		// instead of reporting each one missing. It starts from the same size and
+		// precision it suggests, which also satisfies the required flags.
+		startWizard = cmd.Flags().NFlag() == 0 && isTerminal(cmd.InOrStdin())
+		if startWizard {
+			if err := cmd.Flags().Set("size", wizardDefaults.Size); err != nil {
+				return err
+			}
+			return cmd.Flags().Set("precision", wizardDefaults.Precision)
+		}
+		if err := checkMutuallyExclusivePrecisionFlags(cmd); err != nil {
+			return err
+		}
+		return checkRequiredSettings(cmd)
 	},
-	Run: func(cmd *cobra.Command, args []string) {
-		parameterSize, err := getParameterSize(size)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Anything failing from here on is a bad value rather than bad usage
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		if printSchema {
+			_, err := out.Write(outputSchema)
+			return err
+		}
+		if startWizard {
+			runWizard(cmd.InOrStdin(), out)
+			return nil
+		}
+
+		spec, err := buildEstimateSpec(cmd)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return err
+		}
+		if len(spec.sizes) > 1 {
+			return writeSizeComparison(out, spec.format, spec.input, spec.sizes)
+		}
+		if spec.sweep != nil {
+			return writeSweep(out, spec.format, modelLabel(spec.sizeValue), spec.input, *spec.sweep)
 		}
 
-		precision, err := getPrecision()
+		r, err := newEstimateReport(cmd, out, spec)
 		if err != nil {
-			fmt.Println(err)
-			return
+			return err
+		}
+		if pushgatewayURL != "" {
+			metrics := prometheusMetrics(r.result.Total*r.result.GPUs, r.gpuMemoryBytes, r.fits)
+			if err := pushMetrics(pushgatewayURL, metrics); err != nil {
+				return err
+			}
 		}
 
-		requiredMemory := calculateRequiredMemory(parameterSize, precision, float32(overhead))
+		// Quiet output is only the total, so it can be captured by a script
+		if quiet {
+			if bytesOutput {
+				fmt.Fprintln(out, r.result.Total*r.result.GPUs)
+			} else {
+				fmt.Fprintln(out, formatMemory(r.result.Total*r.result.GPUs))
+			}
+			return r.checkFit()
+		}
 
-		if jsonOutput {
-			output := map[string]string{
-				"mem_size": formatMemory(requiredMemory),
+		if tabularFormats[spec.format] {
+			if err := writeTable(out, spec.format, []estimateRow{{
+				Model:      modelLabel(spec.sizeValue),
+				Precision:  precisionName(),
+				Context:    spec.contextTokens,
+				GPUs:       r.result.GPUs,
+				Components: jsonComponents(r.result),
+				MemBytes:   r.result.Total * r.result.GPUs,
+			}}); err != nil {
+				return err
 			}
-			jsonData, err := json.Marshal(output)
-			if err != nil {
-				fmt.Println("Error generating JSON:", err)
-				return
+			return r.checkFit()
+		}
+
+		if spec.format != "text" {
+			if err := writeStructured(out, spec.format, r.jsonEstimate(cmd)); err != nil {
+				return err
 			}
-			fmt.Println(string(jsonData))
 		} else {
-			fmt.Printf("Estimated memory required: %s\n", formatMemory(requiredMemory))
+			r.print(out)
 		}
+		return r.checkFit()
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
+	os.Exit(run(rootCmd, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command with the given arguments, writing its output and errors to
+// the given streams rather than the process's own, and returns the exit status. Errors
+// are written here rather than by cobra, so they can be written as JSON.
+func run(cmd *cobra.Command, args []string, out, errOut io.Writer) int {
+	cmd.SetArgs(args)
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	registerFlagCompletions(cmd)
+	executed, err := cmd.ExecuteC()
+	if err == nil {
+		return 0
 	}
+	code := exitCode(err)
+	writeError(errOut, flagError(executed, err), code, executed.CommandPath())
+	return code
 }
 
 var (
 	// flags
-	fp32       bool
-	fp16       bool
-	bf16       bool
-	int8       bool
-	int4       bool
-	overhead   int
-	size       string
-	jsonOutput bool
+	unit              string
+	precisionValue    string
+	fp32              bool
+	fp16              bool
+	bf16              bool
+	fp8               bool
+	fp8Scaling        string
+	int8              bool
+	int4              bool
+	gptq              bool
+	awq               bool
+	nf4               bool
+	groupSize         int
+	quant             string
+	bytesPerParam     float64
+	bitsPerWeight     float64
+	overhead          int
+	size              string
+	totalParams       string
+	activeParams      string
+	experts           int
+	activeExperts     int
+	jsonOutput        bool
+	outputFormat      string
+	templateText      string
+	templateFile      string
+	jsonIncludeInputs bool
+	printSchema       bool
+	uncertainty       float64
+	pushgatewayURL    string
+	quiet             bool
+	bytesOutput       bool
+	hiddenDim         int
+	vocabSize         int
+	numLayers         int
+
+	tiedEmbeddings   bool
+	foldNorms        bool
+	streamWeights    bool
+	doubleBuffer     bool
+	intermediateSize int
+	perLayer         bool
+
+	// set when the size was derived from the architecture flags rather than given
+	sizeFromArchitecture bool
+
+	// set when run in a terminal without any settings, to start the wizard
+	startWizard bool
+
+	// the flags given on the command line, before the environment and config file
+	commandLineFlags map[string]bool
+
+	// kv cache
+	contextLength   int
+	sweepContext    string
+	sweepBatch      string
+	reportMinMax    bool
+	batchSize       int
+	kvBlockSize     int
+	kvUtilization   float64
+	slidingWindow   int
+	roundContext    bool
+	noActivations   bool
+	kvDtype         string
+	maxPositions    int
+	ropeScaling     float64
+	prewarm         bool
+	disk            bool
+	showHostRAM     bool
+	loadPrecision   string
+	mmapCheckpoint  bool
+	hostMemory      string
+	maxContext      int
+	maxBatch        int
+	kvBuckets       string
+	promptChars     int
+	charsPerToken   float64
+	headDim         int
+	heads           int
+	kvHeads         int
+	headDimMultiple int
+
+	// a/b testing
+	abPrecision string
+
+	embedPrecision string
+	headPrecision  string
+
+	// sparsity
+	sparsity       string
+	sparseFraction float64
+
+	// parallelism
+	tensorParallel      int
+	replicateEmbeddings bool
+	pipelineParallel    int
+	nodes               int
+	gpusPerNode         int
+	zeroStage           int
+	fsdp                string
+	fsdpShardDegree     int
+	offloadOptimizer    bool
+
+	// serving framework
+	framework            string
+	gpuMemoryUtilization float64
+	swapSpace            float64
+	enforceEager         bool
+	cudaMemoryFraction   float64
+	kvCacheFreeFraction  float64
+	maxNumTokens         int
+
+	// hardware and cost
+	gpuMemory    string
+	gpu          string
+	gpuDB        string
+	pricePerHour float64
+	cost         bool
+	costDB       string
+	detect       bool
+	vendor       string
+
+	// throughput planning
+	bandwidth             float64
+	targetTokensPerSecond float64
+	tokensPerSecondPerGPU float64
+
+	// lora fine-tuning
+	mode                  string
+	gradientCheckpointing bool
+	optimizerName         string
+	microBatch            int
+	gradAccum             int
+	pureBF16              bool
+	loraRank              int
+	loraTargets           int
+
+	// speculative decoding
+	layerSkip      bool
+	draftTokens    int
+	draftModelName string
+	draftSize      string
+	draftPrecision string
+
+	visionParams    string
+	projectorParams string
+	visionPrecision string
+	imageTokens     int
+	images          int
+
+	medusaHeads      int
+	medusaTreeTokens int
+
+	indexVectors   int
+	indexDim       int
+	indexPrecision string
+
+	// rounding
+	roundTo string
+	maxVRAM string
+
+	// utilization thresholds
+	warnAt    string
+	colorMode string
+
+	// logging
+	verbose   bool
+	logFormat string
+
+	capacity  bool
+	breakdown bool
+
+	// output
+	explain bool
+
+	// config
+	configFile string
+	profile    string
+
+	// model registry
+	registryModelName string
+	modelDB           string
+
+	// hugging face hub
+	hfModel   string
+	hfCache   string
+	hfOffline bool
+
+	// local model directory
+	modelDir string
+
+	// estimator producing the estimate
+	backend string
 
 	// versioning
 	appVersion string = "0.1.0"
 )
 
 func init() {
-	// Define a flag for the parameter size of the model in millions (m) or billions (b)
-	rootCmd.Flags().StringVarP(&size, "size", "s", "", "model parameter size (e.g., 7b) - required")
-	rootCmd.MarkFlagRequired("size")
-
-	// Define a flag group for all these precision values - fp32, fp16, bf16, int8, int4100M
-	// eg. --fp32, --fp16, --bf16, --int8, --int4
-	// each of them is a boolean flag
-	// only one of them can be provided at any given time.
-	rootCmd.Flags().BoolVar(&fp32, "fp32", false, "use fp32 precision")
-	rootCmd.Flags().BoolVar(&fp16, "fp16", false, "use fp16 precision")
-	rootCmd.Flags().BoolVar(&bf16, "bf16", false, "use bf16 precision")
-	rootCmd.Flags().BoolVar(&int8, "int8", false, "use int8 precision")
-	rootCmd.Flags().BoolVar(&int4, "int4", false, "use int4 precision")
-	rootCmd.MarkFlagsOneRequired("fp32", "fp16", "bf16", "int8", "int4")
+	// Define a flag for the parameter size of the model, such as 7b or 1.5b
+	rootCmd.Flags().VarP(&sizeListValue{value: &size}, "size", "s", "model parameter size (e.g., 7b, 1.5b or 1.8t), repeated or separated by commas to compare several - required")
+	rootCmd.Flags().StringVar(&totalParams, "total-params", "", "total parameters of a mixture-of-experts model, in place of --size (e.g., 47b)")
+	rootCmd.Flags().StringVar(&activeParams, "active-params", "", "parameters of a mixture-of-experts model used for each token (e.g., 13b)")
+	rootCmd.Flags().IntVar(&experts, "experts", 0, "number of experts in each layer of a mixture-of-experts model (e.g., 8)")
+	rootCmd.Flags().IntVar(&activeExperts, "active-experts", 0, "number of experts each token is routed to, deriving --active-params from the architecture (e.g., 2)")
+	rootCmd.MarkFlagsRequiredTogether("experts", "active-experts")
+	rootCmd.MarkFlagsMutuallyExclusive("active-experts", "active-params")
+	rootCmd.MarkFlagsOneRequired("size", "total-params")
+	rootCmd.Flags().SetNormalizeFunc(normalizeArchitectureFlags)
+	rootCmd.MarkFlagsMutuallyExclusive("size", "total-params")
+
+	addPrecisionFlags(rootCmd)
+	rootCmd.Flags().StringVar(&fp8Scaling, "fp8-scaling", "", "include the scales stored with --fp8 weights, either tensor or channel wise (requires --num-layers and --hidden-dim)")
 
 	// Define a flag for the overhead
 	rootCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
 
+	// Define flags for the model architecture, needed by the terms that depend on
+	// more than the parameter count
+	rootCmd.Flags().IntVar(&hiddenDim, "hidden-dim", 0, "model hidden dimension (e.g., 4096)")
+	rootCmd.Flags().IntVar(&vocabSize, "vocab-size", 0, "model vocabulary size (e.g., 32000)")
+	rootCmd.Flags().IntVar(&numLayers, "num-layers", 0, "number of transformer layers (e.g., 32)")
+	rootCmd.Flags().BoolVar(&tiedEmbeddings, "tied-embeddings", false, "the LM head shares its weights with the embedding table")
+	rootCmd.Flags().BoolVar(&foldNorms, "fold-norms", false, "the runtime folds the norms into the adjacent linear layers instead of keeping them separately")
+	rootCmd.Flags().BoolVar(&streamWeights, "stream-weights", false, "stream the layer weights from host memory, keeping one layer resident (requires --num-layers)")
+	rootCmd.Flags().BoolVar(&doubleBuffer, "double-buffer", false, "with --stream-weights, keep the next layer resident as well to overlap transfers with compute")
+	rootCmd.Flags().IntVar(&intermediateSize, "intermediate-size", 0, "MLP intermediate size (e.g., 11008)")
+	rootCmd.Flags().BoolVar(&perLayer, "per-layer", false, "show the memory of each transformer block derived from the architecture (requires --num-layers, --hidden-dim and --intermediate-size)")
+
+	// Define flags for the KV cache, which is only included when a context length is given.
+	// Paged allocators hand out the cache in blocks of tokens, so the context can be
+	// rounded up to a whole number of blocks.
+	rootCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the KV cache for (requires --num-layers and --hidden-dim)")
+	rootCmd.Flags().StringVar(&sweepContext, "sweep-context", "", "estimate at each context length of a range doubling from the start (e.g., 2k:128k), a range with a step (e.g., 4k:32k:4k) or a list (e.g., 4k,8k,32k)")
+	rootCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	rootCmd.Flags().StringVar(&sweepBatch, "sweep-batch", "", "estimate at each batch size of a range doubling from the start (e.g., 1:64), a range with a step (e.g., 8:64:8) or a list (e.g., 1,8,32) at a fixed --context")
+	rootCmd.MarkFlagsMutuallyExclusive("sweep-context", "sweep-batch")
+	rootCmd.Flags().BoolVar(&reportMinMax, "report-min-max", false, "end a sweep with the least and most memory and the values giving them")
+	rootCmd.Flags().IntVar(&kvBlockSize, "kv-block-size", 16, "tokens per KV cache block used by paged attention")
+	rootCmd.Flags().BoolVar(&roundContext, "round-context", false, "round the context up to a multiple of --kv-block-size before sizing the KV cache")
+	rootCmd.Flags().Float64Var(&kvUtilization, "kv-utilization", 1, "share of the paged KV cache's blocks expected to hold tokens, the rest lost to fragmentation and free blocks kept by the scheduler (e.g., 0.85)")
+	rootCmd.Flags().IntVar(&slidingWindow, "sliding-window", 0, "sliding attention window in tokens, capping the context kept in the KV cache (e.g., 4096 for Mistral 7B)")
+	rootCmd.Flags().BoolVar(&noActivations, "no-activations", false, "leave out the activations of prefilling the context, keeping only the weights and KV cache")
+	rootCmd.Flags().IntVar(&maxPositions, "max-positions", 0, "positions the model was trained for, to size the precomputed RoPE buffer (requires --head-dim)")
+	rootCmd.Flags().Float64Var(&ropeScaling, "rope-scaling", 1, "RoPE scaling factor such as YaRN or NTK extending --max-positions (e.g., 4)")
+	rootCmd.Flags().StringVar(&kvDtype, "kv-dtype", "", "precision or quantized type of the KV cache when it differs from the weights (e.g., fp8, int8 or q4_0)")
+	rootCmd.Flags().BoolVar(&breakdown, "breakdown", false, "show a table of every component with its bytes and share of the total")
+	rootCmd.Flags().BoolVar(&capacity, "capacity", false, "report how many sequences of --context tokens fit at once in the memory of each GPU")
+	rootCmd.Flags().BoolVar(&disk, "disk", false, "also report the size of the model files on disk at the precision, for planning storage and downloads")
+	rootCmd.Flags().BoolVar(&showHostRAM, "host-ram", false, "also report the peak host RAM needed to load the model, warning when it is more than the host has")
+	rootCmd.Flags().StringVar(&loadPrecision, "load-precision", "", "with --host-ram, precision of the checkpoint when it is quantized as it is loaded (e.g., fp16 for bitsandbytes)")
+	rootCmd.Flags().BoolVar(&mmapCheckpoint, "mmap", false, "with --host-ram, the checkpoint is memory-mapped, as llama.cpp does with GGUF files")
+	rootCmd.Flags().StringVar(&hostMemory, "host-memory", "", "with --host-ram, system memory of the host (e.g., 64gb; default detected)")
+	rootCmd.Flags().BoolVar(&prewarm, "prewarm", false, "report the KV cache pre-allocated at startup for --max-context and --max-batch separately")
+	rootCmd.Flags().IntVar(&maxContext, "max-context", 0, "maximum context length in tokens the framework pre-allocates for with --prewarm")
+	rootCmd.Flags().IntVar(&maxBatch, "max-batch", 0, "maximum number of sequences the framework pre-allocates for with --prewarm (default --batch-size)")
+	rootCmd.Flags().StringVar(&kvBuckets, "kv-buckets", "", "size the KV cache for the largest of these scheduler buckets instead of --context (e.g., 8x2048,32x512)")
+	rootCmd.Flags().IntVar(&promptChars, "prompt-chars", 0, "size the KV cache for a prompt of this many characters instead of --context")
+	rootCmd.Flags().Float64Var(&charsPerToken, "chars-per-token", 4, "average number of characters per token used with --prompt-chars")
+	rootCmd.Flags().IntVar(&headDim, "head-dim", 0, "attention head dimension, padded to --head-dim-multiple in the KV cache (e.g., 128)")
+	rootCmd.Flags().IntVar(&heads, "heads", 0, "number of attention heads, setting --head-dim to the hidden dimension divided by it (requires --hidden-dim)")
+	rootCmd.MarkFlagsMutuallyExclusive("head-dim", "heads")
+	rootCmd.Flags().IntVar(&kvHeads, "kv-heads", 0, "number of key/value heads with grouped-query or multi-query attention, sizing the KV cache (e.g., 8; requires --head-dim or --heads)")
+	rootCmd.Flags().IntVar(&headDimMultiple, "head-dim-multiple", 0, "multiple the attention kernels pad the head dimension to (e.g., 64 or 128)")
+
+	// Define a flag for loading a second copy of the model at another precision, such as
+	// an fp16 and an int4 copy evaluated side by side
+	rootCmd.Flags().StringVar(&abPrecision, "ab-precision", "", "also load a copy of the weights at this precision for A/B testing (e.g., int4 or Q4_K_M)")
+
+	// Define flags for keeping the embeddings and LM head at their own precision, as
+	// quantized checkpoints often do
+	rootCmd.Flags().StringVar(&embedPrecision, "embed-precision", "", "precision of the embedding table when it differs from the weights (e.g., fp16 or Q6_K; requires --vocab-size and --hidden-dim)")
+	rootCmd.Flags().StringVar(&headPrecision, "head-precision", "", "precision of the LM head when it differs from the weights (e.g., fp16 or Q6_K; requires --vocab-size and --hidden-dim)")
+
+	// Define flags for structured sparsity, where only N of every M weights are stored
+	// for the sparse fraction of the model
+	rootCmd.Flags().StringVar(&sparsity, "sparsity", "", "structured sparsity pattern for the weights (e.g., 2:4)")
+	rootCmd.Flags().Float64Var(&sparseFraction, "sparse-fraction", 1, "fraction of the weights stored with --sparsity")
+
+	// Define a flag for tensor parallelism, reporting the memory each GPU needs when the
+	// model is sharded across several of them
+	rootCmd.Flags().IntVar(&tensorParallel, "tensor-parallel", 1, "number of GPUs to shard the model across with tensor parallelism")
+	rootCmd.Flags().IntVar(&tensorParallel, "gpus", 1, "same as --tensor-parallel")
+	rootCmd.MarkFlagsMutuallyExclusive("tensor-parallel", "gpus")
+	rootCmd.Flags().IntVar(&zeroStage, "zero", 0, "DeepSpeed ZeRO stage (1, 2 or 3) partitioning the training state across --gpus data parallel ranks")
+	rootCmd.MarkFlagsMutuallyExclusive("zero", "tensor-parallel")
+	rootCmd.Flags().StringVar(&fsdp, "fsdp", "", "PyTorch FSDP sharding strategy sharding the training state across --gpus ranks ("+strings.Join(estimator.FSDPStrategies, ", ")+")")
+	rootCmd.Flags().IntVar(&fsdpShardDegree, "fsdp-shard-degree", 0, "number of GPUs in each sharding group with --fsdp hybrid-shard (e.g., 8 for a node)")
+	rootCmd.MarkFlagsMutuallyExclusive("fsdp", "zero", "tensor-parallel")
+	rootCmd.Flags().BoolVar(&offloadOptimizer, "offload-optimizer", false, "when training, keep the optimizer states in host memory instead of on the GPU")
+	rootCmd.Flags().IntVar(&pipelineParallel, "pipeline-parallel", 1, "number of pipeline stages to split the layers across, each on its own --tensor-parallel GPUs (requires --num-layers and --hidden-dim)")
+	rootCmd.Flags().IntVar(&nodes, "nodes", 0, "number of nodes in the cluster, reporting the memory per node and in total across the replicas of the model they hold")
+	rootCmd.Flags().IntVar(&gpusPerNode, "gpus-per-node", 8, "with --nodes, number of GPUs in each node")
+	rootCmd.Flags().BoolVar(&replicateEmbeddings, "replicate-embeddings", false, "the framework replicates the embeddings on each GPU instead of sharding them with --tensor-parallel")
+
+	// Define a flag for the serving framework, whose preset replaces the default overhead
+	// and KV cache layout unless they are set explicitly
+	rootCmd.Flags().StringVar(&framework, "framework", "", "serving framework preset for overhead and KV cache layout ("+strings.Join(frameworkNames(), ", ")+")")
+	rootCmd.Flags().Float64Var(&gpuMemoryUtilization, "gpu-memory-utilization", 0.9, "with --framework vllm, fraction of each GPU's memory vLLM claims")
+	rootCmd.Flags().Float64Var(&swapSpace, "swap-space", 4, "with --framework vllm, GiB of host memory per GPU for swapping out KV cache blocks")
+	rootCmd.Flags().BoolVar(&enforceEager, "enforce-eager", false, "with --framework vllm, run without CUDA graphs and the memory they take")
+	rootCmd.Flags().Float64Var(&cudaMemoryFraction, "cuda-memory-fraction", 1, "with --framework tgi, fraction of each GPU's memory TGI claims")
+	rootCmd.Flags().Float64Var(&kvCacheFreeFraction, "kv-cache-free-gpu-memory-fraction", 0.9, "with --framework tensorrt-llm, fraction of the memory the engine leaves free that goes to the KV cache")
+	rootCmd.Flags().IntVar(&maxNumTokens, "max-num-tokens", frameworkPresets["tensorrt-llm"].ActivationTokens, "with --framework tensorrt-llm, tokens per batch the engine's activation buffers are built for")
+
+	// Define flags for the GPUs the model is deployed on, used to work out how many are
+	// needed and what they cost to run
+	rootCmd.Flags().StringVar(&gpuMemory, "gpu-memory", "", "memory of each GPU (e.g., 80gb) to calculate the number of GPUs required")
+	rootCmd.Flags().StringVar(&gpu, "gpu", "", "GPU model from the built-in database (e.g., rtx4090) to check the fit against")
+	rootCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for --gpu, such as {\"my-card\": {\"memory\": \"48gb\", \"bandwidth\": 960}}")
+	rootCmd.MarkFlagsMutuallyExclusive("gpu-memory", "gpu")
+	rootCmd.Flags().BoolVar(&detect, "detect", false, "detect the local GPUs, or the unified memory of a Mac, and check the fit against their free memory")
+	rootCmd.Flags().StringVar(&vendor, "vendor", "auto", "vendor of the GPUs --detect looks for (auto, "+strings.Join(gpuVendors, ", ")+"), needed when both are present")
+	rootCmd.MarkFlagsMutuallyExclusive("detect", "gpu")
+	rootCmd.MarkFlagsMutuallyExclusive("detect", "gpu-memory")
+	rootCmd.Flags().Float64Var(&pricePerHour, "price-per-hour", 0, "price of one GPU per hour to estimate the hourly and monthly cost")
+	rootCmd.Flags().BoolVar(&cost, "cost", false, "list the cheapest cloud instances that fit the estimate with their hourly and monthly cost")
+	rootCmd.Flags().StringVar(&costDB, "cost-db", "", "JSON file of additional cloud instances for --cost, such as {\"my-node\": {\"provider\": \"on-prem\", \"gpu\": \"h100\", \"gpus\": 8, \"price_per_hour\": 20}}")
+
+	// Define a flag for the memory bandwidth of each GPU, used for a rough decode speed
+	rootCmd.Flags().Float64Var(&bandwidth, "bandwidth", 0, "memory bandwidth of each GPU in GB/s for a rough decode tokens/s estimate")
+
+	// Define flags for planning against a throughput target, which is cross-checked with
+	// the number of GPUs needed to hold the model
+	rootCmd.Flags().Float64Var(&targetTokensPerSecond, "target-tokens-per-second", 0, "throughput target to size the number of GPUs for (requires --gpu-memory and --tokens-per-second-per-gpu)")
+	rootCmd.Flags().Float64Var(&tokensPerSecondPerGPU, "tokens-per-second-per-gpu", 0, "throughput each GPU is assumed to deliver")
+
+	// Define flags for LoRA fine-tuning. Setting a rank switches the estimate from serving
+	// to training the adapters on top of the frozen base weights.
+	rootCmd.Flags().StringVar(&mode, "mode", "inference", "estimate memory for inference or full fine-tuning with train")
+	rootCmd.Flags().StringVar(&optimizerName, "optimizer", "adamw", "optimizer whose states are kept when training ("+strings.Join(estimator.Optimizers, ", ")+")")
+	rootCmd.Flags().IntVar(&microBatch, "micro-batch", 0, "when training, sequences each GPU runs through a forward and backward pass at once, in place of --batch-size")
+	rootCmd.MarkFlagsMutuallyExclusive("micro-batch", "batch-size")
+	rootCmd.Flags().IntVar(&gradAccum, "grad-accum", 1, "when training, micro-batches whose gradients are accumulated for each optimizer step, splitting --batch-size into micro-batches when --micro-batch isn't given")
+	rootCmd.Flags().BoolVar(&pureBF16, "pure-bf16", false, "when training in bf16, update the bf16 weights directly with bf16 optimizer states instead of an fp32 master copy")
+	rootCmd.Flags().BoolVar(&gradientCheckpointing, "gradient-checkpointing", false, "when training, keep activations only at every sqrt(layers) layers and recompute the rest")
+	rootCmd.Flags().IntVar(&loraRank, "lora-rank", 0, "estimate LoRA fine-tuning memory with adapters of this rank (requires --num-layers and --hidden-dim)")
+	rootCmd.Flags().IntVar(&loraTargets, "lora-targets", 4, "number of projections per layer that get LoRA adapters")
+
+	// Define flags for LayerSkip self-speculation, where the early layers of the model
+	// act as the draft and exit through the shared LM head
+	rootCmd.Flags().BoolVar(&layerSkip, "layer-skip", false, "include early-exit head memory for LayerSkip self-speculation (requires --hidden-dim and --vocab-size)")
+	rootCmd.Flags().IntVar(&draftTokens, "draft-tokens", 4, "number of tokens drafted per speculation step")
+
+	// Define flags for a separate draft model proposing tokens for the target model, both
+	// kept resident with a KV cache of their own
+	rootCmd.Flags().StringVar(&draftModelName, "draft-model", "", "well-known draft model for speculative decoding, resident alongside the target (e.g., llama3.2-1b)")
+	rootCmd.Flags().StringVar(&draftSize, "draft-size", "", "size of the draft model for speculative decoding (e.g., 1b)")
+	rootCmd.Flags().StringVar(&draftPrecision, "draft-precision", "", "precision of the draft model (default the precision of the target)")
+	rootCmd.Flags().Int("draft-num-layers", 0, "number of layers of the draft model, to size its KV cache")
+	rootCmd.Flags().Int("draft-hidden-dim", 0, "hidden dimension of the draft model, to size its KV cache")
+	rootCmd.Flags().Int("draft-head-dim", 0, "attention head dimension of the draft model")
+	rootCmd.Flags().Int("draft-kv-heads", 0, "number of key/value heads of the draft model (requires --draft-head-dim)")
+
+	// Define flags for the vision tower of a vision-language model, whose image tokens
+	// take their place in the context of the language model
+	rootCmd.Flags().StringVar(&visionParams, "vision-params", "", "parameters of the vision encoder of a vision-language model (e.g., 300m)")
+	rootCmd.Flags().StringVar(&projectorParams, "projector-params", "", "parameters of the projector mapping image features to tokens (e.g., 21m)")
+	rootCmd.Flags().StringVar(&visionPrecision, "vision-precision", "", "precision of the vision encoder and projector (default the precision of the language model)")
+	rootCmd.Flags().IntVar(&imageTokens, "image-tokens", 576, "tokens each image adds to the context")
+	rootCmd.Flags().IntVar(&images, "images", 0, "images in each sequence, whose tokens are added to the context")
+	rootCmd.Flags().IntVar(&medusaHeads, "medusa-heads", 0, "include Medusa speculative decoding with this many heads (requires --num-layers, --hidden-dim and --vocab-size)")
+	rootCmd.Flags().IntVar(&medusaTreeTokens, "medusa-tree-tokens", 64, "number of candidate tokens in the Medusa tree verified in one forward pass")
+	rootCmd.Flags().IntVar(&indexVectors, "index-vectors", 0, "number of vectors in a retrieval index kept in GPU memory alongside the model (requires --index-dim)")
+	rootCmd.Flags().IntVar(&indexDim, "index-dim", 0, "dimension of the retrieval index vectors (e.g., 768)")
+	rootCmd.Flags().StringVar(&indexPrecision, "index-precision", "fp32", "precision of the retrieval index vectors (e.g., fp16)")
+
+	// Define a flag to round the total up to the granularity memory is allocated in
+	rootCmd.Flags().StringVar(&roundTo, "round-to", "", "round the required memory up to a multiple of this size (e.g., 1gb)")
+	rootCmd.Flags().StringVar(&maxVRAM, "max-vram", "", "memory budget of each GPU (e.g., 24gb), exiting with a non-zero status when the estimate exceeds it")
+	rootCmd.Flags().StringVar(&warnAt, "warn-at", "", "with --gpu, --gpu-memory or --max-vram, color the estimate by the share of each GPU it uses and warn from this share on (e.g., 90%)")
+	rootCmd.Flags().StringVar(&colorMode, "color", "auto", "color the output: auto when it is a terminal and NO_COLOR is unset, always or never")
+
+	// Define a flag to show the arithmetic behind the estimate
+	rootCmd.Flags().BoolVar(&explain, "explain", false, "show the formula behind each term with the actual values substituted")
+
 	// Define a flag for JSON output
 	rootCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.Flags().StringVar(&outputFormat, "output", "", "output format ("+strings.Join(outputFormats, ", ")+"), --json being the same as json (default text)")
+	rootCmd.MarkFlagsMutuallyExclusive("json", "output")
+	rootCmd.Flags().StringVar(&templateText, "template", "", "Go text/template executed against the JSON result to print it in any form (e.g., 'nvidia.com/gpu-memory: {{gi .mem_bytes}}')")
+	rootCmd.Flags().StringVar(&templateFile, "template-file", "", "file holding a template for --template")
+	rootCmd.MarkFlagsMutuallyExclusive("template", "template-file", "json", "output")
+	// The unit is persistent so the subcommands format their sizes in it as well
+	rootCmd.PersistentFlags().StringVar(&unit, "unit", "auto", "unit of memory sizes ("+strings.Join(estimator.MemoryUnitNames, ", ")+"), auto picking a decimal unit by size")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "log the steps of the estimate and the sources of its settings, such as the registry, the Hugging Face Hub and GPU detection, to stderr")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "format of the logs on stderr: text, or json for log pipelines")
+	rootCmd.Flags().BoolVar(&jsonIncludeInputs, "json-include-inputs", false, "with --json, include the resolved value of every flag under \"inputs\"")
+	rootCmd.Flags().BoolVar(&printSchema, "schema", false, "print the JSON Schema of the --json and --output yaml estimate and exit")
+	rootCmd.Flags().Float64Var(&uncertainty, "uncertainty", 0, "percentage the overhead and KV cache may be off by, to report a low to high range (e.g., 25)")
+	rootCmd.Flags().StringVar(&pushgatewayURL, "pushgateway-url", "", "push the estimate as Prometheus metrics to this Pushgateway (e.g., http://localhost:9091)")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "print only the estimated memory required")
+	rootCmd.Flags().BoolVar(&bytesOutput, "bytes", false, "with --quiet, print the estimate as a raw number of bytes")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "json")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "output")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "template")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "template-file")
+
+	// Define a flag for the config file supplying defaults for the other flags, persistent
+	// for every subcommand
+	rootCmd.PersistentFlags().StringVar(&configFile, "config-file", "", "config file with default flag values (default $XDG_CONFIG_HOME/"+userConfigFile+" or $HOME/"+defaultConfigFile+")")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "same as --config-file")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "named profile of the config file whose settings replace its defaults (e.g., prod-h100)")
+
+	// Define flags for taking the size and architecture from a model of the built-in registry
+	rootCmd.Flags().StringVar(&registryModelName, "model", "", "well-known model to take the size and architecture from (e.g., llama3.1-8b or mixtral-8x7b)")
+	rootCmd.Flags().StringVar(&modelDB, "model-db", "", "JSON file of additional models for --model, such as {\"my-model\": {\"parameters\": \"7b\", \"num_layers\": 32}}")
+
+	// Define flags for deriving the size and architecture from a model on the Hugging Face Hub
+	rootCmd.Flags().StringVar(&hfModel, "hf-model", "", "Hugging Face model ID to derive the size, precision and architecture from (e.g., meta-llama/Llama-3.1-8B)")
+	rootCmd.Flags().StringVar(&hfCache, "hf-cache-dir", "", "directory the files fetched for --hf-model are cached in (default the user cache directory)")
+	rootCmd.Flags().BoolVar(&hfOffline, "hf-offline", false, "read the files for --hf-model from the cache instead of the Hub")
+	rootCmd.Flags().StringVar(&modelDir, "model-dir", "", "local directory of a model in the Hugging Face format to derive the size, precision and architecture from its config.json, without the Hub")
+	rootCmd.MarkFlagsMutuallyExclusive("model", "hf-model", "model-dir")
+
+	// Define a flag for selecting another estimator, persistent so every subcommand
+	// estimates with it
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", estimator.BuiltinBackend, "estimator producing the estimate: builtin, another registered one, or exec: followed by a program answering the JSON protocol (e.g., exec:./fitted-model)")
 
 	// Define a flag for version
 	rootCmd.Flags().BoolP("version", "v", false, "Print the version number")
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests with their output")
+
+// resetFlags returns every flag of the command and its subcommands to its default value
+// and marks it as not given, along with the settings taken from the config file and the
+// unit of memory sizes, so each run of the command starts afresh
+func resetFlags(t *testing.T, cmd *cobra.Command) {
+	t.Helper()
+	// The subcommands go first, so the command's own defaults win for the variables their
+	// flags share
+	for _, sub := range cmd.Commands() {
+		resetFlags(t, sub)
+	}
+	reset := func(f *pflag.Flag) {
+		if err := resetFlag(f); err != nil {
+			t.Fatalf("resetting --%s: %v", f.Name, err)
+		}
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	calibratedOverheads = map[string]int{}
+	memoryUnit = estimator.AutoUnit
+}
+
+// runRoot runs the root command with the arguments, away from any config file or
+// terminal, and returns the exit code along with what it wrote to stdout and stderr
+func runRoot(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	resetFlags(t, rootCmd)
+	t.Cleanup(func() { resetFlags(t, rootCmd) })
+
+	var out, errOut bytes.Buffer
+	rootCmd.SetIn(strings.NewReader(""))
+	code := run(rootCmd, args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// estimateRoot runs the root command with the arguments and --json, and returns the
+// estimate it wrote
+func estimateRoot(t *testing.T, args ...string) jsonEstimate {
+	t.Helper()
+	code, out, errOut := runRoot(t, append(args, "--json")...)
+	if code != 0 {
+		t.Fatalf("gpu-mem-for-llm %s exited with %d: %s", strings.Join(args, " "), code, errOut)
+	}
+	var estimate jsonEstimate
+	if err := json.Unmarshal([]byte(out), &estimate); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	return estimate
+}
+
+func TestRootGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"text", []string{"-s", "7b", "--precision", "fp16"}},
+		{"json", []string{"-s", "7b", "--precision", "fp16", "--json"}},
+		{"missing-precision", []string{"-s", "7b"}},
+		{"two-precisions", []string{"-s", "7b", "--precision", "fp16", "--quant", "Q4_K_M"}},
+		{"bad-size", []string{"-s", "7x", "--precision", "fp16"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out, errOut := runRoot(t, tt.args...)
+			got := fmt.Sprintf("exit %d\n-- stdout --\n%s-- stderr --\n%s", code, out, errOut)
+
+			golden := filepath.Join("testdata", "root", tt.name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("gpu-mem-for-llm %s:\n%s\nwant:\n%s", strings.Join(tt.args, " "), got, want)
+			}
+		})
+	}
+}
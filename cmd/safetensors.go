@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// safetensorsMaxHeader bounds the size of a header read from a file, so a corrupt file is
+// reported rather than exhausting memory. The format itself limits headers to 100 MB.
+const safetensorsMaxHeader = 100_000_000
+
+// safetensorsDtypeBytes maps the dtypes of safetensors tensors to the bytes per element
+var safetensorsDtypeBytes = map[string]int{
+	"F64":     8,
+	"F32":     4,
+	"F16":     2,
+	"BF16":    2,
+	"F8_E4M3": 1,
+	"F8_E5M2": 1,
+	"I64":     8,
+	"I32":     4,
+	"I16":     2,
+	"I8":      1,
+	"U64":     8,
+	"U32":     4,
+	"U16":     2,
+	"U8":      1,
+	"BOOL":    1,
+}
+
+// safetensorsTensor is the entry for a single tensor in a safetensors header
+type safetensorsTensor struct {
+	Dtype string `json:"dtype"`
+	Shape []int  `json:"shape"`
+}
+
+// safetensorsTotals is the number of parameters and bytes of each dtype across the
+// tensors of one or more safetensors files
+type safetensorsTotals struct {
+	Tensors    int
+	Parameters map[string]int
+	Bytes      map[string]int
+}
+
+// readSafetensorsHeader adds the tensors of a single safetensors file to the totals. Only
+// the JSON header at the start of the file is read, never the tensor data.
+func readSafetensorsHeader(path string, totals *safetensorsTotals) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var size uint64
+	if err := binary.Read(f, binary.LittleEndian, &size); err != nil {
+		return fmt.Errorf("%s: not a safetensors file", path)
+	}
+	if size > safetensorsMaxHeader {
+		return fmt.Errorf("%s: header of %d bytes is too large", path, size)
+	}
+	header := make([]byte, size)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return fmt.Errorf("%s: not a safetensors file", path)
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(header, &entries); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for name, raw := range entries {
+		if name == "__metadata__" {
+			continue
+		}
+		var tensor safetensorsTensor
+		if err := json.Unmarshal(raw, &tensor); err != nil {
+			return fmt.Errorf("%s: tensor %s: %v", path, name, err)
+		}
+		bytes, ok := safetensorsDtypeBytes[tensor.Dtype]
+		if !ok {
+			return fmt.Errorf("%s: tensor %s: unknown dtype %q", path, name, tensor.Dtype)
+		}
+		elements := 1
+		for _, n := range tensor.Shape {
+			elements *= n
+		}
+		totals.Tensors++
+		totals.Parameters[tensor.Dtype] += elements
+		totals.Bytes[tensor.Dtype] += elements * bytes
+	}
+	return nil
+}
+
+// readSafetensors returns the totals of a safetensors file, or of every shard listed in
+// the weight map of a sharded checkpoint's model.safetensors.index.json
+func readSafetensors(path string) (safetensorsTotals, error) {
+	totals := safetensorsTotals{Parameters: make(map[string]int), Bytes: make(map[string]int)}
+
+	if !strings.HasSuffix(path, ".json") {
+		return totals, readSafetensorsHeader(path, &totals)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return totals, err
+	}
+	var index struct {
+		WeightMap map[string]string `json:"weight_map"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return totals, fmt.Errorf("%s: %v", path, err)
+	}
+
+	// Each shard holds many tensors, so every shard is read once
+	shards := make(map[string]bool)
+	for _, shard := range index.WeightMap {
+		shards[shard] = true
+	}
+	if len(shards) == 0 {
+		return totals, fmt.Errorf("%s: no shards in the weight map", path)
+	}
+	names := make([]string, 0, len(shards))
+	for shard := range shards {
+		names = append(names, shard)
+	}
+	sort.Strings(names)
+	for _, shard := range names {
+		if err := readSafetensorsHeader(filepath.Join(filepath.Dir(path), shard), &totals); err != nil {
+			return totals, err
+		}
+	}
+	return totals, nil
+}
+
+// readLocalConfig reads the config.json saved next to a checkpoint, which holds the
+// architecture the safetensors headers don't
+func readLocalConfig(path string) (hfConfig, error) {
+	var config hfConfig
+	configPath := filepath.Join(filepath.Dir(path), "config.json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return config, err
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", configPath, err)
+	}
+	return config, nil
+}
+
+// jsonSafetensors is the shape of the output produced by the inspect-safetensors
+// subcommand with --json
+type jsonSafetensors struct {
+	File          string            `json:"file"`
+	TensorCount   int               `json:"tensor_count"`
+	Parameters    int               `json:"parameters"`
+	Dtypes        map[string]int    `json:"dtypes"`
+	WeightBytes   int               `json:"weight_bytes"`
+	BitsPerWeight float64           `json:"bits_per_weight"`
+	MemSize       string            `json:"mem_size"`
+	MemBytes      int               `json:"mem_bytes"`
+	Breakdown     map[string]string `json:"breakdown"`
+}
+
+// inspectSafetensorsCmd estimates the memory for a local safetensors checkpoint from its
+// headers
+var inspectSafetensorsCmd = &cobra.Command{
+	Use:   "inspect-safetensors FILE",
+	Short: "Estimate memory for a local safetensors checkpoint from its headers",
+	Long: `Read the headers of a safetensors file, or of every shard listed in a sharded
+checkpoint's model.safetensors.index.json, and estimate the memory for the weights as
+stored: the element count of every tensor at its dtype. With --context, the KV cache is
+sized from the config.json next to the checkpoint. Only the headers are read, never the
+tensor data.
+
+For example:
+./gpu-mem-for-llm inspect-safetensors Llama-3.1-8B/model.safetensors.index.json --context 8192
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		totals, err := readSafetensors(args[0])
+		if err != nil {
+			return err
+		}
+
+		var parameters, weightBytes int
+		for dtype, n := range totals.Parameters {
+			parameters += n
+			weightBytes += totals.Bytes[dtype]
+		}
+		if parameters == 0 {
+			return fmt.Errorf("%s: no tensors in the checkpoint", args[0])
+		}
+
+		// The weights are sized with the average bytes per parameter across all tensors,
+		// since a checkpoint can mix dtypes
+		input := estimator.ModelSpec{
+			ParameterSize: parameters,
+			Precision:     estimator.Precision(float64(weightBytes) / float64(parameters)),
+			Overhead:      float32(overhead),
+		}
+		if contextLength > 0 {
+			config, err := readLocalConfig(args[0])
+			if errors.Is(err, os.ErrNotExist) {
+				return errors.New("--context requires a config.json next to the checkpoint to size the KV cache")
+			}
+			if err != nil {
+				return err
+			}
+			if config.NumHiddenLayers <= 0 || config.HiddenSize <= 0 {
+				return errors.New("--context requires num_hidden_layers and hidden_size in config.json")
+			}
+			// The cache is kept at the precision the model was saved in
+			if dtype, ok := hfDtypes[config.TorchDtype]; ok {
+				input.KVPrecision, err = estimator.PrecisionByName(dtype)
+				if err != nil {
+					return err
+				}
+			}
+			input.NumLayers = config.NumHiddenLayers
+			input.HiddenDim = config.HiddenSize
+			if config.NumAttentionHeads > 0 && config.NumKeyValueHeads > 0 {
+				input.HeadDim = config.HiddenSize / config.NumAttentionHeads
+				input.KVHeads = config.NumKeyValueHeads
+			}
+			input.ContextLength = contextLength
+			input.BatchSize = batchSize
+		}
+
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+		bitsPerWeight := float64(weightBytes) * 8 / float64(parameters)
+
+		if jsonOutput {
+			return writeStructured(out, "json", jsonSafetensors{
+				File:          args[0],
+				TensorCount:   totals.Tensors,
+				Parameters:    parameters,
+				Dtypes:        totals.Parameters,
+				WeightBytes:   weightBytes,
+				BitsPerWeight: bitsPerWeight,
+				MemSize:       formatMemory(result.Total),
+				MemBytes:      result.Total,
+				Breakdown:     result.BreakdownIn(memoryUnit),
+			})
+		}
+
+		dtypes := make([]string, 0, len(totals.Parameters))
+		for _, dtype := range sortedByCount(totals.Parameters) {
+			dtypes = append(dtypes, fmt.Sprintf("%s (%s params)", dtype, estimator.FormatCount(totals.Parameters[dtype])))
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+		fmt.Fprintf(tw, "Tensors:\t%d\n", totals.Tensors)
+		fmt.Fprintf(tw, "Dtypes:\t%s\n", strings.Join(dtypes, ", "))
+		fmt.Fprintf(tw, "Parameters:\t%s\n", estimator.FormatCount(parameters))
+		fmt.Fprintf(tw, "Weights:\t%s (%.2f bits per weight)\n", formatMemory(weightBytes), bitsPerWeight)
+		tw.Flush()
+		fmt.Fprintf(out, "Estimated memory required: %s\n", formatMemory(result.Total))
+		printBreakdown(out, result)
+		return nil
+	},
+}
+
+func init() {
+	inspectSafetensorsCmd.Flags().IntVar(&contextLength, "context", 0, "context length in tokens to size the KV cache for (requires a config.json next to the checkpoint)")
+	inspectSafetensorsCmd.Flags().IntVar(&batchSize, "batch-size", 1, "number of sequences held in the KV cache at once")
+	inspectSafetensorsCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage")
+	inspectSafetensorsCmd.Flags().BoolVar(&jsonOutput, "json", false, "output results in JSON format")
+	rootCmd.AddCommand(inspectSafetensorsCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+// schemaVersion is the version of the structured output, bumped whenever a field is
+// removed or changes meaning. Adding a field keeps the version.
+const schemaVersion = "1"
+
+// outputSchema is the JSON Schema of the estimate, printed by --schema
+//
+//go:embed schema.json
+var outputSchema []byte
+
+// versionedJSON encodes a result as JSON with schema_version as the first field, of the
+// result itself or, for a list of results, of each of them
+func versionedJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		return withSchemaVersion(data), nil
+	case bytes.HasPrefix(data, []byte("[")):
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			if bytes.HasPrefix(item, []byte("{")) {
+				items[i] = withSchemaVersion(item)
+			}
+		}
+		return json.Marshal(items)
+	}
+	return data, nil
+}
+
+// withSchemaVersion adds schema_version to the start of an object encoded as JSON
+func withSchemaVersion(object []byte) []byte {
+	field := fmt.Sprintf(`{"schema_version":%q`, schemaVersion)
+	rest := object[1:]
+	if !bytes.Equal(bytes.TrimSpace(rest), []byte("}")) {
+		field += ","
+	}
+	return append([]byte(field), rest...)
+}
@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+// estimateRequest is the body of a POST /estimate request. The fields are named after the
+// flags of the main command with underscores in place of dashes, and the precision is
+// given by name as in the config file.
+type estimateRequest struct {
+	Size             string  `json:"size"`
+	Precision        string  `json:"precision"`
+	Quant            string  `json:"quant"`
+	BytesPerParam    float64 `json:"bytes_per_param"`
+	Overhead         *int    `json:"overhead"`
+	NumLayers        int     `json:"num_layers"`
+	HiddenDim        int     `json:"hidden_dim"`
+	VocabSize        int     `json:"vocab_size"`
+	IntermediateSize int     `json:"intermediate_size"`
+	TiedEmbeddings   bool    `json:"tied_embeddings"`
+	HeadDim          int     `json:"head_dim"`
+	KVHeads          int     `json:"kv_heads"`
+	Context          int     `json:"context"`
+	SlidingWindow    int     `json:"sliding_window"`
+	BatchSize        int     `json:"batch_size"`
+	KVDtype          string  `json:"kv_dtype"`
+	TensorParallel   int     `json:"tensor_parallel"`
+	Mode             string  `json:"mode"`
+	GPU              string  `json:"gpu"`
+	GPUMemory        string  `json:"gpu_memory"`
+}
+
+// jsonGPU is the shape of a single GPU in the GET /gpus response
+type jsonGPU struct {
+	Name        string  `json:"name"`
+	Memory      string  `json:"memory"`
+	MemoryBytes int     `json:"memory_bytes"`
+	Bandwidth   float64 `json:"bandwidth,omitempty"`
+}
+
+// requestPrecision returns the precision of the weights and its name from the request,
+// which gives exactly one of a precision, a llama.cpp quantization or bytes per parameter
+func requestPrecision(req estimateRequest) (estimator.Precision, string, error) {
+	var given int
+	for _, set := range []bool{req.Precision != "", req.Quant != "", req.BytesPerParam != 0} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return 0, "", errors.New("exactly one of precision, quant and bytes_per_param is required")
+	}
+
+	switch {
+	case req.Quant != "":
+		precision, err := estimator.QuantPrecision(req.Quant)
+		return precision, strings.ToUpper(req.Quant), err
+	case req.BytesPerParam != 0:
+		if req.BytesPerParam < 0 {
+			return 0, "", errors.New("invalid bytes_per_param; must be greater than 0")
+		}
+		return estimator.Precision(req.BytesPerParam), "custom", nil
+	}
+	precision, err := lookupPrecision(req.Precision)
+	return precision, lookupPrecisionName(req.Precision), err
+}
+
+// serveEstimate estimates the memory for a request the way the main command does for the
+// same flags, returning the same JSON shape as --json
+func serveEstimate(req estimateRequest) (jsonEstimate, error) {
+	if req.Size == "" {
+		return jsonEstimate{}, errors.New("size is required")
+	}
+	parameterSize, err := getParameterSize(req.Size)
+	if err != nil {
+		return jsonEstimate{}, err
+	}
+	precision, precisionName, err := requestPrecision(req)
+	if err != nil {
+		return jsonEstimate{}, err
+	}
+
+	input := estimator.ModelSpec{
+		ParameterSize:    parameterSize,
+		Precision:        precision,
+		Overhead:         20,
+		NumLayers:        req.NumLayers,
+		HiddenDim:        req.HiddenDim,
+		VocabSize:        req.VocabSize,
+		IntermediateSize: req.IntermediateSize,
+		TiedEmbeddings:   req.TiedEmbeddings,
+		HeadDim:          req.HeadDim,
+		KVHeads:          req.KVHeads,
+		ContextLength:    req.Context,
+		SlidingWindow:    req.SlidingWindow,
+		BatchSize:        max(req.BatchSize, 1),
+		TensorParallel:   max(req.TensorParallel, 1),
+	}
+	if req.Overhead != nil {
+		input.Overhead = float32(*req.Overhead)
+	}
+	switch strings.ToLower(req.Mode) {
+	case "", "inference":
+	case "train":
+		input.Train = true
+	default:
+		return jsonEstimate{}, fmt.Errorf("unknown mode %q; must be one of %s", req.Mode, strings.Join(estimateModes, ", "))
+	}
+
+	// llama.cpp keeps the KV cache in f16 whatever the weights are quantized to
+	kvPrecisionName := req.KVDtype
+	if kvPrecisionName == "" && req.Quant != "" {
+		kvPrecisionName = "fp16"
+	}
+	if kvPrecisionName != "" {
+		input.KVPrecision, err = estimator.KVPrecisionByName(kvPrecisionName)
+		if err != nil {
+			return jsonEstimate{}, err
+		}
+	}
+
+	result, err := calculate(input)
+	if err != nil {
+		return jsonEstimate{}, err
+	}
+
+	output := jsonEstimate{
+		MemSize:           formatMemory(result.Total * result.GPUs),
+		MemBytes:          result.Total * result.GPUs,
+		Parameters:        parameterSize,
+		Precision:         precisionName,
+		BytesPerParameter: json.Number(strconv.FormatFloat(float64(precision), 'g', -1, 32)),
+		OverheadPercent:   result.OverheadPercent,
+		Components:        jsonComponents(result),
+		Breakdown:         result.BreakdownIn(memoryUnit),
+	}
+	if result.GPUs > 1 {
+		output.MemSizePerGPU = formatMemory(result.Total)
+		output.MemBytesPerGPU = result.Total
+	}
+
+	var gpuMemoryBytes int
+	switch {
+	case req.GPU != "" && req.GPUMemory != "":
+		return jsonEstimate{}, errors.New("only one of gpu and gpu_memory can be given")
+	case req.GPU != "":
+		spec, err := getGPUSpec(req.GPU)
+		if err != nil {
+			return jsonEstimate{}, err
+		}
+		gpuMemoryBytes = spec.Memory
+		output.GPU = strings.ToLower(req.GPU)
+	case req.GPUMemory != "":
+		gpuMemoryBytes, err = parseMemorySize(req.GPUMemory)
+		if err != nil {
+			return jsonEstimate{}, err
+		}
+	}
+	if gpuMemoryBytes > 0 {
+		fits := result.Total <= gpuMemoryBytes
+		gpuCount := estimator.CalculateGPUCount(result.Total*result.GPUs, gpuMemoryBytes, result.GPUs)
+		headroomBytes, headroomPercent := estimator.CalculateHeadroom(result.Total*result.GPUs, gpuMemoryBytes, gpuCount)
+		output.Fits = &fits
+		output.GPUsRequired = gpuCount
+		output.HeadroomBytes = headroomBytes
+		output.HeadroomPercent = math.Round(headroomPercent*10) / 10
+	}
+	return output, nil
+}
+
+// listGPUs returns the GPUs of the database in the order of their names
+func listGPUs() []jsonGPU {
+	gpus := make([]jsonGPU, 0, len(gpuDatabase))
+	for _, name := range gpuNames() {
+		spec := gpuDatabase[name]
+		gpus = append(gpus, jsonGPU{Name: name, Memory: formatMemory(spec.Memory), MemoryBytes: spec.Memory, Bandwidth: spec.Bandwidth})
+	}
+	return gpus
+}
+
+// writeJSON writes the value as the JSON body of a response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := versionedJSON(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeJSONError writes an error as a JSON body of the form {"error": "..."}
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// servePaths lists the paths of the API, which the request counters are labelled with,
+// counting any other path as "other"
+var servePaths = []string{"/estimate", "/gpus", "/healthz", "/metrics"}
+
+// statusRecorder keeps the status code of a response for the request counters
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.code = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// countRequests counts every response of the handler in the metrics
+func countRequests(metrics *serveMetrics, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		path := "other"
+		if slices.Contains(servePaths, r.URL.Path) {
+			path = r.URL.Path
+		}
+		metrics.countRequest(r.Method, path, recorder.code)
+	})
+}
+
+// newServeMux returns the handler of the API: POST /estimate, GET /gpus, GET /metrics and
+// GET /healthz
+func newServeMux(metrics *serveMetrics) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /estimate", func(w http.ResponseWriter, r *http.Request) {
+		var req estimateRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+			return
+		}
+		output, err := serveEstimate(req)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, output)
+	})
+	mux.HandleFunc("GET /gpus", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, listGPUs())
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		// The local GPUs are read on each scrape, and left out on a machine without any
+		gpus, _ := detectGPUs(vendor)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, metrics.render(gpus))
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// serveCmd exposes the estimator over HTTP
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the estimator as an HTTP API",
+	Long: `Start an HTTP server exposing the estimator as a JSON API, to run as a sidecar
+rather than running the binary for each estimate:
+
+  POST /estimate  estimate the memory for a model, given as a JSON object with the
+                  flags of the main command as fields, such as
+                  {"size": "7b", "precision": "fp16", "context": 8192,
+                   "num_layers": 32, "hidden_dim": 4096, "gpu": "a10"}
+  GET  /gpus      list the GPUs of the database with their memory
+  GET  /metrics   report the estimates of the models of --models, the memory of the
+                  local GPUs and the number of requests in the Prometheus format
+  GET  /healthz   report that the server is up
+
+With --grpc-addr, the estimator is also served over gRPC on that address, as the
+EstimatorService of api/gpumem/v1/estimator.proto.
+
+The estimate has the same shape as the output of --json. Invalid requests get a 400
+response with the problem under "error".
+
+For example:
+./gpu-mem-for-llm serve --addr :8080
+./gpu-mem-for-llm serve --addr :8080 --grpc-addr :9090
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		// The database is read once at startup, so requests only ever read it
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+
+		// The models reported by GET /metrics are estimated once at startup as well
+		metrics := &serveMetrics{}
+		if serveModels != "" {
+			models, err := readBatchModels(serveModels)
+			if err != nil {
+				return err
+			}
+			for i, m := range models {
+				name := m.Name
+				if name == "" {
+					name = m.Size
+				}
+				estimate, err := serveEstimate(m.estimateRequest)
+				if err != nil {
+					return fmt.Errorf("%s: model %d (%s): %v", serveModels, i+1, name, err)
+				}
+				metrics.Models = append(metrics.Models, jsonBatchEstimate{Name: name, jsonEstimate: estimate})
+			}
+		}
+
+		server := &http.Server{
+			Addr:              serveAddr,
+			Handler:           countRequests(metrics, newServeMux(metrics)),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		// Shut down cleanly when the container or the terminal stops the server
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// The gRPC API is only served on an address of its own, given with --grpc-addr
+		var grpcServer *grpc.Server
+		if serveGRPCAddr != "" {
+			listener, err := net.Listen("tcp", serveGRPCAddr)
+			if err != nil {
+				return internalError(err)
+			}
+			grpcServer = newGRPCServer()
+			go func() {
+				if err := grpcServer.Serve(listener); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "gRPC server: %v\n", err)
+					stop()
+				}
+			}()
+			fmt.Fprintf(cmd.ErrOrStderr(), "Serving gRPC on %s\n", serveGRPCAddr)
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+			if grpcServer != nil {
+				grpcServer.GracefulStop()
+			}
+		}()
+
+		fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s\n", serveAddr)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return internalError(err)
+		}
+		return nil
+	},
+}
+
+var (
+	serveAddr     string
+	serveGRPCAddr string
+	serveModels   string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "address to listen on (e.g., :8080 to listen on every interface)")
+	serveCmd.Flags().StringVar(&serveGRPCAddr, "grpc-addr", "", "address to also serve the gRPC API of api/gpumem/v1 on (e.g., :9090)")
+	serveCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for the gpu field and GET /gpus")
+	serveCmd.Flags().StringVar(&serveModels, "models", "", "YAML or JSON file of models, as for the batch subcommand, whose estimates GET /metrics reports")
+	serveCmd.Flags().StringVar(&vendor, "vendor", "auto", "vendor of the local GPUs GET /metrics reports the memory of (auto, "+strings.Join(gpuVendors, ", ")+")")
+	rootCmd.AddCommand(serveCmd)
+}
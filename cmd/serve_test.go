@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+func TestRequestPrecision(t *testing.T) {
+	q4KM, _ := estimator.QuantPrecision("Q4_K_M")
+	tests := []struct {
+		req      estimateRequest
+		want     estimator.Precision
+		wantName string
+	}{
+		{estimateRequest{Precision: "FP16"}, 2, "fp16"},
+		{estimateRequest{Precision: "q4_k_m"}, q4KM, "Q4_K_M"},
+		{estimateRequest{Quant: "q4_k_m"}, q4KM, "Q4_K_M"},
+		{estimateRequest{BytesPerParam: 0.75}, 0.75, "custom"},
+	}
+	for _, tt := range tests {
+		got, name, err := requestPrecision(tt.req)
+		if err != nil {
+			t.Errorf("requestPrecision(%+v) failed: %v", tt.req, err)
+			continue
+		}
+		if got != tt.want || name != tt.wantName {
+			t.Errorf("requestPrecision(%+v) = %v (%s), want %v (%s)", tt.req, got, name, tt.want, tt.wantName)
+		}
+	}
+
+	for _, req := range []estimateRequest{
+		{},
+		{Precision: "fp16", Quant: "Q4_K_M"},
+		{Precision: "q9"},
+		{BytesPerParam: -1},
+	} {
+		if _, _, err := requestPrecision(req); err == nil {
+			t.Errorf("requestPrecision(%+v) was accepted", req)
+		}
+	}
+}
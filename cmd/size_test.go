@@ -0,0 +1,41 @@
+package cmd
+
+import "testing"
+
+func TestGetParameterSize(t *testing.T) {
+	tests := []struct {
+		param   string
+		want    int
+		wantErr bool
+	}{
+		{param: "500k", want: 500_000},
+		{param: "500K", want: 500_000},
+		{param: "1.5b", want: 1_500_000_000},
+		{param: "7000000000", want: 7_000_000_000},
+		{param: "0k", want: 0},
+		{param: "0", want: 0},
+		{param: "1.5", wantErr: true},
+		{param: "7x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := getParameterSize(tt.param)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("getParameterSize(%q) = %d, want an error", tt.param, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("getParameterSize(%q) returned error: %v", tt.param, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("getParameterSize(%q) = %d, want %d", tt.param, got, tt.want)
+		}
+	}
+
+	// A size of 0k estimates the same as a size of zero
+	if zeroK, zero := estimateRoot(t, "-s", "0k", "--precision", "fp16"), estimateRoot(t, "-s", "0", "--precision", "fp16"); zeroK.MemBytes != zero.MemBytes {
+		t.Errorf("-s 0k needs %d bytes, want %d as for -s 0", zeroK.MemBytes, zero.MemBytes)
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// singleSizeFlags lists the flags describing a single estimate in more detail, which
+// can't be combined with several sizes
+var singleSizeFlags = []string{
+	"active-params", "active-experts", "per-layer", "prewarm", "explain", "uncertainty",
+	"round-to", "target-tokens-per-second", "pushgateway-url", "json-include-inputs", "detect",
+	"max-vram", "capacity",
+}
+
+// sizeListValue is the value of --size, which takes several sizes either separated by
+// commas or by repeating the flag
+type sizeListValue struct {
+	value   *string
+	changed bool
+}
+
+func (v *sizeListValue) Set(s string) error {
+	if v.changed {
+		*v.value += "," + s
+	} else {
+		*v.value = s
+	}
+	v.changed = true
+	return nil
+}
+
+func (v *sizeListValue) String() string { return *v.value }
+
+func (v *sizeListValue) Type() string { return "string" }
+
+// checkMultipleSizes returns an error when several sizes are combined with a flag that
+// only applies to a single estimate
+func checkMultipleSizes(cmd *cobra.Command) error {
+	for _, name := range singleSizeFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s cannot be combined with several sizes", name)
+		}
+	}
+	return nil
+}
+
+// jsonSizeEstimate is the shape of a single model in the output for several sizes
+type jsonSizeEstimate struct {
+	Model          string          `json:"model"`
+	Parameters     int             `json:"parameters"`
+	MemSize        string          `json:"mem_size"`
+	MemBytes       int             `json:"mem_bytes"`
+	MemBytesPerGPU int             `json:"mem_bytes_per_gpu,omitempty"`
+	Components     []jsonComponent `json:"components"`
+	Fits           *bool           `json:"fits,omitempty"`
+}
+
+// writeSizeComparison estimates the model described by the input at each of the given
+// sizes and writes them as a single table, with a column for every component
+func writeSizeComparison(w io.Writer, format string, input estimator.ModelSpec, sizes []string) error {
+	gpuMemoryBytes, err := resolveGPUMemory()
+	if err != nil {
+		return err
+	}
+
+	rows := make([]estimateRow, 0, len(sizes))
+	output := make([]jsonSizeEstimate, 0, len(sizes))
+	for _, s := range sizes {
+		parameterSize, err := getParameterSize(s)
+		if err != nil {
+			return fmt.Errorf("invalid size %q: %v", s, err)
+		}
+		input.ParameterSize = parameterSize
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+
+		rows = append(rows, estimateRow{
+			Model:      s,
+			Precision:  precisionName(),
+			Context:    input.ContextLength,
+			GPUs:       result.GPUs,
+			Components: jsonComponents(result),
+			MemBytes:   result.Total * result.GPUs,
+		})
+		estimate := jsonSizeEstimate{
+			Model:      s,
+			Parameters: parameterSize,
+			MemSize:    formatMemory(result.Total * result.GPUs),
+			MemBytes:   result.Total * result.GPUs,
+			Components: jsonComponents(result),
+		}
+		if result.GPUs > 1 {
+			estimate.MemBytesPerGPU = result.Total
+		}
+		if gpuMemoryBytes > 0 {
+			fits := result.Total <= gpuMemoryBytes
+			estimate.Fits = &fits
+		}
+		output = append(output, estimate)
+	}
+
+	switch {
+	case quiet:
+		for _, row := range rows {
+			if bytesOutput {
+				fmt.Fprintf(w, "%s\t%d\n", row.Model, row.MemBytes)
+			} else {
+				fmt.Fprintf(w, "%s\t%s\n", row.Model, formatMemory(row.MemBytes))
+			}
+		}
+		return nil
+	case tabularFormats[format]:
+		return writeTable(w, format, rows)
+	case format != "text":
+		return writeStructured(w, format, output)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for _, c := range row.Components {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "Model\t" + strings.Join(names, "\t") + "\tTotal"
+	if gpuMemoryBytes > 0 {
+		header += "\tFits"
+	}
+	fmt.Fprintln(tw, header)
+	for i, row := range rows {
+		values := make(map[string]string, len(row.Components))
+		for _, c := range row.Components {
+			values[c.Name] = formatMemory(c.Bytes)
+		}
+		cells := []string{row.Model}
+		for _, name := range names {
+			cells = append(cells, values[name])
+		}
+		cells = append(cells, formatMemory(row.MemBytes))
+		if fits := output[i].Fits; fits != nil {
+			verdict := "no"
+			if *fits {
+				verdict = "yes"
+			}
+			cells = append(cells, verdict)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// specFlags names the flags setting each field of estimator.ModelSpec, so the errors of
+// the estimator, which name the fields, can be reported in terms of the flags
+var specFlags = map[string]string{
+	"ParameterSize":         "--size",
+	"Overhead":              "--overhead",
+	"ActiveParameters":      "--active-params",
+	"FP8Scaling":            "--fp8-scaling",
+	"EmbeddingPrecision":    "--embed-precision",
+	"HeadPrecision":         "--head-precision",
+	"SparseFraction":        "--sparse-fraction",
+	"HiddenDim":             "--hidden-dim",
+	"VocabSize":             "--vocab-size",
+	"NumLayers":             "--num-layers",
+	"TiedEmbeddings":        "--tied-embeddings",
+	"StreamWeights":         "--stream-weights",
+	"DoubleBuffer":          "--double-buffer",
+	"HeadDim":               "--head-dim",
+	"Heads":                 "--heads",
+	"KVHeads":               "--kv-heads",
+	"MaxPositions":          "--max-positions",
+	"RoPEScaling":           "--rope-scaling",
+	"ContextLength":         "--context",
+	"BatchSize":             "--batch-size",
+	"KVUtilization":         "--kv-utilization",
+	"KVBuckets":             "--kv-buckets",
+	"GradientCheckpointing": "--gradient-checkpointing",
+	"TensorParallel":        "--tensor-parallel",
+	"PipelineParallel":      "--pipeline-parallel",
+	"Train":                 "--mode train",
+	"PureBF16":              "--pure-bf16",
+	"Optimizer":             "--optimizer",
+	"ZeROStage":             "--zero",
+	"DataParallel":          "--gpus",
+	"FSDPStrategy":          "--fsdp",
+	"FSDPShardDegree":       "--fsdp-shard-degree",
+	"OffloadOptimizer":      "--offload-optimizer",
+	"LoRARank":              "--lora-rank",
+	"LayerSkip":             "--layer-skip",
+	"MedusaHeads":           "--medusa-heads",
+	"Draft.NumLayers":       "--draft-num-layers",
+	"Draft.HiddenDim":       "--draft-hidden-dim",
+	"IndexVectors":          "--index-vectors",
+	"IndexDim":              "--index-dim",
+}
+
+// specFieldPattern matches the name of a field of the spec, or of a field of one of its
+// own fields such as Draft.NumLayers
+var specFieldPattern = regexp.MustCompile(`\b[A-Z][A-Za-z0-9]*(\.[A-Z][A-Za-z0-9]*)?\b`)
+
+// flagError rewrites an error about the spec from the estimator, replacing the fields it
+// names with the flags of the command setting them. The batch size comes from
+// --micro-batch instead when that is given. Any other error is returned as it is.
+func flagError(cmd *cobra.Command, err error) error {
+	var invalid *estimator.SpecError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	return errors.New(specFieldPattern.ReplaceAllStringFunc(err.Error(), func(field string) string {
+		if field == "BatchSize" && cmd.Flags().Changed("micro-batch") {
+			return "--micro-batch"
+		}
+		if flag, ok := specFlags[field]; ok {
+			return flag
+		}
+		return field
+	}))
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// maxStreamLine is the longest request line stream accepts, as for a POST /estimate body
+const maxStreamLine = 1 << 20
+
+// streamRequest is a single line of the stream input: the fields of POST /estimate along
+// with an optional id of any JSON type, echoed in the result so they can be matched
+type streamRequest struct {
+	ID json.RawMessage `json:"id,omitempty"`
+	estimateRequest
+}
+
+// jsonStreamEstimate is the shape of a single line of the stream output for a request
+// that could be estimated
+type jsonStreamEstimate struct {
+	ID json.RawMessage `json:"id,omitempty"`
+	jsonEstimate
+}
+
+// jsonStreamError is the shape of a single line of the stream output for a request that
+// could not be estimated
+type jsonStreamError struct {
+	ID    json.RawMessage `json:"id,omitempty"`
+	Line  int             `json:"line"`
+	Error string          `json:"error"`
+}
+
+// streamCmd estimates newline-delimited JSON requests as a long-lived coprocess
+var streamCmd = &cobra.Command{
+	Use:   "stream",
+	Short: "Estimate newline-delimited JSON requests from stdin, one result per line",
+	Long: `Read one JSON request per line from stdin and write one JSON result per line to stdout,
+in the same order, so the estimator can run as a long-lived coprocess of another tool.
+Each request takes the same fields as POST /estimate of the serve subcommand, plus an
+optional "id" of any type that is copied to its result:
+
+{"id": 1, "size": "7b", "precision": "fp16", "context": 8192, "num_layers": 32, "hidden_dim": 4096}
+
+Each result has the same shape as the output of --json. A request that can't be
+estimated gets a line with its "id", its "line" number and the problem under "error",
+and the stream carries on. Blank lines are skipped, and every result is written as soon
+as it is ready. The --overhead flag applies to every request that doesn't set its own.
+
+For example:
+./gpu-mem-for-llm stream < requests.jsonl > results.jsonl
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if gpuDB != "" {
+			if err := loadGPUDatabase(gpuDB); err != nil {
+				return err
+			}
+		}
+
+		scanner := bufio.NewScanner(cmd.InOrStdin())
+		scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+		out := bufio.NewWriter(cmd.OutOrStdout())
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var req streamRequest
+			var result any
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&req); err != nil {
+				result = jsonStreamError{Line: lineNumber, Error: fmt.Sprintf("invalid request: %v", err)}
+			} else {
+				if req.Overhead == nil {
+					req.Overhead = &overhead
+				}
+				if estimate, err := serveEstimate(req.estimateRequest); err != nil {
+					result = jsonStreamError{ID: req.ID, Line: lineNumber, Error: err.Error()}
+				} else {
+					result = jsonStreamEstimate{ID: req.ID, jsonEstimate: estimate}
+				}
+			}
+
+			if err := writeStructured(out, "json", result); err != nil {
+				return err
+			}
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("error reading requests: %v", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	streamCmd.Flags().IntVarP(&overhead, "overhead", "o", 20, "overhead as a percentage for the requests that don't set their own")
+	streamCmd.Flags().StringVar(&gpuDB, "gpu-db", "", "JSON file of additional GPUs for the gpu field")
+	rootCmd.AddCommand(streamCmd)
+}
@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// maxSweepPoints bounds the values of a single sweep
+	maxSweepPoints = 1000
+
+	// sweepBarWidth is the width of the bar of the largest estimate in the text chart
+	sweepBarWidth = 30
+)
+
+// parseTokenCount parses a number of tokens such as 4096, 4k or 1m, where k is 1,024
+// tokens and m 1,048,576 as context lengths are usually quoted
+func parseTokenCount(s string) (int, error) {
+	re := regexp.MustCompile(`^(\d+)([kmKM]?)$`)
+	matches := re.FindStringSubmatch(strings.TrimSpace(s))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid token count %q; must be a whole number optionally followed by 'k' or 'm'", s)
+	}
+	n, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(matches[2]) {
+	case "k":
+		n *= 1024
+	case "m":
+		n *= 1024 * 1024
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid token count %q; must be greater than 0", s)
+	}
+	return n, nil
+}
+
+// parseBatchSize parses a number of sequences in a batch
+func parseBatchSize(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid batch size %q; must be a whole number greater than 0", s)
+	}
+	return n, nil
+}
+
+// parseSweep returns the values of a sweep, each parsed with parse: a list such as
+// 4k,8k,32k, a range such as 2k:128k doubling from the start to the end, or a range with
+// a step such as 4k:32k:4k
+func parseSweep(value string, parse func(string) (int, error)) ([]int, error) {
+	if !strings.Contains(value, ":") {
+		var values []int
+		for _, entry := range strings.Split(value, ",") {
+			n, err := parse(entry)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, n)
+		}
+		return values, nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid sweep %q; must be START:END or START:END:STEP", value)
+	}
+	bounds := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := parse(part)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = n
+	}
+	start, end := bounds[0], bounds[1]
+	if end < start {
+		return nil, fmt.Errorf("invalid sweep %q; the end must not be below the start", value)
+	}
+
+	var values []int
+	for n := start; n <= end; {
+		values = append(values, n)
+		if len(values) > maxSweepPoints {
+			return nil, fmt.Errorf("invalid sweep %q; at most %d values", value, maxSweepPoints)
+		}
+		if len(bounds) == 3 {
+			n += bounds[2]
+		} else {
+			n *= 2
+		}
+	}
+	return values, nil
+}
+
+// sweep is a setting estimated at each of several values in a single table
+type sweep struct {
+	// Flag is the flag giving the values, such as sweep-context
+	Flag string
+
+	// Name is the column of the values in tables, such as Context
+	Name string
+
+	// Unit is what the values count in the summary, such as tokens
+	Unit string
+
+	// Values are the values of the setting, in the order given
+	Values []int
+
+	// apply sets the value on the model being estimated
+	apply func(*estimator.ModelSpec, int)
+}
+
+// contextSweep and batchSweep return the sweeps of --sweep-context and --sweep-batch
+func contextSweep(values []int) sweep {
+	return sweep{Flag: "sweep-context", Name: "Context", Unit: "tokens", Values: values, apply: func(in *estimator.ModelSpec, n int) {
+		in.ContextLength = n
+	}}
+}
+
+func batchSweep(values []int) sweep {
+	return sweep{Flag: "sweep-batch", Name: "Batch", Unit: "sequences", Values: values, apply: func(in *estimator.ModelSpec, n int) {
+		in.BatchSize = n
+	}}
+}
+
+// sweepConflicts are the flags each sweep cannot be combined with, as they set the swept
+// setting another way
+var sweepConflicts = map[string][]string{
+	"sweep-context": {"context", "prompt-chars", "kv-buckets"},
+	"sweep-batch":   {"batch-size", "micro-batch", "kv-buckets"},
+}
+
+// parseSweepFlags returns the sweep given with --sweep-context or --sweep-batch, if any,
+// checking it isn't combined with a flag that only applies to a single estimate or sets
+// the swept setting another way. --max-vram stays, marking the values that fit.
+func parseSweepFlags(cmd *cobra.Command, sizes []string) (*sweep, error) {
+	var s sweep
+	switch {
+	case sweepContext != "":
+		values, err := parseSweep(sweepContext, parseTokenCount)
+		if err != nil {
+			return nil, err
+		}
+		s = contextSweep(values)
+	case sweepBatch != "":
+		values, err := parseSweep(sweepBatch, parseBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		s = batchSweep(values)
+	default:
+		if reportMinMax {
+			return nil, errors.New("--report-min-max requires --sweep-context or --sweep-batch")
+		}
+		return nil, nil
+	}
+
+	if len(sizes) > 1 {
+		return nil, fmt.Errorf("--%s cannot be combined with several sizes", s.Flag)
+	}
+	for _, name := range append(slices.Clone(sweepConflicts[s.Flag]), singleSizeFlags...) {
+		if name != "max-vram" && cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%s cannot be combined with --%s", s.Flag, name)
+		}
+	}
+	if numLayers <= 0 || hiddenDim <= 0 {
+		return nil, fmt.Errorf("--%s requires --num-layers and --hidden-dim to size the KV cache", s.Flag)
+	}
+	if s.Flag == "sweep-batch" && contextLength <= 0 && promptChars <= 0 {
+		return nil, errors.New("--sweep-batch requires --context to size the KV cache of each sequence")
+	}
+	return &s, nil
+}
+
+// jsonSweepEstimate is the shape of a single value in the output of a sweep
+type jsonSweepEstimate struct {
+	Context        int             `json:"context,omitempty"`
+	Batch          int             `json:"batch,omitempty"`
+	MemSize        string          `json:"mem_size"`
+	MemBytes       int             `json:"mem_bytes"`
+	MemBytesPerGPU int             `json:"mem_bytes_per_gpu,omitempty"`
+	Components     []jsonComponent `json:"components"`
+	Fits           *bool           `json:"fits,omitempty"`
+}
+
+// jsonSweepExtreme is the shape of the value of a sweep needing the least or the most
+// memory
+type jsonSweepExtreme struct {
+	Context  int    `json:"context,omitempty"`
+	Batch    int    `json:"batch,omitempty"`
+	MemSize  string `json:"mem_size"`
+	MemBytes int    `json:"mem_bytes"`
+}
+
+// jsonSweepMinMax is the shape of the output of a sweep with --report-min-max, the
+// estimates followed by those needing the least and the most memory
+type jsonSweepMinMax struct {
+	Estimates []jsonSweepEstimate `json:"estimates"`
+	Min       jsonSweepExtreme    `json:"min"`
+	Max       jsonSweepExtreme    `json:"max"`
+}
+
+// sweepExtremes returns the indexes of the estimates needing the least and the most
+// memory, the first of them when several need the same
+func sweepExtremes(rows []estimateRow) (lowest, highest int) {
+	for i, row := range rows {
+		if row.MemBytes < rows[lowest].MemBytes {
+			lowest = i
+		}
+		if row.MemBytes > rows[highest].MemBytes {
+			highest = i
+		}
+	}
+	return lowest, highest
+}
+
+// writeSweepMinMax writes the footer of --report-min-max after a table of the tabular
+// formats. CSV and TSV get a second table after a blank line, with a row for each of the
+// least and the most memory.
+func writeSweepMinMax(w io.Writer, format string, s sweep, rows []estimateRow) error {
+	lowest, highest := sweepExtremes(rows)
+	if format == "markdown" {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Least memory: %s at %d %s\n", formatMemory(rows[lowest].MemBytes), s.Values[lowest], s.Unit)
+		fmt.Fprintf(w, "Most memory: %s at %d %s\n", formatMemory(rows[highest].MemBytes), s.Values[highest], s.Unit)
+		return nil
+	}
+
+	fmt.Fprintln(w)
+	cw := csv.NewWriter(w)
+	if format == "tsv" {
+		cw.Comma = '\t'
+	}
+	cw.Write([]string{"extreme", strings.ToLower(s.Name), "mem_bytes"})
+	cw.Write([]string{"min", strconv.Itoa(s.Values[lowest]), strconv.Itoa(rows[lowest].MemBytes)})
+	cw.Write([]string{"max", strconv.Itoa(s.Values[highest]), strconv.Itoa(rows[highest].MemBytes)})
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSweep estimates the model described by the input at each value of the sweep and
+// writes them as a single table, with a bar chart of the totals in text. The estimates are
+// checked against --max-vram, or else the memory of the GPU when it is known, with the
+// largest value that fits reported. With --report-min-max, the values needing the least
+// and the most memory follow the table.
+func writeSweep(w io.Writer, format, model string, input estimator.ModelSpec, s sweep) error {
+	budget, err := resolveGPUMemory()
+	if err != nil {
+		return err
+	}
+	if maxVRAM != "" {
+		if budget, err = parseMemorySize(maxVRAM); err != nil {
+			return err
+		}
+	}
+
+	rows := make([]estimateRow, 0, len(s.Values))
+	output := make([]jsonSweepEstimate, 0, len(s.Values))
+	for _, value := range s.Values {
+		s.apply(&input, value)
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+
+		row := estimateRow{
+			Model:      model,
+			Precision:  precisionName(),
+			Context:    input.ContextLength,
+			GPUs:       result.GPUs,
+			Components: jsonComponents(result),
+			MemBytes:   result.Total * result.GPUs,
+		}
+		estimate := jsonSweepEstimate{
+			Context:    input.ContextLength,
+			MemSize:    formatMemory(result.Total * result.GPUs),
+			MemBytes:   result.Total * result.GPUs,
+			Components: jsonComponents(result),
+		}
+		if s.Flag == "sweep-batch" {
+			row.Batch = value
+			estimate.Batch = value
+		}
+		if result.GPUs > 1 {
+			estimate.MemBytesPerGPU = result.Total
+		}
+		if budget > 0 {
+			fits := result.Total <= budget
+			estimate.Fits = &fits
+		}
+		rows = append(rows, row)
+		output = append(output, estimate)
+	}
+
+	switch {
+	case quiet:
+		for i, row := range rows {
+			if bytesOutput {
+				fmt.Fprintf(w, "%d\t%d\n", s.Values[i], row.MemBytes)
+			} else {
+				fmt.Fprintf(w, "%d\t%s\n", s.Values[i], formatMemory(row.MemBytes))
+			}
+		}
+		return nil
+	case tabularFormats[format]:
+		if err := writeTable(w, format, rows); err != nil {
+			return err
+		}
+		if reportMinMax {
+			return writeSweepMinMax(w, format, s, rows)
+		}
+		return nil
+	case format != "text" && reportMinMax:
+		lowest, highest := sweepExtremes(rows)
+		extreme := func(i int) jsonSweepExtreme {
+			return jsonSweepExtreme{Context: output[i].Context, Batch: output[i].Batch, MemSize: output[i].MemSize, MemBytes: output[i].MemBytes}
+		}
+		return writeStructured(w, format, jsonSweepMinMax{Estimates: output, Min: extreme(lowest), Max: extreme(highest)})
+	case format != "text":
+		return writeStructured(w, format, output)
+	}
+
+	var largest int
+	for _, row := range rows {
+		largest = max(largest, row.MemBytes)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := s.Name + "\tKV cache\tTotal"
+	if budget > 0 {
+		header += "\tFits"
+	}
+	fmt.Fprintln(tw, header+"\t")
+	fitting := 0
+	for i, row := range rows {
+		var kvCache int
+		for _, c := range row.Components {
+			if c.Name == "kv cache" {
+				kvCache = c.Bytes
+			}
+		}
+		cells := []string{strconv.Itoa(s.Values[i]), formatMemory(kvCache), formatMemory(row.MemBytes)}
+		if fits := output[i].Fits; fits != nil {
+			verdict := "no"
+			if *fits {
+				verdict = "yes"
+				fitting = max(fitting, s.Values[i])
+			}
+			cells = append(cells, verdict)
+		}
+		cells = append(cells, strings.Repeat("#", max(row.MemBytes*sweepBarWidth/max(largest, 1), 1)))
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if reportMinMax {
+		lowest, highest := sweepExtremes(rows)
+		fmt.Fprintf(w, "Least memory: %s at %d %s\n", formatMemory(rows[lowest].MemBytes), s.Values[lowest], s.Unit)
+		fmt.Fprintf(w, "Most memory: %s at %d %s\n", formatMemory(rows[highest].MemBytes), s.Values[highest], s.Unit)
+	}
+	if budget > 0 {
+		name := strings.ToLower(s.Name)
+		if fitting == 0 {
+			fmt.Fprintf(w, "No %s fits in %s per GPU\n", name, formatMemory(budget))
+		} else {
+			fmt.Fprintf(w, "Largest %s that fits in %s per GPU: %d %s\n", name, formatMemory(budget), fitting, s.Unit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+)
+
+func TestWriteSweepMinMax(t *testing.T) {
+	reportMinMax = true
+	t.Cleanup(func() { reportMinMax = false })
+
+	input := estimator.ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, BatchSize: 1}
+	s := contextSweep([]int{4096, 1024, 2048})
+
+	var out bytes.Buffer
+	if err := writeSweep(&out, "json", "7b", input, s); err != nil {
+		t.Fatal(err)
+	}
+	var got jsonSweepMinMax
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(got.Estimates) != 3 {
+		t.Fatalf("got %d estimates, want 3", len(got.Estimates))
+	}
+	lowest, highest := got.Estimates[0], got.Estimates[0]
+	for _, e := range got.Estimates {
+		if e.MemBytes < lowest.MemBytes {
+			lowest = e
+		}
+		if e.MemBytes > highest.MemBytes {
+			highest = e
+		}
+	}
+	if got.Min.Context != 1024 || got.Min.MemBytes != lowest.MemBytes {
+		t.Errorf("min = %+v, want context 1024 and %d bytes", got.Min, lowest.MemBytes)
+	}
+	if got.Max.Context != 4096 || got.Max.MemBytes != highest.MemBytes {
+		t.Errorf("max = %+v, want context 4096 and %d bytes", got.Max, highest.MemBytes)
+	}
+
+	out.Reset()
+	if err := writeSweep(&out, "csv", "7b", input, s); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := fmt.Sprintf("\nextreme,context,mem_bytes\nmin,1024,%d\nmax,4096,%d\n", lowest.MemBytes, highest.MemBytes)
+	if !strings.HasSuffix(out.String(), wantCSV) {
+		t.Errorf("CSV output %q does not end with %q", out.String(), wantCSV)
+	}
+
+	out.Reset()
+	if err := writeSweep(&out, "text", "7b", input, s); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("Least memory: %s at 1024 tokens\n", formatMemory(lowest.MemBytes)),
+		fmt.Sprintf("Most memory: %s at 4096 tokens\n", formatMemory(highest.MemBytes)),
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("text output %q does not contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import "testing"
+
+func TestThroughputBindsGPUs(t *testing.T) {
+	// A 7b model fits on one 80 GB GPU, but 1000 tokens/s at 300 per GPU takes four
+	got := estimateRoot(t, "-s", "7b", "--precision", "fp16", "--gpu-memory", "80gb",
+		"--target-tokens-per-second", "1000", "--tokens-per-second-per-gpu", "300")
+
+	if got.GPUsForMemory != 1 || got.GPUsForThroughput != 4 {
+		t.Errorf("GPUs for memory and throughput = %d and %d, want 1 and 4", got.GPUsForMemory, got.GPUsForThroughput)
+	}
+	if got.GPUsRequired != 4 || got.BindingConstraint != "throughput" {
+		t.Errorf("GPUs required = %d bound by %q, want 4 bound by throughput", got.GPUsRequired, got.BindingConstraint)
+	}
+}
@@ -0,0 +1,4 @@
+package cmd
+
+// estimateModes lists the values --mode accepts
+var estimateModes = []string{"inference", "train"}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// visionFlags lists the flags describing the vision tower of a vision-language model
+var visionFlags = []string{"projector-params", "vision-precision", "image-tokens", "images"}
+
+// visionTower returns the vision encoder and projector given with --vision-params and
+// --projector-params, along with the image tokens they add to each sequence. It is the
+// zero VisionTower for a text-only model.
+func visionTower(cmd *cobra.Command) (estimator.VisionTower, error) {
+	var vision estimator.VisionTower
+	if visionParams == "" {
+		for _, name := range visionFlags {
+			if cmd.Flags().Changed(name) {
+				return vision, errors.New("--" + name + " requires --vision-params")
+			}
+		}
+		return vision, nil
+	}
+
+	var err error
+	vision.EncoderParameters, err = getParameterSize(visionParams)
+	if err != nil {
+		return vision, err
+	}
+	if projectorParams != "" {
+		vision.ProjectorParameters, err = getParameterSize(projectorParams)
+		if err != nil {
+			return vision, err
+		}
+	}
+	if visionPrecision != "" {
+		vision.Precision, err = lookupPrecision(visionPrecision)
+		if err != nil {
+			return vision, err
+		}
+	}
+	if imageTokens <= 0 || images < 0 {
+		return vision, errors.New("invalid image tokens or images; must be greater than 0 and 0 or more")
+	}
+	vision.ImageTokens = imageTokens
+	vision.Images = images
+	return vision, nil
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ashprao/gpu-mem-for-llm/pkg/estimator"
+	"github.com/spf13/cobra"
+)
+
+// clearScreen moves the cursor home and clears a terminal so each sample replaces the last
+const clearScreen = "\033[H\033[2J"
+
+// jsonWatchGPU is the shape of a single GPU in a watch sample
+type jsonWatchGPU struct {
+	Index          int    `json:"index"`
+	Name           string `json:"name"`
+	EstimatedBytes int    `json:"estimated_bytes"`
+	UsedBytes      int    `json:"used_bytes"`
+	MemoryBytes    int    `json:"memory_bytes"`
+}
+
+// jsonWatchSample is the shape of a single sample of watch with --json, written one per
+// line
+type jsonWatchSample struct {
+	Time                   string         `json:"time"`
+	EstimatedBytes         int            `json:"estimated_bytes"`
+	UsedBytes              int            `json:"used_bytes"`
+	DifferenceBytes        int            `json:"difference_bytes"`
+	DifferencePercent      float64        `json:"difference_percent"`
+	OverheadPercent        float32        `json:"overhead_percent"`
+	ImpliedOverheadPercent float64        `json:"implied_overhead_percent"`
+	GPUs                   []jsonWatchGPU `json:"gpus"`
+}
+
+// parseDevices parses the comma separated GPU indices of --devices
+func parseDevices(value string) ([]int, error) {
+	var devices []int
+	for _, entry := range strings.Split(value, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid device %q; must be a GPU index such as 0", entry)
+		}
+		devices = append(devices, index)
+	}
+	return devices, nil
+}
+
+// watchedGPUs returns the local GPUs the model runs on, all of them when no devices are
+// given
+func watchedGPUs(devices []int) ([]localGPU, error) {
+	gpus, err := detectGPUs(vendor)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return gpus, nil
+	}
+	var watched []localGPU
+	for _, index := range devices {
+		i := slices.IndexFunc(gpus, func(g localGPU) bool { return g.Index == index })
+		if i < 0 {
+			return nil, fmt.Errorf("no local GPU with index %d", index)
+		}
+		watched = append(watched, gpus[i])
+	}
+	return watched, nil
+}
+
+// impliedOverhead returns the overhead percentage that would make the estimate match the
+// memory in use, over the components the estimate adds it to
+func impliedOverhead(result estimator.Estimate, used int) float64 {
+	base := (result.Total - result.Overhead) * result.GPUs
+	if base <= 0 {
+		return 0
+	}
+	return 100 * (float64(used)/float64(base) - 1)
+}
+
+// watchSample compares the estimate, split evenly over the GPUs, with the memory in use on
+// each of them
+func watchSample(now time.Time, result estimator.Estimate, gpus []localGPU) jsonWatchSample {
+	sample := jsonWatchSample{
+		Time:            now.Format(time.RFC3339),
+		EstimatedBytes:  result.Total * result.GPUs,
+		OverheadPercent: result.OverheadPercent,
+	}
+	for _, g := range gpus {
+		used := g.Memory - g.Free
+		sample.UsedBytes += used
+		sample.GPUs = append(sample.GPUs, jsonWatchGPU{
+			Index:          g.Index,
+			Name:           g.Name,
+			EstimatedBytes: result.Total,
+			UsedBytes:      used,
+			MemoryBytes:    g.Memory,
+		})
+	}
+	sample.DifferenceBytes = sample.UsedBytes - sample.EstimatedBytes
+	sample.DifferencePercent = math.Round(1000*float64(sample.DifferenceBytes)/float64(sample.EstimatedBytes)) / 10
+	sample.ImpliedOverheadPercent = math.Round(10*impliedOverhead(result, sample.UsedBytes)) / 10
+	return sample
+}
+
+// signedMemory formats a difference in memory with its sign
+func signedMemory(bytes int) string {
+	if bytes < 0 {
+		return "-" + formatMemory(-bytes)
+	}
+	return "+" + formatMemory(bytes)
+}
+
+// printWatchSample prints the estimated and actual memory of each GPU side by side, with
+// the overhead the actual memory implies
+func printWatchSample(w io.Writer, sample jsonWatchSample, interval time.Duration) error {
+	fmt.Fprintf(w, "Estimated vs actual memory at %s, refreshing every %s (Ctrl+C to stop)\n", sample.Time, interval)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GPU\tName\tEstimated\tActual\tDifference")
+	for _, g := range sample.GPUs {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s of %s\t%s\n", g.Index, g.Name, formatMemory(g.EstimatedBytes), formatMemory(g.UsedBytes), formatMemory(g.MemoryBytes), signedMemory(g.UsedBytes-g.EstimatedBytes))
+	}
+	if len(sample.GPUs) > 1 {
+		fmt.Fprintf(tw, "Total\t\t%s\t%s\t%s\n", formatMemory(sample.EstimatedBytes), formatMemory(sample.UsedBytes), signedMemory(sample.DifferenceBytes))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Difference: %s (%+.1f%%); the actual memory implies an overhead of %.1f%% against the %g%% assumed\n", signedMemory(sample.DifferenceBytes), sample.DifferencePercent, sample.ImpliedOverheadPercent, sample.OverheadPercent)
+	return nil
+}
+
+// watchCmd compares the estimate with the memory in use on the local GPUs as a model runs
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Compare the estimate with the live memory usage of the local GPUs",
+	Long: `Provide the model that is running to poll nvidia-smi, rocm-smi or the unified memory
+of a Mac every --interval and display the estimated and actual memory of each GPU side by
+side, along with the overhead percentage the actual memory implies. The estimate is split
+evenly across the GPUs watched, all of the local GPUs or those given with --devices. The
+actual memory is everything in use on each GPU, including other processes.
+
+For example:
+./gpu-mem-for-llm watch --model llama3.1-8b --precision bf16 --context 8192 --devices 0
+`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyModelFlags(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		out := cmd.OutOrStdout()
+
+		if watchInterval <= 0 {
+			return errors.New("invalid interval; must be greater than 0")
+		}
+		if watchCount < 0 {
+			return errors.New("invalid count; must be 0 or greater")
+		}
+		var devices []int
+		if watchDevices != "" {
+			var err error
+			if devices, err = parseDevices(watchDevices); err != nil {
+				return err
+			}
+		}
+		gpus, err := watchedGPUs(devices)
+		if err != nil {
+			return err
+		}
+
+		input, err := modelSpecFromFlags()
+		if err != nil {
+			return err
+		}
+		input.TensorParallel = len(gpus)
+		result, err := calculate(input)
+		if err != nil {
+			return err
+		}
+
+		// Each sample replaces the last on a terminal, and is appended otherwise so the
+		// output can be logged
+		f, ok := out.(*os.File)
+		redraw := ok && !jsonOutput && isTerminal(f)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for samples := 1; ; samples++ {
+			sample := watchSample(time.Now(), result, gpus)
+			if jsonOutput {
+				err = writeStructured(out, "json", sample)
+			} else {
+				if redraw {
+					fmt.Fprint(out, clearScreen)
+				} else if samples > 1 {
+					fmt.Fprintln(out)
+				}
+				err = printWatchSample(out, sample, watchInterval)
+			}
+			if err != nil {
+				return err
+			}
+			if samples == watchCount {
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+			if gpus, err = watchedGPUs(devices); err != nil {
+				return err
+			}
+		}
+	},
+}
+
+var (
+	watchInterval time.Duration
+	watchCount    int
+	watchDevices  string
+)
+
+func init() {
+	addModelFlags(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 2*time.Second, "time between samples (e.g., 5s)")
+	watchCmd.Flags().IntVar(&watchCount, "count", 0, "number of samples to take before exiting, 0 to watch until interrupted")
+	watchCmd.Flags().StringVar(&watchDevices, "devices", "", "comma separated indices of the GPUs the model runs on (default all of them)")
+	watchCmd.Flags().StringVar(&vendor, "vendor", "auto", "vendor of the GPUs to watch (auto, "+strings.Join(gpuVendors, ", ")+"), needed when both are present")
+	watchCmd.Flags().BoolVar(&jsonOutput, "json", false, "output each sample as a line of JSON")
+	rootCmd.AddCommand(watchCmd)
+}
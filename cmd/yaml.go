@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+)
+
+// cutYAMLKeyValue splits a "key: value" line of the subset of YAML read from config and
+// batch files, trimming both and removing a trailing comment from the value
+func cutYAMLKeyValue(line string) (key, value string, ok bool) {
+	key, value, found := strings.Cut(line, ":")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", false
+	}
+	return key, cutYAMLComment(strings.TrimSpace(value)), true
+}
+
+// cutYAMLComment removes a trailing comment from a YAML value. A '#' only starts a comment
+// at the start of the value or after a space or tab, and not inside a quoted value, so
+// values such as "a#b" are kept whole.
+func cutYAMLComment(value string) string {
+	start := 0
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			start = end + 2
+		}
+	}
+	for i := start; i < len(value); i++ {
+		if value[i] == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// unquoteYAML removes the single or double quotes around a YAML value, reporting whether
+// there were any
+func unquoteYAML(value string) (string, bool) {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1], true
+	}
+	return value, false
+}
+
+// isYAMLFlowMap reports whether a value is a flow mapping such as "{name: b, size: 13b}"
+func isYAMLFlowMap(value string) bool {
+	return strings.HasPrefix(value, "{")
+}
+
+// parseYAMLFlowMap parses the "key: value" pairs of a flow mapping such as
+// "{name: b, size: 13b}" in the order they are given. Commas inside quoted values don't
+// separate pairs, and the values keep their quotes.
+func parseYAMLFlowMap(value string) ([][2]string, error) {
+	inner, _ := strings.CutPrefix(value, "{")
+	inner, found := strings.CutSuffix(inner, "}")
+	if !found {
+		return nil, errors.New("expected a flow mapping ending in '}'")
+	}
+
+	var pairs [][2]string
+	var quote byte
+	start := 0
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) {
+			switch c := inner[i]; {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c != ',':
+				continue
+			}
+		}
+		entry := strings.TrimSpace(inner[start:i])
+		start = i + 1
+		if entry == "" {
+			continue
+		}
+		key, val, ok := cutYAMLKeyValue(entry)
+		if !ok {
+			return nil, errors.New("expected 'key: value' pairs separated by commas")
+		}
+		pairs = append(pairs, [2]string{key, val})
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quoted value")
+	}
+	return pairs, nil
+}
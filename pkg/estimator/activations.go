@@ -0,0 +1,80 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+)
+
+// minActivationBytes is the size of a single activation for weights stored below 16 bits;
+// quantized weights are dequantized and multiplied against fp16 or bf16 activations.
+const minActivationBytes = 2
+
+// mlpRatio is the MLP intermediate size as a multiple of the hidden dimension assumed when
+// the intermediate size isn't known.
+const mlpRatio = 4
+
+// calculateActivationWidth returns the activations held per token at the peak of a forward
+// pass, in the MLP of a layer: the residual stream and its normalized copy of the hidden
+// dimension, the gate and up projections of the intermediate size and their product.
+// Layers run one after another at inference, so only one layer's activations are live.
+func calculateActivationWidth(hiddenDim, intermediateSize int) int {
+	if intermediateSize <= 0 {
+		intermediateSize = mlpRatio * hiddenDim
+	}
+	return 2*hiddenDim + 3*intermediateSize
+}
+
+// activationComponent returns the peak activation memory while prefilling the context for
+// every sequence in the batch in a single forward pass.
+func activationComponent(hiddenDim, intermediateSize, contextLength, batchSize int, precision Precision) Component {
+	width := calculateActivationWidth(hiddenDim, intermediateSize)
+	bytes := max(precision, minActivationBytes)
+	if intermediateSize <= 0 {
+		intermediateSize = mlpRatio * hiddenDim
+	}
+	return Component{
+		Name:  "activations",
+		Bytes: int(float64(batchSize) * float64(contextLength) * float64(width) * float64(bytes)),
+		Formula: fmt.Sprintf("%d sequences x %s tokens x (2 x %d hidden + 3 x %d intermediate) x %g bytes",
+			batchSize, FormatCount(contextLength), hiddenDim, intermediateSize, bytes),
+	}
+}
+
+// trainingActivationWidth is the activations each layer keeps per token and hidden dimension
+// for the backward pass, 34 bytes at 16-bit following Korthikanti et al., with flash
+// attention so the attention scores aren't kept.
+const trainingActivationWidth = 17
+
+// calculateCheckpointSegments returns the number of segments the layers are split into with
+// gradient checkpointing, the square root of the number of layers rounded up, which
+// balances the checkpoints kept against the layers recomputed at once.
+func calculateCheckpointSegments(numLayers int) int {
+	return int(math.Ceil(math.Sqrt(float64(numLayers))))
+}
+
+// trainingActivationComponent returns the activations kept for the backward pass. Every
+// layer keeps its activations, unless gradient checkpointing keeps only the input of each
+// segment of layers and recomputes the activations of one segment at a time.
+func trainingActivationComponent(numLayers, hiddenDim, contextLength, batchSize int, precision Precision, checkpointing bool) Component {
+	bytes := max(precision, minActivationBytes)
+	tokens := float64(batchSize) * float64(contextLength)
+	layer := tokens * float64(trainingActivationWidth*hiddenDim) * float64(bytes)
+	if !checkpointing {
+		return Component{
+			Name:  "activations",
+			Bytes: int(float64(numLayers) * layer),
+			Formula: fmt.Sprintf("%d layers x %d sequences x %s tokens x %d x %d hidden x %g bytes",
+				numLayers, batchSize, FormatCount(contextLength), trainingActivationWidth, hiddenDim, bytes),
+		}
+	}
+
+	segments := calculateCheckpointSegments(numLayers)
+	segmentLayers := (numLayers + segments - 1) / segments
+	checkpoints := float64(segments) * tokens * float64(hiddenDim) * float64(bytes)
+	return Component{
+		Name:  fmt.Sprintf("activations (checkpointed every %d layers)", segmentLayers),
+		Bytes: int(checkpoints + float64(segmentLayers)*layer),
+		Formula: fmt.Sprintf("(%d checkpoints x %d hidden + %d layers x %d x %d hidden) x %d sequences x %s tokens x %g bytes",
+			segments, hiddenDim, segmentLayers, trainingActivationWidth, hiddenDim, batchSize, FormatCount(contextLength), bytes),
+	}
+}
@@ -0,0 +1,62 @@
+package estimator
+
+// CalculateEmbeddingParameters returns the number of parameters in the input embedding
+// table and the LM head, each of which is a vocab x hidden matrix. Models with tied
+// embeddings reuse the embedding table as the LM head, so it is only counted once.
+func CalculateEmbeddingParameters(vocabSize, hiddenDim int, tied bool) int {
+	if tied {
+		return vocabSize * hiddenDim
+	}
+	return 2 * vocabSize * hiddenDim
+}
+
+// LayerParameters holds the parameter counts of a single transformer block
+type LayerParameters struct {
+	Attention int
+	MLP       int
+	Norms     int
+}
+
+// CalculateLayerParameters returns the parameter counts of one transformer block. The
+// attention has query, key, value and output projections of hidden x hidden, the MLP is
+// gated as in Llama-style models with gate, up and down projections of hidden x
+// intermediate, and there are two normalization layers.
+func CalculateLayerParameters(hiddenDim, intermediateSize int) LayerParameters {
+	return LayerParameters{
+		Attention: 4 * hiddenDim * hiddenDim,
+		MLP:       3 * hiddenDim * intermediateSize,
+		Norms:     2 * hiddenDim,
+	}
+}
+
+// Total returns the number of parameters in the block
+func (p LayerParameters) Total() int {
+	return p.Attention + p.MLP + p.Norms
+}
+
+// CalculateNormParameters returns the number of normalization parameters: two norms in
+// every transformer block and a final norm before the LM head, each of the hidden dimension.
+func CalculateNormParameters(numLayers, hiddenDim int) int {
+	return (2*numLayers + 1) * hiddenDim
+}
+
+// CalculateArchitectureParameters returns the total number of parameters of a Llama-style
+// model from its architecture: every transformer block, the final norm and the embeddings.
+func CalculateArchitectureParameters(numLayers, hiddenDim, intermediateSize, vocabSize int, tied bool) int {
+	blocks := numLayers * CalculateLayerParameters(hiddenDim, intermediateSize).Total()
+	return blocks + hiddenDim + CalculateEmbeddingParameters(vocabSize, hiddenDim, tied)
+}
+
+// minimumArchitectureParameters returns the fewest parameters a model of the spec's
+// architecture can have: the query and output projections of every layer, the key and
+// value projections at the width of the KV heads, the norms and the embeddings. The MLP
+// is left out, as its width isn't always known, so any real model has more.
+func (in ModelSpec) minimumArchitectureParameters() int {
+	kvDim := calculateKVDimension(in.HiddenDim, in.HeadDim, in.KVHeads, 0)
+	attention := 2*in.HiddenDim*in.HiddenDim + 2*in.HiddenDim*kvDim
+	params := in.NumLayers*attention + CalculateNormParameters(in.NumLayers, in.HiddenDim)
+	if in.VocabSize > 0 {
+		params += CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, in.TiedEmbeddings)
+	}
+	return params
+}
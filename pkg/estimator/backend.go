@@ -0,0 +1,175 @@
+package estimator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BuiltinBackend is the name of the estimator of this package, Calculate
+const BuiltinBackend = "builtin"
+
+// commandTimeout bounds how long the program of a CommandEstimator may take
+const commandTimeout = 30 * time.Second
+
+// Estimator estimates the memory of a model. Calculate is the built-in estimator, and
+// another, such as a memory model fitted to measurements, can be registered with Register
+// to produce the estimates in its place.
+type Estimator interface {
+	Estimate(in ModelSpec) (Estimate, error)
+}
+
+// EstimatorFunc adapts a function to an Estimator
+type EstimatorFunc func(in ModelSpec) (Estimate, error)
+
+// Estimate calls the function
+func (f EstimatorFunc) Estimate(in ModelSpec) (Estimate, error) {
+	return f(in)
+}
+
+// ErrBackendFailed is returned when the program of a CommandEstimator can't be run or
+// doesn't answer with an estimate, rather than rejecting the model
+var ErrBackendFailed = errors.New("the estimation backend failed")
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Estimator{BuiltinBackend: EstimatorFunc(Calculate)}
+)
+
+// Register makes an estimator available under a name, as database/sql does with drivers,
+// usually from the init function of the package defining it. It panics when the name is
+// taken or the estimator is nil.
+func Register(name string, e Estimator) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if e == nil {
+		panic("estimator: Register of a nil estimator")
+	}
+	name = strings.ToLower(name)
+	if _, ok := backends[name]; ok {
+		panic(fmt.Sprintf("estimator: Register called twice for %q", name))
+	}
+	backends[name] = e
+}
+
+// Backend returns the estimator registered under a name
+func Backend(name string) (Estimator, error) {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	if e, ok := backends[strings.ToLower(name)]; ok {
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q; must be one of %s, or exec: followed by a program", name, strings.Join(backendNames(), ", "))
+}
+
+// BackendNames returns the names of the registered estimators in alphabetical order
+func BackendNames() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	return backendNames()
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commandComponent is a component in the answer of the program of a CommandEstimator
+type commandComponent struct {
+	Name    string `json:"name"`
+	Bytes   int    `json:"bytes"`
+	Formula string `json:"formula,omitempty"`
+}
+
+// commandResponse is the answer of the program of a CommandEstimator
+type commandResponse struct {
+	Components      []commandComponent `json:"components"`
+	OverheadPercent float32            `json:"overhead_percent"`
+	Overhead        int                `json:"overhead"`
+	Total           int                `json:"total"`
+	GPUs            int                `json:"gpus"`
+	Error           string             `json:"error"`
+}
+
+// CommandEstimator is an estimator implemented by another program, so a memory model can
+// be written in any language. The program is run for each estimate with the ModelSpec
+// encoded as JSON, with its Go field names, on its standard input, and answers on its
+// standard output with a JSON object of the components of a single GPU, such as
+// {"components": [{"name": "weights", "bytes": 14000000000}], "overhead": 2800000000}.
+// The total is the sum of the components and the overhead unless it gives "total", and
+// the GPUs are those of the tensor parallelism unless it gives "gpus". A model the
+// program can't estimate is reported with {"error": "..."}.
+type CommandEstimator struct {
+	Path string
+	Args []string
+}
+
+// Estimate runs the program to estimate the model
+func (c CommandEstimator) Estimate(in ModelSpec) (Estimate, error) {
+	request, err := json.Marshal(in)
+	if err != nil {
+		return Estimate{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	slog.Debug("running estimation backend", "path", c.Path, "args", c.Args)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = fmt.Errorf("%v: %s", err, message)
+		}
+		return Estimate{}, fmt.Errorf("%w: %s: %v", ErrBackendFailed, c.Path, err)
+	}
+
+	var response commandResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return Estimate{}, fmt.Errorf("%w: %s answered with invalid JSON: %v", ErrBackendFailed, c.Path, err)
+	}
+	if response.Error != "" {
+		return Estimate{}, errors.New(response.Error)
+	}
+	if len(response.Components) == 0 {
+		return Estimate{}, fmt.Errorf("%w: %s answered without any components", ErrBackendFailed, c.Path)
+	}
+
+	result := Estimate{
+		OverheadPercent: response.OverheadPercent,
+		Overhead:        response.Overhead,
+		Total:           response.Total,
+		GPUs:            response.GPUs,
+	}
+	var sum int
+	for _, component := range response.Components {
+		result.Components = append(result.Components, Component{Name: component.Name, Bytes: component.Bytes, Formula: component.Formula})
+		sum += component.Bytes
+	}
+	if result.Total == 0 {
+		result.Total = sum + result.Overhead
+	}
+	if result.OverheadPercent == 0 && sum > 0 {
+		result.OverheadPercent = float32(math.Round(float64(result.Overhead)*1000/float64(sum)) / 10)
+	}
+	if result.GPUs == 0 {
+		result.GPUs = max(in.TensorParallel, 1)
+	}
+	logEstimate(result)
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package estimator
+
+import "errors"
+
+// maxConcurrency bounds the search for the most sequences served at once
+const maxConcurrency = 1 << 20
+
+// CalculateConcurrency returns the most sequences of the context length the model serves
+// at once in the given memory of each GPU, before their KV caches exhaust what the
+// weights and everything else leave of it. Serving prefills the prompts of new requests
+// in turn rather than all together, so the activations are sized for a single sequence
+// unless they are already set for a number of tokens. It is zero when not even one
+// sequence fits.
+func CalculateConcurrency(in ModelSpec, memory int) (int, error) {
+	if in.ContextLength <= 0 {
+		return 0, errors.New("the concurrency requires a context length")
+	}
+	if in.ActivationTokens == 0 {
+		in.ActivationTokens = in.ContextLength
+	}
+	fits := func(sequences int) (bool, error) {
+		in.BatchSize = sequences
+		result, err := Calculate(in)
+		if err != nil {
+			return false, err
+		}
+		return result.Total <= memory, nil
+	}
+
+	ok, err := fits(1)
+	if err != nil || !ok {
+		return 0, err
+	}
+	// The memory grows with every sequence, so the most that fit are found by doubling
+	// past them and bisecting back
+	low, high := 1, 2
+	for high <= maxConcurrency {
+		ok, err := fits(high)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		low, high = high, high*2
+	}
+	for high-low > 1 {
+		mid := (low + high) / 2
+		ok, err := fits(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low, nil
+}
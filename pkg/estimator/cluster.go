@@ -0,0 +1,80 @@
+package estimator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ClusterLayout is an estimate laid out across the nodes of a cluster, with as many
+// replicas of the model as its GPUs hold. The GPUs of each replica are filled node by
+// node, a stage at a time for a pipeline parallel model.
+type ClusterLayout struct {
+	Nodes       int
+	GPUsPerNode int
+
+	// Replicas of the model across the cluster, each on GPUsPerReplica GPUs, and the GPUs
+	// left over that can't hold another one
+	Replicas       int
+	GPUsPerReplica int
+	IdleGPUs       int
+
+	// Memory of the GPU and of the node needing the most, and of the whole cluster
+	PerGPU  int
+	PerNode int
+	Total   int
+
+	// CrossNodeTensorParallel reports whether a tensor parallel group has to span nodes,
+	// either because it has more GPUs than a node or because a node's GPUs don't divide
+	// into whole groups
+	CrossNodeTensorParallel bool
+}
+
+// LayoutCluster lays the estimate of a replica of the model, sharded with the given tensor
+// parallel degree, out across the nodes of a cluster.
+func LayoutCluster(result Estimate, tensorParallel, gpusPerNode, nodes int) (ClusterLayout, error) {
+	if gpusPerNode <= 0 || nodes <= 0 {
+		return ClusterLayout{}, errors.New("the GPUs per node and the number of nodes must be greater than 0")
+	}
+	gpus := gpusPerNode * nodes
+	replicaGPUs := max(result.GPUs, 1)
+	if replicaGPUs > gpus {
+		return ClusterLayout{}, fmt.Errorf("a replica of the model needs %d GPUs, more than the %d of %d nodes of %d GPUs", replicaGPUs, gpus, nodes, gpusPerNode)
+	}
+
+	// The memory of each GPU of a replica, the largest stage's for every GPU when the
+	// model isn't split into stages
+	var replica []int
+	if len(result.Stages) > 0 {
+		perStage := replicaGPUs / len(result.Stages)
+		for _, s := range result.Stages {
+			for range perStage {
+				replica = append(replica, s.Total)
+			}
+		}
+	} else {
+		for range replicaGPUs {
+			replica = append(replica, result.Total)
+		}
+	}
+
+	layout := ClusterLayout{
+		Nodes:                   nodes,
+		GPUsPerNode:             gpusPerNode,
+		Replicas:                gpus / replicaGPUs,
+		GPUsPerReplica:          replicaGPUs,
+		IdleGPUs:                gpus % replicaGPUs,
+		CrossNodeTensorParallel: tensorParallel > 1 && gpusPerNode%tensorParallel != 0,
+	}
+	var node int
+	for i := range layout.Replicas * replicaGPUs {
+		if i%gpusPerNode == 0 {
+			node = 0
+		}
+		bytes := replica[i%replicaGPUs]
+		node += bytes
+		layout.Total += bytes
+		layout.PerGPU = max(layout.PerGPU, bytes)
+		layout.PerNode = max(layout.PerNode, node)
+	}
+	return layout, nil
+}
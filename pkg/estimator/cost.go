@@ -0,0 +1,11 @@
+package estimator
+
+// hoursPerMonth is the average number of hours in a month used for monthly costs
+const hoursPerMonth = 730
+
+// CalculateCost returns the hourly and monthly cost of running the given number of GPUs
+// at the given price per GPU per hour. The price is currency agnostic.
+func CalculateCost(gpuCount int, pricePerHour float64) (perHour, perMonth float64) {
+	perHour = float64(gpuCount) * pricePerHour
+	return perHour, perHour * hoursPerMonth
+}
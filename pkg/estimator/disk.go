@@ -0,0 +1,38 @@
+package estimator
+
+// CalculateDiskSize returns the size of the model files of the checkpoint the input
+// describes, which is what has to be stored and downloaded rather than what is loaded.
+// Every parameter is counted at its precision, with the embeddings and LM head at theirs
+// and the scales of fp8 weights, but none of the runtime memory: the KV cache,
+// activations, overhead and training states are allocated when the model runs, and
+// streamed or offloaded weights are still on disk. The metadata of a safetensors or GGUF
+// file is a few megabytes at most and is left out.
+func CalculateDiskSize(in ModelSpec) (int, error) {
+	blockParams := in.ParameterSize
+	var size int
+	if in.VocabSize > 0 && in.HiddenDim > 0 {
+		blockParams -= CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, in.TiedEmbeddings)
+		table := in.VocabSize * in.HiddenDim
+		embeddingPrecision, headPrecision := in.Precision, in.Precision
+		if in.EmbeddingPrecision > 0 {
+			embeddingPrecision = in.EmbeddingPrecision
+		}
+		if in.HeadPrecision > 0 {
+			headPrecision = in.HeadPrecision
+		}
+		size += CalculateWeightMemory(table, embeddingPrecision)
+		if !in.TiedEmbeddings {
+			size += CalculateWeightMemory(table, headPrecision)
+		}
+	}
+	size += in.WeightMemory(max(blockParams, 0))
+
+	if in.FP8Scaling != "" {
+		scales, err := fp8ScaleComponent(in.FP8Scaling, in.NumLayers, in.HiddenDim)
+		if err != nil {
+			return 0, err
+		}
+		size += scales.Bytes
+	}
+	return size, nil
+}
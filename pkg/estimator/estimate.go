@@ -0,0 +1,602 @@
+// Package estimator calculates the GPU memory needed to serve or fine-tune a large
+// language model. Describe the model with a ModelSpec and pass it to Calculate to get an
+// Estimate broken down into its components.
+package estimator
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ModelSpec holds every value the estimate depends on. Only the parameter size and
+// precision are required; the other terms are included when their fields are set.
+type ModelSpec struct {
+	ParameterSize int
+	Precision     Precision
+	Overhead      float32
+
+	// Parameters a mixture-of-experts model uses for each token, zero for a dense model.
+	// The weights still hold every expert, so only per-token terms use this count.
+	ActiveParameters int
+
+	// Granularity of the scaling factors stored alongside fp8 weights, tensor or channel,
+	// empty when no scales are included
+	FP8Scaling string
+
+	// Precision of a second copy of the weights loaded alongside the first for A/B
+	// testing, zero when there is only one copy
+	ABPrecisionName string
+	ABPrecision     Precision
+
+	// Precisions of the embedding table and the LM head when they are kept apart from the
+	// quantized transformer blocks, such as at fp16, zero when they share the precision
+	EmbeddingPrecision Precision
+	HeadPrecision      Precision
+
+	// N:M structured sparsity applied to a fraction of the weights, zero when dense
+	SparseKept     int
+	SparseGroup    int
+	SparseFraction float32
+
+	// Architecture details, zero when not provided
+	HiddenDim      int
+	VocabSize      int
+	NumLayers      int
+	TiedEmbeddings bool
+	FoldNorms      bool
+
+	// MLP intermediate size, taken as four times the hidden dimension when zero
+	IntermediateSize int
+
+	// Weight streaming from host memory, keeping only a window of layers resident
+	StreamWeights bool
+	DoubleBuffer  bool
+
+	// Attention head dimension and the multiple kernels pad it to, zero when not provided
+	HeadDim         int
+	HeadDimMultiple int
+
+	// Query heads of attention, zero when not provided. The head dimension isn't derived
+	// from them, but the hidden dimension and any key/value heads are checked against them.
+	Heads int
+
+	// Key/value heads of grouped-query or multi-query attention, which need the head
+	// dimension. Zero when every query head has its own.
+	KVHeads int
+
+	// Positions the rotary embedding tables are precomputed for and the RoPE scaling factor
+	// extending them, only included when the positions are provided
+	MaxPositions int
+	RoPEScaling  float64
+
+	// KV cache, only included when a context length is provided. The cache is stored at
+	// the precision of the weights unless given its own.
+	KVPrecision   Precision
+	ContextLength int
+	BatchSize     int
+	KVBlockSize   int
+	RoundContext  bool
+
+	// Share of the blocks of a paged KV cache expected to hold tokens, the rest lost to
+	// fragmentation and to the blocks the scheduler keeps free. Zero when all of them do.
+	KVUtilization float64
+
+	// Sliding window of attention, capping the tokens of each sequence kept in the KV
+	// cache. Zero when every layer attends to the whole context.
+	SlidingWindow int
+
+	// Tokens the activation buffers of inference are allocated for up front, in place of
+	// the context of the batch, as engines built ahead of time size them for the most
+	// tokens a forward pass accepts. Zero to size them for the context and batch.
+	ActivationTokens int
+
+	// Leaves out the activations, which are otherwise included along with the KV cache:
+	// those of the prefill forward pass for inference, or those kept for the backward pass
+	// when training
+	SkipActivations bool
+
+	// Gradient checkpointing when training, keeping the activations of only some layers
+	// and recomputing the rest
+	GradientCheckpointing bool
+
+	// Scheduler buckets sizing the KV cache instead of the context and batch size
+	KVBuckets []KVBucket
+
+	// Number of GPUs the model is sharded across with tensor parallelism, and whether
+	// the framework replicates the embeddings on each of them instead of sharding them
+	TensorParallel      int
+	ReplicateEmbeddings bool
+
+	// Number of stages the layers are split across with pipeline parallelism, each on its
+	// own GPUs
+	PipelineParallel int
+
+	// Full fine-tuning, adding gradients and optimizer states for every parameter
+	Train bool
+
+	// Pure bf16 training, updating the bf16 weights directly with bf16 optimizer states
+	// instead of keeping an fp32 master copy of the weights as mixed precision does
+	PureBF16 bool
+
+	// Optimizer whose states are kept for the trainable parameters when training, one of
+	// Optimizers, AdamW when empty
+	Optimizer string
+
+	// ZeRO stage of data parallel training and the number of GPUs the training state is
+	// partitioned across, zero when it isn't partitioned
+	ZeROStage    int
+	DataParallel int
+
+	// FSDP sharding strategy and the number of GPUs in each sharding group for
+	// hybrid-shard, empty when FSDP isn't used
+	FSDPStrategy    string
+	FSDPShardDegree int
+
+	// Keeps the optimizer states in host memory instead of on the GPU when training
+	OffloadOptimizer bool
+
+	// LoRA fine-tuning, only included when a rank is provided
+	LoRARank    int
+	LoRATargets int
+
+	// Precision of the adapter weights and gradients, fp32 when zero
+	LoRAAdapterPrecision Precision
+
+	// LayerSkip self-speculation
+	LayerSkip   bool
+	DraftTokens int
+
+	// Medusa speculative decoding, only included when a number of heads is provided
+	MedusaHeads      int
+	MedusaTreeTokens int
+
+	// Separate draft model for speculative decoding, resident alongside the target model,
+	// only included when its parameters are provided
+	Draft DraftModel
+
+	// Vision encoder and projector of a vision-language model, only included when the
+	// parameters of the encoder are provided. The image tokens of each sequence are added
+	// to the context length.
+	Vision VisionTower
+
+	// Vector index for retrieval kept in GPU memory alongside the model, only included
+	// when a number of vectors is provided
+	IndexVectors   int
+	IndexDim       int
+	IndexPrecision Precision
+
+	// Set on the spec of each pipeline stage: the micro-batches whose activations it keeps
+	// when training, and whether it holds buffers for the hidden states passed between
+	// stages
+	inFlightMicroBatches int
+	pipelineBuffers      bool
+}
+
+// Component is a single named term of the estimate, in bytes, along with the
+// arithmetic it was calculated from for --explain.
+type Component struct {
+	Name    string
+	Bytes   int
+	Formula string
+
+	// ShardLimit is the most GPUs the component can be split across with tensor
+	// parallelism, zero when it splits across any number and one when it is replicated
+	ShardLimit int
+}
+
+// Estimate is the result of a calculation: the individual components and the total
+// memory required once the overhead has been applied to them. When the model is sharded
+// across several GPUs, the components and totals describe a single GPU.
+type Estimate struct {
+	Components      []Component
+	OverheadPercent float32
+	Overhead        int
+	Total           int
+	GPUs            int
+
+	// Offloaded holds the components kept in host memory instead of on the GPU, which
+	// aren't part of the total
+	Offloaded []Component
+
+	// Stages holds every stage of a pipeline parallel model, whose largest stage the rest
+	// of the estimate describes
+	Stages []PipelineStage
+}
+
+// CalculateWeightMemory returns the memory in bytes needed to hold the model weights.
+func CalculateWeightMemory(parameterSize int, precision Precision) int {
+	return bytesOf(float64(parameterSize), precision)
+}
+
+// WeightMemory returns the memory for the given number of transformer weights, applying
+// structured sparsity when it is set.
+func (in ModelSpec) WeightMemory(parameterSize int) int {
+	if in.SparseGroup > 0 {
+		return calculateSparseWeightMemory(parameterSize, in.Precision, in.SparseKept, in.SparseGroup, in.SparseFraction)
+	}
+	return CalculateWeightMemory(parameterSize, in.Precision)
+}
+
+// weightFormula describes how WeightMemory was calculated for the given number of weights.
+func (in ModelSpec) weightFormula(parameterSize int) string {
+	formula := weightFormula(parameterSize, in.Precision)
+	if in.SparseGroup > 0 {
+		formula += fmt.Sprintf(" with %d:%d sparsity on %g%% of the weights", in.SparseKept, in.SparseGroup, in.SparseFraction*100)
+	}
+	return formula
+}
+
+// weightComponent returns a component for the given number of transformer weights.
+func (in ModelSpec) weightComponent(name string, parameterSize int) Component {
+	return Component{
+		Name:    name,
+		Bytes:   in.WeightMemory(parameterSize),
+		Formula: in.weightFormula(parameterSize),
+	}
+}
+
+// ParametersPerToken returns the number of parameters used to generate each token, which is
+// every parameter unless the model is a mixture of experts.
+func (in ModelSpec) ParametersPerToken() int {
+	if in.ActiveParameters > 0 {
+		return in.ActiveParameters
+	}
+	return in.ParameterSize
+}
+
+// kvPrecision returns the bytes per element of the KV cache.
+func (in ModelSpec) kvPrecision() Precision {
+	if in.KVPrecision > 0 {
+		return in.KVPrecision
+	}
+	return in.Precision
+}
+
+// kvTokens returns the tokens of a sequence kept in the KV cache for the context length,
+// which a sliding window caps at the most recent ones.
+func (in ModelSpec) kvTokens(contextLength int) int {
+	if in.SlidingWindow > 0 {
+		return min(contextLength, in.SlidingWindow)
+	}
+	return contextLength
+}
+
+// KVCacheBytesPerToken returns the memory each token of context adds to the KV cache.
+func (in ModelSpec) KVCacheBytesPerToken() int {
+	kvDim := calculateKVDimension(in.HiddenDim, in.HeadDim, in.KVHeads, in.HeadDimMultiple)
+	return calculateKVCacheBytesPerToken(in.NumLayers, kvDim, in.kvPrecision())
+}
+
+// KVCacheComponent returns the KV cache for the context and batch size, or for the peak
+// scheduler bucket when buckets are given.
+func (in ModelSpec) KVCacheComponent() (Component, error) {
+	if err := in.validateKVCache(); err != nil {
+		return Component{}, err
+	}
+	name, contextLength, batchSize := "kv cache", in.ContextLength, in.BatchSize
+	if len(in.KVBuckets) > 0 {
+		peak := peakKVBucket(in.KVBuckets)
+		name = fmt.Sprintf("kv cache (peak bucket %dx%d)", peak.Count, peak.Tokens)
+		contextLength, batchSize = peak.Tokens, peak.Count
+	}
+	contextLength = in.kvTokens(contextLength)
+	if in.RoundContext {
+		contextLength = RoundUpToMultiple(contextLength, in.KVBlockSize)
+	}
+	kvDim := calculateKVDimension(in.HiddenDim, in.HeadDim, in.KVHeads, in.HeadDimMultiple)
+
+	// The cache is split by attention heads, so it can only be sharded as many ways as
+	// there are heads when the head dimension is known. With fewer KV heads than GPUs,
+	// each KV head is replicated rather than split.
+	var shardLimit int
+	if in.HeadDim > 0 {
+		shardLimit = in.HiddenDim / in.HeadDim
+	}
+	if in.KVHeads > 0 {
+		shardLimit = in.KVHeads
+	}
+	bytes := calculateKVCacheMemory(in.NumLayers, kvDim, contextLength, batchSize, in.kvPrecision())
+	formula := fmt.Sprintf("2 x %d layers x %s x %s tokens x %d sequences x %g bytes",
+		in.NumLayers, kvDimensionFormula(in.HiddenDim, in.HeadDim, in.KVHeads, in.HeadDimMultiple), FormatCount(contextLength), batchSize, in.kvPrecision())
+
+	// Only part of the blocks allocated hold tokens, so the cache takes that much more
+	if in.KVUtilization > 0 && in.KVUtilization < 1 {
+		bytes = int(float64(bytes) / in.KVUtilization)
+		formula = fmt.Sprintf("(%s) / %g utilization", formula, in.KVUtilization)
+	}
+	return Component{
+		Name:       name,
+		Bytes:      bytes,
+		Formula:    formula,
+		ShardLimit: shardLimit,
+	}, nil
+}
+
+// Calculate builds the breakdown of memory components for the given input and
+// applies the overhead percentage to their sum.
+func Calculate(in ModelSpec) (Estimate, error) {
+	if err := in.Validate(); err != nil {
+		return Estimate{}, err
+	}
+	in.ContextLength += in.Vision.imageTokens()
+	if in.PipelineParallel > 1 {
+		return calculatePipeline(in)
+	}
+	tensorParallel := max(in.TensorParallel, 1)
+	dataParallel := max(in.DataParallel, 1)
+
+	// The embedding table and LM head can be a large share of a small model, and norms
+	// are kept in their own buffers, so both are reported on their own when the
+	// architecture is known. An LM head of its own is reported apart from the embedding
+	// table it would otherwise share. They remain part of the parameter count, so the
+	// total is unchanged, unless the embeddings and LM head are kept at a precision of
+	// their own or the runtime folds the norms into the adjacent linear layers and they
+	// need no memory of their own.
+	weightParams := in.ParameterSize
+	var architecture []Component
+	if in.VocabSize > 0 && in.HiddenDim > 0 {
+		weightParams -= CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, in.TiedEmbeddings)
+		names := []string{"embeddings"}
+		if !in.TiedEmbeddings {
+			names = append(names, "lm head")
+		}
+		table := in.VocabSize * in.HiddenDim
+		for _, name := range names {
+			precision := in.Precision
+			if name == "embeddings" && in.EmbeddingPrecision > 0 {
+				precision = in.EmbeddingPrecision
+			} else if name == "lm head" && in.HeadPrecision > 0 {
+				precision = in.HeadPrecision
+			}
+			embeddings := Component{
+				Name:    name,
+				Bytes:   CalculateWeightMemory(table, precision),
+				Formula: weightFormula(table, precision),
+			}
+			if in.ReplicateEmbeddings {
+				embeddings.ShardLimit = 1
+			}
+			architecture = append(architecture, embeddings)
+		}
+	}
+	if in.NumLayers > 0 && in.HiddenDim > 0 {
+		normParams := CalculateNormParameters(in.NumLayers, in.HiddenDim)
+		weightParams -= normParams
+		if !in.FoldNorms {
+			// Quantization leaves the norms in fp16 or bf16, like the activations they scale
+			normPrecision := max(in.Precision, minActivationBytes)
+			architecture = append(architecture, Component{
+				Name:       "norms",
+				Bytes:      CalculateWeightMemory(normParams, normPrecision),
+				Formula:    weightFormula(normParams, normPrecision),
+				ShardLimit: 1,
+			})
+		}
+	}
+	if weightParams < 0 {
+		return Estimate{}, specError("the parameters of the architecture exceed ParameterSize")
+	}
+
+	// Every expert of a mixture-of-experts model stays resident even though each token
+	// only routes through a few of them
+	weightsName := "weights"
+	if in.ActiveParameters > 0 {
+		weightsName = "weights (all experts)"
+	}
+
+	// Streaming keeps the embeddings and norms resident, but only a window of the layers
+	if in.StreamWeights {
+		resident := residentLayers(in.DoubleBuffer)
+		weightParams = calculateStreamedWeightParameters(weightParams, in.NumLayers, resident)
+		weightsName = fmt.Sprintf("%s (%d of %d layers resident)", weightsName, min(resident, in.NumLayers), in.NumLayers)
+	}
+
+	components := append([]Component{in.weightComponent(weightsName, weightParams)}, architecture...)
+
+	if in.FP8Scaling != "" {
+		scales, err := fp8ScaleComponent(in.FP8Scaling, in.NumLayers, in.HiddenDim)
+		if err != nil {
+			return Estimate{}, err
+		}
+		components = append(components, scales)
+	}
+
+	// Everything so far is parameters, which ZeRO-3 partitions
+	paramComponents := len(components)
+
+	if in.ABPrecision > 0 {
+		components = append(components, Component{
+			Name:    fmt.Sprintf("weights (%s copy)", in.ABPrecisionName),
+			Bytes:   CalculateWeightMemory(in.ParameterSize, in.ABPrecision),
+			Formula: weightFormula(in.ParameterSize, in.ABPrecision),
+		})
+	}
+
+	if in.Train {
+		training, err := trainingComponents(in.ParameterSize, in.Precision, in.Optimizer, in.HiddenDim, in.PureBF16)
+		if err != nil {
+			return Estimate{}, err
+		}
+		components = append(components, training...)
+	}
+
+	if in.LoRARank > 0 {
+		// The weights are the frozen base model when fine-tuning with adapters
+		components[0].Name = "base " + components[0].Name
+		adapterPrecision := in.LoRAAdapterPrecision
+		if adapterPrecision == 0 {
+			adapterPrecision = loraAdapterBytes
+		}
+		lora, err := loraComponents(in.NumLayers, in.HiddenDim, in.LoRARank, in.LoRATargets, adapterPrecision, in.Optimizer)
+		if err != nil {
+			return Estimate{}, err
+		}
+		components = append(components, lora...)
+	}
+
+	if in.ContextLength > 0 || len(in.KVBuckets) > 0 {
+		// Training runs each sequence in a single forward pass without caching keys and
+		// values for later tokens, and its activations already hold them
+		training := in.Train || in.LoRARank > 0
+		if !training {
+			kvCache, err := in.KVCacheComponent()
+			if err != nil {
+				return Estimate{}, err
+			}
+			components = append(components, kvCache)
+		}
+
+		// Prefilling the prompts runs every token of the context through each layer at
+		// once, which for a large batch of long prompts needs room of its own, while
+		// training keeps the activations of every layer for the backward pass
+		if in.ContextLength > 0 && !in.SkipActivations {
+			if training {
+				batchSize := max(in.BatchSize, 1) * max(in.inFlightMicroBatches, 1)
+				components = append(components, trainingActivationComponent(in.NumLayers, in.HiddenDim, in.ContextLength, batchSize, in.Precision, in.GradientCheckpointing))
+			} else if in.ActivationTokens > 0 {
+				components = append(components, activationComponent(in.HiddenDim, in.IntermediateSize, in.ActivationTokens, 1, in.Precision))
+			} else {
+				components = append(components, activationComponent(in.HiddenDim, in.IntermediateSize, in.ContextLength, max(in.BatchSize, 1), in.Precision))
+			}
+		}
+		if in.pipelineBuffers && in.ContextLength > 0 {
+			components = append(components, pipelineBufferComponent(in.HiddenDim, in.ContextLength, max(in.BatchSize, 1), in.Precision))
+		}
+	}
+
+	if in.MaxPositions > 0 {
+		rope := ropeComponent(in.MaxPositions, in.RoPEScaling, in.HeadDim)
+		rope.ShardLimit = 1
+		components = append(components, rope)
+	}
+
+	if in.LayerSkip {
+		components = append(components, layerSkipComponents(in.HiddenDim, in.VocabSize, in.DraftTokens, in.Precision)...)
+	}
+
+	if in.IndexVectors > 0 {
+		components = append(components, indexComponent(in.IndexVectors, in.IndexDim, in.IndexPrecision))
+	}
+
+	if in.MedusaHeads > 0 {
+		// The heads draft on top of the base model, which is verified as a whole
+		if !strings.HasPrefix(components[0].Name, "base ") {
+			components[0].Name = "base " + components[0].Name
+		}
+		kvDim := calculateKVDimension(in.HiddenDim, in.HeadDim, in.KVHeads, in.HeadDimMultiple)
+		components = append(components, medusaComponents(in.NumLayers, kvDim, in.VocabSize, in.HiddenDim,
+			in.MedusaHeads, in.MedusaTreeTokens, in.Precision, in.kvPrecision())...)
+	}
+
+	if in.Draft.ParameterSize > 0 {
+		draft, err := in.draftComponents()
+		if err != nil {
+			return Estimate{}, err
+		}
+		components = append(components, draft...)
+	}
+
+	if in.Vision.EncoderParameters > 0 {
+		components = append(components, in.visionComponents()...)
+	}
+
+	if in.ZeROStage > 0 {
+		var err error
+		components, err = zeroComponents(components, paramComponents, in.ZeROStage, dataParallel, in.NumLayers, weightParams, in.Precision)
+		if err != nil {
+			return Estimate{}, err
+		}
+	}
+
+	if in.FSDPStrategy != "" {
+		degree, err := fsdpShardDegree(in.FSDPStrategy, dataParallel, in.FSDPShardDegree)
+		if err != nil {
+			return Estimate{}, err
+		}
+		components, err = fsdpComponents(components, paramComponents, degree, in.NumLayers, weightParams, in.Precision)
+		if err != nil {
+			return Estimate{}, err
+		}
+	}
+
+	// The optimizer states and the master weights they update, offloaded to host memory,
+	// are reported apart from the rest
+	var offloaded []Component
+	if in.OffloadOptimizer {
+		kept := components[:0]
+		for _, c := range components {
+			if c.Name == "optimizer states" || c.Name == "master weights" {
+				offloaded = append(offloaded, c)
+				continue
+			}
+			kept = append(kept, c)
+		}
+		components = kept
+	}
+
+	if tensorParallel > 1 {
+		components = append(shardComponents(components, tensorParallel), communicationComponent())
+	}
+
+	total := calculateRequiredMemory(components, in.Overhead)
+
+	result := Estimate{
+		Components:      components,
+		OverheadPercent: in.Overhead,
+		Overhead:        total - sumComponents(components),
+		Total:           total,
+		GPUs:            tensorParallel * dataParallel,
+		Offloaded:       offloaded,
+	}
+	logEstimate(result)
+	return result, nil
+}
+
+// logEstimate logs each component of an estimate and its total at the debug level
+func logEstimate(e Estimate) {
+	for _, c := range e.Components {
+		slog.Debug("calculated component", "name", c.Name, "bytes", c.Bytes, "formula", c.Formula)
+	}
+	slog.Debug("calculated estimate", "overhead_percent", e.OverheadPercent, "overhead_bytes", e.Overhead, "total_bytes", e.Total, "gpus", e.GPUs)
+}
+
+// sumComponents returns the combined size of all components in bytes.
+func sumComponents(components []Component) int {
+	var sum int
+	for _, c := range components {
+		sum += c.Bytes
+	}
+	return sum
+}
+
+// Breakdown returns the formatted size of each component, including the overhead,
+// keyed by component name.
+func (e Estimate) Breakdown() map[string]string {
+	return e.BreakdownIn(AutoUnit)
+}
+
+// BreakdownIn returns the sizes of Breakdown in the given unit.
+func (e Estimate) BreakdownIn(unit MemoryUnit) map[string]string {
+	out := make(map[string]string, len(e.Components)+1)
+	for _, c := range e.Components {
+		out[c.Name] = unit.Format(c.Bytes)
+	}
+	out["overhead"] = unit.Format(e.Overhead)
+	return out
+}
+
+// calculateRequiredMemory returns the gpu memory required for serving llms by adding
+// the overhead percentage to the combined size of the memory components
+func calculateRequiredMemory(components []Component, overhead float32) int {
+	// Calculate the memory required for all components
+	memoryForComponents := float64(sumComponents(components))
+
+	// Convert overhead to a percentage
+	overheadFactor := 1 + float64(overhead)/100
+
+	// Add overhead to the calculated memory
+	totalMemoryRequired := int(memoryForComponents * overheadFactor)
+
+	return totalMemoryRequired
+}
@@ -0,0 +1,41 @@
+package estimator
+
+import "testing"
+
+func TestABPrecisionCopy(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, ABPrecisionName: "int4", ABPrecision: 0.5}
+	got, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if abCopy := componentBytes(got, "weights (int4 copy)"); abCopy != 3_500_000_000 {
+		t.Errorf("int4 copy of the weights = %d bytes, want %d", abCopy, 3_500_000_000)
+	}
+	if want := 14_000_000_000 + 3_500_000_000; got.Total != want {
+		t.Errorf("fp16 weights with an int4 copy = %d bytes, want %d", got.Total, want)
+	}
+}
+
+func TestFoldNorms(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096}
+	separate, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	norms := componentBytes(separate, "norms")
+	if want := CalculateWeightMemory(CalculateNormParameters(32, 4096), 2); norms != want {
+		t.Fatalf("norms = %d bytes, want %d", norms, want)
+	}
+
+	spec.FoldNorms = true
+	folded, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes := componentBytes(folded, "norms"); bytes != -1 {
+		t.Errorf("norms folded into the linear layers = %d bytes, want no norms component", bytes)
+	}
+	if folded.Total != separate.Total-norms {
+		t.Errorf("total with folded norms = %d bytes, want %d without the norms", folded.Total, separate.Total-norms)
+	}
+}
@@ -0,0 +1,34 @@
+package estimator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// weightFormula describes the memory of the given number of weights at a precision
+func weightFormula(parameterSize int, precision Precision) string {
+	return fmt.Sprintf("%s params x %g bytes", FormatCount(parameterSize), precision)
+}
+
+// Explanation returns one line per component showing the arithmetic behind it, followed
+// by the overhead and the total.
+func (e Estimate) Explanation() []string {
+	return e.ExplanationIn(AutoUnit)
+}
+
+// ExplanationIn returns the lines of Explanation with the sizes in the given unit.
+func (e Estimate) ExplanationIn(unit MemoryUnit) []string {
+	lines := make([]string, 0, len(e.Components)+2)
+	terms := make([]string, 0, len(e.Components)+1)
+
+	for _, c := range e.Components {
+		lines = append(lines, fmt.Sprintf("%s: %s = %s", c.Name, c.Formula, unit.Format(c.Bytes)))
+		terms = append(terms, unit.Format(c.Bytes))
+	}
+
+	lines = append(lines, fmt.Sprintf("overhead: %s x %.2f = %s", unit.Format(sumComponents(e.Components)), e.OverheadPercent/100, unit.Format(e.Overhead)))
+	terms = append(terms, unit.Format(e.Overhead))
+	lines = append(lines, fmt.Sprintf("total: %s = %s", strings.Join(terms, " + "), unit.Format(e.Total)))
+
+	return lines
+}
@@ -0,0 +1,105 @@
+package estimator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// memoryUnits maps the units memory can be formatted in to their size in bytes. Decimal
+// units match model cards and marketing, binary ones nvidia-smi and GPU spec sheets.
+var memoryUnits = map[string]int{
+	"B":   1,
+	"MB":  1_000_000,
+	"MiB": 1 << 20,
+	"GB":  1_000_000_000,
+	"GiB": 1 << 30,
+}
+
+// MemoryUnitNames lists the units accepted by ParseMemoryUnit, "auto" first
+var MemoryUnitNames = []string{"auto", "B", "MB", "MiB", "GB", "GiB"}
+
+// MemoryUnit is the unit sizes are formatted in, such as "GiB". The zero value, AutoUnit,
+// picks a decimal unit by size.
+type MemoryUnit string
+
+// AutoUnit picks a decimal unit for each size, as FormatMemory does
+const AutoUnit MemoryUnit = ""
+
+// ParseMemoryUnit returns the unit of the given name, such as "GiB", given in any case.
+// "auto" returns AutoUnit.
+func ParseMemoryUnit(name string) (MemoryUnit, error) {
+	if strings.EqualFold(name, "auto") {
+		return AutoUnit, nil
+	}
+	for unit := range memoryUnits {
+		if strings.EqualFold(name, unit) {
+			return MemoryUnit(unit), nil
+		}
+	}
+	return AutoUnit, fmt.Errorf("unknown unit %q; must be one of %s", name, strings.Join(MemoryUnitNames, ", "))
+}
+
+// Format returns the memory in bytes as a string in the unit, gigabytes and gibibytes to
+// two decimal places and sizes under one of the unit to three significant digits.
+// AutoUnit formats it as FormatMemory does.
+func (u MemoryUnit) Format(memoryBytes int) string {
+	if u == AutoUnit {
+		return FormatMemory(memoryBytes)
+	}
+	value := float64(memoryBytes) / float64(memoryUnits[string(u)])
+	switch {
+	case u == "B":
+		return fmt.Sprintf("%d B", memoryBytes)
+	case memoryBytes > 0 && value < 1:
+		return fmt.Sprintf("%.3g %s", value, u)
+	case u == "GB" || u == "GiB":
+		return fmt.Sprintf("%.2f %s", value, u)
+	default:
+		return fmt.Sprintf("%d %s", memoryBytes/memoryUnits[string(u)], u)
+	}
+}
+
+// FormatMemory takes an integer representing memory in bytes and returns a formatted string
+// with the memory in terabytes, gigabytes, megabytes or kilobytes, depending on the size.
+// Terabytes and gigabytes are rounded to two decimal places for readability, while smaller
+// sizes are shown as whole numbers. Sizes under a kilobyte are shown in bytes. Use the
+// Format method of a MemoryUnit for a fixed unit.
+func FormatMemory(memoryBytes int) string {
+	const (
+		kilobyte = 1_000
+		megabyte = 1_000_000
+		gigabyte = 1_000_000_000
+		terabyte = 1_000_000_000_000
+	)
+
+	switch {
+	case memoryBytes >= terabyte:
+		return fmt.Sprintf("%.2f TB", float64(memoryBytes)/terabyte)
+	case memoryBytes >= gigabyte:
+		return fmt.Sprintf("%.2f GB", float64(memoryBytes)/gigabyte)
+	case memoryBytes >= megabyte:
+		return fmt.Sprintf("%d MB", memoryBytes/megabyte)
+	case memoryBytes >= kilobyte:
+		return fmt.Sprintf("%d KB", memoryBytes/kilobyte)
+	default:
+		return fmt.Sprintf("%d B", memoryBytes)
+	}
+}
+
+// FormatCount formats a whole number with thousands separators, such as 7,000,000,000
+func FormatCount(n int) string {
+	digits := strconv.Itoa(n)
+	if n < 0 {
+		return "-" + FormatCount(-n)
+	}
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package estimator
+
+import "testing"
+
+func TestFormatMemory(t *testing.T) {
+	tests := []struct {
+		bytes int
+		want  string
+	}{
+		{bytes: 999, want: "999 B"},
+		{bytes: 1_000, want: "1 KB"},
+		{bytes: 999_999, want: "999 KB"},
+		{bytes: 1_000_000, want: "1 MB"},
+		{bytes: 999_999_999, want: "999 MB"},
+		{bytes: 1_000_000_000, want: "1.00 GB"},
+		{bytes: 999_990_000_000, want: "999.99 GB"},
+		{bytes: 1_000_000_000_000, want: "1.00 TB"},
+		{bytes: 1_500_000_000_000, want: "1.50 TB"},
+	}
+	for _, tt := range tests {
+		if got := FormatMemory(tt.bytes); got != tt.want {
+			t.Errorf("FormatMemory(%d) = %q, want %q", tt.bytes, got, tt.want)
+		}
+	}
+}
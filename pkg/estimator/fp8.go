@@ -0,0 +1,48 @@
+package estimator
+
+import "fmt"
+
+const (
+	// fp8ScaleBytes is the size of a single fp8 scaling factor, which is kept in fp32
+	fp8ScaleBytes = 4
+
+	// projectionsPerLayer is the number of linear projections in a transformer layer that
+	// carry their own scales: query, key, value and output in attention, and gate, up and
+	// down in the MLP
+	projectionsPerLayer = 7
+)
+
+// calculateFP8Scales returns the number of scaling factors stored alongside fp8 weights.
+// Tensor-wise scaling keeps one scale for each projection, while channel-wise scaling keeps
+// one for every output channel, taking the hidden dimension as the channel count.
+func calculateFP8Scales(scaling string, numLayers, hiddenDim int) (int, error) {
+	switch scaling {
+	case "tensor":
+		return numLayers * projectionsPerLayer, nil
+	case "channel":
+		return numLayers * projectionsPerLayer * hiddenDim, nil
+	default:
+		return 0, fmt.Errorf("unknown fp8 scaling %q; must be tensor or channel", scaling)
+	}
+}
+
+// fp8ScaleComponent returns the memory for the scaling factors of fp8 weights.
+func fp8ScaleComponent(scaling string, numLayers, hiddenDim int) (Component, error) {
+	if numLayers <= 0 || hiddenDim <= 0 {
+		return Component{}, specError("FP8Scaling requires NumLayers and HiddenDim")
+	}
+	scales, err := calculateFP8Scales(scaling, numLayers, hiddenDim)
+	if err != nil {
+		return Component{}, err
+	}
+
+	formula := fmt.Sprintf("%d layers x %d projections", numLayers, projectionsPerLayer)
+	if scaling == "channel" {
+		formula += fmt.Sprintf(" x %d channels", hiddenDim)
+	}
+	return Component{
+		Name:    fmt.Sprintf("fp8 scales (%s-wise)", scaling),
+		Bytes:   scales * fp8ScaleBytes,
+		Formula: fmt.Sprintf("%s x %d bytes", formula, fp8ScaleBytes),
+	}, nil
+}
@@ -0,0 +1,33 @@
+package estimator
+
+import "testing"
+
+func TestFP8Scales(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 1, NumLayers: 32, HiddenDim: 4096}
+	tests := []struct {
+		scaling string
+		scales  int
+	}{
+		{scaling: "tensor", scales: 32 * 7 * 4},
+		{scaling: "channel", scales: 32 * 7 * 4096 * 4},
+	}
+	totals := make(map[string]int)
+	for _, tt := range tests {
+		spec.FP8Scaling = tt.scaling
+		got, err := Calculate(spec)
+		if err != nil {
+			t.Errorf("Calculate with %s-wise scales returned error: %v", tt.scaling, err)
+			continue
+		}
+		if bytes := componentBytes(got, "fp8 scales ("+tt.scaling+"-wise)"); bytes != tt.scales {
+			t.Errorf("%s-wise fp8 scales = %d bytes, want %d", tt.scaling, bytes, tt.scales)
+		}
+		totals[tt.scaling] = got.Total
+	}
+
+	// Channel-wise scales cost more, but only a sliver of the fp8 weights
+	extra := totals["channel"] - totals["tensor"]
+	if extra <= 0 || extra > totals["tensor"]/1000 {
+		t.Errorf("channel-wise scales add %d bytes over tensor-wise, want more than none and at most 0.1%% of %d", extra, totals["tensor"])
+	}
+}
@@ -0,0 +1,65 @@
+package estimator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fsdpUnshardedCopies is the number of full copies of a layer each rank holds while it is
+// unsharded: the parameters of the layer being computed and of the next one prefetched,
+// and the gradients of the layer before they are reduce-scattered.
+const fsdpUnshardedCopies = 3
+
+// FSDPStrategies lists the FSDP sharding strategies: full-shard shards the parameters,
+// gradients and optimizer states across every rank, while hybrid-shard shards them within
+// groups of ranks, usually a node, and replicates them across the groups.
+var FSDPStrategies = []string{"full-shard", "hybrid-shard"}
+
+// fsdpShardDegree returns the number of ranks the training state is sharded across for
+// the strategy, checking the degree given for hybrid sharding divides the ranks evenly.
+func fsdpShardDegree(strategy string, ranks, shardDegree int) (int, error) {
+	switch strategy {
+	case "full-shard":
+		if shardDegree > 0 && shardDegree != ranks {
+			return 0, specError("FSDPShardDegree only applies to hybrid-shard; full-shard shards across every GPU")
+		}
+		return ranks, nil
+	case "hybrid-shard":
+		if shardDegree <= 0 {
+			return 0, specError("hybrid-shard requires FSDPShardDegree, the number of GPUs in each sharding group")
+		}
+		if ranks%shardDegree != 0 {
+			return 0, specError("invalid FSDPShardDegree; must divide DataParallel evenly")
+		}
+		return shardDegree, nil
+	default:
+		return 0, specError("unknown FSDPStrategy %q; must be one of %s", strategy, strings.Join(FSDPStrategies, ", "))
+	}
+}
+
+// fsdpComponents returns the training memory each rank holds with FSDP sharding across the
+// given degree. The parameters are the first paramComponents of the components, the
+// gradients, master weights and optimizer states follow them, and all of them are sharded. Each layer is
+// unsharded in turn for the forward and backward passes.
+func fsdpComponents(components []Component, paramComponents, degree, numLayers, weightParams int, precision Precision) ([]Component, error) {
+	if numLayers <= 0 {
+		return nil, specError("FSDPStrategy requires NumLayers to size the unsharded layer")
+	}
+
+	sharded := make([]Component, 0, len(components)+1)
+	for i, c := range components {
+		if i < paramComponents || c.Name == "gradients" || c.Name == "optimizer states" || c.Name == "master weights" {
+			c = partitionComponent(c, degree)
+		}
+		sharded = append(sharded, c)
+	}
+
+	layerParams := weightParams / numLayers
+	sharded = append(sharded, Component{
+		Name:  "unsharded layer",
+		Bytes: fsdpUnshardedCopies * CalculateWeightMemory(layerParams, precision),
+		Formula: fmt.Sprintf("(2 layers of parameters + 1 of gradients) x %s params x %g bytes",
+			FormatCount(layerParams), precision),
+	})
+	return sharded, nil
+}
@@ -0,0 +1,22 @@
+package estimator
+
+// CalculateGPUCount returns the number of GPUs with the given memory needed to hold the
+// required memory, never fewer than the minimum the deployment is already sharded across.
+func CalculateGPUCount(requiredMemory, gpuMemory, minimum int) int {
+	count := (requiredMemory + gpuMemory - 1) / gpuMemory
+	return max(count, minimum)
+}
+
+// CalculateHeadroom returns the memory left over once the required memory is placed on
+// the given number of GPUs, in bytes and as a percentage of their combined memory.
+func CalculateHeadroom(requiredMemory, gpuMemory, gpuCount int) (int, float64) {
+	capacity := gpuMemory * gpuCount
+	headroom := capacity - requiredMemory
+	return headroom, float64(headroom) / float64(capacity) * 100
+}
+
+// CalculateMaxParameters solves the estimate in reverse, returning the largest number of
+// parameters whose weights and overhead fit in the given memory.
+func CalculateMaxParameters(memory int, precision Precision, overhead float32) int {
+	return int(float64(memory) / (float64(precision) * (1 + float64(overhead)/100)))
+}
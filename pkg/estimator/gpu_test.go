@@ -0,0 +1,28 @@
+package estimator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalculateHeadroom(t *testing.T) {
+	tests := []struct {
+		required, gpuMemory, gpuCount int
+		wantBytes                     int
+		wantPercent                   float64
+	}{
+		// 48 GB of an 80 GB GPU is 60% used
+		{required: 48_000_000_000, gpuMemory: 80_000_000_000, gpuCount: 1, wantBytes: 32_000_000_000, wantPercent: 40},
+		// The same use across two GPUs
+		{required: 96_000_000_000, gpuMemory: 80_000_000_000, gpuCount: 2, wantBytes: 64_000_000_000, wantPercent: 40},
+		// Too large to fit leaves negative headroom
+		{required: 100_000_000_000, gpuMemory: 80_000_000_000, gpuCount: 1, wantBytes: -20_000_000_000, wantPercent: -25},
+	}
+	for _, tt := range tests {
+		bytes, percent := CalculateHeadroom(tt.required, tt.gpuMemory, tt.gpuCount)
+		if bytes != tt.wantBytes || math.Abs(percent-tt.wantPercent) > 1e-9 {
+			t.Errorf("CalculateHeadroom(%d, %d, %d) = %d, %g, want %d, %g",
+				tt.required, tt.gpuMemory, tt.gpuCount, bytes, percent, tt.wantBytes, tt.wantPercent)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package estimator
+
+// HostRAM is the system memory needed while a model is loaded onto its GPUs and while it
+// runs
+type HostRAM struct {
+	// Staged is the checkpoint read into host memory while its weights are copied to the
+	// GPUs
+	Staged int
+
+	// PageCache reports whether the checkpoint is memory-mapped from disk, as llama.cpp
+	// does with GGUF files, so the kernel can evict the staged pages under pressure and
+	// read them again instead of running out of memory
+	PageCache bool
+
+	// Resident is what stays in host memory for as long as the model runs: the
+	// components offloaded from the GPUs
+	Resident int
+
+	// Peak is the most host memory in use at once, with the checkpoint staged alongside
+	// the resident components
+	Peak int
+}
+
+// CalculateHostRAM returns the host memory needed to load the model the input describes,
+// given its estimate. The checkpoint is staged at sourcePrecision when it is quantized as
+// it is loaded, such as an fp16 checkpoint loaded in 4 bits with bitsandbytes, or at the
+// precision of the weights when sourcePrecision is zero. Loaders that read the tensors
+// one shard at a time need less, so the staged checkpoint is an upper bound.
+func CalculateHostRAM(in ModelSpec, result Estimate, sourcePrecision Precision, mmap bool) (HostRAM, error) {
+	if sourcePrecision > 0 {
+		in.Precision = sourcePrecision
+		in.EmbeddingPrecision = 0
+		in.HeadPrecision = 0
+		in.FP8Scaling = ""
+		in.SparseGroup = 0
+	}
+	staged, err := CalculateDiskSize(in)
+	if err != nil {
+		return HostRAM{}, err
+	}
+
+	resident := sumComponents(result.Offloaded) * result.GPUs
+	return HostRAM{
+		Staged:    staged,
+		PageCache: mmap,
+		Resident:  resident,
+		Peak:      staged + resident,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// kvCacheBits maps the quantized KV cache types of serving frameworks to their bits per
+// element. llama.cpp's cache types store blocks of 32 elements with an fp16 scale, and
+// an fp16 minimum for the _1 types, while vLLM's fp8 formats take one byte per element.
+// q4 and q8 stand for llama.cpp's q4_0 and q8_0.
+var kvCacheBits = map[string]float32{
+	"fp8_e4m3": 8,
+	"fp8_e5m2": 8,
+	"q8_0":     8.5,
+	"q8":       8.5,
+	"q5_1":     6,
+	"q5_0":     5.5,
+	"q4_1":     5,
+	"q4_0":     4.5,
+	"q4":       4.5,
+	"iq4_nl":   4.5,
+}
+
+// KVPrecisionByName returns the bytes per element of a KV cache type given by name, which
+// is a quantized cache type such as "q8_0" or "fp8_e4m3", or any precision, such as "fp8"
+func KVPrecisionByName(name string) (Precision, error) {
+	if bits, ok := kvCacheBits[strings.ToLower(name)]; ok {
+		return Precision(bits / 8), nil
+	}
+	precision, ok := precisionBytes[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown KV cache type %q; must be one of %s", name, strings.Join(KVPrecisionNames(), ", "))
+	}
+	return precision, nil
+}
+
+// KVPrecisionNames returns the names of all precisions followed by the quantized KV cache
+// types from the most bits per element to the fewest
+func KVPrecisionNames() []string {
+	names := make([]string, 0, len(kvCacheBits))
+	for name := range kvCacheBits {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if kvCacheBits[names[i]] != kvCacheBits[names[j]] {
+			return kvCacheBits[names[i]] > kvCacheBits[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return append(PrecisionNames(), names...)
+}
+
+// calculateKVCacheMemory returns the memory in bytes needed for the key/value cache.
+// Every layer stores one key and one value vector of the KV dimension for each
+// token of context in each sequence of the batch.
+func calculateKVCacheMemory(numLayers, kvDim, contextLength, batchSize int, precision Precision) int {
+	elements := 2 * numLayers * kvDim * contextLength * batchSize
+	return bytesOf(float64(elements), precision)
+}
+
+// calculateKVCacheBytesPerToken returns the memory each token of context adds to the KV
+// cache for a single sequence, across all layers.
+func calculateKVCacheBytesPerToken(numLayers, kvDim int, precision Precision) int {
+	return calculateKVCacheMemory(numLayers, kvDim, 1, 1, precision)
+}
+
+// calculateKVDimension returns the width of the keys and values stored for each token in
+// every layer. Without a head dimension this is the hidden dimension. With one, each head
+// is padded up to the multiple the attention kernels work in, which inflates the cache
+// when the head dimension is not already a multiple. Grouped-query and multi-query
+// attention share each key and value head between several query heads, so only the KV
+// heads are stored when their number is given.
+func calculateKVDimension(hiddenDim, headDim, kvHeads, paddingMultiple int) int {
+	if headDim <= 0 {
+		return hiddenDim
+	}
+	heads := kvHeads
+	if heads <= 0 {
+		heads = hiddenDim / headDim
+	}
+	return heads * RoundUpToMultiple(headDim, paddingMultiple)
+}
+
+// kvDimensionFormula describes how the width of calculateKVDimension is made up for the
+// explanation, such as "8 kv heads x 128 head dim"
+func kvDimensionFormula(hiddenDim, headDim, kvHeads, paddingMultiple int) string {
+	if headDim <= 0 {
+		return fmt.Sprintf("%d hidden", hiddenDim)
+	}
+	heads, kind := kvHeads, "kv heads"
+	if heads <= 0 {
+		heads, kind = hiddenDim/headDim, "heads"
+	}
+	padded := RoundUpToMultiple(headDim, paddingMultiple)
+	if padded != headDim {
+		return fmt.Sprintf("%d %s x %d head dim (padded from %d)", heads, kind, padded, headDim)
+	}
+	return fmt.Sprintf("%d %s x %d head dim", heads, kind, headDim)
+}
+
+// RoundUpToMultiple rounds the value up to the next multiple, such as a context length
+// to the KV cache block size of a paged allocator. A multiple of zero leaves the value as is.
+func RoundUpToMultiple(value, multiple int) int {
+	if multiple <= 0 {
+		return value
+	}
+	return (value + multiple - 1) / multiple * multiple
+}
+
+// CalculateTokenCount estimates the number of tokens in a prompt of the given number of
+// characters from the average number of characters per token of the tokenizer.
+func CalculateTokenCount(characters int, charsPerToken float64) int {
+	return int(math.Ceil(float64(characters) / charsPerToken))
+}
+
+// KVBucket is a group of requests a token-bucket scheduler runs together, each holding
+// up to the given number of tokens in the KV cache
+type KVBucket struct {
+	Count  int
+	Tokens int
+}
+
+// ParseKVBuckets parses a comma separated list of buckets such as "8x2048,32x512", where
+// each entry is the number of requests followed by the tokens per request.
+func ParseKVBuckets(value string) ([]KVBucket, error) {
+	re := regexp.MustCompile(`^(\d+)x(\d+)$`)
+
+	var buckets []KVBucket
+	for _, entry := range strings.Split(value, ",") {
+		matches := re.FindStringSubmatch(strings.TrimSpace(entry))
+		if matches == nil {
+			return nil, fmt.Errorf("invalid bucket %q; must be in the form COUNTxTOKENS such as 8x2048", entry)
+		}
+		count, countErr := strconv.Atoi(matches[1])
+		tokens, tokensErr := strconv.Atoi(matches[2])
+		if countErr != nil || tokensErr != nil || count <= 0 || tokens <= 0 {
+			return nil, fmt.Errorf("invalid bucket %q; the count and tokens must be greater than 0", entry)
+		}
+		buckets = append(buckets, KVBucket{Count: count, Tokens: tokens})
+	}
+
+	return buckets, nil
+}
+
+// peakKVBucket returns the bucket holding the most tokens. Buckets are scheduled one at a
+// time, so the KV cache only has to hold the largest of them rather than their sum.
+func peakKVBucket(buckets []KVBucket) KVBucket {
+	var peak KVBucket
+	for _, b := range buckets {
+		if b.Count*b.Tokens > peak.Count*peak.Tokens {
+			peak = b
+		}
+	}
+	return peak
+}
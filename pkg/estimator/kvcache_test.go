@@ -0,0 +1,191 @@
+package estimator
+
+import "testing"
+
+func TestParseKVBuckets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []KVBucket
+		wantErr bool
+	}{
+		{value: "8x2048", want: []KVBucket{{Count: 8, Tokens: 2048}}},
+		{value: "8x2048, 32x512", want: []KVBucket{{Count: 8, Tokens: 2048}, {Count: 32, Tokens: 512}}},
+		{value: "0x0", wantErr: true},
+		{value: "0x2048", wantErr: true},
+		{value: "8x0", wantErr: true},
+		{value: "8x2048,0x512", wantErr: true},
+		{value: "99999999999999999999x2048", wantErr: true},
+		{value: "8*2048", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKVBuckets(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseKVBuckets(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKVBuckets(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseKVBuckets(%q) = %v, want %v", tt.value, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseKVBuckets(%q) = %v, want %v", tt.value, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestKVCachePeakBucket(t *testing.T) {
+	spec := ModelSpec{NumLayers: 32, HiddenDim: 4096, Precision: 2}
+	buckets, err := ParseKVBuckets("8x2048,32x1024,64x128")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sum int
+	for _, b := range buckets {
+		single := spec
+		single.ContextLength, single.BatchSize = b.Tokens, b.Count
+		kv, err := single.KVCacheComponent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += kv.Bytes
+	}
+
+	spec.KVBuckets = buckets
+	got, err := spec.KVCacheComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 32 requests of 1024 tokens hold the most, 32768 tokens against 16384 and 8192
+	want := calculateKVCacheMemory(32, 4096, 1024, 32, 2)
+	if got.Bytes != want {
+		t.Errorf("peak bucket KV cache = %d bytes, want %d", got.Bytes, want)
+	}
+	if got.Bytes >= sum {
+		t.Errorf("peak bucket KV cache = %d bytes, want less than the sum of the buckets %d", got.Bytes, sum)
+	}
+	if got.Name != "kv cache (peak bucket 32x1024)" {
+		t.Errorf("component name = %q, want the 32x1024 bucket", got.Name)
+	}
+}
+
+func TestRoundContextToBlocks(t *testing.T) {
+	tests := []struct {
+		value, multiple, want int
+	}{
+		{value: 1500, multiple: 256, want: 1536},
+		{value: 1536, multiple: 256, want: 1536},
+		{value: 1537, multiple: 256, want: 1792},
+		{value: 1500, multiple: 0, want: 1500},
+	}
+	for _, tt := range tests {
+		if got := RoundUpToMultiple(tt.value, tt.multiple); got != tt.want {
+			t.Errorf("RoundUpToMultiple(%d, %d) = %d, want %d", tt.value, tt.multiple, got, tt.want)
+		}
+	}
+
+	spec := ModelSpec{NumLayers: 32, HiddenDim: 4096, Precision: 2, BatchSize: 1, ContextLength: 1500, KVBlockSize: 256, RoundContext: true}
+	rounded, err := spec.KVCacheComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.ContextLength, spec.RoundContext = 1536, false
+	want, err := spec.KVCacheComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rounded.Bytes != want.Bytes {
+		t.Errorf("KV cache for 1500 tokens rounded to blocks of 256 = %d bytes, want %d for 1536 tokens", rounded.Bytes, want.Bytes)
+	}
+}
+
+func TestHeadDimPadding(t *testing.T) {
+	// 32 heads of 80 dimensions each, as in phi-2
+	spec := ModelSpec{NumLayers: 32, HiddenDim: 2560, HeadDim: 80, Precision: 2, BatchSize: 1, ContextLength: 2048}
+	unpadded, err := spec.KVCacheComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.HeadDimMultiple = 128
+	padded, err := spec.KVCacheComponent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 2 * 32 * 32 * 128 * 2048 * 2; padded.Bytes != want {
+		t.Errorf("KV cache with head dim 80 padded to 128 = %d bytes, want %d", padded.Bytes, want)
+	}
+	if padded.Bytes*80 != unpadded.Bytes*128 {
+		t.Errorf("KV cache with head dim 80 padded to 128 = %d bytes, want 128/80 of the %d unpadded", padded.Bytes, unpadded.Bytes)
+	}
+}
+
+func TestTokenCountKVCache(t *testing.T) {
+	tests := []struct {
+		characters    int
+		charsPerToken float64
+		want          int
+	}{
+		{characters: 8000, charsPerToken: 4, want: 2000},
+		{characters: 8001, charsPerToken: 4, want: 2001},
+		{characters: 1000, charsPerToken: 3.5, want: 286},
+		{characters: 0, charsPerToken: 4, want: 0},
+	}
+	for _, tt := range tests {
+		tokens := CalculateTokenCount(tt.characters, tt.charsPerToken)
+		if tokens != tt.want {
+			t.Errorf("CalculateTokenCount(%d, %g) = %d, want %d", tt.characters, tt.charsPerToken, tokens, tt.want)
+			continue
+		}
+		if tokens == 0 {
+			continue
+		}
+
+		// The tokens of the prompt size the KV cache as a context of that many tokens would
+		spec := ModelSpec{NumLayers: 32, HiddenDim: 4096, Precision: 2, BatchSize: 1, ContextLength: tokens}
+		kvCache, err := spec.KVCacheComponent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := 2 * 32 * 4096 * tokens * 2; kvCache.Bytes != want {
+			t.Errorf("KV cache for %d characters at %g per token = %d bytes, want %d", tt.characters, tt.charsPerToken, kvCache.Bytes, want)
+		}
+	}
+}
+
+func TestKVPrecisionOfItsOwn(t *testing.T) {
+	// W4/A16/KV8: int4 weights with an int8 KV cache
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 0.5, NumLayers: 32, HiddenDim: 4096, BatchSize: 1, ContextLength: 4096}
+	followsWeights, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.KVPrecision = 1
+	got, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 2 * 32 * 4096 * 4096 * 1; componentBytes(got, "kv cache") != want {
+		t.Errorf("int8 KV cache = %d bytes, want %d", componentBytes(got, "kv cache"), want)
+	}
+	if componentBytes(got, "kv cache") != 2*componentBytes(followsWeights, "kv cache") {
+		t.Errorf("int8 KV cache = %d bytes, want twice the %d of an int4 one", componentBytes(got, "kv cache"), componentBytes(followsWeights, "kv cache"))
+	}
+	if componentBytes(got, "weights") != componentBytes(followsWeights, "weights") {
+		t.Errorf("weights = %d bytes with an int8 KV cache, want the %d of int4 weights", componentBytes(got, "weights"), componentBytes(followsWeights, "weights"))
+	}
+	// The norms stay in fp16 whatever the weights and KV cache are quantized to
+	if want := CalculateWeightMemory(CalculateNormParameters(32, 4096), 2); componentBytes(got, "norms") != want {
+		t.Errorf("norms = %d bytes with int4 weights, want the %d of fp16 norms", componentBytes(got, "norms"), want)
+	}
+}
@@ -0,0 +1,130 @@
+package estimator
+
+import "errors"
+
+const (
+	// llamaCppBatch and llamaCppUBatch are llama.cpp's default logical and physical batch
+	// sizes, -b and -ub
+	llamaCppBatch  = 2048
+	llamaCppUBatch = 512
+
+	// llamaCppMinBatch is the smallest logical batch suggested before layers are offloaded
+	llamaCppMinBatch = 64
+
+	// llamaCppContextStep is the multiple the suggested context is rounded down to, the
+	// padding of llama.cpp's KV cache
+	llamaCppContextStep = 256
+
+	// llamaCppDefaultContext is the context suggested when not even the smallest one fits
+	// with every layer on the GPU, and llamaCppMaxContext the longest searched for when
+	// neither a context nor the model's positions are given
+	llamaCppDefaultContext = 4096
+	llamaCppMaxContext     = 131072
+
+	// llamaCppComputeBytes is the size of the f32 tensors of llama.cpp's compute graph
+	llamaCppComputeBytes = 4
+
+	// llamaCppHeadDim is the attention head dimension assumed when it isn't given
+	llamaCppHeadDim = 128
+)
+
+// LlamaCppPlan is the runtime flags suggested for llama.cpp to fit a model in the memory of
+// a GPU, and the memory they lead to.
+type LlamaCppPlan struct {
+	// Values of -ngl, -c, -b and -ub
+	NGPULayers int
+	Context    int
+	Batch      int
+	UBatch     int
+
+	// Whether every layer, including the output layer, is on the GPU
+	AllLayers bool
+
+	// Compute buffer of the graph for a physical batch, on the GPU
+	ComputeBytes int
+
+	// Layers on the GPU with their weights and KV cache, and the memory needed on the GPU
+	// and in host memory
+	Split LayerSplit
+}
+
+// llamaCppComputeBuffer returns the compute buffer llama.cpp reserves for a physical batch
+// of tokens: the activations of a layer, the logits over the vocabulary and the attention
+// scores of every head against the context, all in f32.
+func llamaCppComputeBuffer(in ModelSpec, context, ubatch int) int {
+	headDim := in.HeadDim
+	if headDim <= 0 {
+		headDim = llamaCppHeadDim
+	}
+	width := calculateActivationWidth(in.HiddenDim, in.IntermediateSize) + in.VocabSize + max(in.HiddenDim/headDim, 1)*context
+	return ubatch * width * llamaCppComputeBytes
+}
+
+// llamaCppLayout returns how the layers of the model split between the GPU and host memory
+// for the context and logical batch, once the compute buffer is set aside.
+func llamaCppLayout(in ModelSpec, memory, context, batch int) (LlamaCppPlan, error) {
+	plan := LlamaCppPlan{Context: context, Batch: batch, UBatch: min(batch, llamaCppUBatch)}
+	plan.ComputeBytes = llamaCppComputeBuffer(in, context, plan.UBatch)
+
+	in.ContextLength = context
+	in.BatchSize = 1
+	factor := 1 + float64(in.Overhead)/100
+	split, err := CalculateLayerSplit(in, max(memory-int(factor*float64(plan.ComputeBytes)), 0))
+	if err != nil {
+		return LlamaCppPlan{}, err
+	}
+	split.GPUBytes += int(factor * float64(plan.ComputeBytes))
+	plan.Split = split
+	plan.NGPULayers = split.NGPULayers()
+	plan.AllLayers = split.OutputOnGPU
+	return plan, nil
+}
+
+// PlanLlamaCpp suggests llama.cpp's -ngl, -c, -b and -ub for the model in the given memory
+// of a GPU. With a context length, the logical batch is halved from llama.cpp's default
+// until every layer fits, and layers are offloaded to the CPU when none does. Without
+// one, the longest context with every layer on the GPU is suggested, up to the model's
+// positions when given. The KV cache is f16 unless given another precision, as in
+// llama.cpp.
+func PlanLlamaCpp(in ModelSpec, memory int) (LlamaCppPlan, error) {
+	if in.NumLayers <= 0 || in.HiddenDim <= 0 {
+		return LlamaCppPlan{}, errors.New("a llama.cpp plan requires the number of layers and the hidden dimension")
+	}
+	if in.KVPrecision == 0 {
+		in.KVPrecision = 2
+	}
+
+	if in.ContextLength > 0 {
+		for batch := llamaCppBatch; batch >= llamaCppMinBatch; batch /= 2 {
+			plan, err := llamaCppLayout(in, memory, in.ContextLength, batch)
+			if err != nil || plan.AllLayers {
+				return plan, err
+			}
+		}
+		return llamaCppLayout(in, memory, in.ContextLength, llamaCppBatch)
+	}
+
+	// The memory grows with the context, so the longest one that fits is found by bisection
+	limit := llamaCppMaxContext
+	if in.MaxPositions > 0 {
+		limit = in.MaxPositions
+	}
+	low, high := 0, limit/llamaCppContextStep
+	var best LlamaCppPlan
+	for low < high {
+		mid := (low + high + 1) / 2
+		plan, err := llamaCppLayout(in, memory, mid*llamaCppContextStep, llamaCppBatch)
+		if err != nil {
+			return LlamaCppPlan{}, err
+		}
+		if plan.AllLayers {
+			low, best = mid, plan
+		} else {
+			high = mid - 1
+		}
+	}
+	if low == 0 {
+		return llamaCppLayout(in, memory, min(llamaCppDefaultContext, limit), llamaCppBatch)
+	}
+	return best, nil
+}
@@ -0,0 +1,45 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// loraAdapterBytes is the default size of each trainable adapter parameter and its
+	// gradient; adapters are trained in fp32 even when the base weights are quantized
+	loraAdapterBytes = 4
+)
+
+// calculateLoRAParameters returns the number of trainable adapter parameters. Each
+// targeted projection gets a pair of low rank matrices, rank x hidden and hidden x rank,
+// in every layer of the model.
+func calculateLoRAParameters(numLayers, hiddenDim, rank, targetModules int) int {
+	return numLayers * targetModules * 2 * rank * hiddenDim
+}
+
+// CalculateLoRATargets returns the number of projections in each layer that get adapters
+// when the given fraction of a layer's linear projections is targeted, at least one.
+func CalculateLoRATargets(fraction float64) int {
+	return max(int(math.Round(fraction*projectionsPerLayer)), 1)
+}
+
+// loraComponents returns the memory for LoRA fine-tuning on top of the frozen base
+// weights: the adapter weights and their gradients at the adapter precision, and the
+// optimizer states, which are only kept for the adapters.
+func loraComponents(numLayers, hiddenDim, rank, targetModules int, adapterPrecision Precision, optimizer string) ([]Component, error) {
+	adapterParams := calculateLoRAParameters(numLayers, hiddenDim, rank, targetModules)
+
+	params := fmt.Sprintf("%d layers x %d targets x 2 x rank %d x %d dims", numLayers, targetModules, rank, hiddenDim)
+	adapterBytes := CalculateWeightMemory(adapterParams, adapterPrecision)
+
+	states, err := optimizerStatesComponent(optimizer, adapterParams, rank, hiddenDim, fp32StateBytes)
+	if err != nil {
+		return nil, err
+	}
+	return []Component{
+		{Name: "adapter weights", Bytes: adapterBytes, Formula: fmt.Sprintf("%s x %g bytes", params, adapterPrecision)},
+		{Name: "adapter gradients", Bytes: adapterBytes, Formula: fmt.Sprintf("%s x %g bytes", params, adapterPrecision)},
+		states,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package estimator
+
+// CalculateExpertParameters returns the number of parameters in a single expert of a
+// mixture-of-experts model across every layer, each a gated MLP of hidden x intermediate.
+func CalculateExpertParameters(numLayers, hiddenDim, intermediateSize int) int {
+	return numLayers * CalculateLayerParameters(hiddenDim, intermediateSize).MLP
+}
+
+// CalculateRouterParameters returns the number of parameters in the routers of a
+// mixture-of-experts model, a hidden x experts gate in every layer.
+func CalculateRouterParameters(numLayers, hiddenDim, experts int) int {
+	return numLayers * hiddenDim * experts
+}
+
+// CalculateActiveParameters returns the number of parameters a mixture-of-experts model
+// uses for each token, which is every parameter except the experts the token isn't
+// routed to.
+func CalculateActiveParameters(totalParameters, expertParameters, experts, activeExperts int) int {
+	return totalParameters - (experts-activeExperts)*expertParameters
+}
@@ -0,0 +1,74 @@
+package estimator
+
+// LayerSplit is how the layers of a model are split between the GPU and host memory when
+// the ones that don't fit are offloaded to the CPU, as with llama.cpp's --n-gpu-layers.
+type LayerSplit struct {
+	// Transformer layers on the GPU and in host memory
+	GPULayers int
+	CPULayers int
+
+	// Whether the LM head and final norm, which llama.cpp counts as one more layer, are
+	// on the GPU as well
+	OutputOnGPU bool
+
+	// Memory needed on the GPU, including the overhead, and in host memory
+	GPUBytes int
+	CPUBytes int
+}
+
+// NGPULayers returns the value of llama.cpp's --n-gpu-layers for the split, which counts
+// the output layer as one more layer.
+func (s LayerSplit) NGPULayers() int {
+	if s.OutputOnGPU {
+		return s.GPULayers + 1
+	}
+	return s.GPULayers
+}
+
+// CalculateLayerSplit returns how many layers of the model fit in the given GPU memory
+// and how much is left in host memory. Each layer on the GPU holds its weights and its
+// share of the KV cache, with the overhead applied to them. The input embeddings stay in
+// host memory, and the LM head only moves to the GPU once every layer fits.
+func CalculateLayerSplit(in ModelSpec, memory int) (LayerSplit, error) {
+	if in.NumLayers <= 0 {
+		return LayerSplit{}, specError("invalid NumLayers; must be greater than 0")
+	}
+
+	// The input embeddings and the LM head are each a vocab x hidden table, with the final
+	// norm next to the LM head
+	var embeddingBytes, outputBytes int
+	layerParams := in.ParameterSize
+	if in.VocabSize > 0 && in.HiddenDim > 0 {
+		table := in.VocabSize * in.HiddenDim
+		embeddingBytes = CalculateWeightMemory(table, in.Precision)
+		outputBytes = CalculateWeightMemory(table+in.HiddenDim, in.Precision)
+		layerParams -= CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, in.TiedEmbeddings) + in.HiddenDim
+	}
+	if layerParams <= 0 {
+		return LayerSplit{}, specError("the parameters of the architecture exceed ParameterSize")
+	}
+
+	layerBytes := CalculateWeightMemory(layerParams/in.NumLayers, in.Precision)
+	if in.ContextLength > 0 {
+		if in.HiddenDim <= 0 {
+			return LayerSplit{}, specError("ContextLength requires HiddenDim")
+		}
+		layerBytes += in.KVCacheBytesPerToken() / in.NumLayers * in.kvTokens(in.ContextLength) * max(in.BatchSize, 1)
+	}
+
+	factor := 1 + float64(in.Overhead)/100
+	split := LayerSplit{GPULayers: min(in.NumLayers, int(float64(memory)/(factor*float64(layerBytes))))}
+	gpuBytes := split.GPULayers * layerBytes
+	if split.GPULayers == in.NumLayers && factor*float64(gpuBytes+outputBytes) <= float64(memory) {
+		split.OutputOnGPU = true
+		gpuBytes += outputBytes
+	}
+
+	split.CPULayers = in.NumLayers - split.GPULayers
+	split.GPUBytes = int(factor * float64(gpuBytes))
+	split.CPUBytes = split.CPULayers*layerBytes + embeddingBytes
+	if !split.OutputOnGPU {
+		split.CPUBytes += outputBytes
+	}
+	return split, nil
+}
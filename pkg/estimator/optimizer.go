@@ -0,0 +1,57 @@
+package estimator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// fp32StateBytes and bf16StateBytes are the sizes of a full precision optimizer state,
+	// kept in fp32 with mixed precision and in bf16 with pure bf16 training
+	fp32StateBytes = 4
+	bf16StateBytes = 2
+
+	// adam8BitBlockSize is the number of states sharing an fp32 scale in 8-bit AdamW, as
+	// in bitsandbytes' block-wise quantization
+	adam8BitBlockSize = 2048
+
+	// adafactorHiddenDim is the dimension of the square weight matrices Adafactor's
+	// factored states are sized for when the hidden dimension isn't given
+	adafactorHiddenDim = 4096
+)
+
+// Optimizers lists the optimizers whose states the training estimates size: AdamW keeps
+// two fp32 moments per parameter, 8-bit AdamW keeps them as int8 with a scale per block,
+// Adafactor factors its second moment into a row and a column per weight matrix, Lion
+// keeps one fp32 momentum and SGD a single fp32 momentum buffer.
+var Optimizers = []string{"adamw", "adamw-8bit", "adafactor", "lion", "sgd"}
+
+// optimizerStatesComponent returns the states the optimizer keeps for the given number of
+// trainable parameters, held in weight matrices of the given rows and columns, with each
+// full precision state taking stateBytes, 4 for fp32. Adafactor keeps a factor for every
+// row and column of each matrix, a small fraction of it.
+func optimizerStatesComponent(optimizer string, parameterSize, rows, cols, stateBytes int) (Component, error) {
+	params := FormatCount(parameterSize)
+	c := Component{Name: "optimizer states"}
+	switch strings.ToLower(optimizer) {
+	case "", "adamw":
+		c.Bytes = parameterSize * 2 * stateBytes
+		c.Formula = fmt.Sprintf("%s params x 2 moments x %d bytes", params, stateBytes)
+	case "adamw-8bit":
+		blocks := (parameterSize + adam8BitBlockSize - 1) / adam8BitBlockSize
+		c.Bytes = 2*parameterSize + 2*blocks*4
+		c.Formula = fmt.Sprintf("2 moments x (%s params x 1 byte + %d blocks x 4 bytes)", params, blocks)
+	case "adafactor":
+		if rows <= 0 || cols <= 0 {
+			rows, cols = adafactorHiddenDim, adafactorHiddenDim
+		}
+		c.Bytes = int(float64(parameterSize) * float64(rows+cols) / float64(rows*cols) * float64(stateBytes))
+		c.Formula = fmt.Sprintf("%s params x (1/%d rows + 1/%d columns) x %d bytes", params, rows, cols, stateBytes)
+	case "lion", "sgd":
+		c.Bytes = parameterSize * stateBytes
+		c.Formula = fmt.Sprintf("%s params x %d bytes", params, stateBytes)
+	default:
+		return Component{}, fmt.Errorf("unknown optimizer %q; must be one of %s", optimizer, strings.Join(Optimizers, ", "))
+	}
+	return c, nil
+}
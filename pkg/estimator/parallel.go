@@ -0,0 +1,47 @@
+package estimator
+
+import "fmt"
+
+const (
+	// ncclChannels and ncclBufferBytes describe the buffers NCCL allocates on each GPU for
+	// the all-reduces of tensor parallelism: a 4 MiB buffer per channel in each direction
+	ncclChannels    = 16
+	ncclBufferBytes = 4 * 1024 * 1024
+)
+
+// shardComponents returns the share of each component held by a single GPU when the
+// model is split across the given tensor parallel degree. Transformer weights are split
+// by attention heads and MLP columns and the embedding table and LM head are sharded along
+// the vocabulary, so they divide evenly. Components with a shard limit, such as the KV
+// cache with fewer heads than GPUs, are only split that many ways and duplicated beyond
+// it, and a limit of one replicates the component on every GPU.
+func shardComponents(components []Component, degree int) []Component {
+	sharded := make([]Component, len(components))
+	for i, c := range components {
+		shards := degree
+		if c.ShardLimit > 0 {
+			shards = min(degree, c.ShardLimit)
+		}
+
+		formula := fmt.Sprintf("(%s) / %d GPUs", c.Formula, shards)
+		if shards == 1 {
+			formula = fmt.Sprintf("%s, replicated on each GPU", c.Formula)
+		}
+		sharded[i] = Component{
+			Name:    c.Name,
+			Bytes:   c.Bytes / shards,
+			Formula: formula,
+		}
+	}
+	return sharded
+}
+
+// communicationComponent returns the buffers each GPU holds for the collective operations
+// between the GPUs of a tensor parallel group.
+func communicationComponent() Component {
+	return Component{
+		Name:    "communication buffers",
+		Bytes:   2 * ncclChannels * ncclBufferBytes,
+		Formula: fmt.Sprintf("2 directions x %d channels x %d bytes", ncclChannels, ncclBufferBytes),
+	}
+}
@@ -0,0 +1,38 @@
+package estimator
+
+import "testing"
+
+// componentBytes returns the bytes of the named component of the estimate, or -1 when it
+// has none of that name
+func componentBytes(e Estimate, name string) int {
+	for _, c := range e.Components {
+		if c.Name == name {
+			return c.Bytes
+		}
+	}
+	return -1
+}
+
+func TestTensorParallelEmbeddings(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, VocabSize: 32000}
+	single, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := componentBytes(single, "embeddings")
+	if full != 32000*4096*2 {
+		t.Fatalf("embeddings on a single GPU = %d bytes, want %d", full, 32000*4096*2)
+	}
+
+	for _, degree := range []int{2, 4, 8} {
+		spec.TensorParallel = degree
+		got, err := Calculate(spec)
+		if err != nil {
+			t.Errorf("Calculate with TensorParallel %d returned error: %v", degree, err)
+			continue
+		}
+		if bytes := componentBytes(got, "embeddings"); bytes != full/degree {
+			t.Errorf("embeddings per GPU with TensorParallel %d = %d bytes, want %d", degree, bytes, full/degree)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package estimator
+
+import "fmt"
+
+// PipelineStage is one stage of a pipeline parallel model: the layers it holds and the
+// memory each of its GPUs needs.
+type PipelineStage struct {
+	Layers     int
+	Components []Component
+	Total      int
+}
+
+// splitLayers returns the number of layers in each of the given number of stages, split
+// as evenly as possible with the earlier stages taking the remainder.
+func splitLayers(numLayers, stages int) []int {
+	layers := make([]int, stages)
+	for i := range layers {
+		layers[i] = numLayers / stages
+		if i < numLayers%stages {
+			layers[i]++
+		}
+	}
+	return layers
+}
+
+// pipelineBufferComponent returns the buffers a stage holds to send the hidden states of
+// a micro-batch to the next stage and receive them from the previous one.
+func pipelineBufferComponent(hiddenDim, contextLength, batchSize int, precision Precision) Component {
+	bytes := max(precision, minActivationBytes)
+	return Component{
+		Name:  "pipeline buffers",
+		Bytes: int(2 * float64(batchSize) * float64(contextLength) * float64(hiddenDim) * float64(bytes)),
+		Formula: fmt.Sprintf("2 directions x %d sequences x %s tokens x %d hidden x %g bytes",
+			batchSize, FormatCount(contextLength), hiddenDim, bytes),
+	}
+}
+
+// calculatePipeline estimates a model split into pipeline stages, each holding a share of
+// the layers. The first stage also holds the embedding table and the last one the LM head
+// and final norm, so they are usually the largest. When training with a one forward, one
+// backward schedule, each stage keeps the activations of a micro-batch for every stage
+// after it, so the first stage holds the most. The estimate returned is that of the stage
+// needing the most memory, which every GPU has to be sized for.
+func calculatePipeline(in ModelSpec) (Estimate, error) {
+	stages := in.PipelineParallel
+
+	var embeddingParams int
+	if in.VocabSize > 0 {
+		embeddingParams = CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, in.TiedEmbeddings)
+	}
+	layerParams := (in.ParameterSize - embeddingParams - CalculateNormParameters(in.NumLayers, in.HiddenDim)) / in.NumLayers
+	if layerParams <= 0 {
+		return Estimate{}, specError("the parameters of the architecture exceed ParameterSize")
+	}
+
+	var peak Estimate
+	pipeline := make([]PipelineStage, stages)
+	for i, layers := range splitLayers(in.NumLayers, stages) {
+		first, last := i == 0, i == stages-1
+
+		stage := in
+		stage.PipelineParallel = 0
+		stage.NumLayers = layers
+		stage.ParameterSize = layers*layerParams + CalculateNormParameters(layers, in.HiddenDim)
+
+		// The first stage holds the embedding table and the last the LM head, each a
+		// single vocab x hidden matrix. Tied embeddings are kept on both.
+		stage.VocabSize = 0
+		if in.VocabSize > 0 && (first || last) {
+			stage.VocabSize = in.VocabSize
+			stage.TiedEmbeddings = true
+			stage.ParameterSize += CalculateEmbeddingParameters(in.VocabSize, in.HiddenDim, true)
+		}
+		if in.ActiveParameters > 0 {
+			stage.ActiveParameters = int(float64(in.ActiveParameters) * float64(stage.ParameterSize) / float64(in.ParameterSize))
+		}
+
+		// Speculative heads sit after the LM head and a retrieval index before the model
+		if !last {
+			stage.LayerSkip = false
+			stage.MedusaHeads = 0
+		}
+		if !first {
+			stage.IndexVectors = 0
+		}
+
+		stage.inFlightMicroBatches = stages - i
+		stage.pipelineBuffers = true
+
+		result, err := Calculate(stage)
+		if err != nil {
+			return Estimate{}, err
+		}
+		pipeline[i] = PipelineStage{Layers: layers, Components: result.Components, Total: result.Total}
+		if result.Total > peak.Total {
+			peak = result
+		}
+	}
+
+	peak.GPUs *= stages
+	peak.Stages = pipeline
+	return peak, nil
+}
@@ -0,0 +1,95 @@
+package estimator
+
+import (
+	"errors"
+	"sort"
+)
+
+// ParallelPlan is a way of laying a model out across the GPUs of a cluster: the tensor
+// parallel degree within a node, the pipeline stages and the data parallel replicas, and
+// the memory each GPU needs with it.
+type ParallelPlan struct {
+	TensorParallel   int
+	PipelineParallel int
+	DataParallel     int
+
+	// Estimate of a single GPU, of the largest pipeline stage when there are several
+	Estimate Estimate
+}
+
+// GPUsPerReplica returns the GPUs holding a single copy of the model
+func (p ParallelPlan) GPUsPerReplica() int {
+	return p.TensorParallel * p.PipelineParallel
+}
+
+// divisors returns the numbers dividing n in increasing order
+func divisors(n int) []int {
+	var d []int
+	for i := 1; i <= n; i++ {
+		if n%i == 0 {
+			d = append(d, i)
+		}
+	}
+	return d
+}
+
+// PlanParallelism enumerates the tensor, pipeline and data parallel degrees using every
+// GPU of the given nodes, and returns those whose GPUs each fit in the given memory.
+// Tensor parallelism stays within a node, where the all-reduces of every layer have the
+// fastest links, and pipeline stages need the number of layers and the hidden dimension.
+// The state of training is partitioned across the data parallel replicas with the ZeRO
+// stage of the spec when neither of the others is used, and replicated otherwise.
+//
+// The plans are ranked by the fewest GPUs per replica, which leaves the most replicas to
+// serve or train in parallel, then by the fewest pipeline stages, since their bubbles
+// idle the GPUs more than the communication of tensor parallelism within a node does.
+func PlanParallelism(in ModelSpec, memory, gpusPerNode, nodes int) ([]ParallelPlan, error) {
+	if gpusPerNode <= 0 || nodes <= 0 {
+		return nil, errors.New("the GPUs per node and the number of nodes must be greater than 0")
+	}
+	if in.ZeROStage > 0 && !in.Train {
+		return nil, specError("ZeROStage requires Train")
+	}
+	gpus := gpusPerNode * nodes
+
+	var plans []ParallelPlan
+	for _, tp := range divisors(gpusPerNode) {
+		for _, pp := range divisors(gpus / tp) {
+			if pp > 1 && (in.NumLayers <= 0 || in.HiddenDim <= 0 || pp > in.NumLayers) {
+				continue
+			}
+			plan := ParallelPlan{TensorParallel: tp, PipelineParallel: pp, DataParallel: gpus / (tp * pp)}
+
+			spec := in
+			spec.TensorParallel = tp
+			spec.PipelineParallel = pp
+			spec.DataParallel = 0
+			if tp > 1 || pp > 1 {
+				spec.ZeROStage = 0
+			} else if spec.ZeROStage > 0 {
+				spec.DataParallel = plan.DataParallel
+			}
+			result, err := Calculate(spec)
+			if err != nil {
+				return nil, err
+			}
+			if result.Total > memory {
+				continue
+			}
+			plan.Estimate = result
+			plans = append(plans, plan)
+		}
+	}
+
+	sort.SliceStable(plans, func(i, j int) bool {
+		a, b := plans[i], plans[j]
+		if a.GPUsPerReplica() != b.GPUsPerReplica() {
+			return a.GPUsPerReplica() < b.GPUsPerReplica()
+		}
+		if a.PipelineParallel != b.PipelineParallel {
+			return a.PipelineParallel < b.PipelineParallel
+		}
+		return a.Estimate.Total < b.Estimate.Total
+	})
+	return plans, nil
+}
@@ -0,0 +1,79 @@
+package estimator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Precision is the number of bytes each parameter takes, such as 2 for fp16
+type Precision float32
+
+// bytesOf returns the memory of the given number of values at the precision. It is
+// computed in float64 from the shortest decimal form of the precision, so 13b weights at
+// the 0.60625 bytes of Q4_K_M come to exactly 7,881,250,000 bytes instead of carrying the
+// rounding error of the float32.
+func bytesOf(count float64, precision Precision) int {
+	bytes, _ := strconv.ParseFloat(strconv.FormatFloat(float64(precision), 'g', -1, 32), 64)
+	return int(math.Round(count * bytes))
+}
+
+// DefaultGroupSize is the number of weights sharing a scale and zero point in GPTQ and
+// AWQ checkpoints unless they are quantized with another group size
+const DefaultGroupSize = 128
+
+// precisionBytes maps each supported precision name to the number of bytes used per parameter.
+// NF4 stores 4 bits per weight plus a block scale quantized to 8 bits for every 64 weights
+// and an fp32 constant for every 256 blocks, 4.127 bits in all. GPTQ, AWQ and int2 are
+// listed at the default group size. Ternary is the 1.58-bit format of BitNet b1.58, whose
+// weights are -1, 0 or 1: five of them are packed into each byte, 243 of its 256 values,
+// with an fp16 scale for every block of 256 weights, 1.6875 bits in all as llama.cpp's
+// TQ1_0 stores them.
+var precisionBytes = map[string]Precision{
+	"fp32":    4,
+	"fp16":    2,
+	"bf16":    2,
+	"fp8":     1,
+	"int8":    1,
+	"gptq":    GroupQuantPrecision(4, DefaultGroupSize),
+	"awq":     GroupQuantPrecision(4, DefaultGroupSize),
+	"nf4":     0.516,
+	"int4":    0.5,
+	"int2":    GroupQuantPrecision(2, DefaultGroupSize),
+	"ternary": 1.6875 / 8,
+}
+
+// GroupQuantPrecision returns the bytes per parameter of weights quantized in groups, as
+// GPTQ and AWQ do: each weight takes the given number of bits, and each group of weights
+// adds an fp16 scale and a zero point packed at the same number of bits.
+func GroupQuantPrecision(bits, groupSize int) Precision {
+	weightBytes := float32(bits) / 8
+	return Precision(weightBytes + (2+weightBytes)/float32(groupSize))
+}
+
+// PrecisionByName returns the bytes per parameter for a precision given by name, such as "fp16"
+func PrecisionByName(name string) (Precision, error) {
+	precision, ok := precisionBytes[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown precision %q; must be one of %s", name, strings.Join(PrecisionNames(), ", "))
+	}
+	return precision, nil
+}
+
+// PrecisionNames returns the names of all supported precisions, from the most bytes per
+// parameter to the fewest and alphabetically among equals
+func PrecisionNames() []string {
+	names := make([]string, 0, len(precisionBytes))
+	for name := range precisionBytes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if precisionBytes[names[i]] != precisionBytes[names[j]] {
+			return precisionBytes[names[i]] > precisionBytes[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,70 @@
+package estimator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// quantBits maps the llama.cpp quantization schemes to their effective bits per weight.
+// The k-quant mixes keep some tensors, such as the attention value and feed-forward down
+// projections, at a higher type than the rest, so they take more than their base type.
+// The values are those llama.cpp reports for a 7B Llama model.
+var quantBits = map[string]float32{
+	"TQ1_0":   1.69,
+	"TQ2_0":   2.06,
+	"IQ1_S":   1.56,
+	"IQ1_M":   1.75,
+	"IQ2_XXS": 2.06,
+	"IQ2_XS":  2.31,
+	"IQ2_S":   2.5,
+	"IQ2_M":   2.7,
+	"Q2_K":    3.35,
+	"IQ3_XXS": 3.06,
+	"IQ3_S":   3.44,
+	"IQ3_M":   3.66,
+	"Q3_K_S":  3.5,
+	"Q3_K_M":  3.91,
+	"Q3_K_L":  4.27,
+	"IQ4_XS":  4.25,
+	"IQ4_NL":  4.5,
+	"Q4_0":    4.55,
+	"Q4_1":    5.0,
+	"Q4_K_S":  4.58,
+	"Q4_K_M":  4.85,
+	"Q5_0":    5.54,
+	"Q5_1":    6.0,
+	"Q5_K_S":  5.54,
+	"Q5_K_M":  5.69,
+	"Q6_K":    6.59,
+	"Q8_0":    8.5,
+	"F16":     16,
+	"BF16":    16,
+	"F32":     32,
+}
+
+// QuantPrecision returns the bytes per parameter of a llama.cpp quantization scheme given
+// by name, such as "Q4_K_M", from its effective bits per weight
+func QuantPrecision(name string) (Precision, error) {
+	bits, ok := quantBits[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown quantization %q; must be one of %s", name, strings.Join(QuantNames(), ", "))
+	}
+	return Precision(bits / 8), nil
+}
+
+// QuantNames returns the names of all supported llama.cpp quantization schemes, from the
+// fewest bits per weight to the most and alphabetically among equals
+func QuantNames() []string {
+	names := make([]string, 0, len(quantBits))
+	for name := range quantBits {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if quantBits[names[i]] != quantBits[names[j]] {
+			return quantBits[names[i]] < quantBits[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,18 @@
+package estimator
+
+import "fmt"
+
+// calculateIndexMemory returns the memory in bytes needed for a flat vector index kept in
+// GPU memory next to the model, one vector of the given dimension for each entry.
+func calculateIndexMemory(vectors, dim int, precision Precision) int {
+	return int(float64(vectors) * float64(dim) * float64(precision))
+}
+
+// indexComponent returns the retrieval index as a component of the estimate.
+func indexComponent(vectors, dim int, precision Precision) Component {
+	return Component{
+		Name:    "retrieval index",
+		Bytes:   calculateIndexMemory(vectors, dim, precision),
+		Formula: fmt.Sprintf("%s vectors x %d dims x %g bytes", FormatCount(vectors), dim, precision),
+	}
+}
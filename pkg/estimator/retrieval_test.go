@@ -0,0 +1,18 @@
+package estimator
+
+import "testing"
+
+func TestRetrievalIndex(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, IndexVectors: 1_000_000, IndexDim: 768, IndexPrecision: 4}
+	got, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const index = 1_000_000 * 768 * 4
+	if bytes := componentBytes(got, "retrieval index"); bytes != index {
+		t.Errorf("index of 1M vectors of 768 fp32 dims = %d bytes, want %d", bytes, index)
+	}
+	if want := 14_000_000_000 + index; got.Total != want {
+		t.Errorf("7b model with a 1M vector index = %d bytes, want %d", got.Total, want)
+	}
+}
@@ -0,0 +1,24 @@
+package estimator
+
+import "fmt"
+
+// ropeBufferBytes is the size of each precomputed rotary embedding value, which are kept
+// in fp32 regardless of the precision of the weights
+const ropeBufferBytes = 4
+
+// calculateRoPEPositions returns the number of positions the rotary embedding tables are
+// precomputed for once RoPE scaling such as YaRN or NTK extends the trained context.
+func calculateRoPEPositions(maxPositions int, scaling float64) int {
+	return int(float64(maxPositions) * scaling)
+}
+
+// ropeComponent returns the memory for the cos and sin tables of the rotary embeddings,
+// one value for each position and each dimension of an attention head.
+func ropeComponent(maxPositions int, scaling float64, headDim int) Component {
+	positions := calculateRoPEPositions(maxPositions, scaling)
+	return Component{
+		Name:    "rope buffer",
+		Bytes:   2 * positions * headDim * ropeBufferBytes,
+		Formula: fmt.Sprintf("2 x %s positions x %d dims x %d bytes", FormatCount(positions), headDim, ropeBufferBytes),
+	}
+}
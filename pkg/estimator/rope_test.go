@@ -0,0 +1,26 @@
+package estimator
+
+import "testing"
+
+func TestRoPEScaling(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, HeadDim: 128, MaxPositions: 4096, RoPEScaling: 1}
+	trained, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.RoPEScaling = 4
+	scaled, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := 2 * 4096 * 128 * 4; componentBytes(trained, "rope buffer") != want {
+		t.Errorf("rope buffer for 4096 positions = %d bytes, want %d", componentBytes(trained, "rope buffer"), want)
+	}
+	if want := 4 * componentBytes(trained, "rope buffer"); componentBytes(scaled, "rope buffer") != want {
+		t.Errorf("rope buffer scaled 4x = %d bytes, want %d", componentBytes(scaled, "rope buffer"), want)
+	}
+	if scaled.Total <= trained.Total {
+		t.Errorf("total with RoPE scaled 4x = %d bytes, want more than %d", scaled.Total, trained.Total)
+	}
+}
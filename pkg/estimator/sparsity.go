@@ -0,0 +1,43 @@
+package estimator
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"regexp"
+	"strconv"
+)
+
+// ParseSparsity parses a structured sparsity pattern such as "2:4", where 2 values are
+// kept out of every group of 4, and returns the kept and group sizes.
+func ParseSparsity(pattern string) (kept, group int, err error) {
+	re := regexp.MustCompile(`^(\d+):(\d+)$`)
+
+	matches := re.FindStringSubmatch(pattern)
+	if matches == nil {
+		return 0, 0, errors.New("invalid sparsity; must be in the form N:M such as 2:4")
+	}
+
+	kept, _ = strconv.Atoi(matches[1])
+	group, _ = strconv.Atoi(matches[2])
+	if kept <= 0 || kept >= group {
+		return 0, 0, errors.New("invalid sparsity; N must be greater than 0 and less than M")
+	}
+
+	return kept, group, nil
+}
+
+// calculateSparseWeightMemory returns the memory for weights where the given fraction of
+// them is stored with N:M structured sparsity. Only the kept values of the sparse tensors
+// are stored, each with a small index recording its position within the group. The rest
+// of the weights stay dense.
+func calculateSparseWeightMemory(parameterSize int, precision Precision, kept, group int, fraction float32) int {
+	sparseParams := float64(parameterSize) * float64(fraction)
+	denseParams := float64(parameterSize) - sparseParams
+
+	keptParams := sparseParams * float64(kept) / float64(group)
+	indexBits := bits.Len(uint(group - 1))
+	indexBytes := keptParams * float64(indexBits) / 8
+
+	return bytesOf(denseParams+keptParams, precision) + int(math.Round(indexBytes))
+}
@@ -0,0 +1,40 @@
+package estimator
+
+import "testing"
+
+func TestSparseWeights(t *testing.T) {
+	kept, group, err := ParseSparsity("2:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2}
+	want, err := Calculate(dense)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fraction float32
+		weights  int
+	}{
+		// Half the values at 2 bytes each, plus a 2-bit index for each of them
+		{fraction: 1, weights: 3_500_000_000*2 + 3_500_000_000*2/8},
+		{fraction: 0.5, weights: 3_500_000_000*2 + 1_750_000_000*2 + 1_750_000_000*2/8},
+	}
+	for _, tt := range tests {
+		sparse := dense
+		sparse.SparseKept, sparse.SparseGroup, sparse.SparseFraction = kept, group, tt.fraction
+		got, err := Calculate(sparse)
+		if err != nil {
+			t.Errorf("Calculate with 2:4 sparsity on %g of the weights returned error: %v", tt.fraction, err)
+			continue
+		}
+		weights := componentBytes(got, "weights")
+		if weights != tt.weights {
+			t.Errorf("weights with 2:4 sparsity on %g of them = %d bytes, want %d", tt.fraction, weights, tt.weights)
+		}
+		if weights >= componentBytes(want, "weights") || got.Total >= want.Total {
+			t.Errorf("2:4 sparsity on %g of the weights needs %d bytes, want less than the %d of dense weights", tt.fraction, got.Total, want.Total)
+		}
+	}
+}
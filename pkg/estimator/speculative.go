@@ -0,0 +1,103 @@
+package estimator
+
+import "fmt"
+
+// logitBytes is the size of a single logit; logits are kept in fp32 regardless of the
+// precision of the weights.
+const logitBytes = 4
+
+// layerSkipComponents returns the extra memory needed for LayerSkip self-speculation.
+// The draft runs the early layers of the same model and exits through the shared LM
+// head, so the only new parameters are the early-exit normalization weights. The
+// transient cost is the fp32 logits produced for each drafted token.
+func layerSkipComponents(hiddenDim, vocabSize, draftTokens int, precision Precision) []Component {
+	return []Component{
+		{
+			Name:    "early-exit head",
+			Bytes:   CalculateWeightMemory(hiddenDim, precision),
+			Formula: weightFormula(hiddenDim, precision),
+		},
+		{
+			Name:    "early-exit logits",
+			Bytes:   draftTokens * vocabSize * logitBytes,
+			Formula: fmt.Sprintf("%d tokens x %s vocab x %d bytes", draftTokens, FormatCount(vocabSize), logitBytes),
+		},
+	}
+}
+
+// calculateMedusaHeadParameters returns the parameters of the Medusa heads. Each head is
+// a residual block of one hidden x hidden linear layer with a bias, followed by its own
+// projection to the vocabulary.
+func calculateMedusaHeadParameters(hiddenDim, vocabSize, heads int) int {
+	return heads * (hiddenDim*hiddenDim + hiddenDim + hiddenDim*vocabSize)
+}
+
+// medusaComponents returns the extra memory for Medusa speculative decoding on top of the
+// base model: the heads drafting candidate tokens and the transient memory for verifying
+// the tree of candidates in a single forward pass, its fp32 logits and KV cache entries.
+func medusaComponents(numLayers, kvDim, vocabSize, hiddenDim, heads, treeTokens int, precision, kvPrecision Precision) []Component {
+	headParams := calculateMedusaHeadParameters(hiddenDim, vocabSize, heads)
+	treeLogits := treeTokens * vocabSize * logitBytes
+	treeKV := calculateKVCacheMemory(numLayers, kvDim, treeTokens, 1, kvPrecision)
+
+	return []Component{
+		{
+			Name:    "medusa heads",
+			Bytes:   CalculateWeightMemory(headParams, precision),
+			Formula: weightFormula(headParams, precision),
+		},
+		{
+			Name:  "tree verification",
+			Bytes: treeLogits + treeKV,
+			Formula: fmt.Sprintf("%d tokens x %s vocab x %d bytes + 2 x %d layers x %d dims x %d tokens x %g bytes",
+				treeTokens, FormatCount(vocabSize), logitBytes, numLayers, kvDim, treeTokens, kvPrecision),
+		},
+	}
+}
+
+// DraftModel is the smaller model proposing tokens for a target model to verify in
+// speculative decoding. Its architecture sizes its own KV cache, which holds the same
+// context as the target's.
+type DraftModel struct {
+	ParameterSize int
+	Precision     Precision
+	NumLayers     int
+	HiddenDim     int
+	HeadDim       int
+	KVHeads       int
+}
+
+// draftComponents returns the weights of the draft model and, with a context, its KV
+// cache. Both models are resident at once, each caching every token of every sequence.
+// The draft's cache is kept at the KV precision when one is given, and otherwise at the
+// precision of its own weights.
+func (in ModelSpec) draftComponents() ([]Component, error) {
+	d := in.Draft
+	precision := d.Precision
+	if precision == 0 {
+		precision = in.Precision
+	}
+	components := []Component{{
+		Name:    "draft weights",
+		Bytes:   CalculateWeightMemory(d.ParameterSize, precision),
+		Formula: weightFormula(d.ParameterSize, precision),
+	}}
+	if in.ContextLength == 0 && len(in.KVBuckets) == 0 {
+		return components, nil
+	}
+
+	draft := in
+	draft.Precision = precision
+	draft.NumLayers = d.NumLayers
+	draft.HiddenDim = d.HiddenDim
+	draft.HeadDim = d.HeadDim
+	draft.KVHeads = d.KVHeads
+	draft.HeadDimMultiple = 0
+	draft.SlidingWindow = 0
+	kvCache, err := draft.KVCacheComponent()
+	if err != nil {
+		return nil, specError("draft model: %v", err)
+	}
+	kvCache.Name = "draft " + kvCache.Name
+	return append(components, kvCache), nil
+}
@@ -0,0 +1,68 @@
+package estimator
+
+import "testing"
+
+func TestLayerSkipOverhead(t *testing.T) {
+	base := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, VocabSize: 32000}
+	want, err := Calculate(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		draftTokens int
+		extra       int
+	}{
+		// The early-exit norm weights alone
+		{draftTokens: 0, extra: 4096 * 2},
+		// Plus the fp32 logits of each drafted token
+		{draftTokens: 4, extra: 4096*2 + 4*32000*4},
+		{draftTokens: 16, extra: 4096*2 + 16*32000*4},
+	}
+	for _, tt := range tests {
+		spec := base
+		spec.LayerSkip = true
+		spec.DraftTokens = tt.draftTokens
+		got, err := Calculate(spec)
+		if err != nil {
+			t.Errorf("Calculate with %d draft tokens returned error: %v", tt.draftTokens, err)
+			continue
+		}
+		if extra := got.Total - want.Total; extra != tt.extra {
+			t.Errorf("LayerSkip with %d draft tokens adds %d bytes, want %d", tt.draftTokens, extra, tt.extra)
+		}
+		if got.Total-want.Total > want.Total/100 {
+			t.Errorf("LayerSkip with %d draft tokens adds %d bytes, more than 1%% of %d", tt.draftTokens, got.Total-want.Total, want.Total)
+		}
+	}
+}
+
+func TestMedusaComponents(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, VocabSize: 32000, MedusaHeads: 4, MedusaTreeTokens: 64}
+	got, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heads := 4 * (4096*4096 + 4096 + 4096*32000) * 2
+	if bytes := componentBytes(got, "medusa heads"); bytes != heads {
+		t.Errorf("medusa heads = %d bytes, want %d", bytes, heads)
+	}
+	tree := 64*32000*4 + 2*32*4096*64*2
+	if bytes := componentBytes(got, "tree verification"); bytes != tree {
+		t.Errorf("tree verification = %d bytes, want %d", bytes, tree)
+	}
+
+	// With no overhead the total adds up the base model, the heads and the tree verification
+	spec.MedusaHeads, spec.MedusaTreeTokens = 0, 0
+	base, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if componentBytes(got, "base weights") != componentBytes(base, "weights") {
+		t.Errorf("base weights = %d bytes, want the %d of the weights without Medusa", componentBytes(got, "base weights"), componentBytes(base, "weights"))
+	}
+	if want := base.Total + heads + tree; got.Total != want {
+		t.Errorf("total with Medusa = %d bytes, want %d for the base, heads and tree verification", got.Total, want)
+	}
+}
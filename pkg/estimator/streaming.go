@@ -0,0 +1,17 @@
+package estimator
+
+// residentLayers returns how many transformer layers are held in GPU memory while the
+// rest of the weights are streamed in from host memory. Double-buffering keeps the next
+// layer resident while the current one runs, so its transfer overlaps with compute.
+func residentLayers(doubleBuffer bool) int {
+	if doubleBuffer {
+		return 2
+	}
+	return 1
+}
+
+// calculateStreamedWeightParameters returns the number of transformer weights resident at
+// once when streaming, assuming every layer holds an equal share of them.
+func calculateStreamedWeightParameters(blockParams, numLayers, resident int) int {
+	return blockParams / numLayers * min(resident, numLayers)
+}
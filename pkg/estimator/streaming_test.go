@@ -0,0 +1,26 @@
+package estimator
+
+import "testing"
+
+func TestDoubleBufferStreaming(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, StreamWeights: true}
+	single, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.DoubleBuffer = true
+	double, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each layer holds an equal share of the weights outside the norms
+	layerParams := (7_000_000_000 - CalculateNormParameters(32, 4096)) / 32
+	layer := CalculateWeightMemory(layerParams, 2)
+	if got := componentBytes(single, "weights (1 of 32 layers resident)"); got != layer {
+		t.Errorf("weights streamed a layer at a time = %d bytes, want %d", got, layer)
+	}
+	if got := double.Total - single.Total; got != layer {
+		t.Errorf("DoubleBuffer adds %d bytes, want the %d of one layer", got, layer)
+	}
+}
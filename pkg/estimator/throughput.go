@@ -0,0 +1,28 @@
+package estimator
+
+import "math"
+
+// CalculateThroughputGPUCount returns the number of GPUs needed to reach the target
+// throughput when each GPU generates the given number of tokens per second.
+func CalculateThroughputGPUCount(targetTokensPerSecond, tokensPerSecondPerGPU float64) int {
+	return int(math.Ceil(targetTokensPerSecond / tokensPerSecondPerGPU))
+}
+
+// BindingConstraint names whichever of memory or throughput needs more GPUs. Memory
+// wins ties since the model has to fit before it can serve anything.
+func BindingConstraint(memoryGPUs, throughputGPUs int) string {
+	if throughputGPUs > memoryGPUs {
+		return "throughput"
+	}
+	return "memory"
+}
+
+// EstimateDecodeTokensPerSecond returns a rough single-stream decode speed. Generating a
+// token reads every weight once, along with the KV cache of the sequence, so
+// autoregressive decoding is bound by how fast those bytes can be streamed from memory.
+func EstimateDecodeTokensPerSecond(bandwidthGBPerSecond float64, bytesPerToken int) float64 {
+	if bytesPerToken <= 0 {
+		return 0
+	}
+	return bandwidthGBPerSecond * 1_000_000_000 / float64(bytesPerToken)
+}
@@ -0,0 +1,35 @@
+package estimator
+
+import "fmt"
+
+// trainingComponents returns the memory full fine-tuning needs on top of the weights: a
+// gradient for every parameter, kept at the precision of the weights, and the states of
+// the optimizer, the Adam first and second moments unless given another one. Mixed
+// precision training computes with weights below fp32 but updates an fp32 master copy of
+// them, with fp32 optimizer states. Pure bf16 training updates the bf16 weights directly
+// and keeps the optimizer states in bf16 as well.
+func trainingComponents(parameterSize int, precision Precision, optimizer string, hiddenDim int, pureBF16 bool) ([]Component, error) {
+	stateBytes := fp32StateBytes
+	if pureBF16 {
+		stateBytes = bf16StateBytes
+	}
+	// The weight matrices are taken as square ones of the hidden dimension
+	states, err := optimizerStatesComponent(optimizer, parameterSize, hiddenDim, hiddenDim, stateBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	components := []Component{{
+		Name:    "gradients",
+		Bytes:   CalculateWeightMemory(parameterSize, precision),
+		Formula: weightFormula(parameterSize, precision),
+	}}
+	if !pureBF16 && precision < fp32StateBytes {
+		components = append(components, Component{
+			Name:    "master weights",
+			Bytes:   parameterSize * fp32StateBytes,
+			Formula: fmt.Sprintf("%s params x %d bytes (fp32)", FormatCount(parameterSize), fp32StateBytes),
+		})
+	}
+	return append(components, states), nil
+}
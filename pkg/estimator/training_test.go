@@ -0,0 +1,32 @@
+package estimator
+
+import "testing"
+
+func TestTrainingKeepsNoKVCache(t *testing.T) {
+	base := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096, BatchSize: 1, ContextLength: 512}
+	tests := []struct {
+		name string
+		spec func(ModelSpec) ModelSpec
+	}{
+		{name: "full fine-tuning", spec: func(in ModelSpec) ModelSpec { in.Train = true; return in }},
+		{name: "LoRA", spec: func(in ModelSpec) ModelSpec { in.LoRARank = 8; return in }},
+	}
+	for _, tt := range tests {
+		got, err := Calculate(tt.spec(base))
+		if err != nil {
+			t.Errorf("Calculate for %s returned error: %v", tt.name, err)
+			continue
+		}
+		if bytes := componentBytes(got, "kv cache"); bytes != -1 {
+			t.Errorf("%s with a context has a kv cache of %d bytes, want none", tt.name, bytes)
+		}
+		if componentBytes(got, "activations") <= 0 {
+			t.Errorf("%s with a context has no activations, want them kept for the backward pass", tt.name)
+		}
+	}
+
+	// Serving the same model still caches the context
+	if got, err := Calculate(base); err != nil || componentBytes(got, "kv cache") != 2*32*4096*512*2 {
+		t.Errorf("inference kv cache = %d bytes (%v), want %d", componentBytes(got, "kv cache"), err, 2*32*4096*512*2)
+	}
+}
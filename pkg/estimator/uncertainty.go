@@ -0,0 +1,23 @@
+package estimator
+
+import "strings"
+
+// uncertainBytes returns the part of the estimate that rests on assumptions rather than
+// the parameter count: the overhead and the KV cache, which depends on the workload.
+func (e Estimate) uncertainBytes() int {
+	bytes := e.Overhead
+	for _, c := range e.Components {
+		if strings.HasPrefix(c.Name, "kv cache") {
+			bytes += c.Bytes
+		}
+	}
+	return bytes
+}
+
+// UncertaintyRange returns the low and high ends of the estimate when the overhead and KV
+// cache may be off by the given percentage either way. The range is symmetric around the
+// total.
+func (e Estimate) UncertaintyRange(percent float64) (int, int) {
+	margin := int(float64(e.uncertainBytes()) * percent / 100)
+	return e.Total - margin, e.Total + margin
+}
@@ -0,0 +1,23 @@
+package estimator
+
+import "testing"
+
+func TestUncertaintyRange(t *testing.T) {
+	spec := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, Overhead: 20, NumLayers: 32, HiddenDim: 4096, BatchSize: 1, ContextLength: 4096}
+	e, err := Calculate(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the overhead and KV cache are uncertain, not the weights
+	uncertain := e.Overhead + componentBytes(e, "kv cache")
+	for _, percent := range []float64{0, 10, 25} {
+		low, high := e.UncertaintyRange(percent)
+		if low > e.Total || high < e.Total || e.Total-low != high-e.Total {
+			t.Errorf("UncertaintyRange(%g) = %d, %d, want it symmetric around %d", percent, low, high, e.Total)
+		}
+		if want := int(float64(uncertain) * percent / 100); high-e.Total != want {
+			t.Errorf("UncertaintyRange(%g) has a margin of %d bytes, want %d", percent, high-e.Total, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package estimator
+
+import "fmt"
+
+// SpecError is an invalid or inconsistent ModelSpec. Its message names the fields of the
+// spec at fault, such as "KVHeads requires HeadDim", so a caller taking them from its own
+// inputs can relate them back to those.
+type SpecError struct {
+	Message string
+}
+
+func (e *SpecError) Error() string {
+	return e.Message
+}
+
+// specError returns a SpecError with the formatted message
+func specError(format string, args ...any) error {
+	return &SpecError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Validate checks that the fields of the spec are in range and consistent with each
+// other, returning a SpecError for the first that isn't. Calculate validates the spec
+// before estimating it.
+func (in ModelSpec) Validate() error {
+	if in.Overhead < 0 {
+		return specError("invalid Overhead; must not be negative")
+	}
+	if in.ActiveParameters > in.ParameterSize {
+		return specError("ActiveParameters cannot exceed ParameterSize")
+	}
+	if in.ParameterSize > 0 && in.NumLayers > 0 && in.HiddenDim > 0 {
+		if minimum := in.minimumArchitectureParameters(); in.ParameterSize < minimum {
+			return specError("ParameterSize is too small for NumLayers and HiddenDim, which need at least %s parameters", FormatCount(minimum))
+		}
+	}
+	if in.FP8Scaling != "" && (in.NumLayers <= 0 || in.HiddenDim <= 0) {
+		return specError("FP8Scaling requires NumLayers and HiddenDim")
+	}
+	if (in.EmbeddingPrecision > 0 || in.HeadPrecision > 0) && (in.VocabSize <= 0 || in.HiddenDim <= 0) {
+		return specError("EmbeddingPrecision and HeadPrecision require VocabSize and HiddenDim")
+	}
+	if in.HeadPrecision > 0 && in.TiedEmbeddings {
+		return specError("HeadPrecision cannot be combined with TiedEmbeddings, which take EmbeddingPrecision")
+	}
+	if in.SparseGroup > 0 && (in.SparseFraction < 0 || in.SparseFraction > 1) {
+		return specError("invalid SparseFraction; must be between 0 and 1")
+	}
+	if in.DoubleBuffer && !in.StreamWeights {
+		return specError("DoubleBuffer requires StreamWeights")
+	}
+	if in.StreamWeights && in.NumLayers <= 0 {
+		return specError("StreamWeights requires NumLayers")
+	}
+
+	if in.Heads < 0 {
+		return specError("invalid Heads; must not be negative")
+	}
+	if in.Heads > 0 && (in.HiddenDim <= 0 || in.HiddenDim%in.Heads != 0) {
+		return specError("Heads requires HiddenDim, which must be a multiple of it")
+	}
+	if in.KVHeads < 0 {
+		return specError("invalid KVHeads; must not be negative")
+	}
+	if in.Heads > 0 && in.KVHeads > 0 && in.Heads%in.KVHeads != 0 {
+		return specError("invalid KVHeads; must divide Heads evenly")
+	}
+	if in.MaxPositions > 0 {
+		if in.HeadDim <= 0 {
+			return specError("MaxPositions requires HeadDim")
+		}
+		if in.RoPEScaling < 1 {
+			return specError("invalid RoPEScaling; must be at least 1")
+		}
+	}
+
+	if in.ContextLength > 0 || len(in.KVBuckets) > 0 {
+		if err := in.validateKVCache(); err != nil {
+			return err
+		}
+	}
+	if in.KVUtilization < 0 || in.KVUtilization > 1 {
+		return specError("invalid KVUtilization; must be between 0 and 1")
+	}
+	if in.BatchSize < 0 || (in.BatchSize == 0 && in.ContextLength > 0) {
+		return specError("invalid BatchSize; must be at least 1")
+	}
+
+	if in.LoRARank < 0 {
+		return specError("invalid LoRARank; must not be negative")
+	}
+	if in.Train && in.LoRARank > 0 {
+		return specError("LoRARank cannot be combined with Train, which trains every parameter")
+	}
+	training := in.Train || in.LoRARank > 0
+	if in.Optimizer != "" && !training {
+		return specError("Optimizer requires Train or LoRARank")
+	}
+	if in.PureBF16 && !in.Train {
+		return specError("PureBF16 requires Train")
+	}
+	if in.GradientCheckpointing && !training {
+		return specError("GradientCheckpointing requires Train or LoRARank")
+	}
+	if in.LoRARank > 0 && (in.NumLayers <= 0 || in.HiddenDim <= 0) {
+		return specError("LoRARank requires NumLayers and HiddenDim")
+	}
+
+	if in.LayerSkip && (in.HiddenDim <= 0 || in.VocabSize <= 0) {
+		return specError("LayerSkip requires HiddenDim and VocabSize")
+	}
+	if in.MedusaHeads > 0 && (in.NumLayers <= 0 || in.HiddenDim <= 0 || in.VocabSize <= 0) {
+		return specError("MedusaHeads requires NumLayers, HiddenDim and VocabSize")
+	}
+	if in.Draft.ParameterSize > 0 && (in.ContextLength > 0 || len(in.KVBuckets) > 0) && (in.Draft.NumLayers <= 0 || in.Draft.HiddenDim <= 0) {
+		return specError("a draft model with ContextLength requires Draft.NumLayers and Draft.HiddenDim")
+	}
+	if in.IndexVectors > 0 && in.IndexDim <= 0 {
+		return specError("IndexVectors requires IndexDim")
+	}
+
+	if in.PipelineParallel > 1 {
+		if in.Draft.ParameterSize > 0 {
+			return specError("a draft model cannot be combined with PipelineParallel")
+		}
+		if in.Vision.EncoderParameters > 0 {
+			return specError("a vision encoder cannot be combined with PipelineParallel")
+		}
+		if in.NumLayers <= 0 || in.HiddenDim <= 0 {
+			return specError("PipelineParallel requires NumLayers and HiddenDim")
+		}
+		if in.PipelineParallel > in.NumLayers {
+			return specError("invalid PipelineParallel; cannot exceed NumLayers")
+		}
+		if in.ZeROStage > 0 || in.FSDPStrategy != "" || in.StreamWeights {
+			return specError("PipelineParallel cannot be combined with ZeROStage, FSDPStrategy or StreamWeights")
+		}
+	}
+	if in.ZeROStage != 0 {
+		if in.ZeROStage < 1 || in.ZeROStage > 3 {
+			return specError("invalid ZeROStage; must be 1, 2 or 3")
+		}
+		if !in.Train {
+			return specError("ZeROStage requires Train")
+		}
+		if in.TensorParallel > 1 {
+			return specError("ZeROStage cannot be combined with TensorParallel")
+		}
+		if in.ZeROStage == 3 && in.NumLayers <= 0 {
+			return specError("ZeROStage 3 requires NumLayers to size the gather buffers")
+		}
+	}
+	if in.FSDPStrategy != "" {
+		if !in.Train {
+			return specError("FSDPStrategy requires Train")
+		}
+		if in.TensorParallel > 1 || in.ZeROStage > 0 {
+			return specError("FSDPStrategy cannot be combined with TensorParallel or ZeROStage")
+		}
+		if _, err := fsdpShardDegree(in.FSDPStrategy, max(in.DataParallel, 1), in.FSDPShardDegree); err != nil {
+			return err
+		}
+		if in.NumLayers <= 0 {
+			return specError("FSDPStrategy requires NumLayers to size the unsharded layer")
+		}
+	}
+	if in.OffloadOptimizer && !in.Train {
+		return specError("OffloadOptimizer requires Train")
+	}
+	return nil
+}
+
+// validateKVCache checks the fields the KV cache is sized from
+func (in ModelSpec) validateKVCache() error {
+	if in.NumLayers <= 0 || in.HiddenDim <= 0 {
+		return specError("ContextLength and KVBuckets require NumLayers and HiddenDim")
+	}
+	if in.KVHeads > 0 && in.HeadDim <= 0 {
+		return specError("KVHeads requires HeadDim")
+	}
+	if in.HeadDim > 0 && in.KVHeads <= 0 && in.HiddenDim%in.HeadDim != 0 {
+		return specError("HiddenDim must be a multiple of HeadDim")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package estimator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	base := ModelSpec{ParameterSize: 7_000_000_000, Precision: 2, NumLayers: 32, HiddenDim: 4096}
+	tests := []struct {
+		name   string
+		modify func(*ModelSpec)
+		want   string
+	}{
+		{name: "valid", modify: func(in *ModelSpec) { in.ContextLength, in.BatchSize = 2048, 1 }},
+		{name: "kv heads without head dim", modify: func(in *ModelSpec) { in.ContextLength, in.BatchSize, in.KVHeads = 2048, 1, 8 }, want: "KVHeads requires HeadDim"},
+		{name: "kv heads not dividing heads", modify: func(in *ModelSpec) { in.Heads, in.KVHeads = 32, 5 }, want: "invalid KVHeads; must divide Heads evenly"},
+		{name: "heads not dividing hidden dim", modify: func(in *ModelSpec) { in.Heads = 30 }, want: "Heads requires HiddenDim, which must be a multiple of it"},
+		{name: "kv utilization", modify: func(in *ModelSpec) { in.KVUtilization = 1.5 }, want: "invalid KVUtilization; must be between 0 and 1"},
+		{name: "sparse fraction", modify: func(in *ModelSpec) { in.SparseKept, in.SparseGroup, in.SparseFraction = 2, 4, 2 }, want: "invalid SparseFraction; must be between 0 and 1"},
+		{name: "batch size with context", modify: func(in *ModelSpec) { in.ContextLength = 2048 }, want: "invalid BatchSize; must be at least 1"},
+		{name: "optimizer without training", modify: func(in *ModelSpec) { in.Optimizer = "sgd" }, want: "Optimizer requires Train or LoRARank"},
+		{name: "size too small for the architecture", modify: func(in *ModelSpec) { in.NumLayers, in.HiddenDim = 80, 8192 }, want: "ParameterSize is too small for NumLayers and HiddenDim, which need at least 21,476,155,392 parameters"},
+		{name: "negative overhead", modify: func(in *ModelSpec) { in.Overhead = -200 }, want: "invalid Overhead; must not be negative"},
+		{name: "negative lora rank", modify: func(in *ModelSpec) { in.LoRARank = -8 }, want: "invalid LoRARank; must not be negative"},
+		{name: "zero stage", modify: func(in *ModelSpec) { in.Train, in.ZeROStage = true, 4 }, want: "invalid ZeROStage; must be 1, 2 or 3"},
+	}
+	for _, tt := range tests {
+		in := base
+		tt.modify(&in)
+		err := in.Validate()
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() returned error: %v", tt.name, err)
+			}
+			continue
+		}
+		var specErr *SpecError
+		if !errors.As(err, &specErr) || err.Error() != tt.want {
+			t.Errorf("%s: Validate() = %v, want SpecError %q", tt.name, err, tt.want)
+		}
+		if _, err := Calculate(in); err == nil {
+			t.Errorf("%s: Calculate() accepted a spec Validate rejects", tt.name)
+		}
+	}
+}
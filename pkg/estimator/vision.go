@@ -0,0 +1,46 @@
+package estimator
+
+// VisionTower is the image encoder and projector a vision-language model, such as LLaVA
+// or Qwen-VL, carries on top of its language model. The projector maps the encoder's
+// patch embeddings to image tokens, which take their place in the context of the
+// language model like any other token.
+type VisionTower struct {
+	EncoderParameters   int
+	ProjectorParameters int
+	Precision           Precision
+
+	// Tokens each image adds to the context, and images in each sequence
+	ImageTokens int
+	Images      int
+}
+
+// visionComponents returns the weights of the vision encoder and of the projector, kept at
+// their own precision when one is given and otherwise at that of the language model.
+// Frameworks keep a full copy of both on every GPU rather than sharding them.
+func (in ModelSpec) visionComponents() []Component {
+	v := in.Vision
+	precision := v.Precision
+	if precision == 0 {
+		precision = in.Precision
+	}
+	components := []Component{{
+		Name:       "vision encoder",
+		Bytes:      CalculateWeightMemory(v.EncoderParameters, precision),
+		Formula:    weightFormula(v.EncoderParameters, precision),
+		ShardLimit: 1,
+	}}
+	if v.ProjectorParameters > 0 {
+		components = append(components, Component{
+			Name:       "projector",
+			Bytes:      CalculateWeightMemory(v.ProjectorParameters, precision),
+			Formula:    weightFormula(v.ProjectorParameters, precision),
+			ShardLimit: 1,
+		})
+	}
+	return components
+}
+
+// imageTokens returns the tokens the images of each sequence add to its context
+func (v VisionTower) imageTokens() int {
+	return v.Images * v.ImageTokens
+}
@@ -0,0 +1,54 @@
+package estimator
+
+import "fmt"
+
+// zeroPrefetchLayers is the number of layers whose parameters ZeRO-3 holds gathered at
+// once: the layer being computed and the next one, prefetched to overlap the all-gather.
+const zeroPrefetchLayers = 2
+
+// partitionComponent returns the share of a component each GPU holds when ZeRO
+// partitions it across the data parallel ranks.
+func partitionComponent(c Component, degree int) Component {
+	return Component{
+		Name:    c.Name,
+		Bytes:   c.Bytes / degree,
+		Formula: fmt.Sprintf("(%s) / %d GPUs", c.Formula, degree),
+	}
+}
+
+// zeroComponents returns the training memory each GPU holds with ZeRO data parallelism
+// across the given degree. Each stage partitions more of the training state: stage 1 the
+// optimizer states and master weights, stage 2 the gradients as well, and stage 3 the parameters too, which
+// are gathered a layer at a time for the forward and backward passes. The parameters are
+// the first paramComponents of the components, the gradients and optimizer states
+// follow them.
+func zeroComponents(components []Component, paramComponents, stage, degree, numLayers, weightParams int, precision Precision) ([]Component, error) {
+	if stage < 1 || stage > 3 {
+		return nil, specError("invalid ZeROStage; must be 1, 2 or 3")
+	}
+	if stage == 3 && numLayers <= 0 {
+		return nil, specError("ZeROStage 3 requires NumLayers to size the gather buffers")
+	}
+
+	partitioned := make([]Component, 0, len(components)+1)
+	for i, c := range components {
+		switch {
+		case i < paramComponents && stage >= 3,
+			c.Name == "gradients" && stage >= 2,
+			c.Name == "optimizer states", c.Name == "master weights":
+			c = partitionComponent(c, degree)
+		}
+		partitioned = append(partitioned, c)
+	}
+
+	if stage == 3 {
+		layerParams := weightParams / numLayers
+		partitioned = append(partitioned, Component{
+			Name:  "zero-3 gather buffers",
+			Bytes: zeroPrefetchLayers * CalculateWeightMemory(layerParams, precision),
+			Formula: fmt.Sprintf("%d layers x %s params x %g bytes",
+				zeroPrefetchLayers, FormatCount(layerParams), precision),
+		})
+	}
+	return partitioned, nil
+}